@@ -32,6 +32,7 @@ func main() {
 	mux.Use(middleware.RequestID)
 	mux.Use(middleware.RealIP)
 	mux.Use(middleware.Recoverer)
+	mux.Use(serverMiddleware.RequestInfo)
 	mux.Use(serverMiddleware.GzipHandle)
 	mux.Use(serverMiddleware.LoggerMiddleware())
 
@@ -41,10 +42,45 @@ func main() {
 		os.Exit(1)
 	}
 
-	accrualService := service.NewAccrualService(cfg, repoRegistry)
+	if err = service.NewAdminService(cfg, repoRegistry).PromoteAdmins(ctx, cfg.AdminUsers); err != nil {
+		logging.NewLogger().Error().Err(err).Msg("failed grant admin role")
+	}
+
+	eventService := service.NewEventService(cfg, repoRegistry)
+	go eventService.Listen(ctx)
+
+	accrualService := service.NewAccrualService(cfg, repoRegistry, eventService)
 	go helpers.SetTicker(accrualService.Poller(ctx), 5*time.Second)
 
-	makeMetricRoutes(ctx, mux, cfg, repoRegistry)
+	webhookService := service.NewWebhookService(cfg, repoRegistry)
+	go helpers.SetTicker(webhookService.Poller(ctx), 5*time.Second)
+
+	tokenService := service.NewTokenService(cfg, repoRegistry)
+	go helpers.SetTicker(tokenService.Cleaner(ctx), time.Hour)
+
+	withdrawService := service.NewWithdrawService(cfg, repoRegistry, eventService)
+	go helpers.SetTicker(withdrawService.Cleaner(ctx), time.Minute)
+
+	pointsService := service.NewPointsService(cfg, repoRegistry, eventService)
+	go helpers.SetTicker(pointsService.Expirer(ctx), time.Hour)
+
+	tierService := service.NewTierService(cfg, repoRegistry)
+	go helpers.SetDailyTicker(tierService.Recalculator(ctx), cfg.TierRecalcAt)
+
+	reconciliationService := service.NewReconciliationService(cfg, repoRegistry, eventService)
+	go helpers.SetDailyTicker(reconciliationService.Reconciler(ctx), cfg.ReconciliationAt)
+
+	idempotencyService := service.NewIdempotencyService(cfg, repoRegistry)
+	go helpers.SetTicker(idempotencyService.Cleaner(ctx), time.Hour)
+
+	loginGuardService := service.NewLoginGuardService(cfg, repoRegistry)
+	go helpers.SetTicker(loginGuardService.Cleaner(ctx), time.Hour)
+
+	pepperReporter := service.NewUserService(cfg, repoRegistry).PepperReporter(ctx)
+	go pepperReporter()
+	go helpers.SetTicker(pepperReporter, 24*time.Hour)
+
+	makeMetricRoutes(ctx, mux, cfg, repoRegistry, eventService)
 
 	go func() {
 		err := http.ListenAndServe(cfg.Address, mux)
@@ -4,27 +4,105 @@ import (
 	"context"
 	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/handler"
+	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/internal/reporegistry"
 	"github.com/djokcik/gophermart/internal/service"
 	"github.com/djokcik/gophermart/pkg/middleware"
 	"github.com/go-chi/chi/v5"
 )
 
-func makeMetricRoutes(_ context.Context, mux *chi.Mux, cfg config.Config, registry reporegistry.RepoRegistry) *handler.Handler {
-	h := handler.NewHandler(mux, cfg, registry)
+func makeMetricRoutes(
+	_ context.Context,
+	mux *chi.Mux,
+	cfg config.Config,
+	registry reporegistry.RepoRegistry,
+	events service.EventService,
+) *handler.Handler {
+	h := handler.NewHandler(mux, cfg, registry, events)
+	idempotency := middleware.Idempotency(service.NewIdempotencyService(cfg, registry))
+
+	h.Get("/.well-known/jwks.json", h.JWKSHandler())
 
 	h.Route("/api/user", func(r chi.Router) {
 		r.Post("/register", h.RegisterUserHandler())
 		r.Post("/login", h.SignInHandler())
+		r.Post("/login/2fa", h.TwoFactorLoginHandler())
+		r.Post("/token/refresh", h.RefreshTokenHandler())
 
 		r.Route("/", func(r chi.Router) {
-			r.Use(middleware.UserContext(registry.GetUserRepo(), service.NewUserUtilsService(), cfg))
+			r.Use(middleware.UserContext(registry.GetUserRepo(), service.NewUserUtilsService(), service.NewTokenService(cfg, registry)))
+
+			r.Post("/logout", h.LogoutHandler())
+			r.Post("/password", h.ChangePasswordHandler())
+			r.Delete("/", h.DeleteUserHandler())
+
+			r.Post("/2fa/enroll", h.TwoFactorEnrollHandler())
+			r.Post("/2fa/verify", h.TwoFactorVerifyHandler())
+			r.Post("/2fa/disable", h.TwoFactorDisableHandler())
 
-			r.Post("/orders", h.UploadOrderHandler())
+			r.With(idempotency).Post("/orders", h.UploadOrderHandler())
+			r.With(idempotency).Post("/orders/batch", h.UploadOrdersBatchHandler())
 			r.Get("/orders", h.GetOrdersHandler())
+			r.Get("/orders/stream", h.OrdersStreamHandler())
 			r.Get("/balance", h.GetBalanceHandler())
-			r.Post("/balance/withdraw", h.WithdrawHandler())
+			r.Get("/profile", h.ProfileHandler())
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireTwoFactor(service.NewTwoFactorService(cfg, registry), cfg.WithdrawRequireTwoFactor))
+
+				r.With(idempotency).Post("/balance/withdraw", h.WithdrawHandler())
+				r.Post("/balance/withdraw/{id}/confirm", h.ConfirmWithdrawHandler())
+				r.With(idempotency).Post("/balance/transfer", h.TransferHandler())
+			})
 			r.Get("/withdrawals", h.WithdrawLogsHandler())
+			r.Get("/transfers", h.TransferLogsHandler())
+			r.Get("/referrals", h.ReferralsHandler())
+
+			r.Post("/webhooks", h.CreateWebhookHandler())
+			r.Get("/webhooks", h.WebhooksHandler())
+			r.Delete("/webhooks/{id}", h.DeleteWebhookHandler())
+			r.Get("/webhooks/{id}/deliveries", h.WebhookDeliveriesHandler())
+		})
+	})
+
+	h.Route("/api/admin", func(r chi.Router) {
+		r.Use(middleware.UserContext(registry.GetUserRepo(), service.NewUserUtilsService(), service.NewTokenService(cfg, registry)))
+		r.Use(middleware.RequireRole(model.RoleSupport, model.RoleAdmin))
+
+		r.Get("/users", h.AdminSearchUsersHandler())
+		r.Get("/users/{id}", h.AdminUserHandler())
+		r.Get("/users/{id}/orders", h.AdminUserOrdersHandler())
+		r.Get("/users/{id}/withdrawals", h.AdminUserWithdrawalsHandler())
+		r.Get("/users/{id}/balance", h.AdminUserBalanceHandler())
+		r.Get("/users/{id}/reconciliation", h.ReconciliationHandler())
+
+		r.Post("/users/{id}/adjustments", h.CreateAdjustmentHandler())
+		r.Get("/adjustments", h.AdjustmentsHandler())
+		r.Get("/orders/{number}/accrual", h.CheckAccrualHandler())
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(model.RoleAdmin))
+
+			r.Post("/users/{id}/lock", h.AdminLockUserHandler())
+			r.Post("/users/{id}/unlock", h.AdminUnlockUserHandler())
+			r.Put("/users/{id}/role", h.AdminSetRoleHandler())
+
+			r.Post("/adjustments/{id}/approve", h.ApproveAdjustmentHandler())
+			r.Post("/adjustments/{id}/reject", h.RejectAdjustmentHandler())
+
+			r.Post("/orders/{number}/reset", h.ResetOrderHandler())
+			r.Put("/orders/{number}/override", h.OverrideOrderHandler())
+
+			r.Post("/withdrawals/{id}/reverse", h.ReverseWithdrawHandler())
+
+			r.Get("/audit", h.AuditHandler())
+
+			r.Post("/reconciliation", h.RunReconciliationHandler())
+
+			r.Post("/campaigns", h.CreateCampaignHandler())
+			r.Get("/campaigns", h.CampaignsHandler())
+			r.Get("/campaigns/{id}", h.CampaignHandler())
+			r.Put("/campaigns/{id}", h.UpdateCampaignHandler())
+			r.Delete("/campaigns/{id}", h.DeleteCampaignHandler())
 		})
 	})
 
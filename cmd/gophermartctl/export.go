@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/djokcik/gophermart/internal/model"
+	"math"
+	"strconv"
+	"time"
+)
+
+const exportPageSize = 1000
+
+type (
+	// orderRecord and withdrawRecord keep the user, API models hide it
+	orderRecord struct {
+		Number     model.OrderID `json:"number"`
+		UserID     int           `json:"user_id"`
+		Status     model.Status  `json:"status"`
+		Accrual    model.Amount  `json:"accrual"`
+		UploadedAt time.Time     `json:"uploaded_at"`
+	}
+
+	withdrawRecord struct {
+		ID          int                  `json:"id"`
+		UserID      int                  `json:"user_id"`
+		Order       model.OrderID        `json:"order"`
+		Sum         model.Amount         `json:"sum"`
+		ReversedSum model.Amount         `json:"reversed_sum"`
+		Status      model.WithdrawStatus `json:"status,omitempty"`
+		ProcessedAt time.Time            `json:"processed_at"`
+	}
+)
+
+func (c *ctl) export(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "users":
+		users, err := c.registry.GetUserRepo().SearchUsers(ctx, "", math.MaxInt32)
+		if err != nil {
+			return err
+		}
+
+		return c.printUsers(users)
+	case "orders":
+		return c.exportOrders(ctx)
+	case "withdrawals":
+		return c.exportWithdrawals(ctx)
+	case "audit":
+		return c.exportAudit(ctx)
+	}
+
+	return errUsage
+}
+
+func (c *ctl) exportOrders(ctx context.Context) error {
+	records := make([]orderRecord, 0)
+	rows := make([][]string, 0)
+	for _, status := range []model.Status{model.StatusNew, model.StatusProcessing, model.StatusProcessed, model.StatusInvalid} {
+		orders, err := c.registry.GetOrderRepo().OrdersByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			record := orderRecord{
+				Number:     order.ID,
+				UserID:     order.UserID,
+				Status:     order.Status,
+				Accrual:    order.Accrual,
+				UploadedAt: time.Time(order.UploadedAt),
+			}
+
+			records = append(records, record)
+			rows = append(rows, []string{string(record.Number), strconv.Itoa(record.UserID), string(record.Status),
+				formatAmount(record.Accrual), formatTime(record.UploadedAt)})
+		}
+	}
+
+	return c.print(records, []string{"ORDER", "USER", "STATUS", "ACCRUAL", "UPLOADED"}, rows)
+}
+
+func (c *ctl) exportWithdrawals(ctx context.Context) error {
+	records := make([]withdrawRecord, 0)
+	rows := make([][]string, 0)
+	for afterID := 0; ; {
+		withdrawals, err := c.registry.GetWithdrawRepo().WithdrawLogs(ctx, afterID, exportPageSize)
+		if err != nil {
+			return err
+		}
+
+		for _, withdraw := range withdrawals {
+			record := withdrawRecord{
+				ID:          withdraw.ID,
+				UserID:      withdraw.UserID,
+				Order:       withdraw.OrderID,
+				Sum:         withdraw.Sum,
+				ReversedSum: withdraw.ReversedSum,
+				Status:      withdraw.Status,
+				ProcessedAt: time.Time(withdraw.ProcessedAt),
+			}
+
+			records = append(records, record)
+			rows = append(rows, []string{strconv.Itoa(record.ID), strconv.Itoa(record.UserID), string(record.Order),
+				formatAmount(record.Sum), formatAmount(record.ReversedSum), formatTime(record.ProcessedAt)})
+		}
+
+		if len(withdrawals) < exportPageSize {
+			break
+		}
+		afterID = withdrawals[len(withdrawals)-1].ID
+	}
+
+	return c.print(records, []string{"ID", "USER", "ORDER", "SUM", "REVERSED", "PROCESSED"}, rows)
+}
+
+func (c *ctl) exportAudit(ctx context.Context) error {
+	entries := make([]model.AuditEntry, 0)
+	rows := make([][]string, 0)
+	filter := model.AuditFilter{Limit: exportPageSize}
+	for {
+		page, err := c.registry.GetAuditRepo().AuditEntries(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range page {
+			details, err := json.Marshal(entry.Details)
+			if err != nil {
+				return err
+			}
+
+			entries = append(entries, entry)
+			rows = append(rows, []string{strconv.Itoa(entry.ID), strconv.Itoa(entry.ActorID), entry.Action,
+				entry.TargetType + ":" + entry.TargetID, entry.IP, entry.RequestID, string(details), formatTime(entry.CreatedAt)})
+		}
+
+		if len(page) < exportPageSize {
+			break
+		}
+		filter.BeforeID = page[len(page)-1].ID
+	}
+
+	return c.print(entries, []string{"ID", "ACTOR", "ACTION", "TARGET", "IP", "REQUEST ID", "DETAILS", "CREATED"}, rows)
+}
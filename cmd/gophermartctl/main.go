@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+const usage = `gophermartctl is the administrative tool of gophermart, it reads the same configuration as the server.
+
+Usage: gophermartctl [flags] <command> [arguments]
+
+Commands:
+  users list [-q query] [-limit n]          list users whose login contains the query
+  users lookup <id|login>                   show the user
+  balance <id|login>                        reconcile the balance with point lots and history of operations
+  reconcile [-correct]                      reconcile balances of all users, -correct requires -actor
+  orders requeue [-status s] [number...]    return orders to NEW for the accrual poller, requires -actor,
+                                            -status only counts the orders without -yes
+  migrate up|down [n]|force <v>|version     apply, roll back or show migrations of the schema
+  export users|orders|withdrawals|audit     dump the data
+
+Flags:
+`
+
+var errUsage = errors.New("invalid arguments, see gophermartctl -h")
+
+type ctl struct {
+	cfg      config.Config
+	registry reporegistry.RepoRegistry
+	out      io.Writer
+	format   string
+}
+
+func main() {
+	format := flag.String("o", formatTable, "output format: table or json")
+	actor := flag.String("actor", "", "login of the admin making changes, it is recorded to the audit log")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	cfg := config.NewConfig()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := checkFormat(*format); err != nil {
+		fail(err)
+	}
+
+	// logs go to stderr, so they don't mix with the output
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).Level(zerolog.WarnLevel).With().Timestamp().Logger()
+	ctx, cancel := signal.NotifyContext(logging.SetCtxLogger(context.Background(), logger),
+		syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	defer cancel()
+
+	c := &ctl{cfg: cfg, out: os.Stdout, format: *format}
+	if err := c.run(ctx, flag.Args(), *actor); err != nil {
+		fail(err)
+	}
+}
+
+func (c *ctl) run(ctx context.Context, args []string, actor string) error {
+	command, args := args[0], args[1:]
+
+	if command == "migrate" {
+		return c.migrate(args)
+	}
+
+	registry, err := reporegistry.OpenPostgreSQL(ctx, c.cfg)
+	if err != nil {
+		return err
+	}
+	c.registry = registry
+
+	return c.execute(ctx, command, args, actor)
+}
+
+// execute runs the command with the opened registry
+func (c *ctl) execute(ctx context.Context, command string, args []string, actor string) error {
+	if actor != "" {
+		var err error
+		if ctx, err = c.withActor(ctx, actor); err != nil {
+			return err
+		}
+	}
+
+	switch command {
+	case "users":
+		return c.users(ctx, args)
+	case "balance":
+		return c.balance(ctx, args)
+	case "reconcile":
+		return c.reconcile(ctx, args)
+	case "orders":
+		return c.orders(ctx, args)
+	case "export":
+		return c.export(ctx, args)
+	}
+
+	return fmt.Errorf("unknown command %q", command)
+}
+
+// withActor puts the admin to the context the same way as the auth middleware, so services audit the changes.
+// The request id tells entries of the tool from requests to the API.
+func (c *ctl) withActor(ctx context.Context, login string) (context.Context, error) {
+	user, err := c.registry.GetUserRepo().UserByUsername(ctx, login)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("actor %s not found", login)
+		}
+		return nil, err
+	}
+
+	if user.Role != model.RoleAdmin {
+		return nil, fmt.Errorf("actor %s is not an admin", login)
+	}
+
+	user.Username = login
+	ctx = appContext.WithUser(ctx, &user)
+
+	return appContext.WithRequestInfo(ctx, "", "gophermartctl-"+uuid.NewString()), nil
+}
+
+// userID accepts id or login of the user
+func (c *ctl) userID(ctx context.Context, arg string) (int, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		return id, nil
+	}
+
+	user, err := c.registry.GetUserRepo().UserByUsername(ctx, arg)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return 0, fmt.Errorf("user %s not found", arg)
+		}
+		return 0, err
+	}
+
+	return user.ID, nil
+}
+
+func checkFormat(format string) error {
+	if format != formatTable && format != formatJSON {
+		return fmt.Errorf("unknown output format %q", format)
+	}
+
+	return nil
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "gophermartctl: %s\n", err)
+	os.Exit(1)
+}
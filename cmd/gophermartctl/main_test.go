@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRegistry returns the mocked repositories, the tool must not touch the others
+type testRegistry struct {
+	reporegistry.RepoRegistry
+	users  *mocks.UserRepository
+	orders *mocks.OrderRepository
+	audit  *mocks.AuditRepository
+	events *mocks.EventRepository
+}
+
+func newTestRegistry() *testRegistry {
+	return &testRegistry{
+		users:  &mocks.UserRepository{Mock: mock.Mock{}},
+		orders: &mocks.OrderRepository{Mock: mock.Mock{}},
+		audit:  &mocks.AuditRepository{Mock: mock.Mock{}},
+		events: &mocks.EventRepository{Mock: mock.Mock{}},
+	}
+}
+
+func (r *testRegistry) GetUserRepo() storage.UserRepository         { return r.users }
+func (r *testRegistry) GetOrderRepo() storage.OrderRepository       { return r.orders }
+func (r *testRegistry) GetAuditRepo() storage.AuditRepository       { return r.audit }
+func (r *testRegistry) GetEventRepo() storage.EventRepository       { return r.events }
+func (r *testRegistry) GetWebhookRepo() storage.WebhookRepository   { return nil }
+func (r *testRegistry) GetWithdrawRepo() storage.WithdrawRepository { return nil }
+func (r *testRegistry) GetPointLotRepo() storage.PointLotRepository { return nil }
+
+func newTestCtl(registry *testRegistry, format string) (*ctl, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+
+	return &ctl{registry: registry, out: out, format: format}, out
+}
+
+func TestCheckFormat(t *testing.T) {
+	require.Equal(t, checkFormat(formatTable), nil)
+	require.Equal(t, checkFormat(formatJSON), nil)
+	require.NotEqual(t, checkFormat("yaml"), nil)
+}
+
+func Test_ctl_execute(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{name: "users without subcommand", args: []string{"users"}, err: errUsage},
+		{name: "unknown users subcommand", args: []string{"users", "drop"}, err: errUsage},
+		{name: "users lookup without user", args: []string{"users", "lookup"}, err: errUsage},
+		{name: "balance without user", args: []string{"balance"}, err: errUsage},
+		{name: "orders without subcommand", args: []string{"orders"}, err: errUsage},
+		{name: "export of two tables", args: []string{"export", "users", "orders"}, err: errUsage},
+		{name: "unknown export", args: []string{"export", "tokens"}, err: errUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, out := newTestCtl(newTestRegistry(), formatTable)
+
+			err := c.execute(context.Background(), tt.args[0], tt.args[1:], "")
+
+			require.Equal(t, err, tt.err)
+			require.Equal(t, out.Len(), 0)
+		})
+	}
+
+	t.Run("should reject unknown command", func(t *testing.T) {
+		c, _ := newTestCtl(newTestRegistry(), formatTable)
+
+		err := c.execute(context.Background(), "drop", nil, "")
+
+		require.Equal(t, err.Error(), `unknown command "drop"`)
+	})
+
+	t.Run("should reject invalid flag value", func(t *testing.T) {
+		c, _ := newTestCtl(newTestRegistry(), formatTable)
+
+		err := c.execute(context.Background(), "users", []string{"list", "-limit", "many"}, "")
+
+		require.NotEqual(t, err, nil)
+	})
+}
+
+func Test_ctl_users(t *testing.T) {
+	createdAt := time.Date(2021, 5, 1, 10, 0, 0, 0, time.UTC)
+	users := []model.UserSummary{
+		{ID: 1, Login: "anna", Role: model.RoleAdmin, Tier: "GOLD", Balance: 150050, CreatedAt: createdAt},
+		{ID: 2, Login: "hanna", Role: model.RoleUser, Tier: "BASIC", CreatedAt: createdAt, LockedAt: &createdAt, LockReason: "fraud"},
+	}
+
+	t.Run("should pass query and limit and print table", func(t *testing.T) {
+		registry := newTestRegistry()
+		registry.users.On("SearchUsers", mock.Anything, "ann", 5).Return(users, nil)
+
+		c, out := newTestCtl(registry, formatTable)
+
+		err := c.execute(context.Background(), "users", []string{"list", "-q", "ann", "-limit", "5"}, "")
+
+		require.Equal(t, err, nil)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Equal(t, len(lines), 3)
+		require.Equal(t, strings.Fields(lines[0]), []string{"ID", "LOGIN", "ROLE", "TIER", "BALANCE", "CREATED", "LOCKED", "LOCK", "REASON"})
+		require.Equal(t, strings.Fields(lines[1]), []string{"1", "anna", "admin", "GOLD", "1500.50", "2021-05-01T10:00:00Z", "-"})
+		require.Equal(t, strings.Fields(lines[2]), []string{"2", "hanna", "user", "BASIC", "0.00", "2021-05-01T10:00:00Z", "2021-05-01T10:00:00Z", "fraud"})
+	})
+
+	t.Run("should print json", func(t *testing.T) {
+		registry := newTestRegistry()
+		registry.users.On("SearchUsers", mock.Anything, "", 50).Return(users, nil)
+
+		c, out := newTestCtl(registry, formatJSON)
+
+		err := c.execute(context.Background(), "users", []string{"list"}, "")
+
+		require.Equal(t, err, nil)
+
+		var printed []model.UserSummary
+		require.Equal(t, json.Unmarshal(out.Bytes(), &printed), nil)
+		require.Equal(t, printed, users)
+	})
+}
+
+func Test_ctl_actor(t *testing.T) {
+	t.Run("should require actor for corrections of reconcile", func(t *testing.T) {
+		c, _ := newTestCtl(newTestRegistry(), formatTable)
+
+		err := c.execute(context.Background(), "reconcile", []string{"-correct"}, "")
+
+		require.Equal(t, err, errActorRequired)
+	})
+
+	t.Run("should require actor for orders requeue", func(t *testing.T) {
+		registry := newTestRegistry()
+
+		c, _ := newTestCtl(registry, formatTable)
+
+		err := c.execute(context.Background(), "orders", []string{"requeue", "12345678903"}, "")
+
+		require.Equal(t, err, errActorRequired)
+		registry.orders.AssertNotCalled(t, "OverrideOrder", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject actor who is not an admin", func(t *testing.T) {
+		registry := newTestRegistry()
+		registry.users.On("UserByUsername", mock.Anything, "support").Return(model.User{ID: 2, Role: model.RoleSupport}, nil)
+
+		c, _ := newTestCtl(registry, formatTable)
+
+		err := c.execute(context.Background(), "orders", []string{"requeue", "12345678903"}, "support")
+
+		require.Equal(t, err.Error(), "actor support is not an admin")
+	})
+
+	t.Run("should reject unknown actor", func(t *testing.T) {
+		registry := newTestRegistry()
+		registry.users.On("UserByUsername", mock.Anything, "ghost").Return(model.User{}, storage.ErrNotFound)
+
+		c, _ := newTestCtl(registry, formatTable)
+
+		err := c.execute(context.Background(), "orders", []string{"requeue", "12345678903"}, "ghost")
+
+		require.Equal(t, err.Error(), "actor ghost not found")
+	})
+}
+
+func Test_ctl_ordersRequeue(t *testing.T) {
+	t.Run("should only count orders of the status without -yes", func(t *testing.T) {
+		registry := newTestRegistry()
+		registry.users.On("UserByUsername", mock.Anything, "admin").Return(model.User{ID: 1, Role: model.RoleAdmin}, nil)
+		registry.orders.On("OrdersByStatus", mock.Anything, model.StatusProcessed).
+			Return([]model.Order{{ID: "1"}, {ID: "2"}}, nil)
+
+		c, out := newTestCtl(registry, formatTable)
+
+		err := c.execute(context.Background(), "orders", []string{"requeue", "-status", "PROCESSED"}, "admin")
+
+		require.Equal(t, err.Error(), "2 orders have status PROCESSED, add -yes to requeue them")
+		require.Equal(t, out.Len(), 0)
+		registry.orders.AssertNotCalled(t, "OverrideOrder", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should require numbers or status", func(t *testing.T) {
+		registry := newTestRegistry()
+		registry.users.On("UserByUsername", mock.Anything, "admin").Return(model.User{ID: 1, Role: model.RoleAdmin}, nil)
+
+		c, _ := newTestCtl(registry, formatTable)
+
+		err := c.execute(context.Background(), "orders", []string{"requeue"}, "admin")
+
+		require.Equal(t, err, errUsage)
+	})
+
+	t.Run("should reject unknown status", func(t *testing.T) {
+		registry := newTestRegistry()
+		registry.users.On("UserByUsername", mock.Anything, "admin").Return(model.User{ID: 1, Role: model.RoleAdmin}, nil)
+
+		c, _ := newTestCtl(registry, formatTable)
+
+		err := c.execute(context.Background(), "orders", []string{"requeue", "-status", "DONE", "-yes"}, "admin")
+
+		require.Equal(t, err.Error(), `unknown order status "DONE"`)
+	})
+
+	t.Run("should requeue orders of the status with -yes and print json", func(t *testing.T) {
+		registry := newTestRegistry()
+		registry.users.On("UserByUsername", mock.Anything, "admin").Return(model.User{ID: 1, Role: model.RoleAdmin}, nil)
+		registry.orders.On("OrdersByStatus", mock.Anything, model.StatusProcessing).
+			Return([]model.Order{{ID: "2"}}, nil)
+		registry.orders.On("OverrideOrder", mock.Anything, model.OrderID("1"), model.StatusNew, model.Amount(0), mock.Anything).
+			Return(model.OrderOverride{}, storage.ErrNotFound)
+		registry.orders.On("OverrideOrder", mock.Anything, model.OrderID("2"), model.StatusNew, model.Amount(0), mock.Anything).
+			Return(model.OrderOverride{
+				Order:          model.Order{ID: "2", UserID: 666, Status: model.StatusNew},
+				PreviousStatus: model.StatusProcessing,
+			}, nil)
+		registry.audit.On("AddAuditEntry", mock.Anything, mock.Anything).Return(nil)
+		registry.events.On("Notify", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		c, out := newTestCtl(registry, formatJSON)
+
+		err := c.execute(context.Background(), "orders", []string{"requeue", "-status", "PROCESSING", "-yes", "1"}, "admin")
+
+		require.Equal(t, err.Error(), "1 of 2 orders are not requeued")
+
+		var printed []requeueResult
+		require.Equal(t, json.Unmarshal(out.Bytes(), &printed), nil)
+		require.Equal(t, printed, []requeueResult{
+			{Number: "1", Error: storage.ErrNotFound.Error()},
+			{Number: "2", PreviousStatus: model.StatusProcessing},
+		})
+		registry.audit.AssertNumberOfCalls(t, "AddAuditEntry", 1)
+	})
+}
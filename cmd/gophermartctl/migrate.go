@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/golang-migrate/migrate/v4"
+	"strconv"
+)
+
+type migrationVersion struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
+}
+
+// migrate changes the schema and prints the resulting version, down rolls back one migration by default
+func (c *ctl) migrate(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	m, err := reporegistry.NewMigrator(c.cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errUsage
+		}
+
+		err = m.Up()
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return errUsage
+			}
+		} else if len(args) != 1 {
+			return errUsage
+		}
+
+		err = m.Steps(-steps)
+	case "force":
+		if len(args) != 2 {
+			return errUsage
+		}
+
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return errUsage
+		}
+
+		err = m.Force(version)
+	case "version":
+		if len(args) != 1 {
+			return errUsage
+		}
+	default:
+		return errUsage
+	}
+
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+
+	var current migrationVersion
+	current.Version, current.Dirty, err = m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+
+	return c.print(current, []string{"VERSION", "DIRTY"},
+		[][]string{{strconv.FormatUint(uint64(current.Version), 10), strconv.FormatBool(current.Dirty)}})
+}
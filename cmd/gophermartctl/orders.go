@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+)
+
+var errActorRequired = errors.New("-actor is required for changes")
+
+type requeueResult struct {
+	Number         model.OrderID `json:"number"`
+	PreviousStatus model.Status  `json:"previous_status,omitempty"`
+	BalanceDelta   model.Amount  `json:"balance_delta"`
+	Error          string        `json:"error,omitempty"`
+}
+
+func (c *ctl) orders(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "requeue" {
+		return errUsage
+	}
+
+	fs := flag.NewFlagSet("orders requeue", flag.ContinueOnError)
+	status := fs.String("status", "", "requeue every order with the status, e.g. PROCESSING")
+	yes := fs.Bool("yes", false, "confirm requeue of every order with the status")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if appContext.User(ctx) == nil {
+		return errActorRequired
+	}
+
+	ids := make([]model.OrderID, 0, fs.NArg())
+	for _, arg := range fs.Args() {
+		ids = append(ids, model.OrderID(arg))
+	}
+
+	if *status != "" {
+		if !model.Status(*status).Valid() {
+			return fmt.Errorf("unknown order status %q", *status)
+		}
+
+		orders, err := c.registry.GetOrderRepo().OrdersByStatus(ctx, model.Status(*status))
+		if err != nil {
+			return err
+		}
+
+		// the status may match every processed order, so the count is shown first
+		if !*yes {
+			return fmt.Errorf("%d orders have status %s, add -yes to requeue them", len(orders), *status)
+		}
+
+		for _, order := range orders {
+			ids = append(ids, order.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return errUsage
+	}
+
+	// ResetOrder takes back credited accrual and audits the reset like the admin API does
+	overrides := service.NewOrderOverrideService(c.cfg, c.registry, service.NewEventService(c.cfg, c.registry))
+
+	results := make([]requeueResult, 0, len(ids))
+	rows := make([][]string, 0, len(ids))
+	failed := 0
+	for _, id := range ids {
+		result := requeueResult{Number: id}
+
+		override, err := overrides.ResetOrder(ctx, id)
+		if err != nil {
+			result.Error = err.Error()
+			failed++
+		} else {
+			result.PreviousStatus, result.BalanceDelta = override.PreviousStatus, override.Delta
+		}
+
+		results = append(results, result)
+		rows = append(rows, []string{string(id), string(result.PreviousStatus), formatAmount(result.BalanceDelta), result.Error})
+	}
+
+	if err := c.print(results, []string{"ORDER", "PREVIOUS STATUS", "BALANCE DELTA", "ERROR"}, rows); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d orders are not requeued", failed, len(ids))
+	}
+
+	return nil
+}
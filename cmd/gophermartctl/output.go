@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/djokcik/gophermart/internal/model"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// print writes v as indented JSON or the rows as a table under the header
+func (c *ctl) print(v interface{}, header []string, rows [][]string) error {
+	if c.format == formatJSON {
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+func formatAmount(amount model.Amount) string {
+	return strconv.FormatFloat(float64(amount)/100, 'f', 2, 64)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return formatTime(*t)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"strconv"
+)
+
+var errBalanceMismatch = errors.New("balance doesn't match point lots or the ledger")
+
+var userHeader = []string{"ID", "LOGIN", "ROLE", "TIER", "BALANCE", "CREATED", "LOCKED", "LOCK REASON"}
+
+func (c *ctl) users(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+		query := fs.String("q", "", "part of the login, case is ignored")
+		limit := fs.Int("limit", 50, "maximum number of users")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		users, err := c.registry.GetUserRepo().SearchUsers(ctx, *query, *limit)
+		if err != nil {
+			return err
+		}
+
+		return c.printUsers(users)
+	case "lookup":
+		if len(args) != 2 {
+			return errUsage
+		}
+
+		id, err := c.userID(ctx, args[1])
+		if err != nil {
+			return err
+		}
+
+		user, err := c.registry.GetUserRepo().UserSummary(ctx, id)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				return fmt.Errorf("user %s not found", args[1])
+			}
+			return err
+		}
+
+		return c.print(user, userHeader, [][]string{userRow(user)})
+	}
+
+	return errUsage
+}
+
+func (c *ctl) printUsers(users []model.UserSummary) error {
+	rows := make([][]string, 0, len(users))
+	for _, user := range users {
+		rows = append(rows, userRow(user))
+	}
+
+	return c.print(users, userHeader, rows)
+}
+
+func userRow(user model.UserSummary) []string {
+	return []string{strconv.Itoa(user.ID), user.Login, string(user.Role), string(user.Tier), formatAmount(user.Balance),
+		formatTime(user.CreatedAt), formatOptionalTime(user.LockedAt), user.LockReason}
+}
+
+// balance prints the reconciliation and fails when the balance doesn't match, so scripts can check the exit code
+func (c *ctl) balance(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	id, err := c.userID(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	r, err := c.registry.GetReconciliationRepo().Reconciliation(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("user %s not found", args[0])
+		}
+		return err
+	}
+
+	rows := [][]string{
+		{"user", fmt.Sprintf("%d (%s)", r.UserID, r.Login)},
+		{"balance", formatAmount(r.Balance)},
+		{"point lots", formatAmount(r.Lots)},
+		{"ledger", formatAmount(r.Ledger)},
+		{"  accruals", formatAmount(r.Accruals)},
+		{"  bonuses", formatAmount(r.Bonuses)},
+		{"  transfers in", formatAmount(r.TransfersIn)},
+		{"  transfers out", formatAmount(-r.TransfersOut)},
+		{"  withdrawn", formatAmount(-r.Withdrawn)},
+		{"  reserved", formatAmount(-r.Reserved)},
+		{"  reversed", formatAmount(r.Reversed)},
+		{"  adjustments", formatAmount(r.Adjustments)},
+		{"  expired", formatAmount(-r.Expired)},
+		{"consistent", strconv.FormatBool(r.Consistent())},
+	}
+
+	if err = c.print(r, []string{"FIELD", "VALUE"}, rows); err != nil {
+		return err
+	}
+
+	if !r.Consistent() {
+		return errBalanceMismatch
+	}
+
+	return nil
+}
+
+// reconcile checks all users and fails while mismatches are left, so it can run from cron
+func (c *ctl) reconcile(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	correct := fs.Bool("correct", false, "write corrective adjustments")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *correct && appContext.User(ctx) == nil {
+		return errActorRequired
+	}
+
+	reconciliations := service.NewReconciliationService(c.cfg, c.registry, service.NewEventService(c.cfg, c.registry))
+
+	report, err := reconciliations.ReconcileAll(ctx, *correct)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(report.Mismatches))
+	for _, mismatch := range report.Mismatches {
+		balanceDelta, lotsDelta := "-", "-"
+		if mismatch.Correction != nil {
+			balanceDelta, lotsDelta = formatAmount(mismatch.Correction.BalanceDelta), formatAmount(mismatch.Correction.LotsDelta)
+		}
+
+		rows = append(rows, []string{strconv.Itoa(mismatch.UserID), mismatch.Login, formatAmount(mismatch.Balance),
+			formatAmount(mismatch.Lots), formatAmount(mismatch.Ledger), balanceDelta, lotsDelta, mismatch.Error})
+	}
+
+	header := []string{"USER", "LOGIN", "BALANCE", "LOTS", "LEDGER", "BALANCE DELTA", "LOTS DELTA", "ERROR"}
+	if err = c.print(report, header, rows); err != nil {
+		return err
+	}
+
+	if left := len(report.Mismatches) - report.Corrected; left > 0 {
+		return fmt.Errorf("%d of %d balances don't match", left, report.Checked)
+	}
+
+	return nil
+}
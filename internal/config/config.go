@@ -1,9 +1,28 @@
 package config
 
 import (
+	"errors"
 	"flag"
 	"github.com/caarlos0/env/v6"
+	"github.com/djokcik/gophermart/pkg/keyring"
 	"github.com/djokcik/gophermart/pkg/logging"
+	"strings"
+	"time"
+)
+
+const (
+	EnvDev        = "dev"
+	EnvProduction = "production"
+
+	LoginAttemptStorePostgres = "postgres"
+	LoginAttemptStoreMemory   = "memory"
+
+	// WithdrawOrderShared allows to upload for accrual the order number paid with points
+	WithdrawOrderShared = "shared"
+	// WithdrawOrderExclusive keeps numbers of withdrawals and accrual orders apart
+	WithdrawOrderExclusive = "exclusive"
+
+	defaultKey = "SecretKey"
 )
 
 type Config struct {
@@ -11,24 +30,193 @@ type Config struct {
 	AccrualSystemAddress string `env:"ACCRUAL_SYSTEM_ADDRESS"`
 	DatabaseURI          string `env:"DATABASE_URI"`
 	Key                  string `env:"KEY"`
-	PasswordPepper       string `env:"PASSWORD_PEPPER"`
+	WebhookMaxAttempts   int    `env:"WEBHOOK_MAX_ATTEMPTS"`
+
+	// MigrationsPath is the source of schema migrations, relative file paths start from the working directory
+	MigrationsPath string `env:"MIGRATIONS_PATH"`
+
+	PasswordPepper   string `env:"PASSWORD_PEPPER"`
+	PasswordPepperID string `env:"PASSWORD_PEPPER_ID"`
+	// PasswordOldPeppers are previous peppers as "id:pepper", kept until users are rehashed on login
+	PasswordOldPeppers []string `env:"PASSWORD_OLD_PEPPERS"`
+
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL"`
+
+	LoginMaxFailures   int           `env:"LOGIN_MAX_FAILURES"`
+	LoginIPMaxFailures int           `env:"LOGIN_IP_MAX_FAILURES"`
+	LoginFailureWindow time.Duration `env:"LOGIN_FAILURE_WINDOW"`
+	LoginLockout       time.Duration `env:"LOGIN_LOCKOUT"`
+	// LoginAttemptStore is "postgres" to share counters between instances or "memory"
+	LoginAttemptStore string `env:"LOGIN_ATTEMPT_STORE"`
+
+	// WithdrawRequireTwoFactor denies withdrawals to users without the second factor
+	WithdrawRequireTwoFactor bool `env:"WITHDRAW_REQUIRE_2FA"`
+	// WithdrawConfirmThreshold is sum in points above which withdrawal waits for password or 2fa code, 0 disables
+	WithdrawConfirmThreshold float64       `env:"WITHDRAW_CONFIRM_THRESHOLD"`
+	WithdrawConfirmTTL       time.Duration `env:"WITHDRAW_CONFIRM_TTL"`
+	// WithdrawOrderPolicy is "shared" or "exclusive"
+	WithdrawOrderPolicy string `env:"WITHDRAW_ORDER_POLICY"`
+
+	// AdminUsers are usernames granted admin role on start, other roles are managed with /api/admin
+	AdminUsers []string `env:"ADMIN_USERS"`
+
+	// TransferDailyLimit is sum in points a user can transfer to other users per day, 0 disables
+	TransferDailyLimit float64 `env:"TRANSFER_DAILY_LIMIT"`
+
+	// ReferralBonus is sum in points credited to both referrer and referee, 0 disables rewards
+	ReferralBonus float64 `env:"REFERRAL_BONUS"`
+	// ReferralMaxRewards is the number of referrals rewarding one referrer, 0 disables the cap
+	ReferralMaxRewards int `env:"REFERRAL_MAX_REWARDS"`
+
+	// AdjustmentThreshold is sum in points above which manual adjustment waits for approval of another admin
+	AdjustmentThreshold float64 `env:"ADJUSTMENT_APPROVAL_THRESHOLD"`
+	// AdjustmentWindow is how long adjustments applied without approval count towards the threshold,
+	// so splitting a sum into small adjustments doesn't skip the approval
+	AdjustmentWindow time.Duration `env:"ADJUSTMENT_APPROVAL_WINDOW"`
+
+	// ReconciliationAt is time of day in UTC when balances are reconciled with point lots and the ledger
+	ReconciliationAt time.Duration `env:"RECONCILIATION_AT"`
+	// ReconciliationCorrect lets the scheduled reconciliation write corrective adjustments, otherwise it only reports
+	ReconciliationCorrect bool `env:"RECONCILIATION_CORRECT"`
+
+	// TierWindow is the rolling window of accruals which decides the loyalty tier
+	TierWindow time.Duration `env:"TIER_WINDOW"`
+	// TierRecalcAt is time of day in UTC when tiers are recalculated, e.g. 3h
+	TierRecalcAt time.Duration `env:"TIER_RECALC_AT"`
+
+	// PointsLifetimeMonths is how long accrued points can be spent
+	PointsLifetimeMonths int `env:"POINTS_LIFETIME_MONTHS"`
+	// PointsExpiringWindow is how far ahead balance reports points which are about to expire
+	PointsExpiringWindow time.Duration `env:"POINTS_EXPIRING_WINDOW"`
+
+	// IdempotencyKeyTTL is how long responses are replayed for retries with the same Idempotency-Key
+	IdempotencyKeyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL"`
+
+	// Env is "production" unless APP_ENV=dev is set explicitly, default secrets are refused outside dev
+	Env string `env:"APP_ENV"`
+	// JWTKeysFile is JSON list of signing keys, when empty tokens are signed by Key with HS256
+	JWTKeysFile     string `env:"JWT_KEYS_FILE"`
+	JWTSigningKeyID string `env:"JWT_SIGNING_KEY_ID"`
+
+	Keys *keyring.Keyring
 }
 
 func NewConfig() Config {
 	cfg := Config{
 		Address:              "127.0.0.1:8080",
 		AccrualSystemAddress: "http://127.0.0.1:8082",
-		Key:                  "SecretKey",
+		Key:                  defaultKey,
 		PasswordPepper:       "pepper",
+		PasswordPepperID:     "1",
 		DatabaseURI:          "postgres://localhost:5432/gophermart?sslmode=disable",
+		MigrationsPath:       "file://internal/storage/psql/migrations",
+		WebhookMaxAttempts:   8,
+		AccessTokenTTL:       15 * time.Minute,
+		RefreshTokenTTL:      30 * 24 * time.Hour,
+		LoginMaxFailures:     5,
+		LoginIPMaxFailures:   50,
+		LoginFailureWindow:   15 * time.Minute,
+		LoginLockout:         15 * time.Minute,
+		LoginAttemptStore:    LoginAttemptStorePostgres,
+		WithdrawConfirmTTL:   15 * time.Minute,
+		WithdrawOrderPolicy:  WithdrawOrderShared,
+		IdempotencyKeyTTL:    24 * time.Hour,
+		PointsLifetimeMonths: 12,
+		TransferDailyLimit:   5000,
+		ReferralBonus:        100,
+		ReferralMaxRewards:   20,
+		TierWindow:           90 * 24 * time.Hour,
+		AdjustmentThreshold:  1000,
+		AdjustmentWindow:     24 * time.Hour,
+		TierRecalcAt:         3 * time.Hour,
+		ReconciliationAt:     4 * time.Hour,
+		PointsExpiringWindow: 30 * 24 * time.Hour,
+		Env:                  EnvProduction,
 	}
 
 	cfg.parseFlags()
 	cfg.parseEnv()
+	cfg.loadKeys()
+	cfg.checkPeppers()
+	cfg.checkWithdrawOrderPolicy()
 
 	return cfg
 }
 
+// PointsExpireAt returns expiry of points credited at t
+func (cfg Config) PointsExpireAt(t time.Time) time.Time {
+	return t.AddDate(0, cfg.PointsLifetimeMonths, 0)
+}
+
+func (cfg *Config) loadKeys() {
+	keys, err := cfg.newKeyring()
+	if err != nil {
+		logging.NewLogger().Fatal().Err(err).Msg("error load jwt keys")
+	}
+
+	cfg.Keys = keys
+}
+
+func (cfg Config) newKeyring() (*keyring.Keyring, error) {
+	if cfg.JWTKeysFile == "" {
+		if cfg.Env != EnvDev && cfg.Key == defaultKey {
+			return nil, errors.New("default jwt secret key is allowed only in dev environment")
+		}
+
+		return keyring.NewHMAC(cfg.Key)
+	}
+
+	keys, err := keyring.LoadFile(cfg.JWTKeysFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if cfg.Env != EnvDev && string(key.Secret) == defaultKey {
+			return nil, errors.New("default jwt secret key is allowed only in dev environment")
+		}
+	}
+
+	signingID := cfg.JWTSigningKeyID
+	if signingID == "" && len(keys) > 0 {
+		signingID = keys[0].ID
+	}
+
+	return keyring.New(signingID, keys...)
+}
+
+// Pepper returns pepper by id, hashes are verified with the pepper they were created with
+func (cfg Config) Pepper(id string) (string, bool) {
+	if id == cfg.PasswordPepperID {
+		return cfg.PasswordPepper, true
+	}
+
+	for _, old := range cfg.PasswordOldPeppers {
+		parts := strings.SplitN(old, ":", 2)
+		if len(parts) == 2 && parts[0] == id {
+			return parts[1], true
+		}
+	}
+
+	return "", false
+}
+
+func (cfg Config) checkPeppers() {
+	for _, old := range cfg.PasswordOldPeppers {
+		parts := strings.SplitN(old, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[0] == cfg.PasswordPepperID {
+			logging.NewLogger().Fatal().Msg("invalid PASSWORD_OLD_PEPPERS, expected id:pepper with id other than current")
+		}
+	}
+}
+
+func (cfg Config) checkWithdrawOrderPolicy() {
+	if cfg.WithdrawOrderPolicy != WithdrawOrderShared && cfg.WithdrawOrderPolicy != WithdrawOrderExclusive {
+		logging.NewLogger().Fatal().Msg("invalid WITHDRAW_ORDER_POLICY, expected shared or exclusive")
+	}
+}
+
 func (cfg *Config) parseEnv() {
 	err := env.Parse(cfg)
 	if err != nil {
@@ -41,6 +229,7 @@ func (cfg *Config) parseFlags() {
 	flag.StringVar(&cfg.DatabaseURI, "d", cfg.DatabaseURI, "Database uri")
 	flag.StringVar(&cfg.AccrualSystemAddress, "r", cfg.AccrualSystemAddress, "accrual system address")
 	flag.StringVar(&cfg.Key, "k", cfg.Key, "jwt secret key")
+	flag.StringVar(&cfg.JWTKeysFile, "jwt-keys", cfg.JWTKeysFile, "jwt keys file")
 
 	flag.Parse()
 }
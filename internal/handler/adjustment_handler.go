@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"net/http"
+	"strconv"
+)
+
+// CreateAdjustmentHandler credits or debits the user, the response status shows whether it waits for approval
+func (h *Handler) CreateAdjustmentHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "CreateAdjustmentHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		var adjustmentDto model.AdjustmentRequestDto
+		if err := json.NewDecoder(r.Body).Decode(&adjustmentDto); err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err := adjustmentDto.Validate(); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		adjustment, err := h.adjustment.CreateAdjustment(ctx, userID, adjustmentDto)
+		if err != nil {
+			writeAdjustmentError(rw, logger, err)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusCreated)
+
+		bytes, _ := json.Marshal(adjustment)
+		rw.Write(bytes)
+	}
+}
+
+// AdjustmentsHandler lists adjustments, optionally filtered by "status"
+func (h *Handler) AdjustmentsHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdjustmentsHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		adjustments, err := h.adjustment.Adjustments(ctx, model.AdjustmentStatus(r.URL.Query().Get("status")))
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid find adjustments")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		writeAdminList(rw, len(adjustments), adjustments)
+	}
+}
+
+func (h *Handler) ApproveAdjustmentHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "ApproveAdjustmentHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(rw, "invalid adjustment id", http.StatusBadRequest)
+			return
+		}
+
+		adjustment, err := h.adjustment.ApproveAdjustment(ctx, id)
+		if err != nil {
+			writeAdjustmentError(rw, logger, err)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(adjustment)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) RejectAdjustmentHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "RejectAdjustmentHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(rw, "invalid adjustment id", http.StatusBadRequest)
+			return
+		}
+
+		adjustment, err := h.adjustment.RejectAdjustment(ctx, id)
+		if err != nil {
+			writeAdjustmentError(rw, logger, err)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(adjustment)
+		rw.Write(bytes)
+	}
+}
+
+func writeAdjustmentError(rw http.ResponseWriter, logger zerolog.Logger, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(rw, "not found", http.StatusNotFound)
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		http.Error(rw, "insufficient funds", http.StatusPaymentRequired)
+	case errors.Is(err, storage.ErrAdjustmentDecided):
+		http.Error(rw, "adjustment already decided", http.StatusConflict)
+	case errors.Is(err, service.ErrSelfApproval):
+		http.Error(rw, "adjustment must be approved by another admin", http.StatusForbidden)
+	case errors.Is(err, service.ErrAdminSelfAction):
+		http.Error(rw, "adjustment of own account is not allowed", http.StatusUnprocessableEntity)
+	case errors.Is(err, service.ErrNotAuthenticated):
+		http.Error(rw, "user not found", http.StatusUnauthorized)
+	default:
+		logger.Error().Err(err).Msg("invalid adjustment")
+		http.Error(rw, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_CreateAdjustmentHandler(t *testing.T) {
+	t.Run("should create pending adjustment", func(t *testing.T) {
+		createdAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		m := mocks.AdjustmentService{Mock: mock.Mock{}}
+		m.On("CreateAdjustment", mock.Anything, 666, model.AdjustmentRequestDto{Sum: -150050, ReasonCode: "FRAUD", Comment: "chargeback"}).
+			Return(model.Adjustment{
+				ID: 7, UserID: 666, Sum: -150050, ReasonCode: "FRAUD", Comment: "chargeback",
+				Status: model.AdjustmentPending, CreatedBy: 1, CreatedAt: createdAt,
+			}, nil)
+
+		body := bytes.NewReader([]byte(`{"sum":-1500.5,"reason_code":"FRAUD","comment":"chargeback"}`))
+		request := httptest.NewRequest(http.MethodPost, "/admin/users/666/adjustments", body)
+
+		h := Handler{adjustment: &m, Mux: chi.NewMux()}
+		h.Post("/admin/users/{id}/adjustments", h.CreateAdjustmentHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusCreated)
+		require.Equal(t, string(resBody), `{"id":7,"user_id":666,"sum":-1500.5,"reason_code":"FRAUD","comment":"chargeback",`+
+			`"status":"PENDING","created_by":1,"created_at":"2022-01-01T00:00:00Z"}`)
+	})
+	t.Run("should reject unknown reason code", func(t *testing.T) {
+		m := mocks.AdjustmentService{Mock: mock.Mock{}}
+
+		body := bytes.NewReader([]byte(`{"sum":10,"reason_code":"BIRTHDAY","comment":"gift"}`))
+		request := httptest.NewRequest(http.MethodPost, "/admin/users/666/adjustments", body)
+
+		h := Handler{adjustment: &m, Mux: chi.NewMux()}
+		h.Post("/admin/users/{id}/adjustments", h.CreateAdjustmentHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+		m.AssertNotCalled(t, "CreateAdjustment", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandler_ApproveAdjustmentHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{name: "should return 403 for the author", err: service.ErrSelfApproval, statusCode: http.StatusForbidden},
+		{name: "should return 422 for the adjusted user", err: service.ErrAdminSelfAction, statusCode: http.StatusUnprocessableEntity},
+		{name: "should return 409 for decided adjustment", err: storage.ErrAdjustmentDecided, statusCode: http.StatusConflict},
+		{name: "should return 402 for insufficient funds", err: storage.ErrInsufficientFunds, statusCode: http.StatusPaymentRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.AdjustmentService{Mock: mock.Mock{}}
+			m.On("ApproveAdjustment", mock.Anything, 7).Return(model.Adjustment{}, tt.err)
+
+			request := httptest.NewRequest(http.MethodPost, "/admin/adjustments/7/approve", nil)
+
+			h := Handler{adjustment: &m, Mux: chi.NewMux()}
+			h.Post("/admin/adjustments/{id}/approve", h.ApproveAdjustmentHandler())
+
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, res.StatusCode, tt.statusCode)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	adminSearchDefaultLimit = 50
+	adminSearchMaxLimit     = 200
+)
+
+// AdminSearchUsersHandler finds users by part of the login given in "query", "limit" is optional
+func (h *Handler) AdminSearchUsersHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdminSearchUsersHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		limit := adminSearchDefaultLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 || parsed > adminSearchMaxLimit {
+				http.Error(rw, "invalid limit", http.StatusBadRequest)
+				return
+			}
+
+			limit = parsed
+		}
+
+		users, err := h.admin.SearchUsers(ctx, r.URL.Query().Get("query"), limit)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid search users")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		writeAdminList(rw, len(users), users)
+	}
+}
+
+func (h *Handler) AdminUserHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdminUserHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		user, err := h.admin.User(ctx, userID)
+		if err != nil {
+			writeAdminError(rw, logger, err)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(user)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) AdminUserOrdersHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdminUserOrdersHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		orders, err := h.admin.UserOrders(ctx, userID)
+		if err != nil {
+			writeAdminError(rw, logger, err)
+			return
+		}
+
+		writeAdminList(rw, len(orders), orders)
+	}
+}
+
+func (h *Handler) AdminUserWithdrawalsHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdminUserWithdrawalsHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		withdrawals, err := h.admin.UserWithdrawals(ctx, userID)
+		if err != nil {
+			writeAdminError(rw, logger, err)
+			return
+		}
+
+		writeAdminList(rw, len(withdrawals), withdrawals)
+	}
+}
+
+func (h *Handler) AdminUserBalanceHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdminUserBalanceHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		balance, err := h.admin.UserBalance(ctx, userID)
+		if err != nil {
+			writeAdminError(rw, logger, err)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(balance)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) AdminLockUserHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdminLockUserHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		var lockDto model.LockUserDto
+		if err := json.NewDecoder(r.Body).Decode(&lockDto); err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err := lockDto.Validate(); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.admin.LockUser(ctx, userID, lockDto.Reason); err != nil {
+			writeAdminError(rw, logger, err)
+			return
+		}
+
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *Handler) AdminUnlockUserHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdminUnlockUserHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		if err := h.admin.UnlockUser(ctx, userID); err != nil {
+			writeAdminError(rw, logger, err)
+			return
+		}
+
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *Handler) AdminSetRoleHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AdminSetRoleHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		var roleDto model.RoleDto
+		if err := json.NewDecoder(r.Body).Decode(&roleDto); err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err := roleDto.Role.Validate(); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.admin.SetRole(ctx, userID, roleDto.Role); err != nil {
+			writeAdminError(rw, logger, err)
+			return
+		}
+
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+func adminUserID(rw http.ResponseWriter, r *http.Request, logger zerolog.Logger) (int, bool) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		logger.Trace().Err(err).Msg("invalid user id")
+		http.Error(rw, "invalid user id", http.StatusBadRequest)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func writeAdminError(rw http.ResponseWriter, logger zerolog.Logger, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(rw, "user not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrAdminSelfAction):
+		http.Error(rw, "action on own account is not allowed", http.StatusUnprocessableEntity)
+	default:
+		logger.Error().Err(err).Msg("invalid admin action")
+		http.Error(rw, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// writeAdminList responds like the user endpoints, with 204 for an empty list
+func writeAdminList(rw http.ResponseWriter, size int, list interface{}) {
+	if size == 0 {
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	bytes, _ := json.Marshal(list)
+	rw.Write(bytes)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_AdminSearchUsersHandler(t *testing.T) {
+	t.Run("should return found users", func(t *testing.T) {
+		createdAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		m := mocks.AdminService{Mock: mock.Mock{}}
+		m.On("SearchUsers", mock.Anything, "ali", 10).Return([]model.UserSummary{
+			{ID: 1, Login: "alice", Role: model.RoleUser, Tier: model.TierBasic, Balance: 1050, CreatedAt: createdAt},
+		}, nil)
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users?query=ali&limit=10", nil)
+
+		h := Handler{admin: &m, Mux: chi.NewMux()}
+		h.Get("/admin/users", h.AdminSearchUsersHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody),
+			`[{"id":1,"login":"alice","role":"user","tier":"BASIC","balance":10.5,"created_at":"2022-01-01T00:00:00Z"}]`)
+	})
+	t.Run("should reject invalid limit", func(t *testing.T) {
+		m := mocks.AdminService{Mock: mock.Mock{}}
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users?limit=1000", nil)
+
+		h := Handler{admin: &m, Mux: chi.NewMux()}
+		h.Get("/admin/users", h.AdminSearchUsersHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+		m.AssertNotCalled(t, "SearchUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandler_AdminUserOrdersHandler(t *testing.T) {
+	t.Run("should return 404 for unknown user", func(t *testing.T) {
+		m := mocks.AdminService{Mock: mock.Mock{}}
+		m.On("UserOrders", mock.Anything, 666).Return(nil, storage.ErrNotFound)
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/666/orders", nil)
+
+		h := Handler{admin: &m, Mux: chi.NewMux()}
+		h.Get("/admin/users/{id}/orders", h.AdminUserOrdersHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestHandler_AdminLockUserHandler(t *testing.T) {
+	t.Run("should lock user", func(t *testing.T) {
+		m := mocks.AdminService{Mock: mock.Mock{}}
+		m.On("LockUser", mock.Anything, 666, "fraud").Return(nil)
+
+		body := bytes.NewReader([]byte(`{"reason":"fraud"}`))
+		request := httptest.NewRequest(http.MethodPost, "/admin/users/666/lock", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 1, Role: model.RoleAdmin}))
+
+		h := Handler{admin: &m, Mux: chi.NewMux()}
+		h.Post("/admin/users/{id}/lock", h.AdminLockUserHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertNumberOfCalls(t, "LockUser", 1)
+		require.Equal(t, res.StatusCode, http.StatusOK)
+	})
+	t.Run("should require reason", func(t *testing.T) {
+		m := mocks.AdminService{Mock: mock.Mock{}}
+
+		body := bytes.NewReader([]byte(`{}`))
+		request := httptest.NewRequest(http.MethodPost, "/admin/users/666/lock", body)
+
+		h := Handler{admin: &m, Mux: chi.NewMux()}
+		h.Post("/admin/users/{id}/lock", h.AdminLockUserHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+	})
+	t.Run("should return 422 for own account", func(t *testing.T) {
+		m := mocks.AdminService{Mock: mock.Mock{}}
+		m.On("LockUser", mock.Anything, 1, "test").Return(service.ErrAdminSelfAction)
+
+		body := bytes.NewReader([]byte(`{"reason":"test"}`))
+		request := httptest.NewRequest(http.MethodPost, "/admin/users/1/lock", body)
+
+		h := Handler{admin: &m, Mux: chi.NewMux()}
+		h.Post("/admin/users/{id}/lock", h.AdminLockUserHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusUnprocessableEntity)
+	})
+}
+
+func TestHandler_AdminSetRoleHandler(t *testing.T) {
+	t.Run("should reject unknown role", func(t *testing.T) {
+		m := mocks.AdminService{Mock: mock.Mock{}}
+
+		body := bytes.NewReader([]byte(`{"role":"root"}`))
+		request := httptest.NewRequest(http.MethodPut, "/admin/users/666/role", body)
+
+		h := Handler{admin: &m, Mux: chi.NewMux()}
+		h.Put("/admin/users/{id}/role", h.AdminSetRoleHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+		m.AssertNotCalled(t, "SetRole", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
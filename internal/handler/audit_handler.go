@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const auditMaxLimit = 1000
+
+var errInvalidAuditFilter = errors.New("invalid audit filter")
+
+// AuditHandler lists the audit log newest first. Optional filters are "actor_id", "action", "target_type",
+// "target_id", "from" and "to" in RFC3339; "before_id" and "limit" page through the log.
+func (h *Handler) AuditHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "AuditHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		filter, err := parseAuditFilter(r.URL.Query())
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid filter")
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entries, err := h.audit.Entries(ctx, filter)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid audit entries")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		writeAdminList(rw, len(entries), entries)
+	}
+}
+
+func parseAuditFilter(query url.Values) (model.AuditFilter, error) {
+	filter := model.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+	}
+
+	ints := []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"actor_id", &filter.ActorID, 0},
+		{"before_id", &filter.BeforeID, 0},
+		{"limit", &filter.Limit, auditMaxLimit},
+	}
+	for _, param := range ints {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || (param.max > 0 && parsed > param.max) {
+			return model.AuditFilter{}, errInvalidAuditFilter
+		}
+
+		*param.value = parsed
+	}
+
+	times := []struct {
+		name  string
+		value *time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	}
+	for _, param := range times {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return model.AuditFilter{}, errInvalidAuditFilter
+		}
+
+		*param.value = parsed
+	}
+
+	return filter, nil
+}
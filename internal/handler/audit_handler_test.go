@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_AuditHandler(t *testing.T) {
+	t.Run("should return filtered audit log", func(t *testing.T) {
+		from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		m := mocks.AuditService{Mock: mock.Mock{}}
+		m.On("Entries", mock.Anything, model.AuditFilter{ActorID: 1, Action: "user_locked", From: from, BeforeID: 10, Limit: 5}).
+			Return([]model.AuditEntry{{
+				ID:         7,
+				ActorID:    1,
+				Action:     "user_locked",
+				TargetType: model.AuditTargetUser,
+				TargetID:   "666",
+				IP:         "10.0.0.1",
+				Details:    map[string]interface{}{"reason": "fraud"},
+				CreatedAt:  from,
+			}}, nil)
+
+		request := httptest.NewRequest(http.MethodGet,
+			"/admin/audit?actor_id=1&action=user_locked&from=2022-01-01T00:00:00Z&before_id=10&limit=5", nil)
+
+		h := Handler{audit: &m, Mux: chi.NewMux()}
+		h.Get("/admin/audit", h.AuditHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody), `[{"id":7,"actor_id":1,"action":"user_locked","target_type":"user","target_id":"666",`+
+			`"ip":"10.0.0.1","details":{"reason":"fraud"},"created_at":"2022-01-01T00:00:00Z"}]`)
+	})
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "should reject invalid actor", query: "actor_id=abc"},
+		{name: "should reject limit above maximum", query: "limit=5000"},
+		{name: "should reject invalid time", query: "from=yesterday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.AuditService{Mock: mock.Mock{}}
+
+			request := httptest.NewRequest(http.MethodGet, "/admin/audit?"+tt.query, nil)
+
+			h := Handler{audit: &m, Mux: chi.NewMux()}
+			h.Get("/admin/audit", h.AuditHandler())
+
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, res.StatusCode, http.StatusBadRequest)
+			m.AssertNotCalled(t, "Entries", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) CreateCampaignHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "CreateCampaignHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		var campaign model.Campaign
+		err := json.NewDecoder(r.Body).Decode(&campaign)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err = campaign.Validate(); err != nil {
+			logger.Trace().Err(err).Msg("invalid validate data")
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		campaign, err = h.campaign.CreateCampaign(ctx, campaign)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid create campaign")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusCreated)
+
+		bytes, _ := json.Marshal(campaign)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) CampaignsHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "CampaignsHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		campaigns, err := h.campaign.Campaigns(ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid find campaigns")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(campaigns)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) CampaignHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "CampaignHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid campaign id")
+			http.Error(rw, "invalid campaign id", http.StatusBadRequest)
+			return
+		}
+
+		campaign, err := h.campaign.Campaign(ctx, id)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				http.Error(rw, "campaign not found", http.StatusNotFound)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid find campaign")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(campaign)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) UpdateCampaignHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "UpdateCampaignHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid campaign id")
+			http.Error(rw, "invalid campaign id", http.StatusBadRequest)
+			return
+		}
+
+		var campaign model.Campaign
+		err = json.NewDecoder(r.Body).Decode(&campaign)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err = campaign.Validate(); err != nil {
+			logger.Trace().Err(err).Msg("invalid validate data")
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		campaign.ID = id
+		campaign, err = h.campaign.UpdateCampaign(ctx, campaign)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				http.Error(rw, "campaign not found", http.StatusNotFound)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid update campaign")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(campaign)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) DeleteCampaignHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "DeleteCampaignHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid campaign id")
+			http.Error(rw, "invalid campaign id", http.StatusBadRequest)
+			return
+		}
+
+		err = h.campaign.DeleteCampaign(ctx, id)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				http.Error(rw, "campaign not found", http.StatusNotFound)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid delete campaign")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write([]byte("OK"))
+	}
+}
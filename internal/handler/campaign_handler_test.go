@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_CreateCampaignHandler(t *testing.T) {
+	t.Run("should create campaign", func(t *testing.T) {
+		startsAt := time.Date(2022, 5, 7, 0, 0, 0, 0, time.UTC)
+		endsAt := time.Date(2022, 5, 9, 0, 0, 0, 0, time.UTC)
+
+		m := mocks.CampaignService{Mock: mock.Mock{}}
+		m.On("CreateCampaign", mock.Anything, model.Campaign{Name: "weekend", StartsAt: startsAt, EndsAt: endsAt, Multiplier: 2}).
+			Return(model.Campaign{ID: 1, Name: "weekend", StartsAt: startsAt, EndsAt: endsAt, Multiplier: 2}, nil)
+
+		body := bytes.NewReader([]byte(`{"name":"weekend","starts_at":"2022-05-07T00:00:00Z","ends_at":"2022-05-09T00:00:00Z","multiplier":2}`))
+		request := httptest.NewRequest(http.MethodPost, "/campaigns", body)
+
+		h := Handler{campaign: &m, Mux: chi.NewMux()}
+		h.Post("/campaigns", h.CreateCampaignHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusCreated)
+		require.Equal(t, string(resBody), `{"id":1,"name":"weekend","starts_at":"2022-05-07T00:00:00Z",`+
+			`"ends_at":"2022-05-09T00:00:00Z","multiplier":2,"created_at":"0001-01-01T00:00:00Z"}`)
+	})
+
+	t.Run("should reject campaign with multiplier and bonus", func(t *testing.T) {
+		m := mocks.CampaignService{Mock: mock.Mock{}}
+
+		body := bytes.NewReader([]byte(`{"name":"weekend","starts_at":"2022-05-07T00:00:00Z","ends_at":"2022-05-09T00:00:00Z","multiplier":2,"bonus":10}`))
+		request := httptest.NewRequest(http.MethodPost, "/campaigns", body)
+
+		h := Handler{campaign: &m, Mux: chi.NewMux()}
+		h.Post("/campaigns", h.CreateCampaignHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertNumberOfCalls(t, "CreateCampaign", 0)
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+	})
+}
+
+func TestHandler_UpdateCampaignHandler(t *testing.T) {
+	t.Run("should return 404 for unknown campaign", func(t *testing.T) {
+		m := mocks.CampaignService{Mock: mock.Mock{}}
+		m.On("UpdateCampaign", mock.Anything, mock.Anything).Return(model.Campaign{}, storage.ErrNotFound)
+
+		body := bytes.NewReader([]byte(`{"name":"weekend","starts_at":"2022-05-07T00:00:00Z","ends_at":"2022-05-09T00:00:00Z","bonus":10}`))
+		request := httptest.NewRequest(http.MethodPut, "/campaigns/7", body)
+
+		h := Handler{campaign: &m, Mux: chi.NewMux()}
+		h.Put("/campaigns/{id}", h.UpdateCampaignHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertCalled(t, "UpdateCampaign", mock.Anything, mock.MatchedBy(func(campaign model.Campaign) bool {
+			return campaign.ID == 7 && campaign.Bonus == 1000
+		}))
+		require.Equal(t, res.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestHandler_DeleteCampaignHandler(t *testing.T) {
+	t.Run("should delete campaign", func(t *testing.T) {
+		m := mocks.CampaignService{Mock: mock.Mock{}}
+		m.On("DeleteCampaign", mock.Anything, 7).Return(nil)
+
+		request := httptest.NewRequest(http.MethodDelete, "/campaigns/7", nil)
+
+		h := Handler{campaign: &m, Mux: chi.NewMux()}
+		h.Delete("/campaigns/{id}", h.DeleteCampaignHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertNumberOfCalls(t, "DeleteCampaign", 1)
+		require.Equal(t, res.StatusCode, http.StatusOK)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/reporegistry"
 	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/pkg/keyring"
 	"github.com/djokcik/gophermart/pkg/logging"
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
@@ -16,17 +17,47 @@ var (
 
 type Handler struct {
 	*chi.Mux
-	user     service.UserService
-	order    service.OrderService
-	withdraw service.WithdrawService
+	user      service.UserService
+	order     service.OrderService
+	withdraw  service.WithdrawService
+	transfer  service.TransferService
+	campaign  service.CampaignService
+	referral  service.ReferralService
+	admin     service.AdminService
+	webhook   service.WebhookService
+	events    service.EventService
+	tokens    service.TokenService
+	twoFactor service.TwoFactorService
+	keys      *keyring.Keyring
+
+	loginGuard service.LoginGuardService
+	adjustment service.AdjustmentService
+	override   service.OrderOverrideService
+	audit      service.AuditService
+	reconcile  service.ReconciliationService
 }
 
-func NewHandler(mux *chi.Mux, cfg config.Config, repoRegistry reporegistry.RepoRegistry) *Handler {
+func NewHandler(mux *chi.Mux, cfg config.Config, repoRegistry reporegistry.RepoRegistry, events service.EventService) *Handler {
 	return &Handler{
-		Mux:      mux,
-		user:     service.NewUserService(cfg, repoRegistry),
-		order:    service.NewOrderService(cfg, repoRegistry),
-		withdraw: service.NewWithdrawService(cfg, repoRegistry),
+		Mux:       mux,
+		user:      service.NewUserService(cfg, repoRegistry),
+		order:     service.NewOrderService(cfg, repoRegistry),
+		withdraw:  service.NewWithdrawService(cfg, repoRegistry, events),
+		transfer:  service.NewTransferService(cfg, repoRegistry, events),
+		campaign:  service.NewCampaignService(cfg, repoRegistry),
+		referral:  service.NewReferralService(cfg, repoRegistry),
+		admin:     service.NewAdminService(cfg, repoRegistry),
+		webhook:   service.NewWebhookService(cfg, repoRegistry),
+		events:    events,
+		tokens:    service.NewTokenService(cfg, repoRegistry),
+		twoFactor: service.NewTwoFactorService(cfg, repoRegistry),
+		keys:      cfg.Keys,
+
+		loginGuard: service.NewLoginGuardService(cfg, repoRegistry),
+		adjustment: service.NewAdjustmentService(cfg, repoRegistry, events),
+		override:   service.NewOrderOverrideService(cfg, repoRegistry, events),
+		audit:      service.NewAuditService(cfg, repoRegistry),
+		reconcile:  service.NewReconciliationService(cfg, repoRegistry, events),
 	}
 }
 
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JWKSHandler publishes public keys, so other services can verify access tokens
+func (h *Handler) JWKSHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Cache-Control", "public, max-age=300")
+
+		bytes, _ := json.Marshal(h.keys.JWKS())
+		rw.Write(bytes)
+	}
+}
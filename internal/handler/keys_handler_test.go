@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/djokcik/gophermart/pkg/keyring"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_JWKSHandler(t *testing.T) {
+	t.Run("should publish only public keys", func(t *testing.T) {
+		public, private, _ := ed25519.GenerateKey(nil)
+		keys, _ := keyring.New("ed-1",
+			keyring.Key{ID: keyring.LegacyKeyID, Algorithm: "HS256", Secret: []byte("key")},
+			keyring.Key{ID: "ed-1", Algorithm: "EdDSA", PrivateKey: private, PublicKey: public},
+		)
+
+		request := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+
+		h := Handler{keys: keys, Mux: chi.NewMux()}
+		h.Get("/.well-known/jwks.json", h.JWKSHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		var set keyring.JWKS
+		require.Equal(t, json.Unmarshal(resBody, &set), nil)
+		require.Equal(t, set.Keys, []keyring.JWK{{
+			KeyType:   "OKP",
+			Use:       "sig",
+			KeyID:     "ed-1",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(public),
+		}})
+	})
+}
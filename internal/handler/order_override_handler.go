@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/djokcik/gophermart/provider"
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+// ResetOrderHandler returns the order to NEW, so the poller requests the accrual system again
+func (h *Handler) ResetOrderHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "ResetOrderHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		override, err := h.override.ResetOrder(ctx, model.OrderID(chi.URLParam(r, "number")))
+		if err != nil {
+			writeOverrideError(rw, logger, err)
+			return
+		}
+
+		writeOverride(rw, override)
+	}
+}
+
+// OverrideOrderHandler sets a final status and accrual of the order decided by the admin
+func (h *Handler) OverrideOrderHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "OverrideOrderHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		var overrideDto model.OrderOverrideDto
+		if err := json.NewDecoder(r.Body).Decode(&overrideDto); err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err := overrideDto.Validate(); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		override, err := h.override.OverrideOrder(ctx, model.OrderID(chi.URLParam(r, "number")), overrideDto)
+		if err != nil {
+			writeOverrideError(rw, logger, err)
+			return
+		}
+
+		writeOverride(rw, override)
+	}
+}
+
+// CheckAccrualHandler requests the accrual system at once and returns its response without changing the order
+func (h *Handler) CheckAccrualHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "CheckAccrualHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		response, err := h.override.CheckAccrual(ctx, model.OrderID(chi.URLParam(r, "number")))
+		if err != nil {
+			var apiErr *provider.ErrAccrualResponse
+			if errors.As(err, &apiErr) {
+				http.Error(rw, fmt.Sprintf("accrual system responded with status %d: %s", apiErr.Code, apiErr.Body), http.StatusBadGateway)
+				return
+			}
+
+			writeOverrideError(rw, logger, err)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(response)
+		rw.Write(bytes)
+	}
+}
+
+func writeOverride(rw http.ResponseWriter, override model.OrderOverride) {
+	rw.Header().Set("Content-Type", "application/json")
+
+	bytes, _ := json.Marshal(override)
+	rw.Write(bytes)
+}
+
+func writeOverrideError(rw http.ResponseWriter, logger zerolog.Logger, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(rw, "order not found", http.StatusNotFound)
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		http.Error(rw, "accrual is already spent", http.StatusPaymentRequired)
+	default:
+		logger.Error().Err(err).Msg("invalid order override")
+		http.Error(rw, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/provider"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_OverrideOrderHandler(t *testing.T) {
+	t.Run("should override order", func(t *testing.T) {
+		m := mocks.OrderOverrideService{Mock: mock.Mock{}}
+		m.On("OverrideOrder", mock.Anything, model.OrderID("12345678903"), model.OrderOverrideDto{Status: model.StatusProcessed, Accrual: 50050}).
+			Return(model.OrderOverride{
+				Order:           model.Order{ID: "12345678903", Status: model.StatusProcessed, Accrual: 50050},
+				PreviousStatus:  model.StatusProcessed,
+				PreviousAccrual: 10000,
+				Delta:           40050,
+			}, nil)
+
+		body := bytes.NewReader([]byte(`{"status":"PROCESSED","accrual":500.5}`))
+		request := httptest.NewRequest(http.MethodPut, "/admin/orders/12345678903/override", body)
+
+		h := Handler{override: &m, Mux: chi.NewMux()}
+		h.Put("/admin/orders/{number}/override", h.OverrideOrderHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody), `{"order":{"number":"12345678903","status":"PROCESSED","uploaded_at":"0001-01-01T00:00:00Z",`+
+			`"accrual":500.5},"previous_status":"PROCESSED","previous_accrual":100,"balance_delta":400.5}`)
+	})
+	t.Run("should reject not final status", func(t *testing.T) {
+		m := mocks.OrderOverrideService{Mock: mock.Mock{}}
+
+		body := bytes.NewReader([]byte(`{"status":"NEW"}`))
+		request := httptest.NewRequest(http.MethodPut, "/admin/orders/12345678903/override", body)
+
+		h := Handler{override: &m, Mux: chi.NewMux()}
+		h.Put("/admin/orders/{number}/override", h.OverrideOrderHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+		m.AssertNotCalled(t, "OverrideOrder", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandler_ResetOrderHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{name: "should reset order", err: nil, statusCode: http.StatusOK},
+		{name: "should return 404 for unknown order", err: storage.ErrNotFound, statusCode: http.StatusNotFound},
+		{name: "should return 402 when accrual is spent", err: storage.ErrInsufficientFunds, statusCode: http.StatusPaymentRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.OrderOverrideService{Mock: mock.Mock{}}
+			m.On("ResetOrder", mock.Anything, model.OrderID("12345678903")).Return(model.OrderOverride{}, tt.err)
+
+			request := httptest.NewRequest(http.MethodPost, "/admin/orders/12345678903/reset", nil)
+
+			h := Handler{override: &m, Mux: chi.NewMux()}
+			h.Post("/admin/orders/{number}/reset", h.ResetOrderHandler())
+
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, res.StatusCode, tt.statusCode)
+		})
+	}
+}
+
+func TestHandler_CheckAccrualHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   provider.AccrualResponse
+		err        error
+		statusCode int
+		body       string
+	}{
+		{
+			name:       "should return response of accrual system",
+			response:   provider.AccrualResponse{Order: "12345678903", Status: model.StatusProcessed, Accrual: 1000},
+			statusCode: http.StatusOK,
+			body:       `{"order":"12345678903","status":"PROCESSED","accrual":10}`,
+		},
+		{
+			name:       "should return 502 when accrual system failed",
+			err:        &provider.ErrAccrualResponse{Code: http.StatusTooManyRequests, Body: "No more than 10 requests per minute allowed"},
+			statusCode: http.StatusBadGateway,
+			body:       "accrual system responded with status 429: No more than 10 requests per minute allowed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.OrderOverrideService{Mock: mock.Mock{}}
+			m.On("CheckAccrual", mock.Anything, model.OrderID("12345678903")).Return(tt.response, tt.err)
+
+			request := httptest.NewRequest(http.MethodGet, "/admin/orders/12345678903/accrual", nil)
+
+			h := Handler{override: &m, Mux: chi.NewMux()}
+			h.Get("/admin/orders/{number}/accrual", h.CheckAccrualHandler())
+
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			resBody, _ := io.ReadAll(res.Body)
+
+			require.Equal(t, res.StatusCode, tt.statusCode)
+			require.Equal(t, string(resBody), tt.body)
+		})
+	}
+}
@@ -3,14 +3,24 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/storage"
 	appContext "github.com/djokcik/gophermart/pkg/context"
 	"github.com/djokcik/gophermart/pkg/logging"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// streamHeartbeat keeps idle event streams alive behind proxies
+var streamHeartbeat = 15 * time.Second
+
+// maxOrdersBatch limits the amount of order numbers in one batch upload
+const maxOrdersBatch = 1000
+
 func (h *Handler) UploadOrderHandler() http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -45,6 +55,12 @@ func (h *Handler) UploadOrderHandler() http.HandlerFunc {
 				return
 			}
 
+			if errors.Is(err, storage.ErrOrderAlreadyPaid) {
+				logger.Trace().Err(err).Msg("")
+				http.Error(rw, "order already paid with points", http.StatusConflict)
+				return
+			}
+
 			http.Error(rw, "internal error", http.StatusInternalServerError)
 			return
 		}
@@ -54,6 +70,68 @@ func (h *Handler) UploadOrderHandler() http.HandlerFunc {
 	}
 }
 
+func (h *Handler) UploadOrdersBatchHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "UploadOrdersBatchHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		orderIDs, err := parseOrdersBatch(r)
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid parse orders")
+			http.Error(rw, "invalid parse orders", http.StatusBadRequest)
+			return
+		}
+
+		if len(orderIDs) == 0 {
+			http.Error(rw, "empty orders batch", http.StatusBadRequest)
+			return
+		}
+
+		if len(orderIDs) > maxOrdersBatch {
+			http.Error(rw, fmt.Sprintf("batch exceeds %d orders", maxOrdersBatch), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		results, err := h.order.ProcessOrders(ctx, orderIDs)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid process orders")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(results)
+		rw.Write(bytes)
+	}
+}
+
+// parseOrdersBatch reads order numbers as JSON array or as newline-delimited list
+func parseOrdersBatch(r *http.Request) ([]model.OrderID, error) {
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		var orderIDs []model.OrderID
+		err := json.NewDecoder(r.Body).Decode(&orderIDs)
+
+		return orderIDs, err
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIDs := make([]model.OrderID, 0)
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			orderIDs = append(orderIDs, model.OrderID(line))
+		}
+	}
+
+	return orderIDs, nil
+}
+
 func (h *Handler) GetOrdersHandler() http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -87,3 +165,54 @@ func (h *Handler) GetOrdersHandler() http.HandlerFunc {
 		rw.Write(bytes)
 	}
 }
+
+func (h *Handler) OrdersStreamHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "OrdersStreamHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		flusher, ok := rw.(http.Flusher)
+		if !ok {
+			h.Log(ctx).Error().Msg("streaming unsupported")
+			http.Error(rw, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		events, cancel := h.events.Subscribe(user.ID)
+		defer cancel()
+
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Header().Set("Cache-Control", "no-cache")
+		rw.Header().Set("Connection", "keep-alive")
+		rw.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(rw, ": ping\n\n")
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+
+				fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+			}
+
+			flusher.Flush()
+		}
+	}
+}
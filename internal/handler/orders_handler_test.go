@@ -143,3 +143,103 @@ func TestHandler_GetOrdersHandler(t *testing.T) {
 		require.Equal(t, string(resBody), `[{"number":"1","status":"PROCESSED","uploaded_at":"2020-12-10T15:15:45+03:00","accrual":500},{"number":"2","status":"PROCESSING","uploaded_at":"2020-12-10T15:15:45+03:00","accrual":100.12}]`)
 	})
 }
+
+func TestHandler_OrdersStreamHandler(t *testing.T) {
+	t.Run("should stream events of user", func(t *testing.T) {
+		events := make(chan model.Event, 1)
+		events <- model.Event{Type: model.EventOrderStatusChanged, UserID: 666, Data: []byte(`{"number":"1"}`)}
+		close(events)
+
+		m := mocks.EventService{Mock: mock.Mock{}}
+		m.On("Subscribe", 666).Return((<-chan model.Event)(events), func() {})
+
+		request := httptest.NewRequest(http.MethodGet, "/user/orders/stream", nil)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{events: &m, Mux: chi.NewMux()}
+		h.Get("/user/orders/stream", h.OrdersStreamHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		m.AssertNumberOfCalls(t, "Subscribe", 1)
+		require.Equal(t, res.Header.Get("Content-Type"), "text/event-stream")
+		require.Equal(t, string(resBody), "event: order.status_changed\ndata: {\"number\":\"1\"}\n\n")
+	})
+}
+
+func TestHandler_UploadOrdersBatchHandler(t *testing.T) {
+	t.Run("1. should upload newline-delimited orders", func(t *testing.T) {
+		m := mocks.OrderService{Mock: mock.Mock{}}
+		m.On("ProcessOrders", mock.Anything, []model.OrderID{"9278923470", "1"}).
+			Return([]model.OrderUploadResult{
+				{ID: "9278923470", Result: model.UploadAccepted},
+				{ID: "1", Result: model.UploadInvalid},
+			}, nil)
+
+		body := bytes.NewReader([]byte("9278923470\r\n\n1\n"))
+		request := httptest.NewRequest(http.MethodPost, "/user/orders/batch", body)
+
+		h := Handler{order: &m, Mux: chi.NewMux()}
+		h.Post("/user/orders/batch", h.UploadOrdersBatchHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		m.AssertNumberOfCalls(t, "ProcessOrders", 1)
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody), `[{"number":"9278923470","result":"accepted"},{"number":"1","result":"invalid"}]`)
+	})
+
+	t.Run("2. should upload orders from json array", func(t *testing.T) {
+		m := mocks.OrderService{Mock: mock.Mock{}}
+		m.On("ProcessOrders", mock.Anything, []model.OrderID{"9278923470", "12345678903"}).
+			Return([]model.OrderUploadResult{
+				{ID: "9278923470", Result: model.UploadAlreadyUploaded},
+				{ID: "12345678903", Result: model.UploadUploadedByAnotherUser},
+			}, nil)
+
+		body := bytes.NewReader([]byte(`["9278923470","12345678903"]`))
+		request := httptest.NewRequest(http.MethodPost, "/user/orders/batch", body)
+		request.Header.Set("Content-Type", "application/json")
+
+		h := Handler{order: &m, Mux: chi.NewMux()}
+		h.Post("/user/orders/batch", h.UploadOrdersBatchHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, string(resBody),
+			`[{"number":"9278923470","result":"already_uploaded"},{"number":"12345678903","result":"uploaded_by_another_user"}]`)
+	})
+
+	t.Run("3. should return error when batch is empty", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/user/orders/batch", bytes.NewReader([]byte("\n")))
+
+		h := Handler{Mux: chi.NewMux()}
+		h.Post("/user/orders/batch", h.UploadOrdersBatchHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"net/http"
+	"strconv"
+)
+
+// ReconciliationHandler compares the balance of the user with point lots and the ledger
+func (h *Handler) ReconciliationHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "ReconciliationHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		userID, ok := adminUserID(rw, r, logger)
+		if !ok {
+			return
+		}
+
+		reconciliation, err := h.reconcile.Reconcile(ctx, userID)
+		if err != nil {
+			writeAdminError(rw, logger, err)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(reconciliation)
+		rw.Write(bytes)
+	}
+}
+
+// RunReconciliationHandler reconciles balances of all users, "correct=true" writes corrective adjustments
+func (h *Handler) RunReconciliationHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "RunReconciliationHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		var correct bool
+		if value := r.URL.Query().Get("correct"); value != "" {
+			var err error
+			if correct, err = strconv.ParseBool(value); err != nil {
+				http.Error(rw, "invalid correct", http.StatusBadRequest)
+				return
+			}
+		}
+
+		report, err := h.reconcile.ReconcileAll(ctx, correct)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid reconcile balances")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(report)
+		rw.Write(bytes)
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_ReconciliationHandler(t *testing.T) {
+	t.Run("should return reconciliation of the user", func(t *testing.T) {
+		m := mocks.ReconciliationService{Mock: mock.Mock{}}
+		m.On("Reconcile", mock.Anything, 666).
+			Return(model.Reconciliation{UserID: 666, Login: "user", Balance: 1500, Lots: 1500, Ledger: 1000, Accruals: 1000}, nil)
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/666/reconciliation", nil)
+
+		h := Handler{reconcile: &m, Mux: chi.NewMux()}
+		h.Get("/admin/users/{id}/reconciliation", h.ReconciliationHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody), `{"user_id":666,"login":"user","balance":15,"lots":15,"ledger":10,"accruals":10,`+
+			`"bonuses":0,"transfers_in":0,"transfers_out":0,"withdrawn":0,"reserved":0,"reversed":0,"adjustments":0,"expired":0}`)
+	})
+	t.Run("should return 404 for unknown user", func(t *testing.T) {
+		m := mocks.ReconciliationService{Mock: mock.Mock{}}
+		m.On("Reconcile", mock.Anything, 666).Return(model.Reconciliation{}, storage.ErrNotFound)
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/666/reconciliation", nil)
+
+		h := Handler{reconcile: &m, Mux: chi.NewMux()}
+		h.Get("/admin/users/{id}/reconciliation", h.ReconciliationHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestHandler_RunReconciliationHandler(t *testing.T) {
+	t.Run("should run reconciliation with corrections", func(t *testing.T) {
+		m := mocks.ReconciliationService{Mock: mock.Mock{}}
+		m.On("ReconcileAll", mock.Anything, true).Return(model.ReconciliationReport{
+			Checked:   10,
+			Corrected: 1,
+			Mismatches: []model.ReconciliationMismatch{{
+				Reconciliation: model.Reconciliation{UserID: 666, Balance: 300, Lots: 200, Ledger: 200},
+				Correction:     &model.ReconciliationCorrection{AdjustmentID: 7, BalanceDelta: -100},
+			}},
+		}, nil)
+
+		request := httptest.NewRequest(http.MethodPost, "/admin/reconciliation?correct=true", nil)
+
+		h := Handler{reconcile: &m, Mux: chi.NewMux()}
+		h.Post("/admin/reconciliation", h.RunReconciliationHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody), `{"checked":10,"corrected":1,"mismatches":[{"user_id":666,"login":"","balance":3,"lots":2,`+
+			`"ledger":2,"accruals":0,"bonuses":0,"transfers_in":0,"transfers_out":0,"withdrawn":0,"reserved":0,"reversed":0,`+
+			`"adjustments":0,"expired":0,"correction":{"adjustment_id":7,"balance_delta":-1,"lots_delta":0}}]}`)
+	})
+	t.Run("should reject invalid correct", func(t *testing.T) {
+		m := mocks.ReconciliationService{Mock: mock.Mock{}}
+
+		request := httptest.NewRequest(http.MethodPost, "/admin/reconciliation?correct=maybe", nil)
+
+		h := Handler{reconcile: &m, Mux: chi.NewMux()}
+		h.Post("/admin/reconciliation", h.RunReconciliationHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+		m.AssertNumberOfCalls(t, "ReconcileAll", 0)
+	})
+}
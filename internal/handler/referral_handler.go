@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/json"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"net/http"
+)
+
+// ReferralsHandler returns the referral code of the user and the users who registered with it
+func (h *Handler) ReferralsHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "ReferralsHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Trace().Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		referrals, err := h.referral.Referrals(ctx, user.ID)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid find referrals")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(referrals)
+		rw.Write(bytes)
+	}
+}
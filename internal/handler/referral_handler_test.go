@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_ReferralsHandler(t *testing.T) {
+	t.Run("should return referral code and referrals", func(t *testing.T) {
+		rewardedAt := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+
+		m := mocks.ReferralService{Mock: mock.Mock{}}
+		m.On("Referrals", mock.Anything, 666).Return(model.ReferralsDto{
+			Code: "AB12CD34",
+			Referrals: []model.Referral{
+				{Login: "friend", CreatedAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), Bonus: 10000, RewardedAt: &rewardedAt},
+				{Login: "family", CreatedAt: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC)},
+			},
+		}, nil)
+
+		request := httptest.NewRequest(http.MethodGet, "/referrals", nil)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{referral: &m, Mux: chi.NewMux()}
+		h.Get("/referrals", h.ReferralsHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody), `{"code":"AB12CD34","referrals":[`+
+			`{"login":"friend","created_at":"2022-01-01T00:00:00Z","bonus":100,"rewarded_at":"2022-01-02T00:00:00Z"},`+
+			`{"login":"family","created_at":"2022-01-03T00:00:00Z"}]}`)
+	})
+}
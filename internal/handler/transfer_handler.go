@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"net/http"
+)
+
+func (h *Handler) TransferHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "TransferHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		var transferDto model.TransferRequestDto
+		err := json.NewDecoder(r.Body).Decode(&transferDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err = transferDto.Validate(); err != nil {
+			logger.Trace().Err(err).Msg("invalid validate data")
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		transfer, err := h.transfer.Transfer(ctx, transferDto.Recipient, transferDto.Sum)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrNotAuthenticated):
+				http.Error(rw, "user not found", http.StatusUnauthorized)
+			case errors.Is(err, storage.ErrNotFound):
+				http.Error(rw, "recipient not found", http.StatusNotFound)
+			case errors.Is(err, service.ErrTransferToSelf):
+				http.Error(rw, "transfer to own account", http.StatusUnprocessableEntity)
+			case errors.Is(err, storage.ErrInsufficientFunds):
+				http.Error(rw, "insufficient funds", http.StatusPaymentRequired)
+			case errors.Is(err, storage.ErrTransferLimitExceeded):
+				http.Error(rw, "daily transfer limit exceeded", http.StatusForbidden)
+			default:
+				logger.Error().Err(err).Msg("invalid transfer")
+				http.Error(rw, "internal error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(transfer)
+		rw.Write(bytes)
+	}
+}
+
+// TransferLogsHandler returns sent and received transfers of the user
+func (h *Handler) TransferLogsHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "TransferLogsHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Trace().Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		transfers, err := h.transfer.TransfersByUserID(ctx, user.ID)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid find transfers")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if len(transfers) == 0 {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(transfers)
+		rw.Write(bytes)
+	}
+}
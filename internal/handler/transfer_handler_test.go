@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler_TransferHandler(t *testing.T) {
+	t.Run("should transfer points", func(t *testing.T) {
+		m := mocks.TransferService{Mock: mock.Mock{}}
+		m.On("Transfer", mock.Anything, "family", model.Amount(1050)).
+			Return(model.Transfer{ID: 3, Sum: 1050}, nil)
+
+		body := bytes.NewReader([]byte(`{"recipient":"family","sum":10.5}`))
+		request := httptest.NewRequest(http.MethodPost, "/balance/transfer", body)
+
+		h := Handler{transfer: &m, Mux: chi.NewMux()}
+		h.Post("/balance/transfer", h.TransferHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		m.AssertNumberOfCalls(t, "Transfer", 1)
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody), `{"id":3,"sum":10.5,"created_at":"0001-01-01T00:00:00Z"}`)
+	})
+
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+	}{
+		{name: "should return 404 for unknown recipient", err: storage.ErrNotFound, statusCode: http.StatusNotFound},
+		{name: "should return 422 for own account", err: service.ErrTransferToSelf, statusCode: http.StatusUnprocessableEntity},
+		{name: "should return 402 for insufficient funds", err: storage.ErrInsufficientFunds, statusCode: http.StatusPaymentRequired},
+		{name: "should return 403 for exceeded limit", err: storage.ErrTransferLimitExceeded, statusCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.TransferService{Mock: mock.Mock{}}
+			m.On("Transfer", mock.Anything, "family", model.Amount(1050)).Return(model.Transfer{}, tt.err)
+
+			body := bytes.NewReader([]byte(`{"recipient":"family","sum":10.5}`))
+			request := httptest.NewRequest(http.MethodPost, "/balance/transfer", body)
+
+			h := Handler{transfer: &m, Mux: chi.NewMux()}
+			h.Post("/balance/transfer", h.TransferHandler())
+
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, request)
+			res := w.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, res.StatusCode, tt.statusCode)
+		})
+	}
+
+	t.Run("should reject request without recipient", func(t *testing.T) {
+		m := mocks.TransferService{Mock: mock.Mock{}}
+
+		body := bytes.NewReader([]byte(`{"sum":10.5}`))
+		request := httptest.NewRequest(http.MethodPost, "/balance/transfer", body)
+
+		h := Handler{transfer: &m, Mux: chi.NewMux()}
+		h.Post("/balance/transfer", h.TransferHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertNumberOfCalls(t, "Transfer", 0)
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+	})
+}
+
+func TestHandler_TransferLogsHandler(t *testing.T) {
+	t.Run("should return transfers of both directions", func(t *testing.T) {
+		m := mocks.TransferService{Mock: mock.Mock{}}
+		m.On("TransfersByUserID", mock.Anything, 666).Return([]model.TransferLog{
+			{ID: 1, Direction: model.TransferOutgoing, Counterparty: "family", Sum: 1000},
+			{ID: 2, Direction: model.TransferIncoming, Counterparty: "friend", Sum: 250},
+		}, nil)
+
+		request := httptest.NewRequest(http.MethodGet, "/transfers", nil)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{transfer: &m, Mux: chi.NewMux()}
+		h.Get("/transfers", h.TransferLogsHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, string(resBody), `[`+
+			`{"id":1,"direction":"OUT","counterparty":"family","sum":10,"created_at":"0001-01-01T00:00:00Z"},`+
+			`{"id":2,"direction":"IN","counterparty":"friend","sum":2.5,"created_at":"0001-01-01T00:00:00Z"}]`)
+	})
+}
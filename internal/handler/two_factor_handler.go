@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// TwoFactorLoginHandler exchanges challenge token from SignInHandler and TOTP or recovery code for tokens
+func (h *Handler) TwoFactorLoginHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "TwoFactorLoginHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		var loginDto model.TwoFactorLoginDto
+		err := json.NewDecoder(r.Body).Decode(&loginDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		challenge, err := h.tokens.ParseChallenge(ctx, loginDto.ChallengeToken)
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid challenge token")
+			http.Error(rw, "invalid challenge token", http.StatusUnauthorized)
+			return
+		}
+
+		ip := clientIP(r)
+
+		retryAfter, err := h.loginGuard.Check(ctx, challenge.Subject, ip)
+		if err != nil {
+			if errors.Is(err, service.ErrTooManyLoginAttempts) {
+				logger.Trace().Err(err).Str("ip", ip).Msg("login blocked")
+				rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				http.Error(rw, "too many login attempts", http.StatusTooManyRequests)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid check login attempts")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		tokens, err := h.user.CompleteTwoFactor(ctx, challenge, loginDto.Code)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+				logger.Trace().Err(err).Msg("invalid code")
+				if err := h.loginGuard.Failure(ctx, challenge.Subject, ip); err != nil {
+					logger.Error().Err(err).Msg("invalid register failed login")
+				}
+
+				http.Error(rw, "invalid code", http.StatusUnauthorized)
+				return
+			}
+
+			if errors.Is(err, service.ErrAccountLocked) {
+				logger.Trace().Err(err).Msg("account is locked")
+				http.Error(rw, "account is locked", http.StatusForbidden)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid complete 2fa")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := h.loginGuard.Success(ctx, challenge.Subject); err != nil {
+			logger.Error().Err(err).Msg("invalid reset login attempts")
+		}
+
+		writeTokens(rw, tokens)
+	}
+}
+
+func (h *Handler) TwoFactorEnrollHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "TwoFactorEnrollHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		enrollment, err := h.twoFactor.Enroll(ctx, *user)
+		if err != nil {
+			if errors.Is(err, service.ErrTwoFactorEnabled) {
+				http.Error(rw, "2fa already enabled", http.StatusConflict)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid enroll 2fa")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(enrollment)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) TwoFactorVerifyHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "TwoFactorVerifyHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		var codeDto model.TwoFactorCodeDto
+		err := json.NewDecoder(r.Body).Decode(&codeDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		codes, err := h.twoFactor.Confirm(ctx, user.ID, codeDto.Code)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidTwoFactorCode):
+				http.Error(rw, "invalid code", http.StatusUnprocessableEntity)
+			case errors.Is(err, service.ErrTwoFactorEnabled):
+				http.Error(rw, "2fa already enabled", http.StatusConflict)
+			case errors.Is(err, service.ErrTwoFactorNotEnrolled):
+				http.Error(rw, "2fa enrollment is not started", http.StatusConflict)
+			default:
+				logger.Error().Err(err).Msg("invalid confirm 2fa")
+				http.Error(rw, "internal error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(model.RecoveryCodesDto{RecoveryCodes: codes})
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) TwoFactorDisableHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "TwoFactorDisableHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		var codeDto model.TwoFactorCodeDto
+		err := json.NewDecoder(r.Body).Decode(&codeDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if !h.checkStepUp(rw, r, logger, user.ID) {
+			return
+		}
+
+		err = h.twoFactor.Disable(ctx, user.ID, codeDto.Code)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrInvalidTwoFactorCode):
+				h.stepUpFailed(r, logger, user.ID)
+				http.Error(rw, "invalid code", http.StatusUnprocessableEntity)
+			case errors.Is(err, service.ErrTwoFactorNotEnabled):
+				http.Error(rw, "2fa is not enabled", http.StatusConflict)
+			default:
+				logger.Error().Err(err).Msg("invalid disable 2fa")
+				http.Error(rw, "internal error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		h.stepUpPassed(r, logger, user.ID)
+
+		rw.Write([]byte("OK"))
+	}
+}
+
+// writeChallenge answers login which requires the second factor
+func writeChallenge(rw http.ResponseWriter, challengeToken string) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusAccepted)
+
+	bytes, _ := json.Marshal(model.TwoFactorChallengeDto{TwoFactorRequired: true, ChallengeToken: challengeToken})
+	rw.Write(bytes)
+}
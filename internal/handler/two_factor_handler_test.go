@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_SignInHandler_TwoFactor(t *testing.T) {
+	t.Run("should return challenge when 2fa is enabled", func(t *testing.T) {
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("Authenticate", mock.Anything, "userLogin", "userPassword").
+			Return(model.AuthTokens{ChallengeToken: "challenge"}, nil)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("Check", mock.Anything, "userLogin", "192.0.2.1").Return(time.Duration(0), nil)
+
+		body := bytes.NewReader([]byte(`{"login":"userLogin","password":"userPassword"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/login", body)
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/login", h.SignInHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusAccepted)
+		require.Equal(t, string(resBody), `{"two_factor_required":true,"challenge_token":"challenge"}`)
+		require.Len(t, res.Cookies(), 0)
+		guard.AssertNumberOfCalls(t, "Success", 0)
+	})
+}
+
+func TestHandler_TwoFactorLoginHandler(t *testing.T) {
+	t.Run("should exchange challenge and code for tokens", func(t *testing.T) {
+		challenge := model.Claims{ID: 666}
+		challenge.Subject = "userLogin"
+
+		tokens := mocks.TokenService{Mock: mock.Mock{}}
+		tokens.On("ParseChallenge", mock.Anything, "challenge").Return(challenge, nil)
+
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("CompleteTwoFactor", mock.Anything, challenge, "123456").
+			Return(model.AuthTokens{AccessToken: "secretToken", RefreshToken: "refreshToken"}, nil)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("Check", mock.Anything, "userLogin", "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("Success", mock.Anything, "userLogin").Return(nil)
+
+		body := bytes.NewReader([]byte(`{"challenge_token":"challenge","code":"123456"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/login/2fa", body)
+
+		h := Handler{user: &m, tokens: &tokens, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/login/2fa", h.TwoFactorLoginHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, string(resBody), `{"token":"secretToken","refresh_token":"refreshToken"}`)
+		require.Equal(t, res.Cookies()[0].Value, "secretToken")
+		guard.AssertNumberOfCalls(t, "Success", 1)
+	})
+
+	t.Run("should register failed attempt for invalid code", func(t *testing.T) {
+		challenge := model.Claims{ID: 666}
+		challenge.Subject = "userLogin"
+
+		tokens := mocks.TokenService{Mock: mock.Mock{}}
+		tokens.On("ParseChallenge", mock.Anything, "challenge").Return(challenge, nil)
+
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("CompleteTwoFactor", mock.Anything, challenge, "000000").
+			Return(model.AuthTokens{}, service.ErrInvalidTwoFactorCode)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("Check", mock.Anything, "userLogin", "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("Failure", mock.Anything, "userLogin", "192.0.2.1").Return(nil)
+
+		body := bytes.NewReader([]byte(`{"challenge_token":"challenge","code":"000000"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/login/2fa", body)
+
+		h := Handler{user: &m, tokens: &tokens, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/login/2fa", h.TwoFactorLoginHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusUnauthorized)
+		guard.AssertNumberOfCalls(t, "Failure", 1)
+	})
+}
+
+func TestHandler_TwoFactorVerifyHandler(t *testing.T) {
+	t.Run("should return recovery codes", func(t *testing.T) {
+		m := mocks.TwoFactorService{Mock: mock.Mock{}}
+		m.On("Confirm", mock.Anything, 666, "123456").Return([]string{"abcde-12345"}, nil)
+
+		body := bytes.NewReader([]byte(`{"code":"123456"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/2fa/verify", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{twoFactor: &m, Mux: chi.NewMux()}
+		h.Post("/user/2fa/verify", h.TwoFactorVerifyHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, string(resBody), `{"recovery_codes":["abcde-12345"]}`)
+	})
+}
+
+func TestHandler_TwoFactorDisableHandler(t *testing.T) {
+	t.Run("should disable 2fa", func(t *testing.T) {
+		m := mocks.TwoFactorService{Mock: mock.Mock{}}
+		m.On("Disable", mock.Anything, 666, "123456").Return(nil)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("SuccessUser", mock.Anything, 666).Return(nil)
+
+		body := bytes.NewReader([]byte(`{"code":"123456"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/2fa/disable", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{twoFactor: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/2fa/disable", h.TwoFactorDisableHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		guard.AssertNumberOfCalls(t, "SuccessUser", 1)
+	})
+
+	t.Run("should count invalid code", func(t *testing.T) {
+		m := mocks.TwoFactorService{Mock: mock.Mock{}}
+		m.On("Disable", mock.Anything, 666, "000000").Return(service.ErrInvalidTwoFactorCode)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("FailureUser", mock.Anything, 666, "192.0.2.1").Return(nil)
+
+		body := bytes.NewReader([]byte(`{"code":"000000"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/2fa/disable", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{twoFactor: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/2fa/disable", h.TwoFactorDisableHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusUnprocessableEntity)
+		guard.AssertNumberOfCalls(t, "FailureUser", 1)
+	})
+
+	t.Run("should not check code while blocked", func(t *testing.T) {
+		m := mocks.TwoFactorService{Mock: mock.Mock{}}
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Minute, service.ErrTooManyLoginAttempts)
+
+		body := bytes.NewReader([]byte(`{"code":"000000"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/2fa/disable", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{twoFactor: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/2fa/disable", h.TwoFactorDisableHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusTooManyRequests)
+		m.AssertNumberOfCalls(t, "Disable", 0)
+	})
+}
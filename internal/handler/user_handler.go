@@ -9,7 +9,11 @@ import (
 	"github.com/djokcik/gophermart/internal/storage"
 	appContext "github.com/djokcik/gophermart/pkg/context"
 	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 )
 
 func (h *Handler) RegisterUserHandler() http.HandlerFunc {
@@ -28,7 +32,7 @@ func (h *Handler) RegisterUserHandler() http.HandlerFunc {
 
 		h.Log(ctx).Info().Msgf("start RegisterUserHandler: %+v", userDto)
 
-		err = h.user.CreateUser(ctx, userDto.Login, userDto.Password)
+		err = h.user.CreateUser(ctx, userDto.Login, userDto.Password, userDto.ReferralCode)
 		if err != nil {
 			if errors.Is(err, storage.ErrLoginAlreadyExists) {
 				logger.Trace().Err(err).Msg("login already exists")
@@ -36,6 +40,12 @@ func (h *Handler) RegisterUserHandler() http.HandlerFunc {
 				return
 			}
 
+			if errors.Is(err, model.ErrInvalidReferralCode) {
+				logger.Trace().Err(err).Msg("invalid referral code")
+				http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+
 			logger.Trace().Err(err).Msg("failed created user")
 			http.Error(rw, "invalid create user", http.StatusBadRequest)
 			return
@@ -48,23 +58,16 @@ func (h *Handler) RegisterUserHandler() http.HandlerFunc {
 			return
 		}
 
-		token, err := h.user.GenerateToken(ctx, user)
+		tokens, err := h.user.GenerateToken(ctx, user)
 		if err != nil {
 			logger.Error().Err(err).Msg("invalid generate token")
 			http.Error(rw, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		cookie := http.Cookie{Name: CookieName, Value: token}
-		http.SetCookie(rw, &cookie)
-
-		rw.Header().Set("Content-Type", "application/json")
-		rw.Header().Set("Authorization", fmt.Sprintf("Bearer: %s", token))
-
 		h.Log(ctx).Info().Msgf("end RegisterUserHandler:")
 
-		bytes, _ := json.Marshal(model.UserResponseDto{Token: token})
-		rw.Write(bytes)
+		writeTokens(rw, tokens)
 	}
 }
 
@@ -82,34 +85,276 @@ func (h *Handler) SignInHandler() http.HandlerFunc {
 			return
 		}
 
-		h.Log(ctx).Trace().Msgf("start SignInHandler: %+v", user)
+		h.Log(ctx).Trace().Msgf("start SignInHandler: %+v", user.Login)
+
+		ip := clientIP(r)
 
-		token, err := h.user.Authenticate(ctx, user.Login, user.Password)
+		retryAfter, err := h.loginGuard.Check(ctx, user.Login, ip)
+		if err != nil {
+			if errors.Is(err, service.ErrTooManyLoginAttempts) {
+				logger.Trace().Err(err).Str("ip", ip).Msg("login blocked")
+				rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				http.Error(rw, "too many login attempts", http.StatusTooManyRequests)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid check login attempts")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		tokens, err := h.user.Authenticate(ctx, user.Login, user.Password)
 		if err != nil {
 			if errors.Is(err, service.ErrWrongPassword) {
 				logger.Trace().Err(err).Msg("invalid password")
+				if err := h.loginGuard.Failure(ctx, user.Login, ip); err != nil {
+					logger.Error().Err(err).Msg("invalid register failed login")
+				}
+
 				http.Error(rw, "invalid password", http.StatusUnauthorized)
 				return
 			}
 
+			if errors.Is(err, service.ErrAccountLocked) {
+				logger.Trace().Err(err).Msg("account is locked")
+				http.Error(rw, "account is locked", http.StatusForbidden)
+				return
+			}
+
 			logger.Trace().Err(err).Msg("invalid authenticate")
 			http.Error(rw, "", http.StatusBadRequest)
 			return
 		}
 
-		cookie := http.Cookie{Name: CookieName, Value: token}
-		http.SetCookie(rw, &cookie)
+		if tokens.ChallengeToken != "" {
+			// failures are kept until the second factor is passed, so codes can't be guessed between logins
+			writeChallenge(rw, tokens.ChallengeToken)
+			return
+		}
 
-		rw.Header().Set("Content-Type", "application/json")
-		rw.Header().Set("Authorization", fmt.Sprintf("Bearer: %s", token))
+		if err := h.loginGuard.Success(ctx, user.Login); err != nil {
+			logger.Error().Err(err).Msg("invalid reset login attempts")
+		}
 
 		h.Log(ctx).Trace().Msgf("end SignInHandler:")
 
-		bytes, _ := json.Marshal(model.UserResponseDto{Token: token})
-		rw.Write(bytes)
+		writeTokens(rw, tokens)
+	}
+}
+
+func (h *Handler) RefreshTokenHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "RefreshTokenHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		var refreshDto model.RefreshRequestDto
+		err := json.NewDecoder(r.Body).Decode(&refreshDto)
+		if err != nil || refreshDto.RefreshToken == "" {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		tokens, err := h.tokens.Refresh(ctx, refreshDto.RefreshToken)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+				logger.Trace().Err(err).Msg("invalid refresh token")
+				http.Error(rw, "invalid refresh token", http.StatusUnauthorized)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid refresh")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		writeTokens(rw, tokens)
+	}
+}
+
+func (h *Handler) LogoutHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "LogoutHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		claims := appContext.Claims(ctx)
+		if claims == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		err := h.tokens.Logout(ctx, *claims)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid logout")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(rw, &http.Cookie{Name: CookieName, Value: "", MaxAge: -1})
+
+		rw.Write([]byte("OK"))
+	}
+}
+
+func (h *Handler) ChangePasswordHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "ChangePasswordHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		var passwordDto model.ChangePasswordRequestDto
+		err := json.NewDecoder(r.Body).Decode(&passwordDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if !h.checkStepUp(rw, r, logger, user.ID) {
+			return
+		}
+
+		// the new session keeps the second factor of the current one
+		claims := appContext.Claims(ctx)
+		mfa := claims != nil && claims.MFA
+
+		tokens, err := h.user.ChangePassword(ctx, *user, passwordDto.CurrentPassword, passwordDto.NewPassword, mfa)
+		if err != nil {
+			if errors.Is(err, service.ErrWrongPassword) {
+				logger.Trace().Err(err).Msg("invalid current password")
+				h.stepUpFailed(r, logger, user.ID)
+				http.Error(rw, "invalid current password", http.StatusForbidden)
+				return
+			}
+
+			if errors.Is(err, model.ErrPasswordEmpty) || errors.Is(err, model.ErrPasswordLength) {
+				logger.Trace().Err(err).Msg("invalid new password")
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid change password")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		h.stepUpPassed(r, logger, user.ID)
+
+		writeTokens(rw, tokens)
+	}
+}
+
+func (h *Handler) DeleteUserHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "DeleteUserHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		var deleteDto model.DeleteUserRequestDto
+		err := json.NewDecoder(r.Body).Decode(&deleteDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if !h.checkStepUp(rw, r, logger, user.ID) {
+			return
+		}
+
+		err = h.user.DeleteUser(ctx, *user, deleteDto.Password)
+		if err != nil {
+			if errors.Is(err, service.ErrWrongPassword) {
+				logger.Trace().Err(err).Msg("invalid password")
+				h.stepUpFailed(r, logger, user.ID)
+				http.Error(rw, "invalid password", http.StatusForbidden)
+				return
+			}
+
+			logger.Error().Err(err).Msg("invalid delete user")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(rw, &http.Cookie{Name: CookieName, Value: "", MaxAge: -1})
+
+		rw.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// checkStepUp answers 429 while failed confirmations of the signed in user or the client ip are blocked
+func (h *Handler) checkStepUp(rw http.ResponseWriter, r *http.Request, logger zerolog.Logger, userID int) bool {
+	ip := clientIP(r)
+
+	retryAfter, err := h.loginGuard.CheckUser(r.Context(), userID, ip)
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyLoginAttempts) {
+			logger.Trace().Err(err).Str("ip", ip).Msg("confirmation blocked")
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			http.Error(rw, "too many attempts", http.StatusTooManyRequests)
+			return false
+		}
+
+		logger.Error().Err(err).Msg("invalid check confirmation attempts")
+		http.Error(rw, "internal error", http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
+// stepUpFailed counts the wrong password or 2fa code given to confirm the action
+func (h *Handler) stepUpFailed(r *http.Request, logger zerolog.Logger, userID int) {
+	if err := h.loginGuard.FailureUser(r.Context(), userID, clientIP(r)); err != nil {
+		logger.Error().Err(err).Msg("invalid register failed confirmation")
+	}
+}
+
+func (h *Handler) stepUpPassed(r *http.Request, logger zerolog.Logger, userID int) {
+	if err := h.loginGuard.SuccessUser(r.Context(), userID); err != nil {
+		logger.Error().Err(err).Msg("invalid reset confirmation attempts")
+	}
+}
+
+// clientIP returns address of the client, RealIP middleware already replaced it from proxy headers
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// writeTokens sends issued tokens in the cookie, the Authorization header and the body
+func writeTokens(rw http.ResponseWriter, tokens model.AuthTokens) {
+	cookie := http.Cookie{Name: CookieName, Value: tokens.AccessToken}
+	http.SetCookie(rw, &cookie)
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Authorization", fmt.Sprintf("Bearer: %s", tokens.AccessToken))
+
+	bytes, _ := json.Marshal(model.UserResponseDto{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+	rw.Write(bytes)
+}
+
 func (h *Handler) GetBalanceHandler() http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -136,3 +381,31 @@ func (h *Handler) GetBalanceHandler() http.HandlerFunc {
 		rw.Write(bytes)
 	}
 }
+
+// ProfileHandler returns the loyalty tier of the user and progress to the next tier
+func (h *Handler) ProfileHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "ProfileHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		profile, err := h.user.Profile(ctx, *user)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid get profile")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(profile)
+		rw.Write(bytes)
+	}
+}
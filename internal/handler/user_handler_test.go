@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
 	"github.com/djokcik/gophermart/internal/service/mocks"
 	appContext "github.com/djokcik/gophermart/pkg/context"
 	"github.com/go-chi/chi/v5"
@@ -13,13 +14,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestHandler_GetBalanceHandler(t *testing.T) {
 	t.Run("should return user balance", func(t *testing.T) {
 		m := mocks.UserService{Mock: mock.Mock{}}
 		m.On("GetBalance", mock.Anything, model.User{ID: 666}).
-			Return(model.UserBalance{Current: 1012, Withdrawn: 5555}, nil)
+			Return(model.UserBalance{Current: 1012, Withdrawn: 5555, ExpiringSoon: 300}, nil)
 
 		request := httptest.NewRequest(http.MethodGet, "/user/balance", nil)
 		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
@@ -36,17 +38,51 @@ func TestHandler_GetBalanceHandler(t *testing.T) {
 		resBody, _ := io.ReadAll(res.Body)
 
 		m.AssertNumberOfCalls(t, "GetBalance", 1)
-		require.Equal(t, string(resBody), `{"current":10.12,"withdrawn":55.55}`)
+		require.Equal(t, string(resBody), `{"current":10.12,"withdrawn":55.55,"expiring_soon":3}`)
+	})
+}
+
+func TestHandler_ProfileHandler(t *testing.T) {
+	t.Run("should return tier and progress", func(t *testing.T) {
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("Profile", mock.Anything, model.User{ID: 666, Username: "user"}).Return(model.Profile{
+			Login:         "user",
+			Tier:          model.TierSilver,
+			Multiplier:    1.1,
+			Volume:        150000,
+			NextTier:      model.TierGold,
+			NextThreshold: 500000,
+			ToNextTier:    350000,
+		}, nil)
+
+		request := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666, Username: "user"}))
+
+		h := Handler{user: &m, Mux: chi.NewMux()}
+		h.Get("/user/profile", h.ProfileHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		m.AssertNumberOfCalls(t, "Profile", 1)
+		require.Equal(t, string(resBody), `{"login":"user","tier":"SILVER","multiplier":1.1,"volume":1500,`+
+			`"next_tier":"GOLD","next_threshold":5000,"to_next_tier":3500}`)
 	})
 }
 
 func TestHandler_RegisterUserHandler(t *testing.T) {
 	t.Run("should user be registered", func(t *testing.T) {
 		m := mocks.UserService{Mock: mock.Mock{}}
-		m.On("CreateUser", mock.Anything, "userLogin", "userPassword").Return(nil)
+		m.On("CreateUser", mock.Anything, "userLogin", "userPassword", "").Return(nil)
 		m.On("GetUserByUsername", mock.Anything, "userLogin").
 			Return(model.User{ID: 666}, nil)
-		m.On("GenerateToken", mock.Anything, model.User{ID: 666}).Return("secretToken", nil)
+		m.On("GenerateToken", mock.Anything, model.User{ID: 666}).
+			Return(model.AuthTokens{AccessToken: "secretToken", RefreshToken: "refreshToken"}, nil)
 
 		body := bytes.NewReader([]byte(`{"login":"userLogin","password":"userPassword"}`))
 
@@ -66,8 +102,7 @@ func TestHandler_RegisterUserHandler(t *testing.T) {
 		m.AssertNumberOfCalls(t, "CreateUser", 1)
 		m.AssertNumberOfCalls(t, "GetUserByUsername", 1)
 		m.AssertNumberOfCalls(t, "GenerateToken", 1)
-		require.Equal(t, string(resBody), `{"token":"secretToken"}`)
-		require.Equal(t, string(resBody), `{"token":"secretToken"}`)
+		require.Equal(t, string(resBody), `{"token":"secretToken","refresh_token":"refreshToken"}`)
 		require.Equal(t, res.Header.Get("Authorization"), "Bearer: secretToken")
 		require.Equal(t, res.Header.Get("Content-Type"), "application/json")
 		require.Equal(t, res.Cookies()[0].Value, "secretToken")
@@ -78,12 +113,16 @@ func TestHandler_SignInHandler(t *testing.T) {
 	t.Run("should user be authorized", func(t *testing.T) {
 		m := mocks.UserService{Mock: mock.Mock{}}
 		m.On("Authenticate", mock.Anything, "userLogin", "userPassword").
-			Return("secretToken", nil)
+			Return(model.AuthTokens{AccessToken: "secretToken", RefreshToken: "refreshToken"}, nil)
 
 		body := bytes.NewReader([]byte(`{"login":"userLogin","password":"userPassword"}`))
 		request := httptest.NewRequest(http.MethodPost, "/user/login", body)
 
-		h := Handler{user: &m, Mux: chi.NewMux()}
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("Check", mock.Anything, "userLogin", "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("Success", mock.Anything, "userLogin").Return(nil)
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
 		h.Post("/user/login", h.SignInHandler())
 
 		w := httptest.NewRecorder()
@@ -95,9 +134,272 @@ func TestHandler_SignInHandler(t *testing.T) {
 		resBody, _ := io.ReadAll(res.Body)
 
 		m.AssertNumberOfCalls(t, "Authenticate", 1)
-		require.Equal(t, string(resBody), `{"token":"secretToken"}`)
+		require.Equal(t, string(resBody), `{"token":"secretToken","refresh_token":"refreshToken"}`)
 		require.Equal(t, res.Header.Get("Authorization"), "Bearer: secretToken")
 		require.Equal(t, res.Header.Get("Content-Type"), "application/json")
 		require.Equal(t, res.Cookies()[0].Value, "secretToken")
+		guard.AssertNumberOfCalls(t, "Success", 1)
+	})
+
+	t.Run("should register failed attempt", func(t *testing.T) {
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("Authenticate", mock.Anything, "userLogin", "wrong").Return(model.AuthTokens{}, service.ErrWrongPassword)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("Check", mock.Anything, "userLogin", "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("Failure", mock.Anything, "userLogin", "192.0.2.1").Return(nil)
+
+		body := bytes.NewReader([]byte(`{"login":"userLogin","password":"wrong"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/login", body)
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/login", h.SignInHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		guard.AssertNumberOfCalls(t, "Failure", 1)
+		require.Equal(t, res.StatusCode, http.StatusUnauthorized)
+	})
+
+	t.Run("should reject blocked login with Retry-After", func(t *testing.T) {
+		m := mocks.UserService{Mock: mock.Mock{}}
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("Check", mock.Anything, "userLogin", "192.0.2.1").
+			Return(90*time.Second+time.Millisecond, service.ErrTooManyLoginAttempts)
+
+		body := bytes.NewReader([]byte(`{"login":"userLogin","password":"userPassword"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/login", body)
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/login", h.SignInHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertNumberOfCalls(t, "Authenticate", 0)
+		require.Equal(t, res.StatusCode, http.StatusTooManyRequests)
+		require.Equal(t, res.Header.Get("Retry-After"), "91")
+	})
+}
+
+func TestHandler_RefreshTokenHandler(t *testing.T) {
+	t.Run("1. should return new pair of tokens", func(t *testing.T) {
+		m := mocks.TokenService{Mock: mock.Mock{}}
+		m.On("Refresh", mock.Anything, "refreshToken").
+			Return(model.AuthTokens{AccessToken: "newToken", RefreshToken: "newRefreshToken"}, nil)
+
+		body := bytes.NewReader([]byte(`{"refresh_token":"refreshToken"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/token/refresh", body)
+
+		h := Handler{tokens: &m, Mux: chi.NewMux()}
+		h.Post("/user/token/refresh", h.RefreshTokenHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		m.AssertNumberOfCalls(t, "Refresh", 1)
+		require.Equal(t, string(resBody), `{"token":"newToken","refresh_token":"newRefreshToken"}`)
+		require.Equal(t, res.Cookies()[0].Value, "newToken")
+	})
+
+	t.Run("2. should return `401` when refresh token reused", func(t *testing.T) {
+		m := mocks.TokenService{Mock: mock.Mock{}}
+		m.On("Refresh", mock.Anything, "refreshToken").
+			Return(model.AuthTokens{}, service.ErrRefreshTokenReused)
+
+		body := bytes.NewReader([]byte(`{"refresh_token":"refreshToken"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/token/refresh", body)
+
+		h := Handler{tokens: &m, Mux: chi.NewMux()}
+		h.Post("/user/token/refresh", h.RefreshTokenHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusUnauthorized)
+	})
+}
+
+func TestHandler_LogoutHandler(t *testing.T) {
+	t.Run("should revoke session and clear cookie", func(t *testing.T) {
+		claims := model.Claims{ID: 666, SessionID: "session"}
+
+		m := mocks.TokenService{Mock: mock.Mock{}}
+		m.On("Logout", mock.Anything, claims).Return(nil)
+
+		request := httptest.NewRequest(http.MethodPost, "/user/logout", nil)
+		request = request.WithContext(appContext.WithClaims(context.Background(), &claims))
+
+		h := Handler{tokens: &m, Mux: chi.NewMux()}
+		h.Post("/user/logout", h.LogoutHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertNumberOfCalls(t, "Logout", 1)
+		require.Equal(t, res.StatusCode, http.StatusOK)
+		require.Equal(t, res.Cookies()[0].MaxAge, -1)
+	})
+}
+
+func TestHandler_ChangePasswordHandler(t *testing.T) {
+	t.Run("should change password and return new tokens", func(t *testing.T) {
+		user := model.User{ID: 666}
+
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("ChangePassword", mock.Anything, user, "current", "next", true).
+			Return(model.AuthTokens{AccessToken: "secretToken", RefreshToken: "refreshToken"}, nil)
+
+		body := bytes.NewReader([]byte(`{"current_password":"current","new_password":"next"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/password", body)
+		ctx := appContext.WithClaims(appContext.WithUser(context.Background(), &user), &model.Claims{MFA: true})
+		request = request.WithContext(ctx)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("SuccessUser", mock.Anything, 666).Return(nil)
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/password", h.ChangePasswordHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		m.AssertNumberOfCalls(t, "ChangePassword", 1)
+		guard.AssertNumberOfCalls(t, "SuccessUser", 1)
+		require.Equal(t, string(resBody), `{"token":"secretToken","refresh_token":"refreshToken"}`)
+	})
+
+	t.Run("should return 403 for wrong current password", func(t *testing.T) {
+		user := model.User{ID: 666}
+
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("ChangePassword", mock.Anything, user, "wrong", "next", false).
+			Return(model.AuthTokens{}, service.ErrWrongPassword)
+
+		body := bytes.NewReader([]byte(`{"current_password":"wrong","new_password":"next"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/password", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &user))
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("FailureUser", mock.Anything, 666, "192.0.2.1").Return(nil)
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/password", h.ChangePasswordHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		guard.AssertNumberOfCalls(t, "FailureUser", 1)
+		require.Equal(t, res.StatusCode, http.StatusForbidden)
+	})
+
+	t.Run("should return 429 while confirmations are blocked", func(t *testing.T) {
+		user := model.User{ID: 666}
+
+		m := mocks.UserService{Mock: mock.Mock{}}
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(90*time.Second, service.ErrTooManyLoginAttempts)
+
+		body := bytes.NewReader([]byte(`{"current_password":"guess","new_password":"next"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/password", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &user))
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/user/password", h.ChangePasswordHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		require.Equal(t, res.StatusCode, http.StatusTooManyRequests)
+		require.Equal(t, res.Header.Get("Retry-After"), "90")
+	})
+}
+
+func TestHandler_DeleteUserHandler(t *testing.T) {
+	t.Run("should delete user and clear cookie", func(t *testing.T) {
+		user := model.User{ID: 666}
+
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("DeleteUser", mock.Anything, user, "userPassword").Return(nil)
+
+		body := bytes.NewReader([]byte(`{"password":"userPassword"}`))
+		request := httptest.NewRequest(http.MethodDelete, "/user", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &user))
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Delete("/user", h.DeleteUserHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		m.AssertNumberOfCalls(t, "DeleteUser", 1)
+		require.Equal(t, res.StatusCode, http.StatusNoContent)
+		require.Equal(t, res.Cookies()[0].MaxAge, -1)
+	})
+	t.Run("should count wrong password", func(t *testing.T) {
+		user := model.User{ID: 666}
+
+		m := mocks.UserService{Mock: mock.Mock{}}
+		m.On("DeleteUser", mock.Anything, user, "guess").Return(service.ErrWrongPassword)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("FailureUser", mock.Anything, 666, "192.0.2.1").Return(nil)
+
+		body := bytes.NewReader([]byte(`{"password":"guess"}`))
+		request := httptest.NewRequest(http.MethodDelete, "/user", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &user))
+
+		h := Handler{user: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Delete("/user", h.DeleteUserHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		guard.AssertNumberOfCalls(t, "FailureUser", 1)
+		require.Equal(t, res.StatusCode, http.StatusForbidden)
 	})
 }
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) CreateWebhookHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "CreateWebhookHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		var webhookDto model.WebhookRequestDto
+		err := json.NewDecoder(r.Body).Decode(&webhookDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid parse body")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		err = webhookDto.Validate()
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid webhook url")
+			http.Error(rw, "invalid webhook url", http.StatusUnprocessableEntity)
+			return
+		}
+
+		webhook, err := h.webhook.CreateWebhook(ctx, webhookDto.URL)
+		if err != nil {
+			logger.Error().Err(err).Msg("invalid create webhook")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusCreated)
+
+		bytes, _ := json.Marshal(webhook)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) WebhooksHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "WebhooksHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		webhooks, err := h.webhook.WebhooksByUser(ctx, user.ID)
+		if err != nil {
+			h.Log(ctx).Err(err).Msg("invalid find webhooks")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if len(webhooks) == 0 {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(webhooks)
+		rw.Write(bytes)
+	}
+}
+
+func (h *Handler) DeleteWebhookHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "DeleteWebhookHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid webhook id")
+			http.Error(rw, "invalid webhook id", http.StatusBadRequest)
+			return
+		}
+
+		err = h.webhook.DeleteWebhook(ctx, user.ID, id)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				http.Error(rw, "webhook not found", http.StatusNotFound)
+				return
+			}
+
+			h.Log(ctx).Err(err).Msg("invalid delete webhook")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		rw.Write([]byte("OK"))
+	}
+}
+
+func (h *Handler) WebhookDeliveriesHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "WebhookDeliveriesHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid webhook id")
+			http.Error(rw, "invalid webhook id", http.StatusBadRequest)
+			return
+		}
+
+		deliveries, err := h.webhook.DeliveriesByWebhook(ctx, user.ID, id)
+		if err != nil {
+			h.Log(ctx).Err(err).Msg("invalid find deliveries")
+			http.Error(rw, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		if len(deliveries) == 0 {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(deliveries)
+		rw.Write(bytes)
+	}
+}
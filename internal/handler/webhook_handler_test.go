@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service/mocks"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_CreateWebhookHandler(t *testing.T) {
+	t.Run("1. should create webhook with status `201`", func(t *testing.T) {
+		m := mocks.WebhookService{Mock: mock.Mock{}}
+		m.On("CreateWebhook", mock.Anything, "https://partner.app/hook").
+			Return(model.Webhook{ID: 1, URL: "https://partner.app/hook", Secret: "secret", CreatedAt: time.Time{}}, nil)
+
+		body := bytes.NewReader([]byte(`{"url":"https://partner.app/hook"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/webhooks", body)
+
+		h := Handler{webhook: &m, Mux: chi.NewMux()}
+		h.Post("/user/webhooks", h.CreateWebhookHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		m.AssertNumberOfCalls(t, "CreateWebhook", 1)
+		require.Equal(t, res.StatusCode, http.StatusCreated)
+		require.Equal(t, string(resBody),
+			`{"id":1,"url":"https://partner.app/hook","secret":"secret","created_at":"0001-01-01T00:00:00Z"}`)
+	})
+
+	t.Run("2. should return error when url is invalid", func(t *testing.T) {
+		body := bytes.NewReader([]byte(`{"url":"ftp://partner.app"}`))
+		request := httptest.NewRequest(http.MethodPost, "/user/webhooks", body)
+
+		h := Handler{Mux: chi.NewMux()}
+		h.Post("/user/webhooks", h.CreateWebhookHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("3. should return error when url points to internal network", func(t *testing.T) {
+		for _, url := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest", "http://localhost/hook", "http://[::1]/hook"} {
+			body := bytes.NewReader([]byte(`{"url":"` + url + `"}`))
+			request := httptest.NewRequest(http.MethodPost, "/user/webhooks", body)
+
+			h := Handler{Mux: chi.NewMux()}
+			h.Post("/user/webhooks", h.CreateWebhookHandler())
+
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, request)
+			res := w.Result()
+			res.Body.Close()
+
+			require.Equal(t, res.StatusCode, http.StatusUnprocessableEntity, url)
+		}
+	})
+}
+
+func TestHandler_WebhookDeliveriesHandler(t *testing.T) {
+	t.Run("should return delivery log of webhook", func(t *testing.T) {
+		m := mocks.WebhookService{Mock: mock.Mock{}}
+		m.On("DeliveriesByWebhook", mock.Anything, 666, 1).Return([]model.WebhookDelivery{
+			{ID: 5, WebhookID: 1, Event: model.EventOrderStatusChanged, Payload: "{}", Status: model.DeliveryDelivered, Attempts: 1},
+		}, nil)
+
+		request := httptest.NewRequest(http.MethodGet, "/user/webhooks/1/deliveries", nil)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{webhook: &m, Mux: chi.NewMux()}
+		h.Get("/user/webhooks/{id}/deliveries", h.WebhookDeliveriesHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		m.AssertNumberOfCalls(t, "DeliveriesByWebhook", 1)
+		require.Equal(t, string(resBody),
+			`[{"id":5,"webhook_id":1,"event":"order.status_changed","payload":"{}","status":"DELIVERED","attempts":1,`+
+				`"next_attempt_at":"0001-01-01T00:00:00Z","created_at":"0001-01-01T00:00:00Z"}]`)
+	})
+}
@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
 	"github.com/djokcik/gophermart/internal/storage"
 	appContext "github.com/djokcik/gophermart/pkg/context"
 	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/go-chi/chi/v5"
 	"net/http"
+	"strconv"
 )
 
 func (h *Handler) WithdrawHandler() http.HandlerFunc {
@@ -37,7 +40,7 @@ func (h *Handler) WithdrawHandler() http.HandlerFunc {
 			return
 		}
 
-		err = h.withdraw.ProcessWithdraw(ctx, withdrawDto.OrderID, withdrawDto.Sum)
+		pending, err := h.withdraw.ProcessWithdraw(ctx, withdrawDto.OrderID, withdrawDto.Sum)
 		if err != nil {
 			if errors.Is(err, storage.ErrInsufficientFunds) {
 				h.Log(ctx).Trace().Err(storage.ErrInsufficientFunds).Msg("WithdrawHandler:")
@@ -46,15 +49,159 @@ func (h *Handler) WithdrawHandler() http.HandlerFunc {
 				return
 			}
 
+			if errors.Is(err, storage.ErrOrderAlreadyPaid) {
+				h.Log(ctx).Trace().Err(err).Msg("WithdrawHandler:")
+				http.Error(rw, "order already paid", http.StatusConflict)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrOrderUploadedForAccrual) {
+				h.Log(ctx).Trace().Err(err).Msg("WithdrawHandler:")
+				http.Error(rw, "order already uploaded for accrual", http.StatusConflict)
+
+				return
+			}
+
 			h.Log(ctx).Trace().Err(err).Msg("WithdrawHandler:")
 			http.Error(rw, "insufficient funds", http.StatusInternalServerError)
 			return
 		}
 
+		if pending != nil {
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(http.StatusAccepted)
+
+			bytes, _ := json.Marshal(model.PendingWithdrawDto{ConfirmationRequired: true, PendingWithdraw: *pending})
+			rw.Write(bytes)
+			return
+		}
+
+		rw.Write([]byte("OK"))
+	}
+}
+
+// ConfirmWithdrawHandler completes pending withdrawal with the password or 2fa code
+func (h *Handler) ConfirmWithdrawHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "ConfirmWithdrawHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid withdraw id")
+			http.Error(rw, "invalid withdraw id", http.StatusBadRequest)
+			return
+		}
+
+		var confirmDto model.WithdrawConfirmDto
+		err = json.NewDecoder(r.Body).Decode(&confirmDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err = confirmDto.Validate(); err != nil {
+			logger.Trace().Err(err).Msg("invalid validate data")
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user := appContext.User(ctx)
+		if user == nil {
+			h.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+			http.Error(rw, "user not found", http.StatusUnauthorized)
+			return
+		}
+
+		if !h.checkStepUp(rw, r, logger, user.ID) {
+			return
+		}
+
+		err = h.withdraw.ConfirmWithdraw(ctx, id, confirmDto)
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrWrongPassword), errors.Is(err, service.ErrInvalidTwoFactorCode):
+				h.stepUpFailed(r, logger, user.ID)
+				http.Error(rw, "invalid confirmation", http.StatusForbidden)
+			case errors.Is(err, service.ErrTwoFactorNotEnabled):
+				http.Error(rw, "invalid confirmation", http.StatusForbidden)
+			case errors.Is(err, storage.ErrNotFound):
+				http.Error(rw, "pending withdraw not found or expired", http.StatusNotFound)
+			case errors.Is(err, storage.ErrOrderAlreadyPaid):
+				http.Error(rw, "order already paid", http.StatusConflict)
+			case errors.Is(err, service.ErrNotAuthenticated):
+				http.Error(rw, "user not found", http.StatusUnauthorized)
+			default:
+				logger.Error().Err(err).Msg("invalid confirm withdraw")
+				http.Error(rw, "internal error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		h.stepUpPassed(r, logger, user.ID)
+
 		rw.Write([]byte("OK"))
 	}
 }
 
+// ReverseWithdrawHandler returns points of the withdrawal to the user, used by support when store order is cancelled
+func (h *Handler) ReverseWithdrawHandler() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := h.Log(ctx).With().Str(logging.ServiceKey, "ReverseWithdrawHandler").Logger()
+		ctx = logging.SetCtxLogger(ctx, logger)
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			logger.Trace().Err(err).Msg("invalid withdraw id")
+			http.Error(rw, "invalid withdraw id", http.StatusBadRequest)
+			return
+		}
+
+		var reverseDto model.ReverseWithdrawDto
+		err = json.NewDecoder(r.Body).Decode(&reverseDto)
+		if err != nil {
+			logger.Trace().Err(err).Msg("failed parse data")
+			http.Error(rw, "invalid parse body", http.StatusBadRequest)
+			return
+		}
+
+		if err = reverseDto.Validate(); err != nil {
+			logger.Trace().Err(err).Msg("invalid validate data")
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reversal, err := h.withdraw.ReverseWithdraw(ctx, id, reverseDto)
+		if err != nil {
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				http.Error(rw, "withdraw not found", http.StatusNotFound)
+			case errors.Is(err, storage.ErrReversalExceeds):
+				http.Error(rw, "reversal exceeds withdrawn sum", http.StatusConflict)
+			case errors.Is(err, service.ErrAdminSelfAction):
+				http.Error(rw, "reversal of own withdrawal is not allowed", http.StatusForbidden)
+			case errors.Is(err, service.ErrNotAuthenticated):
+				http.Error(rw, "user not found", http.StatusUnauthorized)
+			default:
+				logger.Error().Err(err).Msg("invalid reverse withdraw")
+				http.Error(rw, "internal error", http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		bytes, _ := json.Marshal(reversal)
+		rw.Write(bytes)
+	}
+}
+
 func (h *Handler) WithdrawLogsHandler() http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
 	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
 	appContext "github.com/djokcik/gophermart/pkg/context"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/mock"
@@ -13,6 +15,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestHandler_WithdrawLogsHandler(t *testing.T) {
@@ -48,7 +51,7 @@ func TestHandler_WithdrawHandler(t *testing.T) {
 	t.Run("should be correct withdraw", func(t *testing.T) {
 		m := mocks.WithdrawService{Mock: mock.Mock{}}
 		m.On("ProcessWithdraw", mock.Anything, model.OrderID("9278923470"), model.Amount(1012)).
-			Return(nil)
+			Return(nil, nil)
 
 		body := bytes.NewReader([]byte(`{"order":"9278923470","sum":10.12}`))
 
@@ -68,4 +71,222 @@ func TestHandler_WithdrawHandler(t *testing.T) {
 		require.Equal(t, string(resBody), "OK")
 		m.AssertNumberOfCalls(t, "ProcessWithdraw", 1)
 	})
+
+	t.Run("should return pending withdraw above threshold", func(t *testing.T) {
+		expiresAt := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+		m.On("ProcessWithdraw", mock.Anything, model.OrderID("9278923470"), model.Amount(100000)).
+			Return(&model.PendingWithdraw{ID: 7, OrderID: "9278923470", Sum: 100000, ExpiresAt: model.UploadedTime(expiresAt)}, nil)
+
+		body := bytes.NewReader([]byte(`{"order":"9278923470","sum":1000}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/balance/withdraw", body)
+
+		h := Handler{withdraw: &m, Mux: chi.NewMux()}
+		h.Post("/balance/withdraw", h.WithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, res.StatusCode, http.StatusAccepted)
+		require.Equal(t, string(resBody),
+			`{"confirmation_required":true,"id":7,"order":"9278923470","sum":1000,"created_at":"0001-01-01T00:00:00Z","expires_at":"2022-01-01T12:00:00Z"}`,
+		)
+	})
+}
+
+func TestHandler_WithdrawHandler_OrderAlreadyPaid(t *testing.T) {
+	t.Run("should return 409 for already paid order", func(t *testing.T) {
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+		m.On("ProcessWithdraw", mock.Anything, model.OrderID("9278923470"), model.Amount(1012)).
+			Return(nil, storage.ErrOrderAlreadyPaid)
+
+		body := bytes.NewReader([]byte(`{"order":"9278923470","sum":10.12}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/balance/withdraw", body)
+
+		h := Handler{withdraw: &m, Mux: chi.NewMux()}
+		h.Post("/balance/withdraw", h.WithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusConflict)
+	})
+}
+
+func TestHandler_ConfirmWithdrawHandler(t *testing.T) {
+	t.Run("should confirm pending withdraw", func(t *testing.T) {
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+		m.On("ConfirmWithdraw", mock.Anything, 7, model.WithdrawConfirmDto{Password: "password"}).Return(nil)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("SuccessUser", mock.Anything, 666).Return(nil)
+
+		body := bytes.NewReader([]byte(`{"password":"password"}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/balance/withdraw/7/confirm", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{withdraw: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/balance/withdraw/{id}/confirm", h.ConfirmWithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, string(resBody), "OK")
+		m.AssertNumberOfCalls(t, "ConfirmWithdraw", 1)
+		guard.AssertNumberOfCalls(t, "SuccessUser", 1)
+	})
+
+	t.Run("should return 403 for wrong password", func(t *testing.T) {
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+		m.On("ConfirmWithdraw", mock.Anything, 7, model.WithdrawConfirmDto{Password: "wrong"}).Return(service.ErrWrongPassword)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+		guard.On("FailureUser", mock.Anything, 666, "192.0.2.1").Return(nil)
+
+		body := bytes.NewReader([]byte(`{"password":"wrong"}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/balance/withdraw/7/confirm", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{withdraw: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/balance/withdraw/{id}/confirm", h.ConfirmWithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		guard.AssertNumberOfCalls(t, "FailureUser", 1)
+		require.Equal(t, res.StatusCode, http.StatusForbidden)
+	})
+
+	t.Run("should return 404 for expired withdraw", func(t *testing.T) {
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+		m.On("ConfirmWithdraw", mock.Anything, 7, model.WithdrawConfirmDto{Code: "123456"}).Return(storage.ErrNotFound)
+
+		guard := mocks.LoginGuardService{Mock: mock.Mock{}}
+		guard.On("CheckUser", mock.Anything, 666, "192.0.2.1").Return(time.Duration(0), nil)
+
+		body := bytes.NewReader([]byte(`{"code":"123456"}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/balance/withdraw/7/confirm", body)
+		request = request.WithContext(appContext.WithUser(context.Background(), &model.User{ID: 666}))
+
+		h := Handler{withdraw: &m, loginGuard: &guard, Mux: chi.NewMux()}
+		h.Post("/balance/withdraw/{id}/confirm", h.ConfirmWithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestHandler_ReverseWithdrawHandler(t *testing.T) {
+	t.Run("should reverse part of withdraw", func(t *testing.T) {
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+		m.On("ReverseWithdraw", mock.Anything, 7, model.ReverseWithdrawDto{Sum: 500, Reason: "cancelled"}).
+			Return(model.WithdrawReversal{ID: 3, WithdrawID: 7, UserID: 666, Sum: 500, Reason: "cancelled", AdminID: 1}, nil)
+
+		body := bytes.NewReader([]byte(`{"sum":5,"reason":"cancelled"}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/admin/withdrawals/7/reverse", body)
+
+		h := Handler{withdraw: &m, Mux: chi.NewMux()}
+		h.Post("/admin/withdrawals/{id}/reverse", h.ReverseWithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		resBody, _ := io.ReadAll(res.Body)
+
+		require.Equal(t, string(resBody),
+			`{"id":3,"withdraw_id":7,"sum":5,"reason":"cancelled","admin_id":1,"created_at":"0001-01-01T00:00:00Z"}`)
+	})
+
+	t.Run("should require reason", func(t *testing.T) {
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+
+		body := bytes.NewReader([]byte(`{"sum":5}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/admin/withdrawals/7/reverse", body)
+
+		h := Handler{withdraw: &m, Mux: chi.NewMux()}
+		h.Post("/admin/withdrawals/{id}/reverse", h.ReverseWithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusBadRequest)
+		m.AssertNumberOfCalls(t, "ReverseWithdraw", 0)
+	})
+
+	t.Run("should return 409 when reversal exceeds withdraw", func(t *testing.T) {
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+		m.On("ReverseWithdraw", mock.Anything, 7, mock.Anything).Return(model.WithdrawReversal{}, storage.ErrReversalExceeds)
+
+		body := bytes.NewReader([]byte(`{"reason":"cancelled"}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/admin/withdrawals/7/reverse", body)
+
+		h := Handler{withdraw: &m, Mux: chi.NewMux()}
+		h.Post("/admin/withdrawals/{id}/reverse", h.ReverseWithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusConflict)
+	})
+
+	t.Run("should return 403 for own withdrawal", func(t *testing.T) {
+		m := mocks.WithdrawService{Mock: mock.Mock{}}
+		m.On("ReverseWithdraw", mock.Anything, 7, mock.Anything).Return(model.WithdrawReversal{}, service.ErrAdminSelfAction)
+
+		body := bytes.NewReader([]byte(`{"reason":"cancelled"}`))
+
+		request := httptest.NewRequest(http.MethodPost, "/admin/withdrawals/7/reverse", body)
+
+		h := Handler{withdraw: &m, Mux: chi.NewMux()}
+		h.Post("/admin/withdrawals/{id}/reverse", h.ReverseWithdrawHandler())
+
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		require.Equal(t, res.StatusCode, http.StatusForbidden)
+	})
 }
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+type AdjustmentStatus string
+
+const (
+	AdjustmentPending  AdjustmentStatus = "PENDING"
+	AdjustmentApplied  AdjustmentStatus = "APPLIED"
+	AdjustmentRejected AdjustmentStatus = "REJECTED"
+)
+
+// AdjustmentReasons are reason codes accepted for manual adjustments
+var AdjustmentReasons = []string{"GOODWILL", "SERVICE_FAILURE", "MISSING_ACCRUAL", "DUPLICATE_ACCRUAL", "FRAUD", "OTHER"}
+
+var (
+	ErrAdjustmentSumZero       = errors.New("validate adjustment: sum must not be zero")
+	ErrAdjustmentReasonUnknown = errors.New("validate adjustment: unknown reason code")
+	ErrAdjustmentComment       = errors.New("validate adjustment: comment is required")
+)
+
+type (
+	// Adjustment credits positive Sum to the balance or debits negative one
+	Adjustment struct {
+		ID         int              `json:"id"`
+		UserID     int              `json:"user_id"`
+		Sum        Amount           `json:"sum"`
+		ReasonCode string           `json:"reason_code"`
+		Comment    string           `json:"comment"`
+		Status     AdjustmentStatus `json:"status"`
+		CreatedBy  int              `json:"created_by"`
+		DecidedBy  int              `json:"decided_by,omitempty"`
+		CreatedAt  time.Time        `json:"created_at"`
+		DecidedAt  *time.Time       `json:"decided_at,omitempty"`
+	}
+
+	AdjustmentRequestDto struct {
+		Sum        Amount `json:"sum"`
+		ReasonCode string `json:"reason_code"`
+		Comment    string `json:"comment"`
+	}
+)
+
+func (d AdjustmentRequestDto) Validate() error {
+	if d.Sum == 0 {
+		return ErrAdjustmentSumZero
+	}
+
+	known := false
+	for _, reason := range AdjustmentReasons {
+		if d.ReasonCode == reason {
+			known = true
+			break
+		}
+	}
+
+	if !known {
+		return ErrAdjustmentReasonUnknown
+	}
+
+	if d.Comment == "" || len(d.Comment) > 1000 {
+		return ErrAdjustmentComment
+	}
+
+	return nil
+}
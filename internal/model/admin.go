@@ -0,0 +1,58 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+type Role string
+
+const (
+	RoleUser    Role = "user"
+	RoleSupport Role = "support"
+	RoleAdmin   Role = "admin"
+)
+
+var (
+	ErrInvalidRole       = errors.New("validate role: unknown role")
+	ErrLockReasonMissing = errors.New("validate lock: reason is required")
+)
+
+func (r Role) Validate() error {
+	switch r {
+	case RoleUser, RoleSupport, RoleAdmin:
+		return nil
+	}
+
+	return ErrInvalidRole
+}
+
+type (
+	// UserSummary is the user as seen by support and admins
+	UserSummary struct {
+		ID         int        `json:"id"`
+		Login      string     `json:"login"`
+		Role       Role       `json:"role"`
+		Tier       Tier       `json:"tier"`
+		Balance    Amount     `json:"balance"`
+		CreatedAt  time.Time  `json:"created_at"`
+		LockedAt   *time.Time `json:"locked_at,omitempty"`
+		LockReason string     `json:"lock_reason,omitempty"`
+	}
+
+	LockUserDto struct {
+		Reason string `json:"reason"`
+	}
+
+	RoleDto struct {
+		Role Role `json:"role"`
+	}
+)
+
+func (d LockUserDto) Validate() error {
+	if d.Reason == "" {
+		return ErrLockReasonMissing
+	}
+
+	return nil
+}
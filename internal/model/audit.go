@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+const (
+	AuditTargetUser       = "user"
+	AuditTargetLogin      = "login"
+	AuditTargetSession    = "session"
+	AuditTargetOrder      = "order"
+	AuditTargetWithdrawal = "withdrawal"
+	AuditTargetTransfer   = "transfer"
+	AuditTargetCampaign   = "campaign"
+	AuditTargetAdjustment = "adjustment"
+)
+
+type (
+	// AuditEntry records who did what, ActorID is zero for anonymous actions like failed logins
+	AuditEntry struct {
+		ID         int                    `json:"id"`
+		ActorID    int                    `json:"actor_id,omitempty"`
+		Action     string                 `json:"action"`
+		TargetType string                 `json:"target_type,omitempty"`
+		TargetID   string                 `json:"target_id,omitempty"`
+		IP         string                 `json:"ip,omitempty"`
+		RequestID  string                 `json:"request_id,omitempty"`
+		Details    map[string]interface{} `json:"details,omitempty"`
+		CreatedAt  time.Time              `json:"created_at"`
+	}
+
+	// AuditFilter selects entries newest first, zero fields don't filter.
+	// BeforeID continues the list from the last entry of the previous page.
+	AuditFilter struct {
+		ActorID    int
+		Action     string
+		TargetType string
+		TargetID   string
+		From       time.Time
+		To         time.Time
+		BeforeID   int
+		Limit      int
+	}
+)
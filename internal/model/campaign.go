@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrCampaignNameRequired = errors.New("campaign validate: name required")
+	ErrCampaignPeriod       = errors.New("campaign validate: ends_at must be after starts_at")
+	ErrCampaignReward       = errors.New("campaign validate: either multiplier above 1 or bonus required")
+)
+
+type (
+	// Campaign rewards orders uploaded within [StartsAt, EndsAt) on top of the base accrual.
+	// Multiplier scales the base accrual, Bonus is a fixed sum per order. UserCap limits
+	// bonuses of one user within the campaign, zero means no limit.
+	Campaign struct {
+		ID         int       `json:"id"`
+		Name       string    `json:"name"`
+		StartsAt   time.Time `json:"starts_at"`
+		EndsAt     time.Time `json:"ends_at"`
+		Multiplier float64   `json:"multiplier,omitempty"`
+		Bonus      Amount    `json:"bonus,omitempty"`
+		UserCap    Amount    `json:"user_cap,omitempty"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	CampaignBonus struct {
+		ID         int
+		CampaignID int
+		OrderID    OrderID
+		UserID     int
+		Sum        Amount
+		CreatedAt  time.Time
+	}
+)
+
+func (c Campaign) Validate() error {
+	if c.Name == "" {
+		return ErrCampaignNameRequired
+	}
+
+	if !c.EndsAt.After(c.StartsAt) {
+		return ErrCampaignPeriod
+	}
+
+	if c.Multiplier < 0 || c.Bonus < 0 || (c.Multiplier > 0) == (c.Bonus > 0) {
+		return ErrCampaignReward
+	}
+
+	if c.Multiplier > 0 && c.Multiplier <= 1 {
+		return ErrCampaignReward
+	}
+
+	if c.UserCap < 0 {
+		return errors.New("campaign validate: invalid user_cap")
+	}
+
+	return nil
+}
+
+// BonusFor returns the sum on top of the base accrual, multiplier 2 doubles the accrual
+func (c Campaign) BonusFor(accrual Amount) Amount {
+	if c.Multiplier > 0 {
+		return Amount(math.Round(float64(accrual) * (c.Multiplier - 1)))
+	}
+
+	return c.Bonus
+}
@@ -0,0 +1,15 @@
+package model
+
+import "encoding/json"
+
+const (
+	EventBalanceChanged = "balance.changed"
+)
+
+type (
+	Event struct {
+		Type   string          `json:"type"`
+		UserID int             `json:"user_id"`
+		Data   json.RawMessage `json:"data"`
+	}
+)
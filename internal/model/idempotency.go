@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// IdempotencyRecord remembers the request made with Idempotency-Key and its response.
+// StatusCode is 0 while the first request is still processed.
+type IdempotencyRecord struct {
+	UserID      int
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	ContentType string
+	Response    []byte
+	ExpiresAt   time.Time
+}
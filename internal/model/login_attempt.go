@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// LoginAttempt tracks failed logins for a username or a client IP
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	FirstFailedAt time.Time
+	BlockedUntil  time.Time
+}
@@ -14,6 +14,14 @@ const (
 	StatusInvalid    Status = "INVALID"    // The remuneration calculation system refused to calculate
 )
 
+const (
+	UploadAccepted              UploadResult = "accepted"                 // The order has been stored and waits for the accrual
+	UploadAlreadyUploaded       UploadResult = "already_uploaded"         // The order has already been uploaded by the same user
+	UploadUploadedByAnotherUser UploadResult = "uploaded_by_another_user" // The order belongs to another user
+	UploadInvalid               UploadResult = "invalid"                  // The order number does not pass the Luhn validation
+	UploadPaidWithPoints        UploadResult = "paid_with_points"         // The order number is used by a withdrawal
+)
+
 type (
 	Status       string
 	OrderID      string
@@ -27,6 +35,13 @@ type (
 		UploadedAt UploadedTime `json:"uploaded_at"`
 		Accrual    Amount       `json:"accrual,omitempty"`
 	}
+
+	UploadResult string
+
+	OrderUploadResult struct {
+		ID     OrderID      `json:"number"`
+		Result UploadResult `json:"result"`
+	}
 )
 
 func (s Status) Valid() bool {
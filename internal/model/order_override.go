@@ -0,0 +1,44 @@
+package model
+
+import "errors"
+
+var (
+	ErrOverrideStatus  = errors.New("validate override: status must be PROCESSED or INVALID")
+	ErrOverrideAccrual = errors.New("validate override: accrual must not be negative and INVALID order has no accrual")
+)
+
+type (
+	// OrderOverrideDto sets a final status of the order instead of the accrual system
+	OrderOverrideDto struct {
+		Status  Status `json:"status"`
+		Accrual Amount `json:"accrual"`
+	}
+
+	// OrderOverride is the order after the override, Delta has been applied to the balance of the owner.
+	// ReversedBonuses are campaign, tier and referral bonuses of the order taken back by user,
+	// bonuses of the processed order are applied again by the poller.
+	OrderOverride struct {
+		Order           Order          `json:"order"`
+		PreviousStatus  Status         `json:"previous_status"`
+		PreviousAccrual Amount         `json:"previous_accrual"`
+		Delta           Amount         `json:"balance_delta"`
+		ReversedBonuses map[int]Amount `json:"reversed_bonuses,omitempty"`
+	}
+)
+
+func (d OrderOverrideDto) Validate() error {
+	switch d.Status {
+	case StatusProcessed:
+		if d.Accrual < 0 {
+			return ErrOverrideAccrual
+		}
+	case StatusInvalid:
+		if d.Accrual != 0 {
+			return ErrOverrideAccrual
+		}
+	default:
+		return ErrOverrideStatus
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+const (
+	LotSourceAccrual    = "accrual"
+	LotSourceReversal   = "reversal"
+	LotSourceMigration  = "migration"
+	LotSourceTransfer   = "transfer"
+	LotSourceCampaign   = "campaign"
+	LotSourceReferral   = "referral"
+	LotSourceTier       = "tier"
+	LotSourceAdjustment = "adjustment"
+)
+
+type (
+	// PointLot is a portion of points credited at once, the points expire together
+	PointLot struct {
+		ID        int
+		UserID    int
+		Amount    Amount
+		Remaining Amount
+		Source    string
+		CreatedAt time.Time
+		ExpiresAt time.Time
+	}
+
+	// PointExpiration is written when the rest of the lot expires
+	PointExpiration struct {
+		ID        int
+		LotID     int
+		UserID    int
+		Amount    Amount
+		ExpiredAt time.Time
+	}
+)
@@ -0,0 +1,57 @@
+package model
+
+// AdjustmentReasonReconciliation marks corrections written by the reconciliation, admins can't use it.
+// The ledger doesn't count these adjustments, they bring the balance to the ledger.
+const AdjustmentReasonReconciliation = "RECONCILIATION"
+
+type (
+	// Reconciliation compares the stored balance of the user with the remaining points of lots and with
+	// the ledger built from accruals, bonuses, transfers, withdrawals, adjustments and expirations
+	Reconciliation struct {
+		UserID       int    `json:"user_id"`
+		Login        string `json:"login"`
+		Balance      Amount `json:"balance"`
+		Lots         Amount `json:"lots"`
+		Ledger       Amount `json:"ledger"`
+		Accruals     Amount `json:"accruals"`
+		Bonuses      Amount `json:"bonuses"`
+		TransfersIn  Amount `json:"transfers_in"`
+		TransfersOut Amount `json:"transfers_out"`
+		Withdrawn    Amount `json:"withdrawn"`
+		Reserved     Amount `json:"reserved"`
+		Reversed     Amount `json:"reversed"`
+		Adjustments  Amount `json:"adjustments"`
+		Expired      Amount `json:"expired"`
+	}
+
+	// ReconciliationCorrection is what was applied to bring the balance and point lots to the ledger
+	ReconciliationCorrection struct {
+		AdjustmentID int    `json:"adjustment_id,omitempty"`
+		BalanceDelta Amount `json:"balance_delta"`
+		LotsDelta    Amount `json:"lots_delta"`
+	}
+
+	// ReconciliationMismatch is a user whose balance doesn't match, Correction is set when it was corrected
+	ReconciliationMismatch struct {
+		Reconciliation
+		Correction *ReconciliationCorrection `json:"correction,omitempty"`
+		Error      string                    `json:"error,omitempty"`
+	}
+
+	ReconciliationReport struct {
+		Checked    int                      `json:"checked"`
+		Corrected  int                      `json:"corrected"`
+		Mismatches []ReconciliationMismatch `json:"mismatches"`
+	}
+)
+
+// LedgerBalance is the balance the user should have according to the history of operations
+func (r Reconciliation) LedgerBalance() Amount {
+	return r.Accruals + r.Bonuses + r.TransfersIn - r.TransfersOut - r.Withdrawn - r.Reserved +
+		r.Reversed + r.Adjustments - r.Expired
+}
+
+// Consistent reports whether the balance matches both the lots and the ledger
+func (r Reconciliation) Consistent() bool {
+	return r.Balance == r.Lots && r.Balance == r.Ledger
+}
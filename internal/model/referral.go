@@ -0,0 +1,33 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidReferralCode = errors.New("service: invalid referral code")
+
+type (
+	// Referral is a user registered with the referral code, RewardedAt is set after the first processed order
+	Referral struct {
+		Login      string     `json:"login"`
+		CreatedAt  time.Time  `json:"created_at"`
+		Bonus      Amount     `json:"bonus,omitempty"`
+		RewardedAt *time.Time `json:"rewarded_at,omitempty"`
+	}
+
+	ReferralsDto struct {
+		Code      string     `json:"code"`
+		Referrals []Referral `json:"referrals"`
+	}
+
+	ReferralReward struct {
+		ID            int
+		ReferrerID    int
+		RefereeID     int
+		OrderID       OrderID
+		ReferrerBonus Amount
+		RefereeBonus  Amount
+		CreatedAt     time.Time
+	}
+)
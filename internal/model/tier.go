@@ -0,0 +1,100 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+type Tier string
+
+const (
+	TierBasic    Tier = "BASIC"
+	TierSilver   Tier = "SILVER"
+	TierGold     Tier = "GOLD"
+	TierPlatinum Tier = "PLATINUM"
+)
+
+// TierLevel is reached when points accrued in the rolling window are at least Threshold
+type TierLevel struct {
+	Tier       Tier
+	Threshold  Amount
+	Multiplier float64
+}
+
+// TierLevels are sorted by Threshold, the first level is given to every user
+var TierLevels = []TierLevel{
+	{Tier: TierBasic, Threshold: 0, Multiplier: 1},
+	{Tier: TierSilver, Threshold: 100000, Multiplier: 1.1},
+	{Tier: TierGold, Threshold: 500000, Multiplier: 1.25},
+	{Tier: TierPlatinum, Threshold: 2000000, Multiplier: 1.5},
+}
+
+// TierByVolume returns the highest level reached with volume
+func TierByVolume(volume Amount) TierLevel {
+	level := TierLevels[0]
+	for _, next := range TierLevels[1:] {
+		if volume < next.Threshold {
+			break
+		}
+
+		level = next
+	}
+
+	return level
+}
+
+// LevelOf returns the level of tier, unknown tiers are treated as the first level
+func LevelOf(tier Tier) TierLevel {
+	for _, level := range TierLevels {
+		if level.Tier == tier {
+			return level
+		}
+	}
+
+	return TierLevels[0]
+}
+
+// BonusFor returns the sum of the multiplier on top of the accrual
+func (l TierLevel) BonusFor(accrual Amount) Amount {
+	return Amount(math.Round(float64(accrual) * (l.Multiplier - 1)))
+}
+
+// NextLevel returns the level above tier, false for the highest one
+func NextLevel(tier Tier) (TierLevel, bool) {
+	for i, level := range TierLevels[:len(TierLevels)-1] {
+		if level.Tier == tier {
+			return TierLevels[i+1], true
+		}
+	}
+
+	return TierLevel{}, false
+}
+
+type (
+	// AccrualVolume is sum of accruals of the user's processed orders in the rolling window
+	AccrualVolume struct {
+		UserID int
+		Volume Amount
+	}
+
+	// TierBonus is credited on top of the order accrual by the tier multiplier
+	TierBonus struct {
+		ID        int
+		OrderID   OrderID
+		UserID    int
+		Tier      Tier
+		Sum       Amount
+		CreatedAt time.Time
+	}
+
+	// Profile shows the current tier and progress to the next one, Next fields are empty on the highest tier
+	Profile struct {
+		Login         string  `json:"login"`
+		Tier          Tier    `json:"tier"`
+		Multiplier    float64 `json:"multiplier"`
+		Volume        Amount  `json:"volume"`
+		NextTier      Tier    `json:"next_tier,omitempty"`
+		NextThreshold Amount  `json:"next_threshold,omitempty"`
+		ToNextTier    Amount  `json:"to_next_tier,omitempty"`
+	}
+)
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+)
+
+type (
+	// AuthTokens holds either issued tokens or ChallengeToken when the second factor is required
+	AuthTokens struct {
+		AccessToken    string
+		RefreshToken   string
+		ChallengeToken string
+	}
+
+	RefreshRequestDto struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	RefreshToken struct {
+		ID        int
+		UserID    int
+		SessionID string
+		TokenHash string
+		ExpiresAt time.Time
+		Used      bool
+		Revoked   bool
+		// MFA is true when the session was opened with the second factor
+		MFA bool
+	}
+)
@@ -0,0 +1,48 @@
+package model
+
+import "errors"
+
+const (
+	TransferIncoming TransferDirection = "IN"
+	TransferOutgoing TransferDirection = "OUT"
+)
+
+var ErrRecipientRequired = errors.New("service: recipient required")
+
+type (
+	TransferDirection string
+
+	TransferRequestDto struct {
+		Recipient string `json:"recipient"`
+		Sum       Amount `json:"sum"`
+	}
+
+	Transfer struct {
+		ID          int          `json:"id"`
+		SenderID    int          `json:"-"`
+		RecipientID int          `json:"-"`
+		Sum         Amount       `json:"sum"`
+		CreatedAt   UploadedTime `json:"created_at"`
+	}
+
+	// TransferLog is the transfer as seen by one of its sides
+	TransferLog struct {
+		ID           int               `json:"id"`
+		Direction    TransferDirection `json:"direction"`
+		Counterparty string            `json:"counterparty"`
+		Sum          Amount            `json:"sum"`
+		CreatedAt    UploadedTime      `json:"created_at"`
+	}
+)
+
+func (t TransferRequestDto) Validate() error {
+	if t.Recipient == "" {
+		return ErrRecipientRequired
+	}
+
+	if t.Sum <= 0 {
+		return errors.New("transfer validate: invalid sum")
+	}
+
+	return nil
+}
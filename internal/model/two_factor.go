@@ -0,0 +1,33 @@
+package model
+
+type (
+	TwoFactor struct {
+		UserID   int
+		Secret   string
+		Enabled  bool
+		LastStep int64
+	}
+
+	TOTPEnrollment struct {
+		Secret string `json:"secret"`
+		URI    string `json:"uri"`
+	}
+
+	TwoFactorCodeDto struct {
+		Code string `json:"code"`
+	}
+
+	TwoFactorLoginDto struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+
+	TwoFactorChallengeDto struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
+	}
+
+	RecoveryCodesDto struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+)
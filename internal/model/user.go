@@ -11,16 +11,33 @@ var ErrInvalidAccessToken = errors.New("invalid auth token")
 type (
 	Claims struct {
 		jwt.StandardClaims
-		ID int
+		ID        int
+		SessionID string `json:"sid"`
+		// MFA marks tokens of sessions opened with the second factor
+		MFA bool `json:"mfa,omitempty"`
+		// Purpose is set for tokens which are not access tokens, e.g. 2fa challenge
+		Purpose string `json:"pur,omitempty"`
 	}
 
 	UserRequestDto struct {
 		Login    string `json:"login"`
 		Password string `json:"password"`
+		// ReferralCode is optional code of the inviting user on registration
+		ReferralCode string `json:"referral_code,omitempty"`
+	}
+
+	ChangePasswordRequestDto struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+
+	DeleteUserRequestDto struct {
+		Password string `json:"password"`
 	}
 
 	UserResponseDto struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token,omitempty"`
 	}
 
 	User struct {
@@ -30,11 +47,27 @@ type (
 		Balance   Amount    `json:"balance"`
 
 		Password string
+		PepperID string
+
+		ReferralCode string
+		// ReferredBy is id of the user whose referral code was used on registration
+		ReferredBy int
+
+		Role Role
+		// LockedAt is set while the account is locked by an admin
+		LockedAt *time.Time
+	}
+
+	PepperUsage struct {
+		PepperID string
+		Users    int
 	}
 
 	UserBalance struct {
 		Current   Amount `json:"current"`
 		Withdrawn Amount `json:"withdrawn"`
+		// ExpiringSoon are points of the current balance which expire within the configured window
+		ExpiringSoon Amount `json:"expiring_soon"`
 	}
 )
 
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"errors"
+	"github.com/djokcik/gophermart/pkg/netguard"
+	"net/url"
+	"time"
+)
+
+const (
+	EventOrderStatusChanged = "order.status_changed"
+
+	DeliveryPending   DeliveryStatus = "PENDING"   // The delivery waits for the next attempt
+	DeliveryDelivered DeliveryStatus = "DELIVERED" // The receiver answered with 2xx
+	DeliveryFailed    DeliveryStatus = "FAILED"    // All attempts are exhausted
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("validate webhook: invalid url")
+)
+
+type (
+	DeliveryStatus string
+
+	WebhookRequestDto struct {
+		URL string `json:"url"`
+	}
+
+	Webhook struct {
+		ID        int       `json:"id"`
+		UserID    int       `json:"-"`
+		URL       string    `json:"url"`
+		Secret    string    `json:"secret,omitempty"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	WebhookDelivery struct {
+		ID            int            `json:"id"`
+		WebhookID     int            `json:"webhook_id"`
+		Event         string         `json:"event"`
+		Payload       string         `json:"payload"`
+		Status        DeliveryStatus `json:"status"`
+		Attempts      int            `json:"attempts"`
+		ResponseCode  int            `json:"response_code,omitempty"`
+		LastError     string         `json:"last_error,omitempty"`
+		NextAttemptAt time.Time      `json:"next_attempt_at"`
+		CreatedAt     time.Time      `json:"created_at"`
+
+		URL    string `json:"-"`
+		Secret string `json:"-"`
+	}
+
+	OrderStatusEvent struct {
+		Event   string  `json:"event"`
+		OrderID OrderID `json:"order"`
+		Status  Status  `json:"status"`
+		Accrual Amount  `json:"accrual,omitempty"`
+	}
+)
+
+// Validate refuses local and internal hosts early, the client checks resolved addresses on delivery
+func (w WebhookRequestDto) Validate() error {
+	u, err := url.ParseRequestURI(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+
+	if !netguard.IsPublicHost(u.Hostname()) {
+		return ErrInvalidWebhookURL
+	}
+
+	return nil
+}
@@ -2,10 +2,18 @@ package model
 
 import (
 	"errors"
+	"time"
+)
+
+const (
+	WithdrawReversed          WithdrawStatus = "REVERSED"           // All points are returned to the user
+	WithdrawPartiallyReversed WithdrawStatus = "PARTIALLY_REVERSED" // Part of points is returned to the user
 )
 
 var (
-	ErrInvalidOrderID = errors.New("service: invalid orderID")
+	ErrInvalidOrderID       = errors.New("service: invalid orderID")
+	ErrConfirmationRequired = errors.New("service: password or 2fa code required")
+	ErrReasonRequired       = errors.New("service: reason required")
 )
 
 type (
@@ -14,12 +22,55 @@ type (
 		Sum     Amount  `json:"sum"`
 	}
 
+	WithdrawStatus string
+
 	Withdraw struct {
-		ID          int          `json:"-"`
-		OrderID     OrderID      `json:"order"`
-		Sum         Amount       `json:"sum"`
-		ProcessedAt UploadedTime `json:"processed_at"`
-		UserID      int          `json:"-"`
+		ID          int            `json:"-"`
+		OrderID     OrderID        `json:"order"`
+		Sum         Amount         `json:"sum"`
+		ProcessedAt UploadedTime   `json:"processed_at"`
+		UserID      int            `json:"-"`
+		ReversedSum Amount         `json:"reversed_sum,omitempty"`
+		Status      WithdrawStatus `json:"status,omitempty"`
+	}
+
+	// WithdrawReversal returns points of cancelled store order, Sum may be less than the withdrawal
+	WithdrawReversal struct {
+		ID         int          `json:"id"`
+		WithdrawID int          `json:"withdraw_id"`
+		UserID     int          `json:"-"`
+		Sum        Amount       `json:"sum"`
+		Reason     string       `json:"reason"`
+		AdminID    int          `json:"admin_id"`
+		CreatedAt  UploadedTime `json:"created_at"`
+		// ExpiresAt is expiry of points which can't be returned to the consumed lots
+		ExpiresAt time.Time `json:"-"`
+	}
+
+	// ReverseWithdrawDto reverses the rest of the withdrawal when Sum is omitted
+	ReverseWithdrawDto struct {
+		Sum    Amount `json:"sum"`
+		Reason string `json:"reason"`
+	}
+
+	// PendingWithdraw reserves points until the user confirms the withdrawal or it expires
+	PendingWithdraw struct {
+		ID        int          `json:"id"`
+		OrderID   OrderID      `json:"order"`
+		Sum       Amount       `json:"sum"`
+		UserID    int          `json:"-"`
+		CreatedAt UploadedTime `json:"created_at"`
+		ExpiresAt UploadedTime `json:"expires_at"`
+	}
+
+	PendingWithdrawDto struct {
+		ConfirmationRequired bool `json:"confirmation_required"`
+		PendingWithdraw
+	}
+
+	WithdrawConfirmDto struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
 	}
 )
 
@@ -34,3 +85,35 @@ func (w WithdrawRequestDto) Validate() error {
 
 	return nil
 }
+
+func (w WithdrawConfirmDto) Validate() error {
+	if w.Password == "" && w.Code == "" {
+		return ErrConfirmationRequired
+	}
+
+	return nil
+}
+
+func (w ReverseWithdrawDto) Validate() error {
+	if w.Sum < 0 {
+		return errors.New("reverse validate: invalid sum")
+	}
+
+	if w.Reason == "" {
+		return ErrReasonRequired
+	}
+
+	return nil
+}
+
+// WithdrawStatusOf tells whether points of the withdrawal were returned
+func WithdrawStatusOf(sum Amount, reversedSum Amount) WithdrawStatus {
+	switch {
+	case reversedSum <= 0:
+		return ""
+	case reversedSum >= sum:
+		return WithdrawReversed
+	default:
+		return WithdrawPartiallyReversed
+	}
+}
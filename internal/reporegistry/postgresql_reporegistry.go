@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/internal/storage/memory"
 	"github.com/djokcik/gophermart/internal/storage/psql"
 	"github.com/djokcik/gophermart/pkg/logging"
 	"github.com/golang-migrate/migrate/v4"
@@ -24,30 +25,66 @@ type RepoRegistry interface {
 	GetUserRepo() storage.UserRepository
 	GetOrderRepo() storage.OrderRepository
 	GetWithdrawRepo() storage.WithdrawRepository
+	GetWebhookRepo() storage.WebhookRepository
+	GetEventRepo() storage.EventRepository
+	GetTokenRepo() storage.TokenRepository
+	GetLoginAttemptRepo() storage.LoginAttemptRepository
+	GetTwoFactorRepo() storage.TwoFactorRepository
+	GetIdempotencyRepo() storage.IdempotencyRepository
+	GetPointLotRepo() storage.PointLotRepository
+	GetTransferRepo() storage.TransferRepository
+	GetCampaignRepo() storage.CampaignRepository
+	GetReferralRepo() storage.ReferralRepository
+	GetTierRepo() storage.TierRepository
+	GetAdjustmentRepo() storage.AdjustmentRepository
+	GetAuditRepo() storage.AuditRepository
+	GetReconciliationRepo() storage.ReconciliationRepository
 }
 
 type postgresqlRepoRegistry struct {
-	db *sql.DB
+	db            *sql.DB
+	dsn           string
+	loginAttempts storage.LoginAttemptRepository
 }
 
 func NewPostgreSQL(ctx context.Context, cfg config.Config) (RepoRegistry, error) {
-	err := autoMigrate(ctx, "file://internal/storage/psql/migrations", cfg)
+	err := autoMigrate(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
+	return OpenPostgreSQL(ctx, cfg)
+}
+
+// OpenPostgreSQL connects without migrating the schema, tools leave migrations to the operator
+func OpenPostgreSQL(ctx context.Context, cfg config.Config) (RepoRegistry, error) {
 	db, err := open(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &postgresqlRepoRegistry{db: db}, nil
+	loginAttempts := psql.NewLoginAttemptRepository(db)
+	if cfg.LoginAttemptStore == config.LoginAttemptStoreMemory {
+		loginAttempts = memory.NewLoginAttemptRepository()
+	}
+
+	return &postgresqlRepoRegistry{db: db, dsn: cfg.DatabaseURI, loginAttempts: loginAttempts}, nil
+}
+
+// NewMigrator returns migrations of the database schema, the caller closes it
+func NewMigrator(cfg config.Config) (*migrate.Migrate, error) {
+	m, err := migrate.New(cfg.MigrationsPath, cfg.DatabaseURI)
+	if err != nil {
+		return nil, fmt.Errorf("psql: migrations: %w", err)
+	}
+
+	return m, nil
 }
 
-func autoMigrate(ctx context.Context, path string, cfg config.Config) error {
+func autoMigrate(ctx context.Context, cfg config.Config) error {
 	_, logger := logging.GetCtxLogger(ctx)
 
-	m, err := migrate.New(path, cfg.DatabaseURI)
+	m, err := NewMigrator(cfg)
 	if err != nil {
 		return fmt.Errorf("psql: autoMigrate: %w", err)
 	}
@@ -84,3 +121,59 @@ func (r postgresqlRepoRegistry) GetOrderRepo() storage.OrderRepository {
 func (r postgresqlRepoRegistry) GetWithdrawRepo() storage.WithdrawRepository {
 	return psql.NewWithdrawRepository(r.db)
 }
+
+func (r postgresqlRepoRegistry) GetWebhookRepo() storage.WebhookRepository {
+	return psql.NewWebhookRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetEventRepo() storage.EventRepository {
+	return psql.NewEventRepository(r.db, r.dsn)
+}
+
+func (r postgresqlRepoRegistry) GetTokenRepo() storage.TokenRepository {
+	return psql.NewTokenRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetLoginAttemptRepo() storage.LoginAttemptRepository {
+	return r.loginAttempts
+}
+
+func (r postgresqlRepoRegistry) GetTwoFactorRepo() storage.TwoFactorRepository {
+	return psql.NewTwoFactorRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetIdempotencyRepo() storage.IdempotencyRepository {
+	return psql.NewIdempotencyRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetPointLotRepo() storage.PointLotRepository {
+	return psql.NewPointLotRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetTransferRepo() storage.TransferRepository {
+	return psql.NewTransferRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetCampaignRepo() storage.CampaignRepository {
+	return psql.NewCampaignRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetReferralRepo() storage.ReferralRepository {
+	return psql.NewReferralRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetTierRepo() storage.TierRepository {
+	return psql.NewTierRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetAdjustmentRepo() storage.AdjustmentRepository {
+	return psql.NewAdjustmentRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetAuditRepo() storage.AuditRepository {
+	return psql.NewAuditRepository(r.db)
+}
+
+func (r postgresqlRepoRegistry) GetReconciliationRepo() storage.ReconciliationRepository {
+	return psql.NewReconciliationRepository(r.db)
+}
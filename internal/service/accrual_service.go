@@ -6,6 +6,7 @@ import (
 	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
 	"github.com/djokcik/gophermart/pkg/logging"
 	"github.com/djokcik/gophermart/provider"
 	"github.com/rs/zerolog"
@@ -13,20 +14,31 @@ import (
 
 //go:generate mockery --name=AccrualService
 
+// pendingBonusesBatch limits orders whose failed bonuses are retried on one poll
+const pendingBonusesBatch = 100
+
 type AccrualService interface {
 	Poller(ctx context.Context) func()
 }
 
-func NewAccrualService(cfg config.Config, registry reporegistry.RepoRegistry) AccrualService {
+func NewAccrualService(cfg config.Config, registry reporegistry.RepoRegistry, events EventService) AccrualService {
 	return &accrualService{
-		client: provider.NewAccrualClient(cfg),
-		order:  NewOrderService(cfg, registry),
+		client:    provider.NewAccrualClient(cfg),
+		order:     NewOrderService(cfg, registry),
+		campaigns: NewCampaignService(cfg, registry),
+		referrals: NewReferralService(cfg, registry),
+		tiers:     NewTierService(cfg, registry),
+		events:    events,
 	}
 }
 
 type accrualService struct {
-	client provider.AccrualClient
-	order  OrderService
+	client    provider.AccrualClient
+	order     OrderService
+	campaigns CampaignService
+	referrals ReferralService
+	tiers     TierService
+	events    EventService
 }
 
 func (a accrualService) Poller(ctx context.Context) func() {
@@ -36,6 +48,8 @@ func (a accrualService) Poller(ctx context.Context) func() {
 		for _, order := range orders {
 			a.ProcessOrder(ctx, order)
 		}
+
+		a.retryBonuses(ctx)
 	}
 }
 
@@ -54,9 +68,107 @@ func (a accrualService) ProcessOrder(ctx context.Context, order model.Order) {
 
 	err = a.order.UpdateForAccrual(ctx, order, response)
 	if err != nil {
+		// the admin override wins, its accrual is already credited
+		if errors.Is(err, storage.ErrOrderFinalized) {
+			a.Log(ctx).Info().Str("order", string(order.ID)).Msg("UpdateForAccrual: order finalized meanwhile")
+			return
+		}
+
 		a.Log(ctx).Error().Err(err).Msg("UpdateForAccrual:")
 		return
 	}
+
+	var bonus model.Amount
+	if response.Status == model.StatusProcessed {
+		bonus = a.applyBonuses(ctx, order)
+	}
+
+	a.publish(ctx, order, response, bonus)
+}
+
+// applyBonuses credits campaign, tier and referral bonuses of the processed order and returns bonus of the owner.
+// The order stays pending until all of them succeed, unique indexes of the bonuses make the retries idempotent.
+func (a accrualService) applyBonuses(ctx context.Context, order model.Order) model.Amount {
+	failed := false
+
+	bonus, err := a.campaigns.ApplyCampaigns(ctx, order)
+	if err != nil {
+		a.Log(ctx).Error().Err(err).Str("order", string(order.ID)).Msg("applyBonuses: apply campaigns")
+		failed = true
+	}
+
+	tierBonus, err := a.tiers.ApplyTier(ctx, order)
+	if err != nil {
+		a.Log(ctx).Error().Err(err).Str("order", string(order.ID)).Msg("applyBonuses: apply tier")
+		failed = true
+	}
+
+	refereeBonus, err := a.rewardReferral(ctx, order)
+	if err != nil {
+		failed = true
+	}
+
+	if !failed {
+		if err = a.order.CompleteBonuses(ctx, order.ID); err != nil {
+			a.Log(ctx).Error().Err(err).Str("order", string(order.ID)).Msg("applyBonuses: complete bonuses")
+		}
+	}
+
+	return bonus + tierBonus + refereeBonus
+}
+
+// retryBonuses applies bonuses which failed after the accrual of the order was committed
+func (a accrualService) retryBonuses(ctx context.Context) {
+	orders, err := a.order.PendingBonusOrders(ctx, pendingBonusesBatch)
+	if err != nil {
+		a.Log(ctx).Error().Err(err).Msg("retryBonuses: failed get orders")
+		return
+	}
+
+	for _, order := range orders {
+		if bonus := a.applyBonuses(ctx, order); bonus > 0 {
+			if err = a.events.PublishBalance(ctx, order.UserID); err != nil {
+				a.Log(ctx).Error().Err(err).Msg("retryBonuses: publish balance")
+			}
+		}
+	}
+}
+
+// rewardReferral returns bonus of the order owner, the referrer is notified here
+func (a accrualService) rewardReferral(ctx context.Context, order model.Order) (model.Amount, error) {
+	reward, err := a.referrals.RewardReferral(ctx, order)
+	if err != nil {
+		a.Log(ctx).Error().Err(err).Str("order", string(order.ID)).Msg("applyBonuses: reward referral")
+		return 0, err
+	}
+
+	if reward.ReferrerBonus > 0 {
+		if err := a.events.PublishBalance(ctx, reward.ReferrerID); err != nil {
+			a.Log(ctx).Error().Err(err).Msg("publish: referrer balance")
+		}
+	}
+
+	return reward.RefereeBonus, nil
+}
+
+// publish notifies subscribers of the order owner about the order status and balance changes
+func (a accrualService) publish(ctx context.Context, order model.Order, response provider.AccrualResponse, bonus model.Amount) {
+	if order.Status == response.Status {
+		return
+	}
+
+	order.Status = response.Status
+	order.Accrual = response.Accrual
+
+	if err := a.events.PublishOrderStatus(ctx, order); err != nil {
+		a.Log(ctx).Error().Err(err).Msg("publish: order status")
+	}
+
+	if response.Accrual > 0 || bonus > 0 {
+		if err := a.events.PublishBalance(ctx, order.UserID); err != nil {
+			a.Log(ctx).Error().Err(err).Msg("publish: balance")
+		}
+	}
 }
 
 func (a accrualService) getOrders(ctx context.Context) []model.Order {
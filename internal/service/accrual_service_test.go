@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
 	"github.com/djokcik/gophermart/provider"
 	providerMocks "github.com/djokcik/gophermart/provider/mocks"
 	"github.com/stretchr/testify/mock"
@@ -24,12 +26,197 @@ func Test_accrualService_ProcessOrder(t *testing.T) {
 		mockOrder.On("UpdateForAccrual", mock.Anything, order, accrualResponse).
 			Return(nil)
 
-		service := accrualService{order: &mockOrder, client: &mockClient}
+		mockEvents := mocks.EventService{Mock: mock.Mock{}}
+		mockEvents.On("PublishOrderStatus", mock.Anything, model.Order{ID: "1", Accrual: 1000}).Return(nil)
+		mockEvents.On("PublishBalance", mock.Anything, 0).Return(nil)
+
+		service := accrualService{order: &mockOrder, client: &mockClient, events: &mockEvents}
 
 		service.ProcessOrder(context.Background(), order)
 
 		mockClient.AssertNumberOfCalls(t, "GetOrder", 1)
 		mockOrder.AssertNumberOfCalls(t, "UpdateForAccrual", 1)
+		mockEvents.AssertNumberOfCalls(t, "PublishOrderStatus", 1)
+		mockEvents.AssertNumberOfCalls(t, "PublishBalance", 1)
+	})
+
+	t.Run("should skip bonuses and events of order finalized meanwhile", func(t *testing.T) {
+		order := model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing}
+		accrualResponse := provider.AccrualResponse{Order: order.ID, Status: model.StatusProcessed, Accrual: 1000}
+
+		mockClient := providerMocks.AccrualClient{Mock: mock.Mock{}}
+		mockClient.On("GetOrder", mock.Anything, order.ID).Return(accrualResponse, nil)
+
+		mockOrder := mocks.OrderService{Mock: mock.Mock{}}
+		mockOrder.On("UpdateForAccrual", mock.Anything, order, accrualResponse).Return(storage.ErrOrderFinalized)
+
+		mockCampaigns := mocks.CampaignService{Mock: mock.Mock{}}
+		mockEvents := mocks.EventService{Mock: mock.Mock{}}
+
+		service := accrualService{order: &mockOrder, client: &mockClient, campaigns: &mockCampaigns, events: &mockEvents}
+
+		service.ProcessOrder(context.Background(), order)
+
+		mockCampaigns.AssertNotCalled(t, "ApplyCampaigns", mock.Anything, mock.Anything)
+		mockEvents.AssertNumberOfCalls(t, "PublishOrderStatus", 0)
+		mockEvents.AssertNumberOfCalls(t, "PublishBalance", 0)
+	})
+
+	t.Run("should not publish events when status not changed", func(t *testing.T) {
+		order := model.Order{ID: "1", Status: model.StatusProcessing}
+		accrualResponse := provider.AccrualResponse{Order: order.ID, Status: model.StatusProcessing}
+
+		mockClient := providerMocks.AccrualClient{Mock: mock.Mock{}}
+		mockClient.On("GetOrder", mock.Anything, order.ID).
+			Return(accrualResponse, nil)
+
+		mockOrder := mocks.OrderService{Mock: mock.Mock{}}
+		mockOrder.On("UpdateForAccrual", mock.Anything, order, accrualResponse).
+			Return(nil)
+
+		mockEvents := mocks.EventService{Mock: mock.Mock{}}
+
+		service := accrualService{order: &mockOrder, client: &mockClient, events: &mockEvents}
+
+		service.ProcessOrder(context.Background(), order)
+
+		mockEvents.AssertNumberOfCalls(t, "PublishOrderStatus", 0)
+		mockEvents.AssertNumberOfCalls(t, "PublishBalance", 0)
+	})
+
+	t.Run("should apply campaigns when order is processed", func(t *testing.T) {
+		order := model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing}
+		accrualResponse := provider.AccrualResponse{Order: order.ID, Status: model.StatusProcessed, Accrual: 1000}
+
+		mockClient := providerMocks.AccrualClient{Mock: mock.Mock{}}
+		mockClient.On("GetOrder", mock.Anything, order.ID).
+			Return(accrualResponse, nil)
+
+		mockOrder := mocks.OrderService{Mock: mock.Mock{}}
+		mockOrder.On("UpdateForAccrual", mock.Anything, order, accrualResponse).
+			Return(nil)
+		mockOrder.On("CompleteBonuses", mock.Anything, order.ID).Return(nil)
+
+		mockCampaigns := mocks.CampaignService{Mock: mock.Mock{}}
+		mockCampaigns.On("ApplyCampaigns", mock.Anything, order).
+			Return(model.Amount(1000), nil)
+
+		mockEvents := mocks.EventService{Mock: mock.Mock{}}
+		mockEvents.On("PublishOrderStatus", mock.Anything, mock.Anything).Return(nil)
+		mockEvents.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		mockTiers := mocks.TierService{Mock: mock.Mock{}}
+		mockTiers.On("ApplyTier", mock.Anything, order).Return(model.Amount(0), nil)
+
+		mockReferrals := mocks.ReferralService{Mock: mock.Mock{}}
+		mockReferrals.On("RewardReferral", mock.Anything, order).Return(model.ReferralReward{}, nil)
+
+		service := accrualService{order: &mockOrder, client: &mockClient, campaigns: &mockCampaigns, referrals: &mockReferrals, tiers: &mockTiers, events: &mockEvents}
+
+		service.ProcessOrder(context.Background(), order)
+
+		mockCampaigns.AssertNumberOfCalls(t, "ApplyCampaigns", 1)
+		mockTiers.AssertNumberOfCalls(t, "ApplyTier", 1)
+		mockOrder.AssertNumberOfCalls(t, "CompleteBonuses", 1)
+		mockEvents.AssertNumberOfCalls(t, "PublishBalance", 1)
+	})
+
+	t.Run("should leave bonuses pending when one of them fails", func(t *testing.T) {
+		order := model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing}
+		accrualResponse := provider.AccrualResponse{Order: order.ID, Status: model.StatusProcessed, Accrual: 1000}
+
+		mockClient := providerMocks.AccrualClient{Mock: mock.Mock{}}
+		mockClient.On("GetOrder", mock.Anything, order.ID).Return(accrualResponse, nil)
+
+		mockOrder := mocks.OrderService{Mock: mock.Mock{}}
+		mockOrder.On("UpdateForAccrual", mock.Anything, order, accrualResponse).Return(nil)
+
+		mockCampaigns := mocks.CampaignService{Mock: mock.Mock{}}
+		mockCampaigns.On("ApplyCampaigns", mock.Anything, order).
+			Return(model.Amount(0), errors.New("connection reset"))
+
+		mockTiers := mocks.TierService{Mock: mock.Mock{}}
+		mockTiers.On("ApplyTier", mock.Anything, order).Return(model.Amount(100), nil)
+
+		mockReferrals := mocks.ReferralService{Mock: mock.Mock{}}
+		mockReferrals.On("RewardReferral", mock.Anything, order).Return(model.ReferralReward{}, nil)
+
+		mockEvents := mocks.EventService{Mock: mock.Mock{}}
+		mockEvents.On("PublishOrderStatus", mock.Anything, mock.Anything).Return(nil)
+		mockEvents.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := accrualService{order: &mockOrder, client: &mockClient, campaigns: &mockCampaigns, referrals: &mockReferrals, tiers: &mockTiers, events: &mockEvents}
+
+		service.ProcessOrder(context.Background(), order)
+
+		mockTiers.AssertNumberOfCalls(t, "ApplyTier", 1)
+		mockOrder.AssertNumberOfCalls(t, "CompleteBonuses", 0)
+	})
+
+	t.Run("should reward referral when order is processed", func(t *testing.T) {
+		order := model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing}
+		accrualResponse := provider.AccrualResponse{Order: order.ID, Status: model.StatusProcessed}
+
+		mockClient := providerMocks.AccrualClient{Mock: mock.Mock{}}
+		mockClient.On("GetOrder", mock.Anything, order.ID).Return(accrualResponse, nil)
+
+		mockOrder := mocks.OrderService{Mock: mock.Mock{}}
+		mockOrder.On("UpdateForAccrual", mock.Anything, order, accrualResponse).Return(nil)
+		mockOrder.On("CompleteBonuses", mock.Anything, order.ID).Return(nil)
+
+		mockCampaigns := mocks.CampaignService{Mock: mock.Mock{}}
+		mockCampaigns.On("ApplyCampaigns", mock.Anything, order).Return(model.Amount(0), nil)
+
+		mockTiers := mocks.TierService{Mock: mock.Mock{}}
+		mockTiers.On("ApplyTier", mock.Anything, order).Return(model.Amount(0), nil)
+
+		mockReferrals := mocks.ReferralService{Mock: mock.Mock{}}
+		mockReferrals.On("RewardReferral", mock.Anything, order).
+			Return(model.ReferralReward{ID: 1, ReferrerID: 42, RefereeID: 666, ReferrerBonus: 10000, RefereeBonus: 10000}, nil)
+
+		mockEvents := mocks.EventService{Mock: mock.Mock{}}
+		mockEvents.On("PublishOrderStatus", mock.Anything, mock.Anything).Return(nil)
+		mockEvents.On("PublishBalance", mock.Anything, 42).Return(nil)
+		mockEvents.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := accrualService{order: &mockOrder, client: &mockClient, campaigns: &mockCampaigns, referrals: &mockReferrals, tiers: &mockTiers, events: &mockEvents}
+
+		service.ProcessOrder(context.Background(), order)
+
+		mockReferrals.AssertNumberOfCalls(t, "RewardReferral", 1)
+		mockEvents.AssertCalled(t, "PublishBalance", mock.Anything, 42)
+		mockEvents.AssertCalled(t, "PublishBalance", mock.Anything, 666)
+	})
+}
+
+func Test_accrualService_retryBonuses(t *testing.T) {
+	t.Run("should apply pending bonuses with the stored accrual", func(t *testing.T) {
+		order := model.Order{ID: "1", UserID: 666, Status: model.StatusProcessed, Accrual: 1000}
+
+		mockOrder := mocks.OrderService{Mock: mock.Mock{}}
+		mockOrder.On("PendingBonusOrders", mock.Anything, pendingBonusesBatch).Return([]model.Order{order}, nil)
+		mockOrder.On("CompleteBonuses", mock.Anything, order.ID).Return(nil)
+
+		mockCampaigns := mocks.CampaignService{Mock: mock.Mock{}}
+		mockCampaigns.On("ApplyCampaigns", mock.Anything, order).Return(model.Amount(1000), nil)
+
+		mockTiers := mocks.TierService{Mock: mock.Mock{}}
+		// the tier bonus was credited before the failure, the unique index skips it on retry
+		mockTiers.On("ApplyTier", mock.Anything, order).Return(model.Amount(0), nil)
+
+		mockReferrals := mocks.ReferralService{Mock: mock.Mock{}}
+		mockReferrals.On("RewardReferral", mock.Anything, order).Return(model.ReferralReward{}, nil)
+
+		mockEvents := mocks.EventService{Mock: mock.Mock{}}
+		mockEvents.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := accrualService{order: &mockOrder, campaigns: &mockCampaigns, referrals: &mockReferrals, tiers: &mockTiers, events: &mockEvents}
+
+		service.retryBonuses(context.Background())
+
+		mockCampaigns.AssertNumberOfCalls(t, "ApplyCampaigns", 1)
+		mockOrder.AssertNumberOfCalls(t, "CompleteBonuses", 1)
+		mockEvents.AssertNumberOfCalls(t, "PublishBalance", 1)
 	})
 }
 
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"math"
+	"strconv"
+	"time"
+)
+
+//go:generate mockery --name=AdjustmentService
+
+type AdjustmentService interface {
+	// CreateAdjustment applies the adjustment at once when its sum together with the recent adjustments the admin
+	// applied to the user doesn't exceed the approval threshold, otherwise it waits for approval of another admin
+	CreateAdjustment(ctx context.Context, userID int, request model.AdjustmentRequestDto) (model.Adjustment, error)
+	ApproveAdjustment(ctx context.Context, id int) (model.Adjustment, error)
+	RejectAdjustment(ctx context.Context, id int) (model.Adjustment, error)
+	Adjustments(ctx context.Context, status model.AdjustmentStatus) ([]model.Adjustment, error)
+}
+
+func NewAdjustmentService(cfg config.Config, registry reporegistry.RepoRegistry, events EventService) AdjustmentService {
+	return &adjustmentService{
+		cfg:      cfg,
+		repo:     registry.GetAdjustmentRepo(),
+		users:    registry.GetUserRepo(),
+		events:   events,
+		auditLog: NewAuditService(cfg, registry),
+	}
+}
+
+type adjustmentService struct {
+	cfg      config.Config
+	repo     storage.AdjustmentRepository
+	users    storage.UserRepository
+	events   EventService
+	auditLog AuditService
+}
+
+func (s adjustmentService) CreateAdjustment(ctx context.Context, userID int, request model.AdjustmentRequestDto) (model.Adjustment, error) {
+	admin := appContext.User(ctx)
+	if admin == nil {
+		s.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+		return model.Adjustment{}, ErrNotAuthenticated
+	}
+
+	if userID == admin.ID {
+		return model.Adjustment{}, ErrAdminSelfAction
+	}
+
+	if _, err := s.users.UserByID(ctx, userID); err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("CreateAdjustment: find user")
+		return model.Adjustment{}, err
+	}
+
+	now := time.Now()
+	adjustment, err := s.repo.CreateAdjustment(ctx, model.Adjustment{
+		UserID:     userID,
+		Sum:        request.Sum,
+		ReasonCode: request.ReasonCode,
+		Comment:    request.Comment,
+		CreatedBy:  admin.ID,
+	}, model.Amount(math.Round(s.cfg.AdjustmentThreshold*100)), now.Add(-s.cfg.AdjustmentWindow), s.cfg.PointsExpireAt(now))
+	if err != nil {
+		s.Log(ctx).Warn().Err(err).Msg("CreateAdjustment:")
+		return model.Adjustment{}, err
+	}
+
+	s.audit(ctx, "adjustment_created", adjustment)
+	if adjustment.Status == model.AdjustmentApplied {
+		s.publish(ctx, adjustment)
+	}
+
+	return adjustment, nil
+}
+
+func (s adjustmentService) ApproveAdjustment(ctx context.Context, id int) (model.Adjustment, error) {
+	admin := appContext.User(ctx)
+	if admin == nil {
+		s.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+		return model.Adjustment{}, ErrNotAuthenticated
+	}
+
+	adjustment, err := s.repo.Adjustment(ctx, id)
+	if err != nil {
+		return model.Adjustment{}, err
+	}
+
+	// four-eyes: the author can't approve his own adjustment
+	if adjustment.CreatedBy == admin.ID {
+		return model.Adjustment{}, ErrSelfApproval
+	}
+
+	if adjustment.UserID == admin.ID {
+		return model.Adjustment{}, ErrAdminSelfAction
+	}
+
+	adjustment, err = s.repo.ApproveAdjustment(ctx, id, admin.ID, s.cfg.PointsExpireAt(time.Now()))
+	if err != nil {
+		s.Log(ctx).Warn().Err(err).Msg("ApproveAdjustment:")
+		return model.Adjustment{}, err
+	}
+
+	s.audit(ctx, "adjustment_approved", adjustment)
+	s.publish(ctx, adjustment)
+
+	return adjustment, nil
+}
+
+func (s adjustmentService) RejectAdjustment(ctx context.Context, id int) (model.Adjustment, error) {
+	admin := appContext.User(ctx)
+	if admin == nil {
+		s.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+		return model.Adjustment{}, ErrNotAuthenticated
+	}
+
+	adjustment, err := s.repo.RejectAdjustment(ctx, id, admin.ID)
+	if err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("RejectAdjustment:")
+		return model.Adjustment{}, err
+	}
+
+	s.audit(ctx, "adjustment_rejected", adjustment)
+
+	return adjustment, nil
+}
+
+func (s adjustmentService) Adjustments(ctx context.Context, status model.AdjustmentStatus) ([]model.Adjustment, error) {
+	return s.repo.Adjustments(ctx, status)
+}
+
+func (s adjustmentService) publish(ctx context.Context, adjustment model.Adjustment) {
+	if err := s.events.PublishBalance(ctx, adjustment.UserID); err != nil {
+		s.Log(ctx).Error().Err(err).Msg("publish balance")
+	}
+}
+
+func (s adjustmentService) audit(ctx context.Context, action string, adjustment model.Adjustment) {
+	s.auditLog.Record(ctx, model.AuditEntry{
+		Action:     action,
+		TargetType: model.AuditTargetAdjustment,
+		TargetID:   strconv.Itoa(adjustment.ID),
+		Details: map[string]interface{}{
+			"userID": adjustment.UserID,
+			"sum":    adjustment.Sum,
+			"reason": adjustment.ReasonCode,
+			"status": adjustment.Status,
+		},
+	})
+}
+
+func (s adjustmentService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "adjustmentService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	serviceMock "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_adjustmentService_CreateAdjustment(t *testing.T) {
+	tests := []struct {
+		name   string
+		status model.AdjustmentStatus
+	}{
+		{name: "should publish balance of applied adjustment", status: model.AdjustmentApplied},
+		{name: "should not publish balance of pending adjustment", status: model.AdjustmentPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userMock := mocks.UserRepository{Mock: mock.Mock{}}
+			userMock.On("UserByID", mock.Anything, 666).Return(model.User{ID: 666}, nil)
+
+			repoMock := mocks.AdjustmentRepository{Mock: mock.Mock{}}
+			repoMock.On("CreateAdjustment", mock.Anything, model.Adjustment{
+				UserID: 666, Sum: 500, ReasonCode: "GOODWILL", Comment: "test", CreatedBy: 1,
+			}, model.Amount(100000), mock.Anything, mock.Anything).Return(model.Adjustment{ID: 7, UserID: 666, Sum: 500, Status: tt.status}, nil)
+
+			eventsMock := serviceMock.EventService{Mock: mock.Mock{}}
+			eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+			service := adjustmentService{
+				cfg:      config.Config{AdjustmentThreshold: 1000, AdjustmentWindow: 24 * time.Hour, PointsLifetimeMonths: 12},
+				repo:     &repoMock,
+				users:    &userMock,
+				events:   &eventsMock,
+				auditLog: newAuditMock(),
+			}
+			ctx := appContext.WithUser(context.Background(), &model.User{ID: 1, Role: model.RoleSupport})
+
+			adjustment, err := service.CreateAdjustment(ctx, 666, model.AdjustmentRequestDto{Sum: 500, ReasonCode: "GOODWILL", Comment: "test"})
+
+			require.Equal(t, err, nil)
+			require.Equal(t, adjustment.Status, tt.status)
+			if tt.status == model.AdjustmentApplied {
+				eventsMock.AssertNumberOfCalls(t, "PublishBalance", 1)
+			} else {
+				eventsMock.AssertNotCalled(t, "PublishBalance", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func Test_adjustmentService_CreateAdjustment_Self(t *testing.T) {
+	t.Run("should not adjust own balance", func(t *testing.T) {
+		repoMock := mocks.AdjustmentRepository{Mock: mock.Mock{}}
+
+		service := adjustmentService{repo: &repoMock, auditLog: newAuditMock()}
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 1, Role: model.RoleSupport})
+
+		_, err := service.CreateAdjustment(ctx, 1, model.AdjustmentRequestDto{Sum: 100, ReasonCode: "GOODWILL", Comment: "test"})
+
+		require.Equal(t, err, ErrAdminSelfAction)
+		repoMock.AssertNotCalled(t, "CreateAdjustment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func Test_adjustmentService_ApproveAdjustment(t *testing.T) {
+	t.Run("should not be approved by the user it adjusts", func(t *testing.T) {
+		repoMock := mocks.AdjustmentRepository{Mock: mock.Mock{}}
+		repoMock.On("Adjustment", mock.Anything, 7).Return(model.Adjustment{ID: 7, UserID: 2, CreatedBy: 1, Status: model.AdjustmentPending}, nil)
+
+		service := adjustmentService{repo: &repoMock, auditLog: newAuditMock()}
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 2, Role: model.RoleAdmin})
+
+		_, err := service.ApproveAdjustment(ctx, 7)
+
+		require.Equal(t, err, ErrAdminSelfAction)
+		repoMock.AssertNotCalled(t, "ApproveAdjustment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("should not be approved by its author", func(t *testing.T) {
+		repoMock := mocks.AdjustmentRepository{Mock: mock.Mock{}}
+		repoMock.On("Adjustment", mock.Anything, 7).Return(model.Adjustment{ID: 7, CreatedBy: 1, Status: model.AdjustmentPending}, nil)
+
+		service := adjustmentService{repo: &repoMock, auditLog: newAuditMock()}
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 1, Role: model.RoleAdmin})
+
+		_, err := service.ApproveAdjustment(ctx, 7)
+
+		require.Equal(t, err, ErrSelfApproval)
+		repoMock.AssertNotCalled(t, "ApproveAdjustment", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("should be approved by another admin", func(t *testing.T) {
+		repoMock := mocks.AdjustmentRepository{Mock: mock.Mock{}}
+		repoMock.On("Adjustment", mock.Anything, 7).Return(model.Adjustment{ID: 7, CreatedBy: 1, Status: model.AdjustmentPending}, nil)
+		repoMock.On("ApproveAdjustment", mock.Anything, 7, 2, mock.Anything).
+			Return(model.Adjustment{ID: 7, UserID: 666, CreatedBy: 1, DecidedBy: 2, Status: model.AdjustmentApplied}, nil)
+
+		eventsMock := serviceMock.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := adjustmentService{repo: &repoMock, events: &eventsMock, auditLog: newAuditMock()}
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 2, Role: model.RoleAdmin})
+
+		adjustment, err := service.ApproveAdjustment(ctx, 7)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, adjustment.Status, model.AdjustmentApplied)
+		eventsMock.AssertNumberOfCalls(t, "PublishBalance", 1)
+	})
+}
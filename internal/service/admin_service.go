@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"strconv"
+)
+
+//go:generate mockery --name=AdminService
+
+// AdminService serves /api/admin, every call is audited with the acting user from the context
+type AdminService interface {
+	SearchUsers(ctx context.Context, query string, limit int) ([]model.UserSummary, error)
+	User(ctx context.Context, userID int) (model.UserSummary, error)
+	UserOrders(ctx context.Context, userID int) ([]model.Order, error)
+	UserWithdrawals(ctx context.Context, userID int) ([]model.Withdraw, error)
+	UserBalance(ctx context.Context, userID int) (model.UserBalance, error)
+	// LockUser denies login and revokes all sessions of the user
+	LockUser(ctx context.Context, userID int, reason string) error
+	UnlockUser(ctx context.Context, userID int) error
+	SetRole(ctx context.Context, userID int, role model.Role) error
+	// PromoteAdmins grants admin role to configured usernames, so the first admin can be appointed
+	PromoteAdmins(ctx context.Context, usernames []string) error
+}
+
+func NewAdminService(cfg config.Config, registry reporegistry.RepoRegistry) AdminService {
+	return &adminService{
+		cfg:       cfg,
+		users:     registry.GetUserRepo(),
+		orders:    registry.GetOrderRepo(),
+		withdraws: registry.GetWithdrawRepo(),
+		user:      NewUserService(cfg, registry),
+		tokens:    NewTokenService(cfg, registry),
+		auditLog:  NewAuditService(cfg, registry),
+	}
+}
+
+type adminService struct {
+	cfg       config.Config
+	users     storage.UserRepository
+	orders    storage.OrderRepository
+	withdraws storage.WithdrawRepository
+	user      UserService
+	tokens    TokenService
+	auditLog  AuditService
+}
+
+func (s adminService) SearchUsers(ctx context.Context, query string, limit int) ([]model.UserSummary, error) {
+	s.audit(ctx, "admin_users_searched", 0, map[string]interface{}{"query": query})
+
+	return s.users.SearchUsers(ctx, query, limit)
+}
+
+func (s adminService) User(ctx context.Context, userID int) (model.UserSummary, error) {
+	s.audit(ctx, "admin_user_viewed", userID, nil)
+
+	return s.users.UserSummary(ctx, userID)
+}
+
+func (s adminService) UserOrders(ctx context.Context, userID int) ([]model.Order, error) {
+	if _, err := s.users.UserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, "admin_orders_viewed", userID, nil)
+
+	return s.orders.OrdersByUserID(ctx, userID)
+}
+
+func (s adminService) UserWithdrawals(ctx context.Context, userID int) ([]model.Withdraw, error) {
+	if _, err := s.users.UserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, "admin_withdrawals_viewed", userID, nil)
+
+	return s.withdraws.WithdrawLogsByUserID(ctx, userID)
+}
+
+func (s adminService) UserBalance(ctx context.Context, userID int) (model.UserBalance, error) {
+	user, err := s.users.UserByID(ctx, userID)
+	if err != nil {
+		return model.UserBalance{}, err
+	}
+
+	s.audit(ctx, "admin_balance_viewed", userID, nil)
+
+	return s.user.GetBalance(ctx, user)
+}
+
+func (s adminService) LockUser(ctx context.Context, userID int, reason string) error {
+	if s.isSelf(ctx, userID) {
+		return ErrAdminSelfAction
+	}
+
+	if err := s.users.LockUser(ctx, userID, reason); err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("LockUser:")
+		return err
+	}
+
+	s.audit(ctx, "user_locked", userID, map[string]interface{}{"reason": reason})
+
+	if err := s.tokens.RevokeAll(ctx, userID); err != nil {
+		s.Log(ctx).Error().Err(err).Int("userID", userID).Msg("LockUser: revoke sessions")
+		return err
+	}
+
+	return nil
+}
+
+func (s adminService) UnlockUser(ctx context.Context, userID int) error {
+	if err := s.users.UnlockUser(ctx, userID); err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("UnlockUser:")
+		return err
+	}
+
+	s.audit(ctx, "user_unlocked", userID, nil)
+
+	return nil
+}
+
+func (s adminService) SetRole(ctx context.Context, userID int, role model.Role) error {
+	if s.isSelf(ctx, userID) {
+		return ErrAdminSelfAction
+	}
+
+	if err := s.users.SetRole(ctx, userID, role); err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("SetRole:")
+		return err
+	}
+
+	s.audit(ctx, "user_role_changed", userID, map[string]interface{}{"role": role})
+
+	return nil
+}
+
+func (s adminService) PromoteAdmins(ctx context.Context, usernames []string) error {
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	promoted, err := s.users.PromoteAdmins(ctx, usernames)
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("PromoteAdmins:")
+		return err
+	}
+
+	if promoted > 0 {
+		s.audit(ctx, "admins_promoted", 0, map[string]interface{}{"usernames": usernames, "promoted": promoted})
+	}
+
+	return nil
+}
+
+// isSelf protects admins from locking themselves out
+func (s adminService) isSelf(ctx context.Context, userID int) bool {
+	admin := appContext.User(ctx)
+
+	return admin != nil && admin.ID == userID
+}
+
+func (s adminService) audit(ctx context.Context, action string, userID int, details map[string]interface{}) {
+	entry := model.AuditEntry{Action: action, Details: details}
+	if userID != 0 {
+		entry.TargetType, entry.TargetID = model.AuditTargetUser, strconv.Itoa(userID)
+	}
+
+	s.auditLog.Record(ctx, entry)
+}
+
+func (s adminService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "adminService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	serviceMock "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_adminService_LockUser(t *testing.T) {
+	t.Run("should lock user and revoke sessions", func(t *testing.T) {
+		userMock := mocks.UserRepository{Mock: mock.Mock{}}
+		userMock.On("LockUser", mock.Anything, 666, "fraud").Return(nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("RevokeAll", mock.Anything, 666).Return(nil)
+
+		service := adminService{users: &userMock, tokens: &tokensMock, auditLog: newAuditMock()}
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 1, Role: model.RoleAdmin})
+
+		err := service.LockUser(ctx, 666, "fraud")
+
+		require.Equal(t, err, nil)
+		userMock.AssertNumberOfCalls(t, "LockUser", 1)
+		tokensMock.AssertNumberOfCalls(t, "RevokeAll", 1)
+	})
+	t.Run("should not lock own account", func(t *testing.T) {
+		userMock := mocks.UserRepository{Mock: mock.Mock{}}
+
+		service := adminService{users: &userMock, auditLog: newAuditMock()}
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 1, Role: model.RoleAdmin})
+
+		err := service.LockUser(ctx, 1, "fraud")
+
+		require.Equal(t, err, ErrAdminSelfAction)
+		userMock.AssertNotCalled(t, "LockUser", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("should return not found for unknown user", func(t *testing.T) {
+		userMock := mocks.UserRepository{Mock: mock.Mock{}}
+		userMock.On("LockUser", mock.Anything, 666, "fraud").Return(storage.ErrNotFound)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+
+		service := adminService{users: &userMock, tokens: &tokensMock, auditLog: newAuditMock()}
+
+		err := service.LockUser(context.Background(), 666, "fraud")
+
+		require.Equal(t, err, storage.ErrNotFound)
+		tokensMock.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_adminService_UserBalance(t *testing.T) {
+	t.Run("should return balance of the user", func(t *testing.T) {
+		user := model.User{ID: 666, Balance: 1000}
+
+		userMock := mocks.UserRepository{Mock: mock.Mock{}}
+		userMock.On("UserByID", mock.Anything, 666).Return(user, nil)
+
+		balanceMock := serviceMock.UserService{Mock: mock.Mock{}}
+		balanceMock.On("GetBalance", mock.Anything, user).Return(model.UserBalance{Current: 1000, Withdrawn: 500}, nil)
+
+		service := adminService{users: &userMock, user: &balanceMock, auditLog: newAuditMock()}
+
+		balance, err := service.UserBalance(context.Background(), 666)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, balance, model.UserBalance{Current: 1000, Withdrawn: 500})
+	})
+}
+
+func Test_adminService_PromoteAdmins(t *testing.T) {
+	t.Run("should skip empty list", func(t *testing.T) {
+		userMock := mocks.UserRepository{Mock: mock.Mock{}}
+
+		service := adminService{users: &userMock, auditLog: newAuditMock()}
+
+		err := service.PromoteAdmins(context.Background(), nil)
+
+		require.Equal(t, err, nil)
+		userMock.AssertNotCalled(t, "PromoteAdmins", mock.Anything, mock.Anything)
+	})
+	t.Run("should promote configured users", func(t *testing.T) {
+		userMock := mocks.UserRepository{Mock: mock.Mock{}}
+		userMock.On("PromoteAdmins", mock.Anything, []string{"root"}).Return(1, nil)
+
+		service := adminService{users: &userMock, auditLog: newAuditMock()}
+
+		err := service.PromoteAdmins(context.Background(), []string{"root"})
+
+		require.Equal(t, err, nil)
+		userMock.AssertNumberOfCalls(t, "PromoteAdmins", 1)
+	})
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+)
+
+const (
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+//go:generate mockery --name=AuditService
+
+// AuditService records security and money relevant actions to the append-only audit log
+type AuditService interface {
+	// Record appends the entry, the actor, IP and request id are taken from the context unless they are set.
+	// Failures are logged and not returned, so the audit never breaks the audited action.
+	Record(ctx context.Context, entry model.AuditEntry)
+	Entries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error)
+}
+
+func NewAuditService(cfg config.Config, registry reporegistry.RepoRegistry) AuditService {
+	return &auditService{
+		cfg:  cfg,
+		repo: registry.GetAuditRepo(),
+	}
+}
+
+type auditService struct {
+	cfg  config.Config
+	repo storage.AuditRepository
+}
+
+func (s auditService) Record(ctx context.Context, entry model.AuditEntry) {
+	if entry.ActorID == 0 {
+		if user := appContext.User(ctx); user != nil {
+			entry.ActorID = user.ID
+		}
+	}
+
+	if entry.IP == "" {
+		entry.IP = appContext.ClientIP(ctx)
+	}
+
+	if entry.RequestID == "" {
+		entry.RequestID = appContext.RequestID(ctx)
+	}
+
+	s.Log(ctx).Info().
+		Str("audit", entry.Action).
+		Int("actorID", entry.ActorID).
+		Str("targetType", entry.TargetType).
+		Str("targetID", entry.TargetID).
+		Str("ip", entry.IP).
+		Fields(entry.Details).
+		Msg("audit")
+
+	if err := s.repo.AddAuditEntry(ctx, entry); err != nil {
+		s.Log(ctx).Error().Err(err).Str("audit", entry.Action).Msg("Record: failed save audit entry")
+	}
+}
+
+func (s auditService) Entries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = auditDefaultLimit
+	}
+
+	if filter.Limit > auditMaxLimit {
+		filter.Limit = auditMaxLimit
+	}
+
+	entries, err := s.repo.AuditEntries(ctx, filter)
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("Entries:")
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s auditService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "auditService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	serviceMocks "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// newAuditMock accepts every entry, tests of audited services check the entries where they matter
+func newAuditMock() *serviceMocks.AuditService {
+	m := serviceMocks.AuditService{Mock: mock.Mock{}}
+	m.On("Record", mock.Anything, mock.Anything).Return()
+
+	return &m
+}
+
+func Test_auditService_Record(t *testing.T) {
+	t.Run("should take actor, ip and request id from the context", func(t *testing.T) {
+		m := mocks.AuditRepository{Mock: mock.Mock{}}
+		m.On("AddAuditEntry", mock.Anything, model.AuditEntry{
+			ActorID:    1,
+			Action:     "user_locked",
+			TargetType: model.AuditTargetUser,
+			TargetID:   "666",
+			IP:         "10.0.0.1",
+			RequestID:  "host/abc-000001",
+			Details:    map[string]interface{}{"reason": "fraud"},
+		}).Return(nil)
+
+		service := auditService{repo: &m}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 1})
+		ctx = appContext.WithRequestInfo(ctx, "10.0.0.1", "host/abc-000001")
+
+		service.Record(ctx, model.AuditEntry{
+			Action:     "user_locked",
+			TargetType: model.AuditTargetUser,
+			TargetID:   "666",
+			Details:    map[string]interface{}{"reason": "fraud"},
+		})
+
+		m.AssertNumberOfCalls(t, "AddAuditEntry", 1)
+	})
+	t.Run("should keep actor and ip given by the caller", func(t *testing.T) {
+		m := mocks.AuditRepository{Mock: mock.Mock{}}
+		m.On("AddAuditEntry", mock.Anything, model.AuditEntry{ActorID: 666, Action: "login", IP: "10.0.0.2"}).Return(nil)
+
+		service := auditService{repo: &m}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 1})
+		ctx = appContext.WithRequestInfo(ctx, "10.0.0.1", "")
+
+		service.Record(ctx, model.AuditEntry{ActorID: 666, Action: "login", IP: "10.0.0.2"})
+
+		m.AssertNumberOfCalls(t, "AddAuditEntry", 1)
+	})
+}
+
+func Test_auditService_Entries(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{name: "should use default limit", limit: 0, want: auditDefaultLimit},
+		{name: "should keep limit", limit: 10, want: 10},
+		{name: "should cut limit", limit: 5000, want: auditMaxLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := mocks.AuditRepository{Mock: mock.Mock{}}
+			m.On("AuditEntries", mock.Anything, model.AuditFilter{Action: "login", Limit: tt.want}).
+				Return([]model.AuditEntry{{ID: 1, Action: "login"}}, nil)
+
+			service := auditService{repo: &m}
+
+			entries, err := service.Entries(context.Background(), model.AuditFilter{Action: "login", Limit: tt.limit})
+
+			require.Equal(t, err, nil)
+			require.Equal(t, entries, []model.AuditEntry{{ID: 1, Action: "login"}})
+		})
+	}
+}
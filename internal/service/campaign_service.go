@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+//go:generate mockery --name=CampaignService
+
+type CampaignService interface {
+	CreateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error)
+	UpdateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error)
+	DeleteCampaign(ctx context.Context, id int) error
+	Campaign(ctx context.Context, id int) (model.Campaign, error)
+	Campaigns(ctx context.Context) ([]model.Campaign, error)
+	// ApplyCampaigns credits bonuses of campaigns running when the order was uploaded,
+	// every campaign is calculated from the base accrual. The credited sum is returned.
+	ApplyCampaigns(ctx context.Context, order model.Order) (model.Amount, error)
+}
+
+func NewCampaignService(cfg config.Config, registry reporegistry.RepoRegistry) CampaignService {
+	return &campaignService{
+		cfg:      cfg,
+		repo:     registry.GetCampaignRepo(),
+		auditLog: NewAuditService(cfg, registry),
+	}
+}
+
+type campaignService struct {
+	cfg      config.Config
+	repo     storage.CampaignRepository
+	auditLog AuditService
+}
+
+func (s campaignService) CreateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error) {
+	created, err := s.repo.CreateCampaign(ctx, campaign)
+	if err != nil {
+		return model.Campaign{}, err
+	}
+
+	s.audit(ctx, "campaign_created", created)
+
+	return created, nil
+}
+
+func (s campaignService) UpdateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error) {
+	updated, err := s.repo.UpdateCampaign(ctx, campaign)
+	if err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("UpdateCampaign:")
+		return model.Campaign{}, err
+	}
+
+	s.audit(ctx, "campaign_updated", updated)
+
+	return updated, nil
+}
+
+func (s campaignService) DeleteCampaign(ctx context.Context, id int) error {
+	err := s.repo.DeleteCampaign(ctx, id)
+	if err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("DeleteCampaign:")
+		return err
+	}
+
+	s.audit(ctx, "campaign_deleted", model.Campaign{ID: id})
+
+	return nil
+}
+
+func (s campaignService) Campaign(ctx context.Context, id int) (model.Campaign, error) {
+	return s.repo.Campaign(ctx, id)
+}
+
+func (s campaignService) Campaigns(ctx context.Context) ([]model.Campaign, error) {
+	return s.repo.Campaigns(ctx)
+}
+
+func (s campaignService) ApplyCampaigns(ctx context.Context, order model.Order) (model.Amount, error) {
+	campaigns, err := s.repo.ActiveCampaigns(ctx, time.Time(order.UploadedAt))
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("ApplyCampaigns: active campaigns")
+		return 0, err
+	}
+
+	var total model.Amount
+	for _, campaign := range campaigns {
+		// the sum is calculated from the accrual of the locked order, the override may change it meanwhile
+		bonus, err := s.repo.AddCampaignBonus(ctx, model.CampaignBonus{
+			CampaignID: campaign.ID,
+			OrderID:    order.ID,
+			UserID:     order.UserID,
+		}, campaign, s.cfg.PointsExpireAt(time.Now()))
+		if err != nil {
+			s.Log(ctx).Error().Err(err).Int("campaignID", campaign.ID).Msg("ApplyCampaigns: add bonus")
+			return total, err
+		}
+
+		if bonus.Sum > 0 {
+			s.Log(ctx).Info().
+				Int("campaignID", campaign.ID).
+				Int("userID", order.UserID).
+				Str("order", string(order.ID)).
+				Int("sum", int(bonus.Sum)).
+				Msg("campaign bonus credited")
+		}
+
+		total += bonus.Sum
+	}
+
+	return total, nil
+}
+
+func (s campaignService) audit(ctx context.Context, action string, campaign model.Campaign) {
+	entry := model.AuditEntry{Action: action, TargetType: model.AuditTargetCampaign, TargetID: strconv.Itoa(campaign.ID)}
+	if campaign.Name != "" {
+		entry.Details = map[string]interface{}{"name": campaign.Name}
+	}
+
+	s.auditLog.Record(ctx, entry)
+}
+
+func (s campaignService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "campaignService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_campaignService_ApplyCampaigns(t *testing.T) {
+	t.Run("should credit bonuses of campaigns running at upload time", func(t *testing.T) {
+		uploadedAt := time.Now().Add(-time.Hour)
+		order := model.Order{ID: "1", UserID: 666, UploadedAt: model.UploadedTime(uploadedAt)}
+
+		m := mocks.CampaignRepository{Mock: mock.Mock{}}
+		m.On("ActiveCampaigns", mock.Anything, uploadedAt).Return([]model.Campaign{
+			{ID: 1, Multiplier: 1.5},
+			{ID: 2, Bonus: 300, UserCap: 500},
+		}, nil)
+		m.On("AddCampaignBonus", mock.Anything, model.CampaignBonus{CampaignID: 1, OrderID: "1", UserID: 666},
+			model.Campaign{ID: 1, Multiplier: 1.5}, mock.Anything).
+			Return(model.CampaignBonus{ID: 10, Sum: 500}, nil)
+		m.On("AddCampaignBonus", mock.Anything, model.CampaignBonus{CampaignID: 2, OrderID: "1", UserID: 666},
+			model.Campaign{ID: 2, Bonus: 300, UserCap: 500}, mock.Anything).
+			Return(model.CampaignBonus{ID: 11, Sum: 200}, nil)
+
+		service := campaignService{repo: &m}
+
+		bonus, err := service.ApplyCampaigns(context.Background(), order)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, bonus, model.Amount(700))
+	})
+
+	t.Run("should return credited sum on failed bonus", func(t *testing.T) {
+		m := mocks.CampaignRepository{Mock: mock.Mock{}}
+		m.On("ActiveCampaigns", mock.Anything, mock.Anything).Return([]model.Campaign{{ID: 1, Bonus: 300}, {ID: 2, Bonus: 200}}, nil)
+		m.On("AddCampaignBonus", mock.Anything, mock.Anything, model.Campaign{ID: 1, Bonus: 300}, mock.Anything).
+			Return(model.CampaignBonus{ID: 10, Sum: 300}, nil)
+		m.On("AddCampaignBonus", mock.Anything, mock.Anything, model.Campaign{ID: 2, Bonus: 200}, mock.Anything).
+			Return(model.CampaignBonus{}, errors.New("connection reset"))
+
+		service := campaignService{repo: &m}
+
+		bonus, err := service.ApplyCampaigns(context.Background(), model.Order{ID: "1", UserID: 666})
+
+		require.Equal(t, err, errors.New("connection reset"))
+		require.Equal(t, bonus, model.Amount(300))
+	})
+}
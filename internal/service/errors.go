@@ -5,10 +5,32 @@ import "errors"
 var (
 	ErrUnauthorized  = errors.New("unauthorized")
 	ErrWrongPassword = errors.New("authenticate: invalid username or password")
+	ErrUnknownPepper = errors.New("authenticate: password pepper is not configured")
+	ErrAccountLocked = errors.New("authenticate: account is locked")
+
+	ErrTooManyLoginAttempts = errors.New("service: too many login attempts")
+
+	ErrInvalidRefreshToken = errors.New("service: invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("service: refresh token reused")
+	ErrInvalidChallenge    = errors.New("service: invalid 2fa challenge token")
+
+	ErrTwoFactorEnabled     = errors.New("service: 2fa already enabled")
+	ErrTwoFactorNotEnrolled = errors.New("service: 2fa enrollment is not started")
+	ErrTwoFactorNotEnabled  = errors.New("service: 2fa is not enabled")
+	ErrInvalidTwoFactorCode = errors.New("service: invalid 2fa code")
+	ErrTwoFactorRequired    = errors.New("service: 2fa required")
+
+	ErrIdempotencyKeyMismatch   = errors.New("service: idempotency key reused with different request")
+	ErrIdempotencyKeyInProgress = errors.New("service: request with idempotency key is in progress")
 
 	ErrNotAuthenticated                = errors.New("service: no authenticted user found in the context")
 	ErrOrderAlreadyUploadedAnotherUser = errors.New("service: order already uploaded another user")
 	ErrOrderAlreadyUploaded            = errors.New("service: order already uploaded")
+	ErrOrderUploadedForAccrual         = errors.New("service: order number is uploaded for accrual")
 
 	ErrInsufficientFunds = errors.New("service: insufficient funds")
+	ErrTransferToSelf    = errors.New("service: transfer to own account")
+
+	ErrAdminSelfAction = errors.New("service: admin action on own account")
+	ErrSelfApproval    = errors.New("service: adjustment approved by its author")
 )
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/djokcik/gophermart/pkg/pubsub"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+const (
+	eventsChannel       = "gophermart_events"
+	eventsSubscriberBuf = 16
+)
+
+//go:generate mockery --name=EventService
+
+// EventService delivers user events to subscribers of every running instance.
+// Events are published through PostgreSQL NOTIFY and fanned out to the local subscribers by Listen.
+type EventService interface {
+	Publish(ctx context.Context, event model.Event) error
+	PublishOrderStatus(ctx context.Context, order model.Order) error
+	PublishBalance(ctx context.Context, userID int) error
+	Subscribe(userID int) (<-chan model.Event, func())
+	Listen(ctx context.Context)
+}
+
+func NewEventService(cfg config.Config, registry reporegistry.RepoRegistry) EventService {
+	return &eventService{
+		cfg:          cfg,
+		repo:         registry.GetEventRepo(),
+		userRepo:     registry.GetUserRepo(),
+		withdrawRepo: registry.GetWithdrawRepo(),
+		lotRepo:      registry.GetPointLotRepo(),
+		broker:       pubsub.NewBroker(eventsSubscriberBuf),
+	}
+}
+
+type eventService struct {
+	cfg          config.Config
+	repo         storage.EventRepository
+	userRepo     storage.UserRepository
+	withdrawRepo storage.WithdrawRepository
+	lotRepo      storage.PointLotRepository
+	broker       *pubsub.Broker
+}
+
+func (e eventService) Publish(ctx context.Context, event model.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	err = e.repo.Notify(ctx, eventsChannel, string(payload))
+	if err != nil {
+		e.Log(ctx).Error().Err(err).Msg("Publish: deliver only to local subscribers")
+		e.broker.Publish(strconv.Itoa(event.UserID), event)
+		return err
+	}
+
+	return nil
+}
+
+func (e eventService) PublishOrderStatus(ctx context.Context, order model.Order) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+
+	return e.Publish(ctx, model.Event{Type: model.EventOrderStatusChanged, UserID: order.UserID, Data: data})
+}
+
+func (e eventService) PublishBalance(ctx context.Context, userID int) error {
+	user, err := e.userRepo.UserByID(ctx, userID)
+	if err != nil {
+		e.Log(ctx).Error().Err(err).Msg("PublishBalance: failed find user")
+		return err
+	}
+
+	withdrawn, err := e.withdrawRepo.AmountWithdrawByUser(ctx, userID)
+	if err != nil {
+		e.Log(ctx).Error().Err(err).Msg("PublishBalance: failed get withdrawn amount")
+		return err
+	}
+
+	expiring, err := e.lotRepo.ExpiringPoints(ctx, userID, time.Now().Add(e.cfg.PointsExpiringWindow))
+	if err != nil {
+		e.Log(ctx).Error().Err(err).Msg("PublishBalance: failed get expiring points")
+		return err
+	}
+
+	data, err := json.Marshal(model.UserBalance{Current: user.Balance, Withdrawn: withdrawn, ExpiringSoon: expiring})
+	if err != nil {
+		return err
+	}
+
+	return e.Publish(ctx, model.Event{Type: model.EventBalanceChanged, UserID: userID, Data: data})
+}
+
+func (e eventService) Subscribe(userID int) (<-chan model.Event, func()) {
+	messages, cancel := e.broker.Subscribe(strconv.Itoa(userID))
+
+	events := make(chan model.Event)
+	go func() {
+		defer close(events)
+
+		for msg := range messages {
+			if event, ok := msg.(model.Event); ok {
+				events <- event
+			}
+		}
+	}()
+
+	return events, func() {
+		cancel()
+		// drain events so the forwarding goroutine can exit
+		for range events {
+		}
+	}
+}
+
+// Listen forwards notifications of all instances to the local subscribers until ctx is done
+func (e eventService) Listen(ctx context.Context) {
+	notifications, err := e.repo.Listen(ctx, eventsChannel)
+	if err != nil {
+		e.Log(ctx).Error().Err(err).Msg("Listen: failed listen events")
+		return
+	}
+
+	for payload := range notifications {
+		var event model.Event
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			e.Log(ctx).Warn().Err(err).Msg("Listen: invalid event payload")
+			continue
+		}
+
+		e.broker.Publish(strconv.Itoa(event.UserID), event)
+	}
+}
+
+func (e eventService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "eventService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/djokcik/gophermart/pkg/pubsub"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_eventService_PublishBalance(t *testing.T) {
+	t.Run("should notify balance of user", func(t *testing.T) {
+		userMock := mocks.UserRepository{Mock: mock.Mock{}}
+		userMock.On("UserByID", mock.Anything, 666).Return(model.User{ID: 666, Balance: 1050}, nil)
+
+		withdrawMock := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		withdrawMock.On("AmountWithdrawByUser", mock.Anything, 666).Return(model.Amount(200), nil)
+
+		lotMock := mocks.PointLotRepository{Mock: mock.Mock{}}
+		lotMock.On("ExpiringPoints", mock.Anything, 666, mock.Anything).Return(model.Amount(150), nil)
+
+		repoMock := mocks.EventRepository{Mock: mock.Mock{}}
+		repoMock.On("Notify", mock.Anything, eventsChannel,
+			`{"type":"balance.changed","user_id":666,"data":{"current":10.5,"withdrawn":2,"expiring_soon":1.5}}`).
+			Return(nil)
+
+		service := eventService{repo: &repoMock, userRepo: &userMock, withdrawRepo: &withdrawMock, lotRepo: &lotMock}
+
+		err := service.PublishBalance(context.Background(), 666)
+
+		repoMock.AssertNumberOfCalls(t, "Notify", 1)
+		require.Equal(t, err, nil)
+	})
+}
+
+func Test_eventService_Publish(t *testing.T) {
+	t.Run("should deliver to local subscribers when notify failed", func(t *testing.T) {
+		repoMock := mocks.EventRepository{Mock: mock.Mock{}}
+		repoMock.On("Notify", mock.Anything, eventsChannel, mock.Anything).Return(errors.New("connection lost"))
+
+		service := eventService{repo: &repoMock, broker: pubsub.NewBroker(1)}
+
+		events, cancel := service.Subscribe(666)
+		defer cancel()
+
+		event := model.Event{Type: model.EventBalanceChanged, UserID: 666, Data: []byte(`{}`)}
+		err := service.Publish(context.Background(), event)
+
+		require.NotEqual(t, err, nil)
+		require.Equal(t, <-events, event)
+	})
+}
+
+func Test_eventService_Listen(t *testing.T) {
+	t.Run("should forward notifications to subscribers of user", func(t *testing.T) {
+		notifications := make(chan string, 2)
+		notifications <- `{"type":"order.status_changed","user_id":111,"data":{}}`
+		notifications <- `{"type":"order.status_changed","user_id":666,"data":{"number":"1"}}`
+		close(notifications)
+
+		repoMock := mocks.EventRepository{Mock: mock.Mock{}}
+		repoMock.On("Listen", mock.Anything, eventsChannel).Return((<-chan string)(notifications), nil)
+
+		service := eventService{repo: &repoMock, broker: pubsub.NewBroker(1)}
+
+		events, cancel := service.Subscribe(666)
+		defer cancel()
+
+		service.Listen(context.Background())
+
+		require.Equal(t, <-events, model.Event{
+			Type:   model.EventOrderStatusChanged,
+			UserID: 666,
+			Data:   []byte(`{"number":"1"}`),
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+//go:generate mockery --name=IdempotencyService
+
+// IdempotencyService makes retries of POST requests safe. The first request with a key is processed
+// and its response is stored, retries with the same key and body get the stored response.
+type IdempotencyService interface {
+	// Begin reserves the key for the request. When the request was already processed
+	// the stored record is returned with replay flag.
+	Begin(ctx context.Context, userID int, key string, fingerprint string) (model.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, record model.IdempotencyRecord) error
+	// Cancel releases the key, so failed request can be retried
+	Cancel(ctx context.Context, userID int, key string) error
+	Cleaner(ctx context.Context) func()
+}
+
+func NewIdempotencyService(cfg config.Config, registry reporegistry.RepoRegistry) IdempotencyService {
+	return &idempotencyService{
+		cfg:  cfg,
+		repo: registry.GetIdempotencyRepo(),
+	}
+}
+
+type idempotencyService struct {
+	cfg  config.Config
+	repo storage.IdempotencyRepository
+}
+
+func (s idempotencyService) Begin(ctx context.Context, userID int, key string, fingerprint string) (model.IdempotencyRecord, bool, error) {
+	created, err := s.repo.CreateIdempotencyKey(ctx, model.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(s.cfg.IdempotencyKeyTTL),
+	})
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("Begin: create key")
+		return model.IdempotencyRecord{}, false, err
+	}
+
+	if created {
+		return model.IdempotencyRecord{}, false, nil
+	}
+
+	record, err := s.repo.IdempotencyKey(ctx, userID, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			// the first request has just failed and released the key
+			return model.IdempotencyRecord{}, false, ErrIdempotencyKeyInProgress
+		}
+
+		s.Log(ctx).Error().Err(err).Msg("Begin: get key")
+		return model.IdempotencyRecord{}, false, err
+	}
+
+	if record.Fingerprint != fingerprint {
+		s.Log(ctx).Warn().Int("userID", userID).Str("key", key).Msg("Begin: key reused with different request")
+		return model.IdempotencyRecord{}, false, ErrIdempotencyKeyMismatch
+	}
+
+	if record.StatusCode == 0 {
+		return model.IdempotencyRecord{}, false, ErrIdempotencyKeyInProgress
+	}
+
+	return record, true, nil
+}
+
+func (s idempotencyService) Complete(ctx context.Context, record model.IdempotencyRecord) error {
+	err := s.repo.SaveIdempotencyResponse(ctx, record)
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("Complete: save response")
+		return err
+	}
+
+	return nil
+}
+
+func (s idempotencyService) Cancel(ctx context.Context, userID int, key string) error {
+	err := s.repo.DeleteIdempotencyKey(ctx, userID, key)
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("Cancel: delete key")
+		return err
+	}
+
+	return nil
+}
+
+func (s idempotencyService) Cleaner(ctx context.Context) func() {
+	return func() {
+		err := s.repo.DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			s.Log(ctx).Error().Err(err).Msg("Cleaner: failed delete expired idempotency keys")
+		}
+	}
+}
+
+func (s idempotencyService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "idempotencyService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_idempotencyService_Begin(t *testing.T) {
+	t.Run("should reserve new key", func(t *testing.T) {
+		m := mocks.IdempotencyRepository{Mock: mock.Mock{}}
+		m.On("CreateIdempotencyKey", mock.Anything, mock.Anything).Return(true, nil)
+
+		service := idempotencyService{repo: &m, cfg: config.Config{IdempotencyKeyTTL: time.Hour}}
+
+		_, replay, err := service.Begin(context.Background(), 666, "key", "fingerprint")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, replay, false)
+
+		record := m.Calls[0].Arguments.Get(1).(model.IdempotencyRecord)
+		require.Equal(t, record.Fingerprint, "fingerprint")
+		require.WithinDuration(t, record.ExpiresAt, time.Now().Add(time.Hour), time.Second)
+	})
+
+	t.Run("should replay stored response", func(t *testing.T) {
+		stored := model.IdempotencyRecord{UserID: 666, Key: "key", Fingerprint: "fingerprint", StatusCode: 200, Response: []byte("OK")}
+
+		m := mocks.IdempotencyRepository{Mock: mock.Mock{}}
+		m.On("CreateIdempotencyKey", mock.Anything, mock.Anything).Return(false, nil)
+		m.On("IdempotencyKey", mock.Anything, 666, "key").Return(stored, nil)
+
+		service := idempotencyService{repo: &m}
+
+		record, replay, err := service.Begin(context.Background(), 666, "key", "fingerprint")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, replay, true)
+		require.Equal(t, record, stored)
+	})
+
+	t.Run("should reject key reused with another request", func(t *testing.T) {
+		m := mocks.IdempotencyRepository{Mock: mock.Mock{}}
+		m.On("CreateIdempotencyKey", mock.Anything, mock.Anything).Return(false, nil)
+		m.On("IdempotencyKey", mock.Anything, 666, "key").
+			Return(model.IdempotencyRecord{Fingerprint: "another", StatusCode: 200}, nil)
+
+		service := idempotencyService{repo: &m}
+
+		_, _, err := service.Begin(context.Background(), 666, "key", "fingerprint")
+
+		require.Equal(t, err, ErrIdempotencyKeyMismatch)
+	})
+
+	t.Run("should reject key of request in progress", func(t *testing.T) {
+		m := mocks.IdempotencyRepository{Mock: mock.Mock{}}
+		m.On("CreateIdempotencyKey", mock.Anything, mock.Anything).Return(false, nil)
+		m.On("IdempotencyKey", mock.Anything, 666, "key").Return(model.IdempotencyRecord{}, storage.ErrNotFound)
+
+		service := idempotencyService{repo: &m}
+
+		_, _, err := service.Begin(context.Background(), 666, "key", "fingerprint")
+
+		require.Equal(t, err, ErrIdempotencyKeyInProgress)
+	})
+}
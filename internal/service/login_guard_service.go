@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+const (
+	// loginFreeFailures is the number of failures allowed without delay
+	loginFreeFailures = 2
+	loginDelayBase    = time.Second
+	loginDelayMax     = 30 * time.Second
+)
+
+//go:generate mockery --name=LoginGuardService
+
+// LoginGuardService protects login from password guessing. Failures are counted per username and
+// per client IP, every next attempt is delayed progressively and too many failures lock the key.
+type LoginGuardService interface {
+	// Check returns ErrTooManyLoginAttempts and time to wait when login or ip is blocked
+	Check(ctx context.Context, login string, ip string) (time.Duration, error)
+	Failure(ctx context.Context, login string, ip string) error
+	Success(ctx context.Context, login string) error
+	// CheckUser, FailureUser and SuccessUser guard the password or 2fa code confirming actions of the signed in user,
+	// failures are counted per user id apart from logins, so a stolen access token can't be used to guess them
+	CheckUser(ctx context.Context, userID int, ip string) (time.Duration, error)
+	FailureUser(ctx context.Context, userID int, ip string) error
+	SuccessUser(ctx context.Context, userID int) error
+	Cleaner(ctx context.Context) func()
+}
+
+func NewLoginGuardService(cfg config.Config, registry reporegistry.RepoRegistry) LoginGuardService {
+	return &loginGuardService{
+		cfg:      cfg,
+		repo:     registry.GetLoginAttemptRepo(),
+		auditLog: NewAuditService(cfg, registry),
+	}
+}
+
+type loginGuardService struct {
+	cfg      config.Config
+	repo     storage.LoginAttemptRepository
+	auditLog AuditService
+}
+
+func (l loginGuardService) Check(ctx context.Context, login string, ip string) (time.Duration, error) {
+	return l.check(ctx, loginKey(login), ipKey(ip))
+}
+
+func (l loginGuardService) CheckUser(ctx context.Context, userID int, ip string) (time.Duration, error) {
+	return l.check(ctx, userKey(userID), ipKey(ip))
+}
+
+func (l loginGuardService) check(ctx context.Context, keys ...string) (time.Duration, error) {
+	var retryAfter time.Duration
+
+	for _, key := range keys {
+		attempt, err := l.repo.LoginAttempt(ctx, key)
+		if err != nil {
+			l.Log(ctx).Error().Err(err).Msg("Check:")
+			return 0, err
+		}
+
+		if wait := time.Until(attempt.BlockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return retryAfter, ErrTooManyLoginAttempts
+	}
+
+	return 0, nil
+}
+
+func (l loginGuardService) Failure(ctx context.Context, login string, ip string) error {
+	err := l.registerFailure(ctx, loginKey(login), l.cfg.LoginMaxFailures, ip)
+	if err != nil {
+		return err
+	}
+
+	return l.registerFailure(ctx, ipKey(ip), l.cfg.LoginIPMaxFailures, ip)
+}
+
+func (l loginGuardService) FailureUser(ctx context.Context, userID int, ip string) error {
+	err := l.registerFailure(ctx, userKey(userID), l.cfg.LoginMaxFailures, ip)
+	if err != nil {
+		return err
+	}
+
+	return l.registerFailure(ctx, ipKey(ip), l.cfg.LoginIPMaxFailures, ip)
+}
+
+func (l loginGuardService) registerFailure(ctx context.Context, key string, maxFailures int, ip string) error {
+	attempt, err := l.repo.RegisterFailure(ctx, key, l.cfg.LoginFailureWindow)
+	if err != nil {
+		l.Log(ctx).Error().Err(err).Msg("Failure: register failure")
+		return err
+	}
+
+	if attempt.Failures >= maxFailures {
+		err = l.repo.Block(ctx, key, time.Now().Add(l.cfg.LoginLockout))
+		if err != nil {
+			l.Log(ctx).Error().Err(err).Msg("Failure: lockout")
+			return err
+		}
+
+		l.auditLockout(ctx, attempt, ip)
+		return nil
+	}
+
+	if delay := loginDelay(attempt.Failures); delay > 0 {
+		err = l.repo.Block(ctx, key, time.Now().Add(delay))
+		if err != nil {
+			l.Log(ctx).Error().Err(err).Msg("Failure: delay")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l loginGuardService) auditLockout(ctx context.Context, attempt model.LoginAttempt, ip string) {
+	l.auditLog.Record(ctx, model.AuditEntry{
+		Action:     "login_lockout",
+		TargetType: model.AuditTargetLogin,
+		TargetID:   attempt.Key,
+		IP:         ip,
+		Details:    map[string]interface{}{"failures": attempt.Failures, "lockout": l.cfg.LoginLockout.String()},
+	})
+}
+
+// Success forgets failures of the username. Failures of the ip are kept,
+// otherwise an attacker could reset them by logging in to own account.
+func (l loginGuardService) Success(ctx context.Context, login string) error {
+	err := l.repo.ResetLoginAttempts(ctx, loginKey(login))
+	if err != nil {
+		l.Log(ctx).Error().Err(err).Msg("Success:")
+		return err
+	}
+
+	return nil
+}
+
+func (l loginGuardService) SuccessUser(ctx context.Context, userID int) error {
+	err := l.repo.ResetLoginAttempts(ctx, userKey(userID))
+	if err != nil {
+		l.Log(ctx).Error().Err(err).Msg("SuccessUser:")
+		return err
+	}
+
+	return nil
+}
+
+// Cleaner removes counters which are outside the failure window and not blocked
+func (l loginGuardService) Cleaner(ctx context.Context) func() {
+	return func() {
+		err := l.repo.DeleteExpiredLoginAttempts(ctx, time.Now().Add(-l.cfg.LoginFailureWindow))
+		if err != nil {
+			l.Log(ctx).Error().Err(err).Msg("Cleaner: failed delete expired login attempts")
+		}
+	}
+}
+
+// loginDelay doubles the wait after every failure over the free ones
+func loginDelay(failures int) time.Duration {
+	if failures <= loginFreeFailures {
+		return 0
+	}
+
+	delay := loginDelayBase << (failures - loginFreeFailures - 1)
+	if delay > loginDelayMax || delay <= 0 {
+		return loginDelayMax
+	}
+
+	return delay
+}
+
+func loginKey(login string) string {
+	return "login:" + login
+}
+
+func userKey(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (l loginGuardService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "loginGuardService").Logger()
+
+	return &logger
+}
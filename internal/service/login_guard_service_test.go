@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage/memory"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_loginGuardService_Failure(t *testing.T) {
+	cfg := config.Config{
+		LoginMaxFailures:   5,
+		LoginIPMaxFailures: 50,
+		LoginFailureWindow: time.Minute,
+		LoginLockout:       time.Hour,
+	}
+
+	t.Run("should lock login after max failures", func(t *testing.T) {
+		service := loginGuardService{cfg: cfg, repo: memory.NewLoginAttemptRepository(), auditLog: newAuditMock()}
+		ctx := context.Background()
+
+		for i := 0; i < 5; i++ {
+			require.Equal(t, service.Failure(ctx, "user", "10.0.0.1"), nil)
+		}
+
+		retryAfter, err := service.Check(ctx, "user", "10.0.0.2")
+		require.Equal(t, err, ErrTooManyLoginAttempts)
+		require.Greater(t, retryAfter, 59*time.Minute)
+
+		_, err = service.Check(ctx, "another", "10.0.0.3")
+		require.Equal(t, err, nil)
+	})
+
+	t.Run("should delay attempts progressively", func(t *testing.T) {
+		require.Equal(t, loginDelay(2), time.Duration(0))
+		require.Equal(t, loginDelay(3), time.Second)
+		require.Equal(t, loginDelay(4), 2*time.Second)
+		require.Equal(t, loginDelay(100), loginDelayMax)
+	})
+
+	t.Run("should lock ip after max failures for different logins", func(t *testing.T) {
+		m := mocks.LoginAttemptRepository{Mock: mock.Mock{}}
+		m.On("RegisterFailure", mock.Anything, "login:user", time.Minute).
+			Return(model.LoginAttempt{Key: "login:user", Failures: 1}, nil)
+		m.On("RegisterFailure", mock.Anything, "ip:10.0.0.1", time.Minute).
+			Return(model.LoginAttempt{Key: "ip:10.0.0.1", Failures: 50}, nil)
+		m.On("Block", mock.Anything, "ip:10.0.0.1", mock.Anything).Return(nil)
+
+		service := loginGuardService{cfg: cfg, repo: &m, auditLog: newAuditMock()}
+
+		err := service.Failure(context.Background(), "user", "10.0.0.1")
+
+		require.Equal(t, err, nil)
+		m.AssertNumberOfCalls(t, "Block", 1)
+	})
+}
+
+func Test_loginGuardService_FailureUser(t *testing.T) {
+	cfg := config.Config{
+		LoginMaxFailures:   5,
+		LoginIPMaxFailures: 50,
+		LoginFailureWindow: time.Minute,
+		LoginLockout:       time.Hour,
+	}
+
+	t.Run("should lock confirmations of user apart from login", func(t *testing.T) {
+		service := loginGuardService{cfg: cfg, repo: memory.NewLoginAttemptRepository(), auditLog: newAuditMock()}
+		ctx := context.Background()
+
+		for i := 0; i < 5; i++ {
+			require.Equal(t, service.FailureUser(ctx, 666, "10.0.0.1"), nil)
+		}
+
+		retryAfter, err := service.CheckUser(ctx, 666, "10.0.0.2")
+		require.Equal(t, err, ErrTooManyLoginAttempts)
+		require.Greater(t, retryAfter, 59*time.Minute)
+
+		_, err = service.Check(ctx, "666", "10.0.0.3")
+		require.Equal(t, err, nil)
+
+		_, err = service.CheckUser(ctx, 667, "10.0.0.3")
+		require.Equal(t, err, nil)
+	})
+
+	t.Run("should reset confirmations of user", func(t *testing.T) {
+		m := mocks.LoginAttemptRepository{Mock: mock.Mock{}}
+		m.On("ResetLoginAttempts", mock.Anything, "user:666").Return(nil)
+
+		service := loginGuardService{repo: &m}
+
+		err := service.SuccessUser(context.Background(), 666)
+
+		require.Equal(t, err, nil)
+		m.AssertNumberOfCalls(t, "ResetLoginAttempts", 1)
+	})
+}
+
+func Test_loginGuardService_Success(t *testing.T) {
+	t.Run("should reset only login failures", func(t *testing.T) {
+		m := mocks.LoginAttemptRepository{Mock: mock.Mock{}}
+		m.On("ResetLoginAttempts", mock.Anything, "login:user").Return(nil)
+
+		service := loginGuardService{repo: &m}
+
+		err := service.Success(context.Background(), "user")
+
+		require.Equal(t, err, nil)
+		m.AssertNumberOfCalls(t, "ResetLoginAttempts", 1)
+	})
+}
@@ -6,9 +6,8 @@ import (
 	context "context"
 
 	model "github.com/djokcik/gophermart/internal/model"
-	mock "github.com/stretchr/testify/mock"
-
 	provider "github.com/djokcik/gophermart/provider"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // OrderService is an autogenerated mock type for the OrderService type
@@ -16,6 +15,20 @@ type OrderService struct {
 	mock.Mock
 }
 
+// CompleteBonuses provides a mock function with given fields: ctx, id
+func (_m *OrderService) CompleteBonuses(ctx context.Context, id model.OrderID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.OrderID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // OrdersByStatus provides a mock function with given fields: ctx, status
 func (_m *OrderService) OrdersByStatus(ctx context.Context, status model.Status) ([]model.Order, error) {
 	ret := _m.Called(ctx, status)
@@ -62,6 +75,29 @@ func (_m *OrderService) OrdersByUser(ctx context.Context, userID int) ([]model.O
 	return r0, r1
 }
 
+// PendingBonusOrders provides a mock function with given fields: ctx, limit
+func (_m *OrderService) PendingBonusOrders(ctx context.Context, limit int) ([]model.Order, error) {
+	ret := _m.Called(ctx, limit)
+
+	var r0 []model.Order
+	if rf, ok := ret.Get(0).(func(context.Context, int) []model.Order); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Order)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ProcessOrder provides a mock function with given fields: ctx, orderID
 func (_m *OrderService) ProcessOrder(ctx context.Context, orderID model.OrderID) error {
 	ret := _m.Called(ctx, orderID)
@@ -76,6 +112,29 @@ func (_m *OrderService) ProcessOrder(ctx context.Context, orderID model.OrderID)
 	return r0
 }
 
+// ProcessOrders provides a mock function with given fields: ctx, orderIDs
+func (_m *OrderService) ProcessOrders(ctx context.Context, orderIDs []model.OrderID) ([]model.OrderUploadResult, error) {
+	ret := _m.Called(ctx, orderIDs)
+
+	var r0 []model.OrderUploadResult
+	if rf, ok := ret.Get(0).(func(context.Context, []model.OrderID) []model.OrderUploadResult); ok {
+		r0 = rf(ctx, orderIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.OrderUploadResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []model.OrderID) error); ok {
+		r1 = rf(ctx, orderIDs)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateForAccrual provides a mock function with given fields: ctx, order, accrual
 func (_m *OrderService) UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse) error {
 	ret := _m.Called(ctx, order, accrual)
@@ -15,14 +15,14 @@ type UserService struct {
 }
 
 // Authenticate provides a mock function with given fields: ctx, login, password
-func (_m *UserService) Authenticate(ctx context.Context, login string, password string) (string, error) {
+func (_m *UserService) Authenticate(ctx context.Context, login string, password string) (model.AuthTokens, error) {
 	ret := _m.Called(ctx, login, password)
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+	var r0 model.AuthTokens
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) model.AuthTokens); ok {
 		r0 = rf(ctx, login, password)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(model.AuthTokens)
 	}
 
 	var r1 error
@@ -35,13 +35,69 @@ func (_m *UserService) Authenticate(ctx context.Context, login string, password
 	return r0, r1
 }
 
-// CreateUser provides a mock function with given fields: ctx, login, password
-func (_m *UserService) CreateUser(ctx context.Context, login string, password string) error {
-	ret := _m.Called(ctx, login, password)
+// ChangePassword provides a mock function with given fields: ctx, user, current, next, mfa
+func (_m *UserService) ChangePassword(ctx context.Context, user model.User, current string, next string, mfa bool) (model.AuthTokens, error) {
+	ret := _m.Called(ctx, user, current, next, mfa)
+
+	var r0 model.AuthTokens
+	if rf, ok := ret.Get(0).(func(context.Context, model.User, string, string, bool) model.AuthTokens); ok {
+		r0 = rf(ctx, user, current, next, mfa)
+	} else {
+		r0 = ret.Get(0).(model.AuthTokens)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.User, string, string, bool) error); ok {
+		r1 = rf(ctx, user, current, next, mfa)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CompleteTwoFactor provides a mock function with given fields: ctx, challenge, code
+func (_m *UserService) CompleteTwoFactor(ctx context.Context, challenge model.Claims, code string) (model.AuthTokens, error) {
+	ret := _m.Called(ctx, challenge, code)
+
+	var r0 model.AuthTokens
+	if rf, ok := ret.Get(0).(func(context.Context, model.Claims, string) model.AuthTokens); ok {
+		r0 = rf(ctx, challenge, code)
+	} else {
+		r0 = ret.Get(0).(model.AuthTokens)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.Claims, string) error); ok {
+		r1 = rf(ctx, challenge, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateUser provides a mock function with given fields: ctx, login, password, referralCode
+func (_m *UserService) CreateUser(ctx context.Context, login string, password string, referralCode string) error {
+	ret := _m.Called(ctx, login, password, referralCode)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
-		r0 = rf(ctx, login, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, login, password, referralCode)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: ctx, user, password
+func (_m *UserService) DeleteUser(ctx context.Context, user model.User, password string) error {
+	ret := _m.Called(ctx, user, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.User, string) error); ok {
+		r0 = rf(ctx, user, password)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -50,14 +106,14 @@ func (_m *UserService) CreateUser(ctx context.Context, login string, password st
 }
 
 // GenerateToken provides a mock function with given fields: ctx, user
-func (_m *UserService) GenerateToken(ctx context.Context, user model.User) (string, error) {
+func (_m *UserService) GenerateToken(ctx context.Context, user model.User) (model.AuthTokens, error) {
 	ret := _m.Called(ctx, user)
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(context.Context, model.User) string); ok {
+	var r0 model.AuthTokens
+	if rf, ok := ret.Get(0).(func(context.Context, model.User) model.AuthTokens); ok {
 		r0 = rf(ctx, user)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(model.AuthTokens)
 	}
 
 	var r1 error
@@ -111,3 +167,54 @@ func (_m *UserService) GetUserByUsername(ctx context.Context, username string) (
 
 	return r0, r1
 }
+
+// PepperReporter provides a mock function with given fields: ctx
+func (_m *UserService) PepperReporter(ctx context.Context) func() {
+	ret := _m.Called(ctx)
+
+	var r0 func()
+	if rf, ok := ret.Get(0).(func(context.Context) func()); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(func())
+		}
+	}
+
+	return r0
+}
+
+// Profile provides a mock function with given fields: ctx, user
+func (_m *UserService) Profile(ctx context.Context, user model.User) (model.Profile, error) {
+	ret := _m.Called(ctx, user)
+
+	var r0 model.Profile
+	if rf, ok := ret.Get(0).(func(context.Context, model.User) model.Profile); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Get(0).(model.Profile)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// VerifyPassword provides a mock function with given fields: ctx, user, password
+func (_m *UserService) VerifyPassword(ctx context.Context, user model.User, password string) error {
+	ret := _m.Called(ctx, user, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.User, string) error); ok {
+		r0 = rf(ctx, user, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
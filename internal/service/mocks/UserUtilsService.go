@@ -2,41 +2,52 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	model "github.com/djokcik/gophermart/internal/model"
+	keyring "github.com/djokcik/gophermart/pkg/keyring"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // UserUtilsService is an autogenerated mock type for the UserUtilsService type
 type UserUtilsService struct {
 	mock.Mock
 }
 
-// CompareHashAndPassword provides a mock function with given fields: password, hash
-func (_m *UserUtilsService) CompareHashAndPassword(password string, hash string) error {
-	ret := _m.Called(password, hash)
+// CompareHashAndPassword provides a mock function with given fields: pwd, pepper, hash
+func (_m *UserUtilsService) CompareHashAndPassword(pwd string, pepper string, hash string) (bool, error) {
+	ret := _m.Called(pwd, pepper, hash)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(password, hash)
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, string, string) bool); ok {
+		r0 = rf(pwd, pepper, hash)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(bool)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(pwd, pepper, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// CreateToken provides a mock function with given fields: secretKey, id
-func (_m *UserUtilsService) CreateToken(secretKey string, id int) (string, error) {
-	ret := _m.Called(secretKey, id)
+// CreateToken provides a mock function with given fields: keys, claims
+func (_m *UserUtilsService) CreateToken(keys *keyring.Keyring, claims model.Claims) (string, error) {
+	ret := _m.Called(keys, claims)
 
 	var r0 string
-	if rf, ok := ret.Get(0).(func(string, int) string); ok {
-		r0 = rf(secretKey, id)
+	if rf, ok := ret.Get(0).(func(*keyring.Keyring, model.Claims) string); ok {
+		r0 = rf(keys, claims)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, int) error); ok {
-		r1 = rf(secretKey, id)
+	if rf, ok := ret.Get(1).(func(*keyring.Keyring, model.Claims) error); ok {
+		r1 = rf(keys, claims)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -86,20 +97,20 @@ func (_m *UserUtilsService) HashAndSalt(pwd string, pepper string) (string, erro
 	return r0, r1
 }
 
-// ParseToken provides a mock function with given fields: accessToken, secretKey
-func (_m *UserUtilsService) ParseToken(accessToken string, secretKey string) (int, error) {
-	ret := _m.Called(accessToken, secretKey)
+// ParseToken provides a mock function with given fields: accessToken, keys
+func (_m *UserUtilsService) ParseToken(accessToken string, keys *keyring.Keyring) (model.Claims, error) {
+	ret := _m.Called(accessToken, keys)
 
-	var r0 int
-	if rf, ok := ret.Get(0).(func(string, string) int); ok {
-		r0 = rf(accessToken, secretKey)
+	var r0 model.Claims
+	if rf, ok := ret.Get(0).(func(string, *keyring.Keyring) model.Claims); ok {
+		r0 = rf(accessToken, keys)
 	} else {
-		r0 = ret.Get(0).(int)
+		r0 = ret.Get(0).(model.Claims)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(accessToken, secretKey)
+	if rf, ok := ret.Get(1).(func(string, *keyring.Keyring) error); ok {
+		r1 = rf(accessToken, keys)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -35,13 +35,29 @@ func (_m *WithdrawService) AmountWithdrawByUser(ctx context.Context, userID int)
 	return r0, r1
 }
 
-// ProcessWithdraw provides a mock function with given fields: ctx, orderID, sum
-func (_m *WithdrawService) ProcessWithdraw(ctx context.Context, orderID model.OrderID, sum model.Amount) error {
-	ret := _m.Called(ctx, orderID, sum)
+// Cleaner provides a mock function with given fields: ctx
+func (_m *WithdrawService) Cleaner(ctx context.Context) func() {
+	ret := _m.Called(ctx)
+
+	var r0 func()
+	if rf, ok := ret.Get(0).(func(context.Context) func()); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(func())
+		}
+	}
+
+	return r0
+}
+
+// ConfirmWithdraw provides a mock function with given fields: ctx, id, confirm
+func (_m *WithdrawService) ConfirmWithdraw(ctx context.Context, id int, confirm model.WithdrawConfirmDto) error {
+	ret := _m.Called(ctx, id, confirm)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, model.OrderID, model.Amount) error); ok {
-		r0 = rf(ctx, orderID, sum)
+	if rf, ok := ret.Get(0).(func(context.Context, int, model.WithdrawConfirmDto) error); ok {
+		r0 = rf(ctx, id, confirm)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -49,6 +65,50 @@ func (_m *WithdrawService) ProcessWithdraw(ctx context.Context, orderID model.Or
 	return r0
 }
 
+// ProcessWithdraw provides a mock function with given fields: ctx, orderID, sum
+func (_m *WithdrawService) ProcessWithdraw(ctx context.Context, orderID model.OrderID, sum model.Amount) (*model.PendingWithdraw, error) {
+	ret := _m.Called(ctx, orderID, sum)
+
+	var r0 *model.PendingWithdraw
+	if rf, ok := ret.Get(0).(func(context.Context, model.OrderID, model.Amount) *model.PendingWithdraw); ok {
+		r0 = rf(ctx, orderID, sum)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.PendingWithdraw)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.OrderID, model.Amount) error); ok {
+		r1 = rf(ctx, orderID, sum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReverseWithdraw provides a mock function with given fields: ctx, withdrawID, reverse
+func (_m *WithdrawService) ReverseWithdraw(ctx context.Context, withdrawID int, reverse model.ReverseWithdrawDto) (model.WithdrawReversal, error) {
+	ret := _m.Called(ctx, withdrawID, reverse)
+
+	var r0 model.WithdrawReversal
+	if rf, ok := ret.Get(0).(func(context.Context, int, model.ReverseWithdrawDto) model.WithdrawReversal); ok {
+		r0 = rf(ctx, withdrawID, reverse)
+	} else {
+		r0 = ret.Get(0).(model.WithdrawReversal)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int, model.ReverseWithdrawDto) error); ok {
+		r1 = rf(ctx, withdrawID, reverse)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WithdrawLogsByUserID provides a mock function with given fields: ctx, userID
 func (_m *WithdrawService) WithdrawLogsByUserID(ctx context.Context, userID int) ([]model.Withdraw, error) {
 	ret := _m.Called(ctx, userID)
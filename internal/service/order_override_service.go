@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/djokcik/gophermart/provider"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+//go:generate mockery --name=OrderOverrideService
+
+// OrderOverrideService resolves disputed accruals of orders by admins
+type OrderOverrideService interface {
+	// ResetOrder returns the order to NEW for the poller. The credited accrual and bonuses of the order
+	// are taken back and credited again when the accrual system responds.
+	ResetOrder(ctx context.Context, id model.OrderID) (model.OrderOverride, error)
+	// OverrideOrder sets a final status and accrual instead of the accrual system,
+	// bonuses of the previous accrual are taken back and the poller applies bonuses of the new one
+	OverrideOrder(ctx context.Context, id model.OrderID, override model.OrderOverrideDto) (model.OrderOverride, error)
+	// CheckAccrual requests the accrual system at once and returns its response, the order isn't changed
+	CheckAccrual(ctx context.Context, id model.OrderID) (provider.AccrualResponse, error)
+}
+
+func NewOrderOverrideService(cfg config.Config, registry reporegistry.RepoRegistry, events EventService) OrderOverrideService {
+	return &orderOverrideService{
+		cfg:      cfg,
+		repo:     registry.GetOrderRepo(),
+		client:   provider.NewAccrualClient(cfg),
+		webhook:  NewWebhookService(cfg, registry),
+		events:   events,
+		auditLog: NewAuditService(cfg, registry),
+	}
+}
+
+type orderOverrideService struct {
+	cfg      config.Config
+	repo     storage.OrderRepository
+	client   provider.AccrualClient
+	webhook  WebhookService
+	events   EventService
+	auditLog AuditService
+}
+
+func (s orderOverrideService) ResetOrder(ctx context.Context, id model.OrderID) (model.OrderOverride, error) {
+	override, err := s.repo.OverrideOrder(ctx, id, model.StatusNew, 0, s.cfg.PointsExpireAt(time.Now()))
+	if err != nil {
+		s.Log(ctx).Warn().Err(err).Msg("ResetOrder:")
+		return model.OrderOverride{}, err
+	}
+
+	s.audit(ctx, "order_reset", override)
+	s.publish(ctx, override)
+
+	return override, nil
+}
+
+func (s orderOverrideService) OverrideOrder(ctx context.Context, id model.OrderID, dto model.OrderOverrideDto) (model.OrderOverride, error) {
+	override, err := s.repo.OverrideOrder(ctx, id, dto.Status, dto.Accrual, s.cfg.PointsExpireAt(time.Now()))
+	if err != nil {
+		s.Log(ctx).Warn().Err(err).Msg("OverrideOrder:")
+		return model.OrderOverride{}, err
+	}
+
+	s.audit(ctx, "order_overridden", override)
+	s.publish(ctx, override)
+
+	previous := override.Order
+	previous.Status = override.PreviousStatus
+
+	err = s.webhook.NotifyOrderStatus(ctx, previous, provider.AccrualResponse{
+		Order:   override.Order.ID,
+		Status:  override.Order.Status,
+		Accrual: override.Order.Accrual,
+	})
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("OverrideOrder: failed notify webhooks")
+	}
+
+	return override, nil
+}
+
+func (s orderOverrideService) CheckAccrual(ctx context.Context, id model.OrderID) (provider.AccrualResponse, error) {
+	order, err := s.repo.OrderByID(ctx, id)
+	if err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("CheckAccrual: find order")
+		return provider.AccrualResponse{}, err
+	}
+
+	s.auditLog.Record(ctx, model.AuditEntry{
+		Action:     "order_accrual_checked",
+		TargetType: model.AuditTargetOrder,
+		TargetID:   string(order.ID),
+		Details:    map[string]interface{}{"userID": order.UserID},
+	})
+
+	return s.client.GetOrder(ctx, id)
+}
+
+func (s orderOverrideService) publish(ctx context.Context, override model.OrderOverride) {
+	if override.Order.Status != override.PreviousStatus {
+		if err := s.events.PublishOrderStatus(ctx, override.Order); err != nil {
+			s.Log(ctx).Error().Err(err).Msg("publish: order status")
+		}
+	}
+
+	if _, ok := override.ReversedBonuses[override.Order.UserID]; ok || override.Delta != 0 {
+		if err := s.events.PublishBalance(ctx, override.Order.UserID); err != nil {
+			s.Log(ctx).Error().Err(err).Msg("publish: balance")
+		}
+	}
+
+	// the referrer loses the referral bonus
+	for userID := range override.ReversedBonuses {
+		if userID == override.Order.UserID {
+			continue
+		}
+
+		if err := s.events.PublishBalance(ctx, userID); err != nil {
+			s.Log(ctx).Error().Err(err).Msg("publish: referrer balance")
+		}
+	}
+}
+
+func (s orderOverrideService) audit(ctx context.Context, action string, override model.OrderOverride) {
+	s.auditLog.Record(ctx, model.AuditEntry{
+		Action:     action,
+		TargetType: model.AuditTargetOrder,
+		TargetID:   string(override.Order.ID),
+		Details: map[string]interface{}{
+			"userID":          override.Order.UserID,
+			"previousStatus":  override.PreviousStatus,
+			"previousAccrual": override.PreviousAccrual,
+			"status":          override.Order.Status,
+			"accrual":         override.Order.Accrual,
+			"delta":           override.Delta,
+			"reversedBonuses": override.ReversedBonuses,
+		},
+	})
+}
+
+func (s orderOverrideService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "orderOverrideService").Logger()
+
+	return &logger
+}
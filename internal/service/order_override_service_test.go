@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	serviceMocks "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/djokcik/gophermart/provider"
+	providerMocks "github.com/djokcik/gophermart/provider/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_orderOverrideService_ResetOrder(t *testing.T) {
+	t.Run("should reset order and publish taken back accrual", func(t *testing.T) {
+		override := model.OrderOverride{
+			Order:           model.Order{ID: "1", UserID: 666, Status: model.StatusNew},
+			PreviousStatus:  model.StatusProcessed,
+			PreviousAccrual: 1000,
+			Delta:           -1000,
+		}
+
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("OverrideOrder", mock.Anything, model.OrderID("1"), model.StatusNew, model.Amount(0), mock.Anything).
+			Return(override, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishOrderStatus", mock.Anything, override.Order).Return(nil)
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := orderOverrideService{repo: &m, events: &eventsMock, auditLog: newAuditMock()}
+
+		result, err := service.ResetOrder(context.Background(), "1")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, result, override)
+		eventsMock.AssertNumberOfCalls(t, "PublishOrderStatus", 1)
+		eventsMock.AssertNumberOfCalls(t, "PublishBalance", 1)
+	})
+}
+
+func Test_orderOverrideService_OverrideOrder(t *testing.T) {
+	t.Run("should notify webhooks about final status", func(t *testing.T) {
+		override := model.OrderOverride{
+			Order:          model.Order{ID: "1", UserID: 666, Status: model.StatusProcessed, Accrual: 1500},
+			PreviousStatus: model.StatusProcessing,
+			Delta:          1500,
+		}
+
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("OverrideOrder", mock.Anything, model.OrderID("1"), model.StatusProcessed, model.Amount(1500), mock.Anything).
+			Return(override, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishOrderStatus", mock.Anything, override.Order).Return(nil)
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		webhookMock := serviceMocks.WebhookService{Mock: mock.Mock{}}
+		webhookMock.On("NotifyOrderStatus", mock.Anything,
+			model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing, Accrual: 1500},
+			provider.AccrualResponse{Order: "1", Status: model.StatusProcessed, Accrual: 1500}).Return(nil)
+
+		service := orderOverrideService{repo: &m, events: &eventsMock, webhook: &webhookMock, auditLog: newAuditMock()}
+
+		result, err := service.OverrideOrder(context.Background(), "1", model.OrderOverrideDto{Status: model.StatusProcessed, Accrual: 1500})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, result, override)
+		webhookMock.AssertNumberOfCalls(t, "NotifyOrderStatus", 1)
+	})
+	t.Run("should publish balances of users whose bonuses are taken back", func(t *testing.T) {
+		override := model.OrderOverride{
+			Order:           model.Order{ID: "1", UserID: 666, Status: model.StatusInvalid},
+			PreviousStatus:  model.StatusProcessed,
+			PreviousAccrual: 1000,
+			Delta:           -1000,
+			ReversedBonuses: map[int]model.Amount{42: 10000, 666: 10150},
+		}
+
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("OverrideOrder", mock.Anything, model.OrderID("1"), model.StatusInvalid, model.Amount(0), mock.Anything).
+			Return(override, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishOrderStatus", mock.Anything, override.Order).Return(nil)
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+		eventsMock.On("PublishBalance", mock.Anything, 42).Return(nil)
+
+		webhookMock := serviceMocks.WebhookService{Mock: mock.Mock{}}
+		webhookMock.On("NotifyOrderStatus", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		service := orderOverrideService{repo: &m, events: &eventsMock, webhook: &webhookMock, auditLog: newAuditMock()}
+
+		_, err := service.OverrideOrder(context.Background(), "1", model.OrderOverrideDto{Status: model.StatusInvalid})
+
+		require.Equal(t, err, nil)
+		eventsMock.AssertCalled(t, "PublishBalance", mock.Anything, 666)
+		eventsMock.AssertCalled(t, "PublishBalance", mock.Anything, 42)
+		eventsMock.AssertNumberOfCalls(t, "PublishBalance", 2)
+	})
+	t.Run("should return error when accrual is spent", func(t *testing.T) {
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("OverrideOrder", mock.Anything, model.OrderID("1"), model.StatusInvalid, model.Amount(0), mock.Anything).
+			Return(model.OrderOverride{}, storage.ErrInsufficientFunds)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+
+		service := orderOverrideService{repo: &m, events: &eventsMock, auditLog: newAuditMock()}
+
+		_, err := service.OverrideOrder(context.Background(), "1", model.OrderOverrideDto{Status: model.StatusInvalid})
+
+		require.Equal(t, err, storage.ErrInsufficientFunds)
+		eventsMock.AssertNotCalled(t, "PublishBalance", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_orderOverrideService_CheckAccrual(t *testing.T) {
+	t.Run("should return response of accrual system", func(t *testing.T) {
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("OrderByID", mock.Anything, model.OrderID("1")).Return(model.Order{ID: "1", UserID: 666}, nil)
+
+		clientMock := providerMocks.AccrualClient{Mock: mock.Mock{}}
+		clientMock.On("GetOrder", mock.Anything, model.OrderID("1")).
+			Return(provider.AccrualResponse{Order: "1", Status: model.StatusProcessed, Accrual: 1000}, nil)
+
+		service := orderOverrideService{repo: &m, client: &clientMock, auditLog: newAuditMock()}
+
+		response, err := service.CheckAccrual(context.Background(), "1")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, response, provider.AccrualResponse{Order: "1", Status: model.StatusProcessed, Accrual: 1000})
+		m.AssertNotCalled(t, "UpdateForAccrual", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("should not request accrual system for unknown order", func(t *testing.T) {
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("OrderByID", mock.Anything, model.OrderID("1")).Return(model.Order{}, storage.ErrNotFound)
+
+		clientMock := providerMocks.AccrualClient{Mock: mock.Mock{}}
+
+		service := orderOverrideService{repo: &m, client: &clientMock, auditLog: newAuditMock()}
+
+		_, err := service.CheckAccrual(context.Background(), "1")
+
+		require.Equal(t, err, storage.ErrNotFound)
+		clientMock.AssertNotCalled(t, "GetOrder", mock.Anything, mock.Anything)
+	})
+}
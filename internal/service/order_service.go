@@ -11,36 +11,68 @@ import (
 	"github.com/djokcik/gophermart/pkg/logging"
 	"github.com/djokcik/gophermart/provider"
 	"github.com/rs/zerolog"
+	"time"
 )
 
 //go:generate mockery --name=OrderService
 
 type OrderService interface {
 	ProcessOrder(ctx context.Context, orderID model.OrderID) error
+	ProcessOrders(ctx context.Context, orderIDs []model.OrderID) ([]model.OrderUploadResult, error)
 	OrdersByUser(ctx context.Context, userID int) ([]model.Order, error)
 	OrdersByStatus(ctx context.Context, status model.Status) ([]model.Order, error)
 	UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse) error
+	PendingBonusOrders(ctx context.Context, limit int) ([]model.Order, error)
+	CompleteBonuses(ctx context.Context, id model.OrderID) error
 }
 
 func NewOrderService(cfg config.Config, registry reporegistry.RepoRegistry) OrderService {
-	return &orderService{cfg: cfg, repo: registry.GetOrderRepo()}
+	return &orderService{
+		cfg:          cfg,
+		repo:         registry.GetOrderRepo(),
+		withdrawRepo: registry.GetWithdrawRepo(),
+		webhook:      NewWebhookService(cfg, registry),
+	}
 }
 
 type orderService struct {
-	cfg  config.Config
-	repo storage.OrderRepository
+	cfg          config.Config
+	repo         storage.OrderRepository
+	withdrawRepo storage.WithdrawRepository
+	webhook      WebhookService
 }
 
 func (o orderService) UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse) error {
-	err := o.repo.UpdateForAccrual(ctx, order, accrual)
+	err := o.repo.UpdateForAccrual(ctx, order, accrual, o.cfg.PointsExpireAt(time.Now()))
 	if err != nil {
 		o.Log(ctx).Trace().Err(err).Msg("UpdateForAccrual:")
 		return err
 	}
 
+	if order.Status != accrual.Status {
+		err = o.webhook.NotifyOrderStatus(ctx, order, accrual)
+		if err != nil {
+			o.Log(ctx).Error().Err(err).Msg("UpdateForAccrual: failed notify webhooks")
+		}
+	}
+
 	return nil
 }
 
+func (o orderService) PendingBonusOrders(ctx context.Context, limit int) ([]model.Order, error) {
+	orders, err := o.repo.PendingBonusOrders(ctx, limit)
+	if err != nil {
+		o.Log(ctx).Error().Err(err).Msg("PendingBonusOrders:")
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+func (o orderService) CompleteBonuses(ctx context.Context, id model.OrderID) error {
+	return o.repo.CompleteBonuses(ctx, id)
+}
+
 func (o orderService) OrdersByStatus(ctx context.Context, status model.Status) ([]model.Order, error) {
 	orders, err := o.repo.OrdersByStatus(ctx, status)
 	if err != nil {
@@ -84,6 +116,16 @@ func (o orderService) ProcessOrder(ctx context.Context, orderID model.OrderID) e
 		return err
 	}
 
+	paid, err := o.paidWithPoints(ctx, []model.OrderID{orderID})
+	if err != nil {
+		return err
+	}
+
+	if paid[orderID] {
+		o.Log(ctx).Trace().Err(storage.ErrOrderAlreadyPaid).Msg("")
+		return storage.ErrOrderAlreadyPaid
+	}
+
 	order = model.Order{
 		ID:     orderID,
 		UserID: user.ID,
@@ -103,6 +145,101 @@ func (o orderService) ProcessOrder(ctx context.Context, orderID model.OrderID) e
 	return nil
 }
 
+// ProcessOrders uploads a batch of orders, each number gets its own result.
+// Duplicate numbers in the batch are reported once.
+func (o orderService) ProcessOrders(ctx context.Context, orderIDs []model.OrderID) ([]model.OrderUploadResult, error) {
+	user := appContext.User(ctx)
+	if user == nil {
+		o.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+		return nil, ErrNotAuthenticated
+	}
+
+	results := make([]model.OrderUploadResult, 0, len(orderIDs))
+	valid := make([]model.OrderID, 0, len(orderIDs))
+	seen := make(map[model.OrderID]bool, len(orderIDs))
+
+	for _, orderID := range orderIDs {
+		if seen[orderID] {
+			continue
+		}
+		seen[orderID] = true
+
+		results = append(results, model.OrderUploadResult{ID: orderID, Result: model.UploadInvalid})
+		if orderID.Valid() {
+			valid = append(valid, orderID)
+		}
+	}
+
+	paid, err := o.paidWithPoints(ctx, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paid) > 0 {
+		unpaid := make([]model.OrderID, 0, len(valid))
+		for _, orderID := range valid {
+			if !paid[orderID] {
+				unpaid = append(unpaid, orderID)
+			}
+		}
+
+		for i, result := range results {
+			if paid[result.ID] {
+				results[i].Result = model.UploadPaidWithPoints
+			}
+		}
+
+		valid = unpaid
+	}
+
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	stored, err := o.repo.CreateOrders(ctx, user.ID, valid)
+	if err != nil {
+		o.Log(ctx).Trace().Err(err).Msg("service: invalid create orders")
+		return nil, err
+	}
+
+	resultByID := make(map[model.OrderID]model.UploadResult, len(stored))
+	for _, result := range stored {
+		resultByID[result.ID] = result.Result
+	}
+
+	for i, result := range results {
+		if uploadResult, ok := resultByID[result.ID]; ok {
+			results[i].Result = uploadResult
+		}
+	}
+
+	o.Log(ctx).Trace().
+		Int("count", len(valid)).
+		Msg("success batch of orders stored in DB")
+
+	return results, nil
+}
+
+// paidWithPoints returns numbers used by withdrawals when they can't be uploaded for accrual
+func (o orderService) paidWithPoints(ctx context.Context, orderIDs []model.OrderID) (map[model.OrderID]bool, error) {
+	paid := make(map[model.OrderID]bool)
+	if o.cfg.WithdrawOrderPolicy != config.WithdrawOrderExclusive || len(orderIDs) == 0 {
+		return paid, nil
+	}
+
+	paidIDs, err := o.withdrawRepo.PaidOrderIDs(ctx, orderIDs)
+	if err != nil {
+		o.Log(ctx).Error().Err(err).Msg("paidWithPoints:")
+		return nil, err
+	}
+
+	for _, orderID := range paidIDs {
+		paid[orderID] = true
+	}
+
+	return paid, nil
+}
+
 func (o orderService) Log(ctx context.Context) *zerolog.Logger {
 	_, logger := logging.GetCtxLogger(ctx)
 	logger = logger.With().Str(logging.ServiceKey, "orderService").Logger()
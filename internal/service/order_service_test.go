@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/model"
+	serviceMocks "github.com/djokcik/gophermart/internal/service/mocks"
 	"github.com/djokcik/gophermart/internal/storage"
 	"github.com/djokcik/gophermart/internal/storage/mocks"
 	appContext "github.com/djokcik/gophermart/pkg/context"
@@ -15,7 +17,7 @@ import (
 func Test_orderService_UpdateForAccrual(t *testing.T) {
 	t.Run("should update for accrual", func(t *testing.T) {
 		m := mocks.OrderRepository{Mock: mock.Mock{}}
-		m.On("UpdateForAccrual", mock.Anything, model.Order{ID: "1"}, provider.AccrualResponse{Order: "1"}).
+		m.On("UpdateForAccrual", mock.Anything, model.Order{ID: "1"}, provider.AccrualResponse{Order: "1"}, mock.Anything).
 			Return(nil)
 
 		service := orderService{repo: &m}
@@ -25,6 +27,24 @@ func Test_orderService_UpdateForAccrual(t *testing.T) {
 		m.AssertNumberOfCalls(t, "UpdateForAccrual", 1)
 		require.Equal(t, err, nil)
 	})
+
+	t.Run("should notify webhooks when status changed", func(t *testing.T) {
+		order := model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing}
+		accrual := provider.AccrualResponse{Order: "1", Status: model.StatusProcessed, Accrual: 1000}
+
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("UpdateForAccrual", mock.Anything, order, accrual, mock.Anything).Return(nil)
+
+		webhookMock := serviceMocks.WebhookService{Mock: mock.Mock{}}
+		webhookMock.On("NotifyOrderStatus", mock.Anything, order, accrual).Return(nil)
+
+		service := orderService{repo: &m, webhook: &webhookMock}
+
+		err := service.UpdateForAccrual(context.Background(), order, accrual)
+
+		webhookMock.AssertNumberOfCalls(t, "NotifyOrderStatus", 1)
+		require.Equal(t, err, nil)
+	})
 }
 
 func Test_orderService_OrdersByStatus(t *testing.T) {
@@ -118,3 +138,53 @@ func Test_orderService_ProcessOrder(t *testing.T) {
 		require.Equal(t, err, ErrOrderAlreadyUploadedAnotherUser)
 	})
 }
+
+func Test_orderService_ProcessOrders(t *testing.T) {
+	t.Run("should store valid orders and report result for each number", func(t *testing.T) {
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("CreateOrders", mock.Anything, 666, []model.OrderID{"9278923470", "12345678903"}).
+			Return([]model.OrderUploadResult{
+				{ID: "12345678903", Result: model.UploadUploadedByAnotherUser},
+				{ID: "9278923470", Result: model.UploadAccepted},
+			}, nil)
+
+		service := orderService{repo: &m}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		results, err := service.ProcessOrders(ctx, []model.OrderID{"9278923470", "1", "12345678903", "9278923470"})
+
+		m.AssertNumberOfCalls(t, "CreateOrders", 1)
+		require.Equal(t, err, nil)
+		require.Equal(t, results, []model.OrderUploadResult{
+			{ID: "9278923470", Result: model.UploadAccepted},
+			{ID: "1", Result: model.UploadInvalid},
+			{ID: "12345678903", Result: model.UploadUploadedByAnotherUser},
+		})
+	})
+	t.Run("should not upload numbers paid with points when numbers are exclusive", func(t *testing.T) {
+		m := mocks.OrderRepository{Mock: mock.Mock{}}
+		m.On("CreateOrders", mock.Anything, 666, []model.OrderID{"12345678903"}).
+			Return([]model.OrderUploadResult{{ID: "12345678903", Result: model.UploadAccepted}}, nil)
+
+		withdrawMock := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		withdrawMock.On("PaidOrderIDs", mock.Anything, []model.OrderID{"9278923470", "12345678903"}).
+			Return([]model.OrderID{"9278923470"}, nil)
+
+		service := orderService{
+			repo:         &m,
+			withdrawRepo: &withdrawMock,
+			cfg:          config.Config{WithdrawOrderPolicy: config.WithdrawOrderExclusive},
+		}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		results, err := service.ProcessOrders(ctx, []model.OrderID{"9278923470", "12345678903"})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, results, []model.OrderUploadResult{
+			{ID: "9278923470", Result: model.UploadPaidWithPoints},
+			{ID: "12345678903", Result: model.UploadAccepted},
+		})
+	})
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+)
+
+// pointsExpireBatch is the number of lots expired in one transaction
+const pointsExpireBatch = 500
+
+//go:generate mockery --name=PointsService
+
+type PointsService interface {
+	// Expirer debits expired rest of point lots and notifies the owners about the new balance
+	Expirer(ctx context.Context) func()
+}
+
+func NewPointsService(cfg config.Config, registry reporegistry.RepoRegistry, events EventService) PointsService {
+	return &pointsService{
+		cfg:    cfg,
+		repo:   registry.GetPointLotRepo(),
+		events: events,
+	}
+}
+
+type pointsService struct {
+	cfg    config.Config
+	repo   storage.PointLotRepository
+	events EventService
+}
+
+func (s pointsService) Expirer(ctx context.Context) func() {
+	return func() {
+		for {
+			expirations, err := s.repo.ExpirePointLots(ctx, pointsExpireBatch)
+			if err != nil {
+				s.Log(ctx).Error().Err(err).Msg("Expirer: failed expire point lots")
+				return
+			}
+
+			users := make(map[int]bool)
+			for _, expiration := range expirations {
+				s.Log(ctx).Info().
+					Int("userID", expiration.UserID).
+					Int("lotID", expiration.LotID).
+					Int("amount", int(expiration.Amount)).
+					Msg("points expired")
+
+				users[expiration.UserID] = true
+			}
+
+			for userID := range users {
+				if err := s.events.PublishBalance(ctx, userID); err != nil {
+					s.Log(ctx).Error().Err(err).Msg("publish balance")
+				}
+			}
+
+			if len(expirations) < pointsExpireBatch {
+				return
+			}
+		}
+	}
+}
+
+func (s pointsService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "pointsService").Logger()
+
+	return &logger
+}
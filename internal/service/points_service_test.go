@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	serviceMocks "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func Test_pointsService_Expirer(t *testing.T) {
+	t.Run("should publish balance once per user", func(t *testing.T) {
+		m := mocks.PointLotRepository{Mock: mock.Mock{}}
+		m.On("ExpirePointLots", mock.Anything, pointsExpireBatch).
+			Return([]model.PointExpiration{
+				{ID: 1, LotID: 10, UserID: 666, Amount: 100},
+				{ID: 2, LotID: 11, UserID: 666, Amount: 200},
+				{ID: 3, LotID: 12, UserID: 777, Amount: 300},
+			}, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, mock.Anything).Return(nil)
+
+		service := pointsService{repo: &m, events: &eventsMock}
+
+		service.Expirer(context.Background())()
+
+		m.AssertNumberOfCalls(t, "ExpirePointLots", 1)
+		eventsMock.AssertNumberOfCalls(t, "PublishBalance", 2)
+	})
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+const reconciliationPageSize = 500
+
+//go:generate mockery --name=ReconciliationService
+
+// ReconciliationService finds balances which drifted from point lots and the history of operations
+type ReconciliationService interface {
+	Reconcile(ctx context.Context, userID int) (model.Reconciliation, error)
+	// ReconcileAll checks balances of all users, mismatches are corrected when correct is set.
+	// A failed correction is reported in the mismatch and doesn't stop the run.
+	ReconcileAll(ctx context.Context, correct bool) (model.ReconciliationReport, error)
+	// Reconciler checks balances on schedule, corrections are written when enabled by the config
+	Reconciler(ctx context.Context) func()
+}
+
+func NewReconciliationService(cfg config.Config, registry reporegistry.RepoRegistry, events EventService) ReconciliationService {
+	return &reconciliationService{
+		cfg:      cfg,
+		repo:     registry.GetReconciliationRepo(),
+		events:   events,
+		auditLog: NewAuditService(cfg, registry),
+	}
+}
+
+type reconciliationService struct {
+	cfg      config.Config
+	repo     storage.ReconciliationRepository
+	events   EventService
+	auditLog AuditService
+}
+
+func (s reconciliationService) Reconcile(ctx context.Context, userID int) (model.Reconciliation, error) {
+	return s.repo.Reconciliation(ctx, userID)
+}
+
+func (s reconciliationService) ReconcileAll(ctx context.Context, correct bool) (model.ReconciliationReport, error) {
+	report := model.ReconciliationReport{Mismatches: make([]model.ReconciliationMismatch, 0)}
+
+	for afterID := 0; ; {
+		page, err := s.repo.Reconciliations(ctx, afterID, reconciliationPageSize)
+		if err != nil {
+			s.Log(ctx).Error().Err(err).Msg("ReconcileAll: failed select reconciliations")
+			return model.ReconciliationReport{}, err
+		}
+
+		for _, reconciliation := range page {
+			report.Checked++
+			if reconciliation.Consistent() {
+				continue
+			}
+
+			s.Log(ctx).Warn().
+				Int("userID", reconciliation.UserID).
+				Int("balance", int(reconciliation.Balance)).
+				Int("lots", int(reconciliation.Lots)).
+				Int("ledger", int(reconciliation.Ledger)).
+				Msg("balance mismatch")
+
+			mismatch := model.ReconciliationMismatch{Reconciliation: reconciliation}
+			if correct {
+				correction, err := s.correct(ctx, reconciliation.UserID)
+				if err != nil {
+					mismatch.Error = err.Error()
+				} else {
+					mismatch.Correction = &correction
+					report.Corrected++
+				}
+			}
+
+			report.Mismatches = append(report.Mismatches, mismatch)
+		}
+
+		if len(page) < reconciliationPageSize {
+			break
+		}
+		afterID = page[len(page)-1].UserID
+	}
+
+	s.Log(ctx).Info().
+		Int("checked", report.Checked).
+		Int("mismatches", len(report.Mismatches)).
+		Int("corrected", report.Corrected).
+		Msg("balances reconciled")
+
+	return report, nil
+}
+
+func (s reconciliationService) correct(ctx context.Context, userID int) (model.ReconciliationCorrection, error) {
+	var actorID int
+	if user := appContext.User(ctx); user != nil {
+		actorID = user.ID
+	}
+
+	correction, err := s.repo.CorrectBalance(ctx, userID, actorID, s.cfg.PointsExpireAt(time.Now()))
+	if err != nil {
+		s.Log(ctx).Warn().Err(err).Int("userID", userID).Msg("correct: failed correct balance")
+		return model.ReconciliationCorrection{}, err
+	}
+
+	// the balance was corrected by a concurrent run
+	if correction == (model.ReconciliationCorrection{}) {
+		return correction, nil
+	}
+
+	s.auditLog.Record(ctx, model.AuditEntry{
+		Action:     "balance_reconciled",
+		TargetType: model.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+		Details: map[string]interface{}{
+			"adjustmentID": correction.AdjustmentID,
+			"balanceDelta": correction.BalanceDelta,
+			"lotsDelta":    correction.LotsDelta,
+		},
+	})
+
+	if err = s.events.PublishBalance(ctx, userID); err != nil {
+		s.Log(ctx).Error().Err(err).Msg("publish balance")
+	}
+
+	return correction, nil
+}
+
+func (s reconciliationService) Reconciler(ctx context.Context) func() {
+	return func() {
+		if _, err := s.ReconcileAll(ctx, s.cfg.ReconciliationCorrect); err != nil {
+			s.Log(ctx).Error().Err(err).Msg("Reconciler: failed reconcile balances")
+		}
+	}
+}
+
+func (s reconciliationService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "reconciliationService").Logger()
+
+	return &logger
+}
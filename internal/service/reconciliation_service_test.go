@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	serviceMocks "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_reconciliationService_ReconcileAll(t *testing.T) {
+	consistent := model.Reconciliation{UserID: 1, Balance: 100, Lots: 100, Ledger: 100}
+	drifted := model.Reconciliation{UserID: 2, Balance: 300, Lots: 200, Ledger: 200}
+
+	t.Run("should report mismatches without corrections", func(t *testing.T) {
+		m := mocks.ReconciliationRepository{Mock: mock.Mock{}}
+		m.On("Reconciliations", mock.Anything, 0, reconciliationPageSize).
+			Return([]model.Reconciliation{consistent, drifted}, nil)
+
+		service := reconciliationService{repo: &m, auditLog: newAuditMock()}
+
+		report, err := service.ReconcileAll(context.Background(), false)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, report, model.ReconciliationReport{
+			Checked:    2,
+			Mismatches: []model.ReconciliationMismatch{{Reconciliation: drifted}},
+		})
+		m.AssertNumberOfCalls(t, "CorrectBalance", 0)
+	})
+
+	t.Run("should correct mismatches by the actor and publish balance", func(t *testing.T) {
+		m := mocks.ReconciliationRepository{Mock: mock.Mock{}}
+		m.On("Reconciliations", mock.Anything, 0, reconciliationPageSize).
+			Return([]model.Reconciliation{consistent, drifted}, nil)
+		m.On("CorrectBalance", mock.Anything, 2, 666, mock.Anything).
+			Return(model.ReconciliationCorrection{AdjustmentID: 7, BalanceDelta: -100}, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 2).Return(nil)
+
+		auditMock := newAuditMock()
+
+		service := reconciliationService{
+			cfg:      config.Config{PointsLifetimeMonths: 12},
+			repo:     &m,
+			events:   &eventsMock,
+			auditLog: auditMock,
+		}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		report, err := service.ReconcileAll(ctx, true)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, report.Corrected, 1)
+		require.Equal(t, *report.Mismatches[0].Correction, model.ReconciliationCorrection{AdjustmentID: 7, BalanceDelta: -100})
+		eventsMock.AssertCalled(t, "PublishBalance", mock.Anything, 2)
+		auditMock.AssertNumberOfCalls(t, "Record", 1)
+	})
+
+	t.Run("should keep going when correction fails", func(t *testing.T) {
+		other := model.Reconciliation{UserID: 3, Balance: 0, Lots: 50, Ledger: 50}
+
+		m := mocks.ReconciliationRepository{Mock: mock.Mock{}}
+		m.On("Reconciliations", mock.Anything, 0, reconciliationPageSize).
+			Return([]model.Reconciliation{drifted, other}, nil)
+		m.On("CorrectBalance", mock.Anything, 2, 0, mock.Anything).
+			Return(model.ReconciliationCorrection{}, storage.ErrInsufficientFunds)
+		m.On("CorrectBalance", mock.Anything, 3, 0, mock.Anything).
+			Return(model.ReconciliationCorrection{AdjustmentID: 8, BalanceDelta: 50}, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 3).Return(nil)
+
+		service := reconciliationService{repo: &m, events: &eventsMock, auditLog: newAuditMock()}
+
+		report, err := service.ReconcileAll(context.Background(), true)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, report.Corrected, 1)
+		require.Equal(t, report.Mismatches[0].Error, storage.ErrInsufficientFunds.Error())
+		require.Equal(t, report.Mismatches[1].Correction.AdjustmentID, 8)
+	})
+
+	t.Run("should continue with the next page", func(t *testing.T) {
+		page := make([]model.Reconciliation, reconciliationPageSize)
+		for i := range page {
+			page[i] = model.Reconciliation{UserID: i + 1}
+		}
+
+		m := mocks.ReconciliationRepository{Mock: mock.Mock{}}
+		m.On("Reconciliations", mock.Anything, 0, reconciliationPageSize).Return(page, nil)
+		m.On("Reconciliations", mock.Anything, reconciliationPageSize, reconciliationPageSize).
+			Return([]model.Reconciliation{{UserID: reconciliationPageSize + 1}}, nil)
+
+		service := reconciliationService{repo: &m, auditLog: newAuditMock()}
+
+		report, err := service.ReconcileAll(context.Background(), false)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, report.Checked, reconciliationPageSize+1)
+		require.Equal(t, len(report.Mismatches), 0)
+	})
+}
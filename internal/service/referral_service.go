@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"math"
+	"time"
+)
+
+//go:generate mockery --name=ReferralService
+
+type ReferralService interface {
+	Referrals(ctx context.Context, userID int) (model.ReferralsDto, error)
+	// RewardReferral credits referrer and referee once the first order of the referee is processed
+	RewardReferral(ctx context.Context, order model.Order) (model.ReferralReward, error)
+}
+
+func NewReferralService(cfg config.Config, registry reporegistry.RepoRegistry) ReferralService {
+	return &referralService{
+		cfg:  cfg,
+		repo: registry.GetReferralRepo(),
+	}
+}
+
+type referralService struct {
+	cfg  config.Config
+	repo storage.ReferralRepository
+}
+
+func (s referralService) Referrals(ctx context.Context, userID int) (model.ReferralsDto, error) {
+	code, err := s.repo.ReferralCode(ctx, userID)
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("Referrals: referral code")
+		return model.ReferralsDto{}, err
+	}
+
+	referrals, err := s.repo.ReferralsByReferrer(ctx, userID)
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("Referrals:")
+		return model.ReferralsDto{}, err
+	}
+
+	return model.ReferralsDto{Code: code, Referrals: referrals}, nil
+}
+
+func (s referralService) RewardReferral(ctx context.Context, order model.Order) (model.ReferralReward, error) {
+	bonus := model.Amount(math.Round(s.cfg.ReferralBonus * 100))
+	if bonus <= 0 {
+		return model.ReferralReward{}, nil
+	}
+
+	reward, err := s.repo.RewardReferral(ctx, model.ReferralReward{
+		RefereeID:     order.UserID,
+		OrderID:       order.ID,
+		ReferrerBonus: bonus,
+		RefereeBonus:  bonus,
+	}, s.cfg.ReferralMaxRewards, s.cfg.PointsExpireAt(time.Now()))
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("RewardReferral:")
+		return model.ReferralReward{}, err
+	}
+
+	if reward.ID != 0 {
+		s.Log(ctx).Info().
+			Int("referrerID", reward.ReferrerID).
+			Int("refereeID", reward.RefereeID).
+			Int("referrerBonus", int(reward.ReferrerBonus)).
+			Int("refereeBonus", int(reward.RefereeBonus)).
+			Msg("referral rewarded")
+	}
+
+	return reward, nil
+}
+
+func (s referralService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "referralService").Logger()
+
+	return &logger
+}
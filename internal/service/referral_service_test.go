@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_referralService_RewardReferral(t *testing.T) {
+	t.Run("should reward both sides with configured bonus", func(t *testing.T) {
+		order := model.Order{ID: "123", UserID: 666}
+		reward := model.ReferralReward{RefereeID: 666, OrderID: "123", ReferrerBonus: 10050, RefereeBonus: 10050}
+
+		m := mocks.ReferralRepository{Mock: mock.Mock{}}
+		m.On("RewardReferral", mock.Anything, reward, 20, mock.Anything).
+			Return(model.ReferralReward{ID: 1, ReferrerID: 42, RefereeID: 666, ReferrerBonus: 10050, RefereeBonus: 10050}, nil)
+
+		service := referralService{cfg: config.Config{ReferralBonus: 100.5, ReferralMaxRewards: 20, PointsLifetimeMonths: 12}, repo: &m}
+
+		result, err := service.RewardReferral(context.Background(), order)
+
+		m.AssertNumberOfCalls(t, "RewardReferral", 1)
+		require.Equal(t, err, nil)
+		require.Equal(t, result.ReferrerID, 42)
+	})
+	t.Run("should skip reward when bonus is disabled", func(t *testing.T) {
+		m := mocks.ReferralRepository{Mock: mock.Mock{}}
+
+		service := referralService{cfg: config.Config{}, repo: &m}
+
+		result, err := service.RewardReferral(context.Background(), model.Order{ID: "123", UserID: 666})
+
+		m.AssertNotCalled(t, "RewardReferral", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		require.Equal(t, err, nil)
+		require.Equal(t, result, model.ReferralReward{})
+	})
+}
+
+func Test_referralService_Referrals(t *testing.T) {
+	t.Run("should return code with referrals", func(t *testing.T) {
+		m := mocks.ReferralRepository{Mock: mock.Mock{}}
+		m.On("ReferralCode", mock.Anything, 42).Return("AB12CD34", nil)
+		m.On("ReferralsByReferrer", mock.Anything, 42).Return([]model.Referral{{Login: "friend"}}, nil)
+
+		service := referralService{repo: &m}
+
+		result, err := service.Referrals(context.Background(), 42)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, result, model.ReferralsDto{Code: "AB12CD34", Referrals: []model.Referral{{Login: "friend"}}})
+	})
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+//go:generate mockery --name=TierService
+
+type TierService interface {
+	// ApplyTier credits bonus of the tier multiplier on top of the order accrual. The credited sum is returned.
+	ApplyTier(ctx context.Context, order model.Order) (model.Amount, error)
+	// Recalculator assigns tiers to all users by accrual volume of the rolling window
+	Recalculator(ctx context.Context) func()
+}
+
+func NewTierService(cfg config.Config, registry reporegistry.RepoRegistry) TierService {
+	return &tierService{
+		cfg:    cfg,
+		repo:   registry.GetTierRepo(),
+		orders: registry.GetOrderRepo(),
+	}
+}
+
+type tierService struct {
+	cfg    config.Config
+	repo   storage.TierRepository
+	orders storage.OrderRepository
+}
+
+func (s tierService) ApplyTier(ctx context.Context, order model.Order) (model.Amount, error) {
+	tier, err := s.repo.UserTier(ctx, order.UserID)
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("ApplyTier: user tier")
+		return 0, err
+	}
+
+	level := model.LevelOf(tier)
+	if level.Multiplier <= 1 {
+		return 0, nil
+	}
+
+	// the sum is calculated from the accrual of the locked order, the override may change it meanwhile
+	bonus, err := s.repo.AddTierBonus(ctx, model.TierBonus{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Tier:    level.Tier,
+	}, s.cfg.PointsExpireAt(time.Now()))
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("ApplyTier: add bonus")
+		return 0, err
+	}
+
+	if bonus.Sum > 0 {
+		s.Log(ctx).Info().
+			Int("userID", order.UserID).
+			Str("order", string(order.ID)).
+			Str("tier", string(level.Tier)).
+			Int("sum", int(bonus.Sum)).
+			Msg("tier bonus credited")
+	}
+
+	return bonus.Sum, nil
+}
+
+func (s tierService) Recalculator(ctx context.Context) func() {
+	return func() {
+		volumes, err := s.orders.AccrualVolumes(ctx, time.Now().Add(-s.cfg.TierWindow))
+		if err != nil {
+			s.Log(ctx).Error().Err(err).Msg("Recalculator: failed select volumes")
+			return
+		}
+
+		changed := 0
+		for _, volume := range volumes {
+			level := model.TierByVolume(volume.Volume)
+
+			updated, err := s.repo.UpdateTier(ctx, volume.UserID, level.Tier)
+			if err != nil {
+				s.Log(ctx).Error().Err(err).Int("userID", volume.UserID).Msg("Recalculator: failed update tier")
+				continue
+			}
+
+			if updated {
+				changed++
+				s.Log(ctx).Info().
+					Int("userID", volume.UserID).
+					Int("volume", int(volume.Volume)).
+					Str("tier", string(level.Tier)).
+					Msg("tier changed")
+			}
+		}
+
+		s.Log(ctx).Info().Int("users", len(volumes)).Int("changed", changed).Msg("tiers recalculated")
+	}
+}
+
+func (s tierService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "tierService").Logger()
+
+	return &logger
+}
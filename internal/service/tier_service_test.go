@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_tierService_ApplyTier(t *testing.T) {
+	t.Run("should credit bonus by tier multiplier", func(t *testing.T) {
+		order := model.Order{ID: "123", UserID: 666}
+
+		m := mocks.TierRepository{Mock: mock.Mock{}}
+		m.On("UserTier", mock.Anything, 666).Return(model.TierGold, nil)
+		m.On("AddTierBonus", mock.Anything, model.TierBonus{OrderID: "123", UserID: 666, Tier: model.TierGold}, mock.Anything).
+			Return(model.TierBonus{ID: 1, OrderID: "123", UserID: 666, Tier: model.TierGold, Sum: 250}, nil)
+
+		service := tierService{cfg: config.Config{PointsLifetimeMonths: 12}, repo: &m}
+
+		sum, err := service.ApplyTier(context.Background(), order)
+
+		m.AssertNumberOfCalls(t, "AddTierBonus", 1)
+		require.Equal(t, err, nil)
+		require.Equal(t, sum, model.Amount(250))
+	})
+	t.Run("should not credit bonus on basic tier", func(t *testing.T) {
+		m := mocks.TierRepository{Mock: mock.Mock{}}
+		m.On("UserTier", mock.Anything, 666).Return(model.TierBasic, nil)
+
+		service := tierService{repo: &m}
+
+		sum, err := service.ApplyTier(context.Background(), model.Order{ID: "123", UserID: 666})
+
+		m.AssertNotCalled(t, "AddTierBonus", mock.Anything, mock.Anything, mock.Anything)
+		require.Equal(t, err, nil)
+		require.Equal(t, sum, model.Amount(0))
+	})
+}
+
+func Test_tierService_Recalculator(t *testing.T) {
+	t.Run("should assign tiers by volume", func(t *testing.T) {
+		orderMock := mocks.OrderRepository{Mock: mock.Mock{}}
+		orderMock.On("AccrualVolumes", mock.Anything, mock.Anything).Return([]model.AccrualVolume{
+			{UserID: 1, Volume: 0},
+			{UserID: 2, Volume: 100000},
+			{UserID: 3, Volume: 2500000},
+		}, nil)
+
+		m := mocks.TierRepository{Mock: mock.Mock{}}
+		m.On("UpdateTier", mock.Anything, 1, model.TierBasic).Return(false, nil)
+		m.On("UpdateTier", mock.Anything, 2, model.TierSilver).Return(true, nil)
+		m.On("UpdateTier", mock.Anything, 3, model.TierPlatinum).Return(true, nil)
+
+		service := tierService{cfg: config.Config{TierWindow: 90 * 24 * time.Hour}, repo: &m, orders: &orderMock}
+
+		service.Recalculator(context.Background())()
+
+		orderMock.AssertCalled(t, "AccrualVolumes", mock.Anything, mock.MatchedBy(func(since time.Time) bool {
+			return time.Since(since) >= 90*24*time.Hour
+		}))
+		m.AssertNumberOfCalls(t, "UpdateTier", 3)
+	})
+}
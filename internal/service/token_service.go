@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	helpers "github.com/djokcik/gophermart/pkg/helper"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+const (
+	challengePurpose = "2fa_challenge"
+	challengeTTL     = 5 * time.Minute
+)
+
+//go:generate mockery --name=TokenService
+
+// TokenService issues short-lived access tokens together with rotating refresh tokens.
+// All tokens issued from one login share a session, which is revoked as a whole.
+type TokenService interface {
+	// IssueTokens opens a new session, mfa marks that the user has passed the second factor
+	IssueTokens(ctx context.Context, user model.User, mfa bool) (model.AuthTokens, error)
+	Refresh(ctx context.Context, refreshToken string) (model.AuthTokens, error)
+	Logout(ctx context.Context, claims model.Claims) error
+	RevokeAll(ctx context.Context, userID int) error
+	ParseAccessToken(ctx context.Context, accessToken string) (model.Claims, error)
+	// IssueChallenge returns short-lived token which proves that the password has been checked
+	IssueChallenge(ctx context.Context, user model.User) (string, error)
+	ParseChallenge(ctx context.Context, challengeToken string) (model.Claims, error)
+	Cleaner(ctx context.Context) func()
+}
+
+func NewTokenService(cfg config.Config, registry reporegistry.RepoRegistry) TokenService {
+	return &tokenService{
+		cfg:      cfg,
+		repo:     registry.GetTokenRepo(),
+		auth:     NewUserUtilsService(),
+		auditLog: NewAuditService(cfg, registry),
+	}
+}
+
+type tokenService struct {
+	cfg      config.Config
+	repo     storage.TokenRepository
+	auth     UserUtilsService
+	auditLog AuditService
+}
+
+func (t tokenService) IssueTokens(ctx context.Context, user model.User, mfa bool) (model.AuthTokens, error) {
+	sessionID := uuid.NewString()
+
+	refreshToken, token, err := t.newRefreshToken(user.ID, sessionID, mfa)
+	if err != nil {
+		t.Log(ctx).Error().Err(err).Msg("IssueTokens: generate refresh token")
+		return model.AuthTokens{}, err
+	}
+
+	err = t.repo.CreateRefreshToken(ctx, token)
+	if err != nil {
+		t.Log(ctx).Error().Err(err).Msg("IssueTokens:")
+		return model.AuthTokens{}, err
+	}
+
+	accessToken, err := t.newAccessToken(user.ID, sessionID, mfa)
+	if err != nil {
+		t.Log(ctx).Error().Err(err).Msg("IssueTokens: create access token")
+		return model.AuthTokens{}, err
+	}
+
+	t.audit(ctx, "token_issued", user.ID, sessionID, map[string]interface{}{"mfa": mfa})
+
+	return model.AuthTokens{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// Refresh exchanges refresh token for a new pair of tokens. Presenting an already used refresh token
+// means it has leaked, so the whole session is revoked.
+func (t tokenService) Refresh(ctx context.Context, refreshToken string) (model.AuthTokens, error) {
+	current, err := t.repo.RefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			t.Log(ctx).Trace().Err(err).Msg("Refresh: unknown token")
+			return model.AuthTokens{}, ErrInvalidRefreshToken
+		}
+
+		return model.AuthTokens{}, err
+	}
+
+	if current.Used {
+		return model.AuthTokens{}, t.revokeReused(ctx, current)
+	}
+
+	if current.Revoked || time.Now().After(current.ExpiresAt) {
+		t.Log(ctx).Trace().Msg("Refresh: token is revoked or expired")
+		return model.AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	nextRefreshToken, next, err := t.newRefreshToken(current.UserID, current.SessionID, current.MFA)
+	if err != nil {
+		t.Log(ctx).Error().Err(err).Msg("Refresh: generate refresh token")
+		return model.AuthTokens{}, err
+	}
+
+	err = t.repo.RotateRefreshToken(ctx, current.ID, next)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenAlreadyUsed) {
+			return model.AuthTokens{}, t.revokeReused(ctx, current)
+		}
+
+		t.Log(ctx).Error().Err(err).Msg("Refresh:")
+		return model.AuthTokens{}, err
+	}
+
+	accessToken, err := t.newAccessToken(current.UserID, current.SessionID, current.MFA)
+	if err != nil {
+		t.Log(ctx).Error().Err(err).Msg("Refresh: create access token")
+		return model.AuthTokens{}, err
+	}
+
+	t.audit(ctx, "token_refreshed", current.UserID, current.SessionID, nil)
+
+	return model.AuthTokens{AccessToken: accessToken, RefreshToken: nextRefreshToken}, nil
+}
+
+func (t tokenService) revokeReused(ctx context.Context, token model.RefreshToken) error {
+	t.audit(ctx, "refresh_token_reused", token.UserID, token.SessionID, nil)
+
+	if err := t.repo.RevokeSession(ctx, token.SessionID); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+func (t tokenService) Logout(ctx context.Context, claims model.Claims) error {
+	err := t.repo.RevokeSession(ctx, claims.SessionID)
+	if err != nil {
+		t.Log(ctx).Error().Err(err).Msg("Logout: revoke session")
+		return err
+	}
+
+	err = t.repo.RevokeAccessToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0))
+	if err != nil {
+		t.Log(ctx).Error().Err(err).Msg("Logout: revoke access token")
+		return err
+	}
+
+	t.audit(ctx, "logout", claims.ID, claims.SessionID, nil)
+
+	return nil
+}
+
+// RevokeAll ends every session of the user, access tokens are rejected as their sessions are revoked
+func (t tokenService) RevokeAll(ctx context.Context, userID int) error {
+	err := t.repo.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		t.Log(ctx).Error().Err(err).Msg("RevokeAll:")
+		return err
+	}
+
+	t.auditLog.Record(ctx, model.AuditEntry{
+		Action:     "sessions_revoked",
+		TargetType: model.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+	})
+
+	return nil
+}
+
+func (t tokenService) ParseAccessToken(ctx context.Context, accessToken string) (model.Claims, error) {
+	claims, err := t.auth.ParseToken(accessToken, t.cfg.Keys)
+	if err != nil {
+		return model.Claims{}, err
+	}
+
+	if claims.Purpose != "" {
+		return model.Claims{}, model.ErrInvalidAccessToken
+	}
+
+	revoked, err := t.repo.IsRevoked(ctx, claims.Id, claims.SessionID)
+	if err != nil {
+		return model.Claims{}, err
+	}
+
+	if revoked {
+		return model.Claims{}, model.ErrInvalidAccessToken
+	}
+
+	return claims, nil
+}
+
+func (t tokenService) IssueChallenge(_ context.Context, user model.User) (string, error) {
+	return t.auth.CreateToken(t.cfg.Keys, model.Claims{
+		ID:      user.ID,
+		Purpose: challengePurpose,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			Subject:   user.Username,
+			ExpiresAt: time.Now().Add(challengeTTL).Unix(),
+		},
+	})
+}
+
+func (t tokenService) ParseChallenge(_ context.Context, challengeToken string) (model.Claims, error) {
+	claims, err := t.auth.ParseToken(challengeToken, t.cfg.Keys)
+	if err != nil || claims.Purpose != challengePurpose {
+		return model.Claims{}, ErrInvalidChallenge
+	}
+
+	return claims, nil
+}
+
+// Cleaner removes expired refresh tokens and entries of revocation list
+func (t tokenService) Cleaner(ctx context.Context) func() {
+	return func() {
+		if err := t.repo.DeleteExpired(ctx); err != nil {
+			t.Log(ctx).Error().Err(err).Msg("Cleaner: failed delete expired tokens")
+		}
+	}
+}
+
+func (t tokenService) newAccessToken(userID int, sessionID string, mfa bool) (string, error) {
+	return t.auth.CreateToken(t.cfg.Keys, model.Claims{
+		ID:        userID,
+		SessionID: sessionID,
+		MFA:       mfa,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewString(),
+			ExpiresAt: time.Now().Add(t.cfg.AccessTokenTTL).Unix(),
+		},
+	})
+}
+
+func (t tokenService) newRefreshToken(userID int, sessionID string, mfa bool) (string, model.RefreshToken, error) {
+	refreshToken, err := helpers.RandomHex(32)
+	if err != nil {
+		return "", model.RefreshToken{}, err
+	}
+
+	return refreshToken, model.RefreshToken{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(t.cfg.RefreshTokenTTL),
+		MFA:       mfa,
+	}, nil
+}
+
+// hashToken returns SHA-256 of the token, refresh tokens are stored only hashed
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// audit records action on the session, userID is the owner of the session
+func (t tokenService) audit(ctx context.Context, action string, userID int, sessionID string, details map[string]interface{}) {
+	t.auditLog.Record(ctx, model.AuditEntry{
+		ActorID:    userID,
+		Action:     action,
+		TargetType: model.AuditTargetSession,
+		TargetID:   sessionID,
+		Details:    details,
+	})
+}
+
+func (t tokenService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "tokenService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/djokcik/gophermart/pkg/keyring"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_tokenService_IssueTokens(t *testing.T) {
+	t.Run("should issue access token and store hashed refresh token", func(t *testing.T) {
+		m := mocks.TokenRepository{Mock: mock.Mock{}}
+		m.On("CreateRefreshToken", mock.Anything, mock.MatchedBy(func(token model.RefreshToken) bool {
+			return token.UserID == 666 && token.SessionID != "" && token.TokenHash != ""
+		})).Return(nil)
+
+		service := tokenService{
+			repo:     &m,
+			auth:     NewUserUtilsService(),
+			cfg:      config.Config{Keys: testKeyring(), AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour},
+			auditLog: newAuditMock(),
+		}
+
+		tokens, err := service.IssueTokens(context.Background(), model.User{ID: 666}, false)
+		require.Equal(t, err, nil)
+
+		m.AssertNumberOfCalls(t, "CreateRefreshToken", 1)
+		stored := m.Calls[0].Arguments.Get(1).(model.RefreshToken)
+		require.Equal(t, stored.TokenHash, hashToken(tokens.RefreshToken))
+
+		claims, err := service.auth.ParseToken(tokens.AccessToken, service.cfg.Keys)
+		require.Equal(t, err, nil)
+		require.Equal(t, claims.ID, 666)
+		require.Equal(t, claims.SessionID, stored.SessionID)
+	})
+}
+
+func Test_tokenService_Refresh(t *testing.T) {
+	t.Run("should rotate refresh token", func(t *testing.T) {
+		current := model.RefreshToken{ID: 1, UserID: 666, SessionID: "session", ExpiresAt: time.Now().Add(time.Hour)}
+
+		m := mocks.TokenRepository{Mock: mock.Mock{}}
+		m.On("RefreshTokenByHash", mock.Anything, hashToken("refreshToken")).Return(current, nil)
+		m.On("RotateRefreshToken", mock.Anything, 1, mock.MatchedBy(func(next model.RefreshToken) bool {
+			return next.UserID == 666 && next.SessionID == "session"
+		})).Return(nil)
+
+		service := tokenService{
+			repo:     &m,
+			auth:     NewUserUtilsService(),
+			cfg:      config.Config{Keys: testKeyring(), AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour},
+			auditLog: newAuditMock(),
+		}
+
+		tokens, err := service.Refresh(context.Background(), "refreshToken")
+
+		m.AssertNumberOfCalls(t, "RotateRefreshToken", 1)
+		require.Equal(t, err, nil)
+		require.NotEqual(t, tokens.RefreshToken, "refreshToken")
+	})
+
+	t.Run("should revoke session when used refresh token presented", func(t *testing.T) {
+		current := model.RefreshToken{ID: 1, UserID: 666, SessionID: "session", Used: true, ExpiresAt: time.Now().Add(time.Hour)}
+
+		m := mocks.TokenRepository{Mock: mock.Mock{}}
+		m.On("RefreshTokenByHash", mock.Anything, hashToken("refreshToken")).Return(current, nil)
+		m.On("RevokeSession", mock.Anything, "session").Return(nil)
+
+		service := tokenService{repo: &m, auditLog: newAuditMock()}
+
+		_, err := service.Refresh(context.Background(), "refreshToken")
+
+		m.AssertNumberOfCalls(t, "RevokeSession", 1)
+		require.Equal(t, err, ErrRefreshTokenReused)
+	})
+
+	t.Run("should return error when refresh token unknown", func(t *testing.T) {
+		m := mocks.TokenRepository{Mock: mock.Mock{}}
+		m.On("RefreshTokenByHash", mock.Anything, hashToken("refreshToken")).
+			Return(model.RefreshToken{}, storage.ErrNotFound)
+
+		service := tokenService{repo: &m, auditLog: newAuditMock()}
+
+		_, err := service.Refresh(context.Background(), "refreshToken")
+
+		require.Equal(t, err, ErrInvalidRefreshToken)
+	})
+}
+
+func Test_tokenService_Logout(t *testing.T) {
+	t.Run("should revoke session and access token", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Minute).Unix()
+
+		m := mocks.TokenRepository{Mock: mock.Mock{}}
+		m.On("RevokeSession", mock.Anything, "session").Return(nil)
+		m.On("RevokeAccessToken", mock.Anything, "jti", time.Unix(expiresAt, 0)).Return(nil)
+
+		service := tokenService{repo: &m, auditLog: newAuditMock()}
+
+		err := service.Logout(context.Background(), model.Claims{
+			SessionID:      "session",
+			StandardClaims: jwt.StandardClaims{Id: "jti", ExpiresAt: expiresAt},
+		})
+
+		m.AssertNumberOfCalls(t, "RevokeSession", 1)
+		m.AssertNumberOfCalls(t, "RevokeAccessToken", 1)
+		require.Equal(t, err, nil)
+	})
+}
+
+func Test_tokenService_ParseAccessToken(t *testing.T) {
+	t.Run("should reject revoked access token", func(t *testing.T) {
+		auth := NewUserUtilsService()
+		token, _ := auth.CreateToken(testKeyring(), model.Claims{
+			ID:             666,
+			SessionID:      "session",
+			StandardClaims: jwt.StandardClaims{Id: "jti", ExpiresAt: time.Now().Add(time.Minute).Unix()},
+		})
+
+		m := mocks.TokenRepository{Mock: mock.Mock{}}
+		m.On("IsRevoked", mock.Anything, "jti", "session").Return(true, nil)
+
+		service := tokenService{repo: &m, auth: auth, cfg: config.Config{Keys: testKeyring()}}
+
+		_, err := service.ParseAccessToken(context.Background(), token)
+
+		require.Equal(t, err, model.ErrInvalidAccessToken)
+	})
+
+	t.Run("should verify tokens of previous key after rotation", func(t *testing.T) {
+		public, private, _ := ed25519.GenerateKey(rand.Reader)
+		legacy := keyring.Key{ID: keyring.LegacyKeyID, Algorithm: "HS256", Secret: []byte("key")}
+
+		before, _ := keyring.New(keyring.LegacyKeyID, legacy)
+		after, _ := keyring.New("ed-1", legacy, keyring.Key{ID: "ed-1", Algorithm: "EdDSA", PrivateKey: private, PublicKey: public})
+
+		auth := NewUserUtilsService()
+		claims := model.Claims{
+			ID:             666,
+			SessionID:      "session",
+			StandardClaims: jwt.StandardClaims{Id: "jti", ExpiresAt: time.Now().Add(time.Minute).Unix()},
+		}
+		oldToken, _ := auth.CreateToken(before, claims)
+		newToken, _ := auth.CreateToken(after, claims)
+
+		m := mocks.TokenRepository{Mock: mock.Mock{}}
+		m.On("IsRevoked", mock.Anything, "jti", "session").Return(false, nil)
+
+		service := tokenService{repo: &m, auth: auth, cfg: config.Config{Keys: after}}
+
+		parsed, err := service.ParseAccessToken(context.Background(), oldToken)
+		require.Equal(t, err, nil)
+		require.Equal(t, parsed.ID, 666)
+
+		parsed, err = service.ParseAccessToken(context.Background(), newToken)
+		require.Equal(t, err, nil)
+		require.Equal(t, parsed.ID, 666)
+
+		_, err = NewUserUtilsService().ParseToken(newToken, before)
+		require.ErrorIs(t, err.(*jwt.ValidationError).Inner, keyring.ErrUnknownKey)
+	})
+
+	t.Run("should reject token which algorithm doesn't match the key", func(t *testing.T) {
+		public, _, _ := ed25519.GenerateKey(rand.Reader)
+		keys, _ := keyring.New(keyring.LegacyKeyID,
+			keyring.Key{ID: keyring.LegacyKeyID, Algorithm: "HS256", Secret: []byte("key")},
+			keyring.Key{ID: "ed-1", Algorithm: "EdDSA", PublicKey: public},
+		)
+
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, model.Claims{ID: 666})
+		token.Header["kid"] = "ed-1"
+		forged, _ := token.SignedString([]byte(public))
+
+		_, err := NewUserUtilsService().ParseToken(forged, keys)
+		require.ErrorIs(t, err.(*jwt.ValidationError).Inner, keyring.ErrAlgorithmMismatch)
+	})
+}
+
+func Test_tokenService_Challenge(t *testing.T) {
+	t.Run("should not accept challenge as access token", func(t *testing.T) {
+		service := tokenService{auth: NewUserUtilsService(), cfg: config.Config{Keys: testKeyring()}}
+
+		challenge, err := service.IssueChallenge(context.Background(), model.User{ID: 666, Username: "user"})
+		require.Equal(t, err, nil)
+
+		claims, err := service.ParseChallenge(context.Background(), challenge)
+		require.Equal(t, err, nil)
+		require.Equal(t, claims.ID, 666)
+		require.Equal(t, claims.Subject, "user")
+
+		_, err = service.ParseAccessToken(context.Background(), challenge)
+		require.Equal(t, err, model.ErrInvalidAccessToken)
+	})
+}
+
+func testKeyring() *keyring.Keyring {
+	keys, _ := keyring.NewHMAC("key")
+
+	return keys
+}
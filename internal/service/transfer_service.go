@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"math"
+	"strconv"
+)
+
+//go:generate mockery --name=TransferService
+
+type TransferService interface {
+	// Transfer sends points of the user from the context to the recipient login
+	Transfer(ctx context.Context, recipient string, sum model.Amount) (model.Transfer, error)
+	TransfersByUserID(ctx context.Context, userID int) ([]model.TransferLog, error)
+}
+
+func NewTransferService(cfg config.Config, registry reporegistry.RepoRegistry, events EventService) TransferService {
+	return &transferService{
+		cfg:      cfg,
+		repo:     registry.GetTransferRepo(),
+		users:    registry.GetUserRepo(),
+		events:   events,
+		auditLog: NewAuditService(cfg, registry),
+	}
+}
+
+type transferService struct {
+	cfg      config.Config
+	repo     storage.TransferRepository
+	users    storage.UserRepository
+	events   EventService
+	auditLog AuditService
+}
+
+func (s transferService) Transfer(ctx context.Context, recipient string, sum model.Amount) (model.Transfer, error) {
+	user := appContext.User(ctx)
+	if user == nil {
+		s.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+		return model.Transfer{}, ErrNotAuthenticated
+	}
+
+	to, err := s.users.UserByUsername(ctx, recipient)
+	if err != nil {
+		s.Log(ctx).Trace().Err(err).Msg("Transfer: find recipient")
+		return model.Transfer{}, err
+	}
+
+	if to.ID == user.ID {
+		return model.Transfer{}, ErrTransferToSelf
+	}
+
+	dailyLimit := model.Amount(math.Round(s.cfg.TransferDailyLimit * 100))
+
+	transfer, err := s.repo.Transfer(ctx, model.Transfer{SenderID: user.ID, RecipientID: to.ID, Sum: sum}, dailyLimit)
+	if err != nil {
+		s.Log(ctx).Warn().Err(err).Msg("Transfer:")
+		return model.Transfer{}, err
+	}
+
+	s.auditLog.Record(ctx, model.AuditEntry{
+		Action:     "points_transferred",
+		TargetType: model.AuditTargetTransfer,
+		TargetID:   strconv.Itoa(transfer.ID),
+		Details:    map[string]interface{}{"recipientID": to.ID, "sum": sum},
+	})
+
+	for _, userID := range []int{user.ID, to.ID} {
+		if err := s.events.PublishBalance(ctx, userID); err != nil {
+			s.Log(ctx).Error().Err(err).Msg("publish balance")
+		}
+	}
+
+	return transfer, nil
+}
+
+func (s transferService) TransfersByUserID(ctx context.Context, userID int) ([]model.TransferLog, error) {
+	transfers, err := s.repo.TransfersByUserID(ctx, userID)
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("TransfersByUserID:")
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (s transferService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "transferService").Logger()
+
+	return &logger
+}
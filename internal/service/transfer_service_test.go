@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	serviceMocks "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_transferService_Transfer(t *testing.T) {
+	t.Run("should transfer with daily limit and publish balances", func(t *testing.T) {
+		usersMock := mocks.UserRepository{Mock: mock.Mock{}}
+		usersMock.On("UserByUsername", mock.Anything, "family").Return(model.User{ID: 777}, nil)
+
+		m := mocks.TransferRepository{Mock: mock.Mock{}}
+		m.On("Transfer", mock.Anything, model.Transfer{SenderID: 666, RecipientID: 777, Sum: 1050}, model.Amount(500000)).
+			Return(model.Transfer{ID: 3, SenderID: 666, RecipientID: 777, Sum: 1050}, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, mock.Anything).Return(nil)
+
+		service := transferService{
+			cfg:      config.Config{TransferDailyLimit: 5000},
+			repo:     &m,
+			users:    &usersMock,
+			events:   &eventsMock,
+			auditLog: newAuditMock(),
+		}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		transfer, err := service.Transfer(ctx, "family", 1050)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, transfer.ID, 3)
+		eventsMock.AssertCalled(t, "PublishBalance", mock.Anything, 666)
+		eventsMock.AssertCalled(t, "PublishBalance", mock.Anything, 777)
+	})
+
+	t.Run("should reject transfer to own account", func(t *testing.T) {
+		usersMock := mocks.UserRepository{Mock: mock.Mock{}}
+		usersMock.On("UserByUsername", mock.Anything, "me").Return(model.User{ID: 666}, nil)
+
+		m := mocks.TransferRepository{Mock: mock.Mock{}}
+
+		service := transferService{repo: &m, users: &usersMock, auditLog: newAuditMock()}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		_, err := service.Transfer(ctx, "me", 1050)
+
+		require.Equal(t, err, ErrTransferToSelf)
+		m.AssertNumberOfCalls(t, "Transfer", 0)
+	})
+}
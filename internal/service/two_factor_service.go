@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	helpers "github.com/djokcik/gophermart/pkg/helper"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/djokcik/gophermart/pkg/totp"
+	"github.com/rs/zerolog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	totpIssuer         = "Gophermart"
+	recoveryCodesCount = 10
+)
+
+//go:generate mockery --name=TwoFactorService
+
+// TwoFactorService manages optional TOTP second factor. Once enabled, login requires a code
+// from the authenticator app or one of the single-use recovery codes.
+type TwoFactorService interface {
+	Enroll(ctx context.Context, user model.User) (model.TOTPEnrollment, error)
+	// Confirm enables the second factor after the first valid code and returns recovery codes
+	Confirm(ctx context.Context, userID int, code string) ([]string, error)
+	Disable(ctx context.Context, userID int, code string) error
+	Validate(ctx context.Context, userID int, code string) error
+	Enabled(ctx context.Context, userID int) (bool, error)
+}
+
+func NewTwoFactorService(cfg config.Config, registry reporegistry.RepoRegistry) TwoFactorService {
+	return &twoFactorService{
+		cfg:      cfg,
+		repo:     registry.GetTwoFactorRepo(),
+		auditLog: NewAuditService(cfg, registry),
+	}
+}
+
+type twoFactorService struct {
+	cfg      config.Config
+	repo     storage.TwoFactorRepository
+	auditLog AuditService
+}
+
+func (s twoFactorService) Enroll(ctx context.Context, user model.User) (model.TOTPEnrollment, error) {
+	twoFactor, err := s.repo.TwoFactor(ctx, user.ID)
+	if err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+
+	if twoFactor.Enabled {
+		return model.TOTPEnrollment{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.Log(ctx).Error().Err(err).Msg("Enroll: generate secret")
+		return model.TOTPEnrollment{}, err
+	}
+
+	err = s.repo.SaveTOTPSecret(ctx, user.ID, secret)
+	if err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+
+	return model.TOTPEnrollment{Secret: secret, URI: totp.URI(totpIssuer, user.Username, secret)}, nil
+}
+
+func (s twoFactorService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	twoFactor, err := s.repo.TwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor.Enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	if twoFactor.Secret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := helpers.RandomHex(5)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, fmt.Sprintf("%s-%s", code[:5], code[5:]))
+		hashes = append(hashes, hashToken(code))
+	}
+
+	err = s.repo.EnableTOTP(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, "two_factor_enabled", userID)
+
+	return codes, nil
+}
+
+func (s twoFactorService) Disable(ctx context.Context, userID int, code string) error {
+	if err := s.Validate(ctx, userID, code); err != nil {
+		return err
+	}
+
+	err := s.repo.DisableTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	s.audit(ctx, "two_factor_disabled", userID)
+
+	return nil
+}
+
+// Validate accepts TOTP code once per time step or an unused recovery code
+func (s twoFactorService) Validate(ctx context.Context, userID int, code string) error {
+	twoFactor, err := s.repo.TwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !twoFactor.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := totp.Validate(twoFactor.Secret, code, time.Now()); ok {
+		used, err := s.repo.UseTOTPStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+
+		if !used {
+			s.Log(ctx).Warn().Int("userID", userID).Msg("Validate: totp code replayed")
+			return ErrInvalidTwoFactorCode
+		}
+
+		return nil
+	}
+
+	recoveryCode := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hashToken(recoveryCode))
+	if err != nil {
+		return err
+	}
+
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	s.Log(ctx).Info().Int("userID", userID).Msg("recovery code used")
+
+	return nil
+}
+
+func (s twoFactorService) Enabled(ctx context.Context, userID int) (bool, error) {
+	twoFactor, err := s.repo.TwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return twoFactor.Enabled, nil
+}
+
+func (s twoFactorService) audit(ctx context.Context, action string, userID int) {
+	s.auditLog.Record(ctx, model.AuditEntry{
+		ActorID:    userID,
+		Action:     action,
+		TargetType: model.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+	})
+}
+
+func (s twoFactorService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "twoFactorService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/djokcik/gophermart/pkg/totp"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_twoFactorService_Confirm(t *testing.T) {
+	t.Run("should enable 2fa and return recovery codes", func(t *testing.T) {
+		secret, _ := totp.GenerateSecret()
+		code, _ := totp.Code(secret, totp.Step(time.Now()))
+
+		m := mocks.TwoFactorRepository{Mock: mock.Mock{}}
+		m.On("TwoFactor", mock.Anything, 666).Return(model.TwoFactor{UserID: 666, Secret: secret}, nil)
+		m.On("EnableTOTP", mock.Anything, 666, mock.Anything, mock.Anything).Return(nil)
+
+		service := twoFactorService{repo: &m, auditLog: newAuditMock()}
+
+		codes, err := service.Confirm(context.Background(), 666, code)
+
+		require.Equal(t, err, nil)
+		require.Len(t, codes, recoveryCodesCount)
+
+		hashes := m.Calls[1].Arguments.Get(3).([]string)
+		require.Equal(t, hashes[0], hashToken(strings.ReplaceAll(codes[0], "-", "")))
+	})
+
+	t.Run("should reject invalid code", func(t *testing.T) {
+		secret, _ := totp.GenerateSecret()
+
+		m := mocks.TwoFactorRepository{Mock: mock.Mock{}}
+		m.On("TwoFactor", mock.Anything, 666).Return(model.TwoFactor{UserID: 666, Secret: secret}, nil)
+
+		service := twoFactorService{repo: &m, auditLog: newAuditMock()}
+
+		_, err := service.Confirm(context.Background(), 666, "abcdef")
+
+		require.Equal(t, err, ErrInvalidTwoFactorCode)
+		m.AssertNumberOfCalls(t, "EnableTOTP", 0)
+	})
+}
+
+func Test_twoFactorService_Validate(t *testing.T) {
+	t.Run("should reject replayed totp code", func(t *testing.T) {
+		secret, _ := totp.GenerateSecret()
+		code, _ := totp.Code(secret, totp.Step(time.Now()))
+
+		m := mocks.TwoFactorRepository{Mock: mock.Mock{}}
+		m.On("TwoFactor", mock.Anything, 666).Return(model.TwoFactor{UserID: 666, Secret: secret, Enabled: true}, nil)
+		m.On("UseTOTPStep", mock.Anything, 666, mock.Anything).Return(false, nil)
+
+		service := twoFactorService{repo: &m}
+
+		err := service.Validate(context.Background(), 666, code)
+
+		require.Equal(t, err, ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("should accept recovery code", func(t *testing.T) {
+		secret, _ := totp.GenerateSecret()
+
+		m := mocks.TwoFactorRepository{Mock: mock.Mock{}}
+		m.On("TwoFactor", mock.Anything, 666).Return(model.TwoFactor{UserID: 666, Secret: secret, Enabled: true}, nil)
+		m.On("UseRecoveryCode", mock.Anything, 666, hashToken("abcde12345")).Return(true, nil)
+
+		service := twoFactorService{repo: &m}
+
+		err := service.Validate(context.Background(), 666, "ABCDE-12345")
+
+		require.Equal(t, err, nil)
+	})
+}
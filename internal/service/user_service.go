@@ -7,19 +7,36 @@ import (
 	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/internal/reporegistry"
 	"github.com/djokcik/gophermart/internal/storage"
+	helpers "github.com/djokcik/gophermart/pkg/helper"
 	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/djokcik/gophermart/pkg/password"
 	"github.com/rs/zerolog"
-	"golang.org/x/crypto/bcrypt"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const referralCodeAttempts = 3
+
 //go:generate mockery --name=UserService
 
 type UserService interface {
-	Authenticate(ctx context.Context, login string, password string) (string, error)
-	CreateUser(ctx context.Context, login string, password string) error
+	// Authenticate returns only ChallengeToken when the user has enabled the second factor
+	Authenticate(ctx context.Context, login string, password string) (model.AuthTokens, error)
+	CompleteTwoFactor(ctx context.Context, challenge model.Claims, code string) (model.AuthTokens, error)
+	// CreateUser registers the user, optional referralCode of another user makes him the referrer
+	CreateUser(ctx context.Context, login string, password string, referralCode string) error
 	GetUserByUsername(ctx context.Context, username string) (model.User, error)
-	GenerateToken(ctx context.Context, user model.User) (string, error)
+	GenerateToken(ctx context.Context, user model.User) (model.AuthTokens, error)
 	GetBalance(ctx context.Context, user model.User) (model.UserBalance, error)
+	// Profile returns the tier of the user and progress to the next one by the rolling accrual volume
+	Profile(ctx context.Context, user model.User) (model.Profile, error)
+	// ChangePassword revokes all sessions of the user, new tokens of the caller keep mfa of the current session
+	ChangePassword(ctx context.Context, user model.User, current string, next string, mfa bool) (model.AuthTokens, error)
+	DeleteUser(ctx context.Context, user model.User, password string) error
+	// VerifyPassword confirms sensitive operation of already authenticated user
+	VerifyPassword(ctx context.Context, user model.User, password string) error
+	PepperReporter(ctx context.Context) func()
 }
 
 func NewUserService(cfg config.Config, registry reporegistry.RepoRegistry) UserService {
@@ -27,7 +44,14 @@ func NewUserService(cfg config.Config, registry reporegistry.RepoRegistry) UserS
 		cfg:          cfg,
 		repo:         registry.GetUserRepo(),
 		withdrawRepo: registry.GetWithdrawRepo(),
+		lots:         registry.GetPointLotRepo(),
+		referrals:    registry.GetReferralRepo(),
+		orderRepo:    registry.GetOrderRepo(),
+		tiers:        registry.GetTierRepo(),
 		auth:         NewUserUtilsService(),
+		tokens:       NewTokenService(cfg, registry),
+		twoFactor:    NewTwoFactorService(cfg, registry),
+		auditLog:     NewAuditService(cfg, registry),
 	}
 }
 
@@ -35,7 +59,14 @@ type userService struct {
 	cfg          config.Config
 	repo         storage.UserRepository
 	withdrawRepo storage.WithdrawRepository
+	lots         storage.PointLotRepository
+	referrals    storage.ReferralRepository
+	orderRepo    storage.OrderRepository
+	tiers        storage.TierRepository
 	auth         UserUtilsService
+	tokens       TokenService
+	twoFactor    TwoFactorService
+	auditLog     AuditService
 }
 
 func (u userService) GetBalance(ctx context.Context, user model.User) (model.UserBalance, error) {
@@ -45,64 +76,317 @@ func (u userService) GetBalance(ctx context.Context, user model.User) (model.Use
 		return model.UserBalance{}, nil
 	}
 
-	return model.UserBalance{Current: user.Balance, Withdrawn: withdrawAmount}, nil
+	expiring, err := u.lots.ExpiringPoints(ctx, user.ID, time.Now().Add(u.cfg.PointsExpiringWindow))
+	if err != nil {
+		u.Log(ctx).Error().Err(err).Msg("GetBalance: expiring points")
+		return model.UserBalance{}, err
+	}
+
+	return model.UserBalance{Current: user.Balance, Withdrawn: withdrawAmount, ExpiringSoon: expiring}, nil
 }
 
-func (u userService) Authenticate(ctx context.Context, login string, password string) (string, error) {
+func (u userService) Authenticate(ctx context.Context, login string, pwd string) (model.AuthTokens, error) {
 	user, err := u.GetUserByUsername(ctx, login)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			u.Log(ctx).Trace().Err(err).Msg("authenticate: wrong username")
-			return "", ErrWrongPassword
+			u.auditLog.Record(ctx, model.AuditEntry{Action: "login_failed", TargetType: model.AuditTargetLogin, TargetID: login})
+			return model.AuthTokens{}, ErrWrongPassword
 		}
 
-		return "", err
+		return model.AuthTokens{}, err
 	}
 
-	if err := u.auth.CompareHashAndPassword(password+u.cfg.PasswordPepper, user.Password); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			u.Log(ctx).Trace().Err(err).Msg("authenticate: wrong password")
-			return "", ErrWrongPassword
+	if err = u.checkPassword(ctx, user, pwd); err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			u.auditLog.Record(ctx, model.AuditEntry{Action: "login_failed", TargetType: model.AuditTargetLogin, TargetID: login})
 		}
 
-		return "", err
+		return model.AuthTokens{}, err
+	}
+
+	// the lock is reported only after the password is checked, so it doesn't reveal existing logins
+	if user.LockedAt != nil {
+		u.Log(ctx).Trace().Int("userID", user.ID).Msg("authenticate: account is locked")
+		u.audit(ctx, "login_denied", user.ID, nil)
+		return model.AuthTokens{}, ErrAccountLocked
 	}
 
-	token, err := u.GenerateToken(ctx, user)
+	enabled, err := u.twoFactor.Enabled(ctx, user.ID)
 	if err != nil {
-		return "", err
+		u.Log(ctx).Error().Err(err).Msg("authenticate: check 2fa")
+		return model.AuthTokens{}, err
+	}
+
+	if enabled {
+		challenge, err := u.tokens.IssueChallenge(ctx, user)
+		if err != nil {
+			u.Log(ctx).Error().Err(err).Msg("authenticate: issue 2fa challenge")
+			return model.AuthTokens{}, err
+		}
+
+		return model.AuthTokens{ChallengeToken: challenge}, nil
+	}
+
+	tokens, err := u.GenerateToken(ctx, user)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	u.audit(ctx, "login", user.ID, map[string]interface{}{"mfa": false})
+
+	return tokens, err
+}
+
+// CompleteTwoFactor finishes login started by Authenticate and opens session with mfa flag
+func (u userService) CompleteTwoFactor(ctx context.Context, challenge model.Claims, code string) (model.AuthTokens, error) {
+	err := u.twoFactor.Validate(ctx, challenge.ID, code)
+	if err != nil {
+		u.Log(ctx).Trace().Err(err).Msg("CompleteTwoFactor: invalid code")
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			u.audit(ctx, "two_factor_failed", challenge.ID, nil)
+		}
+		return model.AuthTokens{}, err
+	}
+
+	user, err := u.repo.UserByID(ctx, challenge.ID)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	if user.LockedAt != nil {
+		u.Log(ctx).Trace().Int("userID", user.ID).Msg("CompleteTwoFactor: account is locked")
+		u.audit(ctx, "login_denied", user.ID, nil)
+		return model.AuthTokens{}, ErrAccountLocked
+	}
+
+	tokens, err := u.tokens.IssueTokens(ctx, user, true)
+	if err != nil {
+		u.Log(ctx).Err(err).Msgf("CompleteTwoFactor: error create token")
+		return model.AuthTokens{}, err
+	}
+
+	u.audit(ctx, "login", user.ID, map[string]interface{}{"mfa": true})
+
+	return tokens, nil
+}
+
+// checkPassword compares password with the hash of the user and upgrades outdated hash
+func (u userService) checkPassword(ctx context.Context, user model.User, pwd string) error {
+	pepper, ok := u.cfg.Pepper(user.PepperID)
+	if !ok {
+		u.Log(ctx).Error().Str("pepperID", user.PepperID).Int("userID", user.ID).Msg("checkPassword: unknown pepper")
+		return ErrUnknownPepper
+	}
+
+	rehash, err := u.auth.CompareHashAndPassword(pwd, pepper, user.Password)
+	if err != nil {
+		if errors.Is(err, password.ErrMismatch) {
+			u.Log(ctx).Trace().Err(err).Msg("checkPassword: wrong password")
+			return ErrWrongPassword
+		}
+
+		return err
+	}
+
+	if rehash || user.PepperID != u.cfg.PasswordPepperID {
+		u.rehashPassword(ctx, user, pwd)
+	}
+
+	return nil
+}
+
+func (u userService) VerifyPassword(ctx context.Context, user model.User, pwd string) error {
+	return u.checkPassword(ctx, user, pwd)
+}
+
+// ChangePassword replaces the password and revokes all sessions of the user, new tokens are issued for the caller
+func (u userService) ChangePassword(ctx context.Context, user model.User, current string, next string, mfa bool) (model.AuthTokens, error) {
+	if err := u.checkPassword(ctx, user, current); err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	err := model.User{Username: user.Username, Password: next}.Validate()
+	if err != nil {
+		u.Log(ctx).Trace().Err(err).Msg("ChangePassword: invalid validate password")
+		return model.AuthTokens{}, err
+	}
+
+	hash, err := u.auth.HashAndSalt(next, u.cfg.PasswordPepper)
+	if err != nil {
+		u.Log(ctx).Error().Err(err).Msg("ChangePassword: error create hash")
+		return model.AuthTokens{}, err
+	}
+
+	err = u.repo.UpdatePassword(ctx, user.ID, hash, u.cfg.PasswordPepperID)
+	if err != nil {
+		u.Log(ctx).Error().Err(err).Msg("ChangePassword: invalid update password")
+		return model.AuthTokens{}, err
+	}
+
+	err = u.tokens.RevokeAll(ctx, user.ID)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	u.audit(ctx, "password_changed", user.ID, nil)
+
+	tokens, err := u.tokens.IssueTokens(ctx, user, mfa)
+	if err != nil {
+		u.Log(ctx).Err(err).Msg("ChangePassword: error create token")
+		return model.AuthTokens{}, err
+	}
+
+	return tokens, nil
+}
+
+// DeleteUser closes the account after the password confirmation
+func (u userService) DeleteUser(ctx context.Context, user model.User, pwd string) error {
+	if err := u.checkPassword(ctx, user, pwd); err != nil {
+		return err
+	}
+
+	err := u.repo.DeleteUser(ctx, user.ID)
+	if err != nil {
+		u.Log(ctx).Error().Err(err).Msg("DeleteUser: invalid delete user")
+		return err
+	}
+
+	err = u.tokens.RevokeAll(ctx, user.ID)
+	if err != nil {
+		return err
 	}
 
-	return token, err
+	u.audit(ctx, "user_deleted", user.ID, nil)
+
+	return nil
+}
+
+// rehashPassword upgrades the hash to the current algorithm and pepper, failure doesn't prevent login
+func (u userService) rehashPassword(ctx context.Context, user model.User, pwd string) {
+	hash, err := u.auth.HashAndSalt(pwd, u.cfg.PasswordPepper)
+	if err != nil {
+		u.Log(ctx).Error().Err(err).Msg("rehashPassword: error create hash")
+		return
+	}
+
+	err = u.repo.UpdatePassword(ctx, user.ID, hash, u.cfg.PasswordPepperID)
+	if err != nil {
+		u.Log(ctx).Error().Err(err).Msg("rehashPassword: invalid update password")
+		return
+	}
+
+	u.Log(ctx).Info().Int("userID", user.ID).Msg("password hash upgraded")
 }
 
-func (u userService) CreateUser(ctx context.Context, login string, password string) error {
-	user := model.User{Username: login, Password: password}
+func (u userService) CreateUser(ctx context.Context, login string, password string, referralCode string) error {
+	user := model.User{Username: login, Password: password, PepperID: u.cfg.PasswordPepperID}
 	err := user.Validate()
 	if err != nil {
 		u.Log(ctx).Trace().Err(err).Msgf("invalid validate user")
 		return err
 	}
 
+	if referralCode != "" {
+		referrer, err := u.referrals.ReferrerByCode(ctx, normalizeReferralCode(referralCode))
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				u.Log(ctx).Trace().Err(err).Msg("unknown referral code")
+				return model.ErrInvalidReferralCode
+			}
+
+			return err
+		}
+
+		user.ReferredBy = referrer.ID
+	}
+
 	user.Password, err = u.auth.HashAndSalt(user.Password, u.cfg.PasswordPepper)
 	if err != nil {
 		u.Log(ctx).Trace().Err(err).Msgf("error create hash")
 		return err
 	}
 
-	err = u.repo.CreateUser(ctx, user)
+	// a collision of random codes is unlikely, a few attempts are enough
+	for attempt := 0; attempt < referralCodeAttempts; attempt++ {
+		user.ReferralCode, err = newReferralCode()
+		if err != nil {
+			return err
+		}
+
+		err = u.repo.CreateUser(ctx, user)
+		if !errors.Is(err, storage.ErrReferralCodeTaken) {
+			break
+		}
+	}
 	if err != nil {
 		u.Log(ctx).Trace().Err(err).Msg("invalid create user")
 		return err
 	}
 
-	u.Log(ctx).Info().
-		Str("Username", user.Username).
-		Msg("success created user")
+	entry := model.AuditEntry{Action: "user_registered", TargetType: model.AuditTargetLogin, TargetID: user.Username}
+	if user.ReferredBy != 0 {
+		entry.Details = map[string]interface{}{"referredBy": user.ReferredBy}
+	}
+	u.auditLog.Record(ctx, entry)
 
 	return nil
 }
 
+// PepperReporter logs how many users still have hashes created with previous peppers.
+// An old pepper may be removed from config once nobody uses it.
+func (u userService) PepperReporter(ctx context.Context) func() {
+	return func() {
+		usage, err := u.repo.PepperUsage(ctx)
+		if err != nil {
+			u.Log(ctx).Error().Err(err).Msg("PepperReporter: failed get pepper usage")
+			return
+		}
+
+		for _, item := range usage {
+			if item.PepperID == u.cfg.PasswordPepperID {
+				u.Log(ctx).Info().Str("pepperID", item.PepperID).Int("users", item.Users).Msg("current pepper")
+				continue
+			}
+
+			_, known := u.cfg.Pepper(item.PepperID)
+			u.Log(ctx).Warn().
+				Str("pepperID", item.PepperID).
+				Int("users", item.Users).
+				Bool("configured", known).
+				Msg("users remain on old pepper")
+		}
+	}
+}
+
+func (u userService) Profile(ctx context.Context, user model.User) (model.Profile, error) {
+	tier, err := u.tiers.UserTier(ctx, user.ID)
+	if err != nil {
+		u.Log(ctx).Error().Err(err).Msg("Profile: user tier")
+		return model.Profile{}, err
+	}
+
+	volume, err := u.orderRepo.AccrualVolume(ctx, user.ID, time.Now().Add(-u.cfg.TierWindow))
+	if err != nil {
+		u.Log(ctx).Error().Err(err).Msg("Profile: accrual volume")
+		return model.Profile{}, err
+	}
+
+	level := model.LevelOf(tier)
+	profile := model.Profile{Login: user.Username, Tier: level.Tier, Multiplier: level.Multiplier, Volume: volume}
+
+	// the tier is recalculated nightly, so the volume may already reach the next threshold
+	if next, ok := model.NextLevel(level.Tier); ok {
+		profile.NextTier = next.Tier
+		profile.NextThreshold = next.Threshold
+		if volume < next.Threshold {
+			profile.ToNextTier = next.Threshold - volume
+		}
+	}
+
+	return profile, nil
+}
+
 func (u userService) GetUserByUsername(ctx context.Context, username string) (model.User, error) {
 	user, err := u.repo.UserByUsername(ctx, username)
 	if err != nil {
@@ -112,14 +396,39 @@ func (u userService) GetUserByUsername(ctx context.Context, username string) (mo
 	return user, nil
 }
 
-func (u userService) GenerateToken(ctx context.Context, user model.User) (string, error) {
-	token, err := u.auth.CreateToken(u.cfg.Key, user.ID)
+func (u userService) GenerateToken(ctx context.Context, user model.User) (model.AuthTokens, error) {
+	tokens, err := u.tokens.IssueTokens(ctx, user, false)
 	if err != nil {
 		u.Log(ctx).Err(err).Msgf("error create token")
+		return model.AuthTokens{}, err
+	}
+
+	return tokens, nil
+}
+
+// newReferralCode returns 8 uppercase hex characters, easy to dictate and type
+func newReferralCode() (string, error) {
+	code, err := helpers.RandomHex(4)
+	if err != nil {
 		return "", err
 	}
 
-	return token, nil
+	return strings.ToUpper(code), nil
+}
+
+func normalizeReferralCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// audit records action of the user on own account
+func (u userService) audit(ctx context.Context, action string, userID int, details map[string]interface{}) {
+	u.auditLog.Record(ctx, model.AuditEntry{
+		ActorID:    userID,
+		Action:     action,
+		TargetType: model.AuditTargetUser,
+		TargetID:   strconv.Itoa(userID),
+		Details:    details,
+	})
 }
 
 func (u userService) Log(ctx context.Context) *zerolog.Logger {
@@ -5,10 +5,15 @@ import (
 	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/model"
 	serviceMock "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
 	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/djokcik/gophermart/pkg/password"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_userService_GetBalance(t *testing.T) {
@@ -16,13 +21,56 @@ func Test_userService_GetBalance(t *testing.T) {
 		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
 		m.On("AmountWithdrawByUser", mock.Anything, 666).Return(model.Amount(1000), nil)
 
-		service := userService{withdrawRepo: &m}
+		lotMock := mocks.PointLotRepository{Mock: mock.Mock{}}
+		lotMock.On("ExpiringPoints", mock.Anything, 666, mock.Anything).Return(model.Amount(500), nil)
+
+		service := userService{cfg: config.Config{PointsExpiringWindow: 30 * 24 * time.Hour}, withdrawRepo: &m, lots: &lotMock}
 
 		amount, err := service.GetBalance(context.Background(), model.User{ID: 666, Balance: 1234})
 
 		m.AssertNumberOfCalls(t, "AmountWithdrawByUser", 1)
+		lotMock.AssertNumberOfCalls(t, "ExpiringPoints", 1)
 		require.Equal(t, err, nil)
-		require.Equal(t, amount, model.UserBalance{Withdrawn: 1000, Current: 1234})
+		require.Equal(t, amount, model.UserBalance{Withdrawn: 1000, Current: 1234, ExpiringSoon: 500})
+	})
+}
+
+func Test_userService_Profile(t *testing.T) {
+	t.Run("should return progress to the next tier", func(t *testing.T) {
+		tierMock := mocks.TierRepository{Mock: mock.Mock{}}
+		tierMock.On("UserTier", mock.Anything, 666).Return(model.TierSilver, nil)
+
+		orderMock := mocks.OrderRepository{Mock: mock.Mock{}}
+		orderMock.On("AccrualVolume", mock.Anything, 666, mock.Anything).Return(model.Amount(150000), nil)
+
+		service := userService{cfg: config.Config{TierWindow: 90 * 24 * time.Hour}, tiers: &tierMock, orderRepo: &orderMock}
+
+		profile, err := service.Profile(context.Background(), model.User{ID: 666, Username: "user"})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, profile, model.Profile{
+			Login:         "user",
+			Tier:          model.TierSilver,
+			Multiplier:    1.1,
+			Volume:        150000,
+			NextTier:      model.TierGold,
+			NextThreshold: 500000,
+			ToNextTier:    350000,
+		})
+	})
+	t.Run("should return no next tier on the highest tier", func(t *testing.T) {
+		tierMock := mocks.TierRepository{Mock: mock.Mock{}}
+		tierMock.On("UserTier", mock.Anything, 666).Return(model.TierPlatinum, nil)
+
+		orderMock := mocks.OrderRepository{Mock: mock.Mock{}}
+		orderMock.On("AccrualVolume", mock.Anything, 666, mock.Anything).Return(model.Amount(3000000), nil)
+
+		service := userService{tiers: &tierMock, orderRepo: &orderMock}
+
+		profile, err := service.Profile(context.Background(), model.User{ID: 666})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, profile, model.Profile{Tier: model.TierPlatinum, Multiplier: 1.5, Volume: 3000000})
 	})
 }
 
@@ -45,16 +93,17 @@ func Test_userService_GetUserByUsername(t *testing.T) {
 
 func Test_userService_GenerateToken(t *testing.T) {
 	t.Run("should be generated token", func(t *testing.T) {
-		m := serviceMock.UserUtilsService{Mock: mock.Mock{}}
-		m.On("CreateToken", "key", 666).Return("secretToken", nil)
+		m := serviceMock.TokenService{Mock: mock.Mock{}}
+		m.On("IssueTokens", mock.Anything, model.User{ID: 666}, false).
+			Return(model.AuthTokens{AccessToken: "secretToken", RefreshToken: "refreshToken"}, nil)
 
-		service := userService{auth: &m, cfg: config.Config{Key: "key"}}
+		service := userService{tokens: &m}
 
-		token, err := service.GenerateToken(context.Background(), model.User{ID: 666})
+		tokens, err := service.GenerateToken(context.Background(), model.User{ID: 666})
 
-		m.AssertNumberOfCalls(t, "CreateToken", 1)
+		m.AssertNumberOfCalls(t, "IssueTokens", 1)
 		require.Equal(t, err, nil)
-		require.Equal(t, token, "secretToken")
+		require.Equal(t, tokens, model.AuthTokens{AccessToken: "secretToken", RefreshToken: "refreshToken"})
 	})
 }
 
@@ -65,17 +114,77 @@ func Test_userService_CreateUser(t *testing.T) {
 			Return("HashedPassword", nil)
 
 		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
-		repoMock.On("CreateUser", mock.Anything, model.User{Username: "UserLogin", Password: "HashedPassword"}).
-			Return(nil)
+		repoMock.On("CreateUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+			return user.Username == "UserLogin" && user.Password == "HashedPassword" && user.PepperID == "1" &&
+				len(user.ReferralCode) == 8 && user.ReferralCode == strings.ToUpper(user.ReferralCode) && user.ReferredBy == 0
+		})).Return(nil)
 
-		service := userService{auth: &authMock, repo: &repoMock, cfg: config.Config{PasswordPepper: "pepper"}}
+		service := userService{auth: &authMock, repo: &repoMock, cfg: config.Config{PasswordPepper: "pepper", PasswordPepperID: "1"}, auditLog: newAuditMock()}
 
-		err := service.CreateUser(context.Background(), "UserLogin", "userPassword")
+		err := service.CreateUser(context.Background(), "UserLogin", "userPassword", "")
 
 		authMock.AssertNumberOfCalls(t, "HashAndSalt", 1)
 		repoMock.AssertNumberOfCalls(t, "CreateUser", 1)
 		require.Equal(t, err, nil)
 	})
+	t.Run("should set referrer by referral code", func(t *testing.T) {
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("HashAndSalt", "userPassword", "pepper").Return("HashedPassword", nil)
+
+		referralMock := mocks.ReferralRepository{Mock: mock.Mock{}}
+		referralMock.On("ReferrerByCode", mock.Anything, "AB12CD34").Return(model.User{ID: 42}, nil)
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("CreateUser", mock.Anything, mock.MatchedBy(func(user model.User) bool {
+			return user.ReferredBy == 42
+		})).Return(nil)
+
+		service := userService{auth: &authMock, repo: &repoMock, referrals: &referralMock, cfg: config.Config{PasswordPepper: "pepper"}, auditLog: newAuditMock()}
+
+		err := service.CreateUser(context.Background(), "UserLogin", "userPassword", " ab12cd34 ")
+
+		referralMock.AssertNumberOfCalls(t, "ReferrerByCode", 1)
+		repoMock.AssertNumberOfCalls(t, "CreateUser", 1)
+		require.Equal(t, err, nil)
+	})
+	t.Run("should reject unknown referral code", func(t *testing.T) {
+		referralMock := mocks.ReferralRepository{Mock: mock.Mock{}}
+		referralMock.On("ReferrerByCode", mock.Anything, "AB12CD34").Return(model.User{}, storage.ErrNotFound)
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+
+		service := userService{repo: &repoMock, referrals: &referralMock, auditLog: newAuditMock()}
+
+		err := service.CreateUser(context.Background(), "UserLogin", "userPassword", "AB12CD34")
+
+		repoMock.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+		require.ErrorIs(t, err, model.ErrInvalidReferralCode)
+	})
+	t.Run("should retry when referral code is taken", func(t *testing.T) {
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("HashAndSalt", "userPassword", "pepper").Return("HashedPassword", nil)
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("CreateUser", mock.Anything, mock.Anything).Return(storage.ErrReferralCodeTaken).Once()
+		repoMock.On("CreateUser", mock.Anything, mock.Anything).Return(nil).Once()
+
+		service := userService{auth: &authMock, repo: &repoMock, cfg: config.Config{PasswordPepper: "pepper"}, auditLog: newAuditMock()}
+
+		err := service.CreateUser(context.Background(), "UserLogin", "userPassword", "")
+
+		repoMock.AssertNumberOfCalls(t, "CreateUser", 2)
+		require.Equal(t, err, nil)
+	})
+	t.Run("should reject login of deleted user", func(t *testing.T) {
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+
+		service := userService{repo: &repoMock, auditLog: newAuditMock()}
+
+		err := service.CreateUser(context.Background(), "deleted-user-0000000666", "userPassword", "")
+
+		repoMock.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+		require.Equal(t, err, model.ErrUsernameLength)
+	})
 }
 
 func Test_userService_Authenticate(t *testing.T) {
@@ -85,22 +194,319 @@ func Test_userService_Authenticate(t *testing.T) {
 			Return(model.User{ID: 666, Password: "HashedPassword"}, nil)
 
 		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
-		authMock.On("CompareHashAndPassword", "userPasswordpepper", "HashedPassword").
-			Return(nil)
-		authMock.On("CreateToken", "key", 666).Return("secretToken", nil)
+		authMock.On("CompareHashAndPassword", "userPassword", "pepper", "HashedPassword").
+			Return(false, nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("IssueTokens", mock.Anything, model.User{ID: 666, Password: "HashedPassword"}, false).
+			Return(model.AuthTokens{AccessToken: "secretToken"}, nil)
 
 		service := userService{
-			auth: &authMock,
-			repo: &repoMock,
-			cfg:  config.Config{PasswordPepper: "pepper", Key: "key"},
+			auth:      &authMock,
+			repo:      &repoMock,
+			tokens:    &tokensMock,
+			twoFactor: disabledTwoFactor(),
+			cfg:       config.Config{PasswordPepper: "pepper"},
+			auditLog:  newAuditMock(),
 		}
 
-		token, err := service.Authenticate(context.Background(), "UserLogin", "userPassword")
+		tokens, err := service.Authenticate(context.Background(), "UserLogin", "userPassword")
 
 		authMock.AssertNumberOfCalls(t, "CompareHashAndPassword", 1)
-		authMock.AssertNumberOfCalls(t, "CreateToken", 1)
+		tokensMock.AssertNumberOfCalls(t, "IssueTokens", 1)
 		repoMock.AssertNumberOfCalls(t, "UserByUsername", 1)
 		require.Equal(t, err, nil)
-		require.Equal(t, token, "secretToken")
+		require.Equal(t, tokens, model.AuthTokens{AccessToken: "secretToken"})
+	})
+	t.Run("should reject locked account", func(t *testing.T) {
+		lockedAt := time.Now()
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UserByUsername", mock.Anything, "UserLogin").
+			Return(model.User{ID: 666, Password: "HashedPassword", LockedAt: &lockedAt}, nil)
+
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("CompareHashAndPassword", "userPassword", "pepper", "HashedPassword").Return(false, nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+
+		service := userService{auth: &authMock, repo: &repoMock, tokens: &tokensMock, cfg: config.Config{PasswordPepper: "pepper"}, auditLog: newAuditMock()}
+
+		_, err := service.Authenticate(context.Background(), "UserLogin", "userPassword")
+
+		require.Equal(t, err, ErrAccountLocked)
+		tokensMock.AssertNotCalled(t, "IssueTokens", mock.Anything, mock.Anything, mock.Anything)
+	})
+	t.Run("should audit failed login", func(t *testing.T) {
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UserByUsername", mock.Anything, "UserLogin").
+			Return(model.User{ID: 666, Password: "HashedPassword"}, nil)
+
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("CompareHashAndPassword", "wrongPassword", "pepper", "HashedPassword").Return(false, password.ErrMismatch)
+
+		auditMock := serviceMock.AuditService{Mock: mock.Mock{}}
+		auditMock.On("Record", mock.Anything, model.AuditEntry{
+			Action:     "login_failed",
+			TargetType: model.AuditTargetLogin,
+			TargetID:   "UserLogin",
+		}).Return()
+
+		service := userService{auth: &authMock, repo: &repoMock, cfg: config.Config{PasswordPepper: "pepper"}, auditLog: &auditMock}
+
+		_, err := service.Authenticate(context.Background(), "UserLogin", "wrongPassword")
+
+		require.Equal(t, err, ErrWrongPassword)
+		auditMock.AssertNumberOfCalls(t, "Record", 1)
+	})
+	t.Run("should upgrade legacy bcrypt hash to argon2id", func(t *testing.T) {
+		legacy, _ := bcrypt.GenerateFromPassword([]byte("userPassword"+"pepper"), bcrypt.MinCost)
+		user := model.User{ID: 666, Password: string(legacy)}
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UserByUsername", mock.Anything, "UserLogin").Return(user, nil)
+		repoMock.On("UpdatePassword", mock.Anything, 666, mock.MatchedBy(func(hash string) bool {
+			return strings.HasPrefix(hash, "$argon2id$")
+		}), "").Return(nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("IssueTokens", mock.Anything, user, false).Return(model.AuthTokens{AccessToken: "secretToken"}, nil)
+
+		service := userService{
+			auth:      NewUserUtilsService(),
+			repo:      &repoMock,
+			tokens:    &tokensMock,
+			twoFactor: disabledTwoFactor(),
+			cfg:       config.Config{PasswordPepper: "pepper"},
+			auditLog:  newAuditMock(),
+		}
+
+		_, err := service.Authenticate(context.Background(), "UserLogin", "userPassword")
+		require.Equal(t, err, nil)
+		repoMock.AssertNumberOfCalls(t, "UpdatePassword", 1)
+
+		hash := repoMock.Calls[1].Arguments.Get(2).(string)
+		rehash, err := service.auth.CompareHashAndPassword("userPassword", "pepper", hash)
+		require.Equal(t, err, nil)
+		require.Equal(t, rehash, false)
+	})
+
+	t.Run("should verify with old pepper and rehash with current one", func(t *testing.T) {
+		user := model.User{ID: 666, Password: "HashedPassword", PepperID: "1"}
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UserByUsername", mock.Anything, "UserLogin").Return(user, nil)
+		repoMock.On("UpdatePassword", mock.Anything, 666, "NewHashedPassword", "2").Return(nil)
+
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("CompareHashAndPassword", "userPassword", "oldPepper", "HashedPassword").Return(false, nil)
+		authMock.On("HashAndSalt", "userPassword", "newPepper").Return("NewHashedPassword", nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("IssueTokens", mock.Anything, user, false).Return(model.AuthTokens{AccessToken: "secretToken"}, nil)
+
+		service := userService{
+			auth:      &authMock,
+			repo:      &repoMock,
+			tokens:    &tokensMock,
+			twoFactor: disabledTwoFactor(),
+			cfg: config.Config{
+				PasswordPepper:     "newPepper",
+				PasswordPepperID:   "2",
+				PasswordOldPeppers: []string{"1:oldPepper"},
+			},
+			auditLog: newAuditMock(),
+		}
+
+		_, err := service.Authenticate(context.Background(), "UserLogin", "userPassword")
+
+		require.Equal(t, err, nil)
+		repoMock.AssertNumberOfCalls(t, "UpdatePassword", 1)
 	})
+
+	t.Run("should fail when pepper of the hash is not configured", func(t *testing.T) {
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UserByUsername", mock.Anything, "UserLogin").
+			Return(model.User{ID: 666, Password: "HashedPassword", PepperID: "0"}, nil)
+
+		service := userService{repo: &repoMock, cfg: config.Config{PasswordPepper: "pepper", PasswordPepperID: "1"}, auditLog: newAuditMock()}
+
+		_, err := service.Authenticate(context.Background(), "UserLogin", "userPassword")
+
+		require.Equal(t, err, ErrUnknownPepper)
+	})
+
+	t.Run("should not truncate long passwords", func(t *testing.T) {
+		auth := NewUserUtilsService()
+		long := strings.Repeat("a", 100)
+
+		hash, err := auth.HashAndSalt(long+"b", "pepper")
+		require.Equal(t, err, nil)
+
+		_, err = auth.CompareHashAndPassword(long+"c", "pepper", hash)
+		require.ErrorIs(t, err, password.ErrMismatch)
+	})
+}
+
+func Test_userService_ChangePassword(t *testing.T) {
+	t.Run("should change password and revoke sessions", func(t *testing.T) {
+		user := model.User{ID: 666, Username: "UserLogin", Password: "HashedPassword", PepperID: "1"}
+
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("CompareHashAndPassword", "current", "pepper", "HashedPassword").Return(false, nil)
+		authMock.On("HashAndSalt", "nextPassword", "pepper").Return("NewHashedPassword", nil)
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UpdatePassword", mock.Anything, 666, "NewHashedPassword", "1").Return(nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("RevokeAll", mock.Anything, 666).Return(nil)
+		tokensMock.On("IssueTokens", mock.Anything, user, false).Return(model.AuthTokens{AccessToken: "secretToken"}, nil)
+
+		service := userService{
+			auth:     &authMock,
+			repo:     &repoMock,
+			tokens:   &tokensMock,
+			cfg:      config.Config{PasswordPepper: "pepper", PasswordPepperID: "1"},
+			auditLog: newAuditMock(),
+		}
+
+		tokens, err := service.ChangePassword(context.Background(), user, "current", "nextPassword", false)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, tokens, model.AuthTokens{AccessToken: "secretToken"})
+		repoMock.AssertNumberOfCalls(t, "UpdatePassword", 1)
+		tokensMock.AssertNumberOfCalls(t, "RevokeAll", 1)
+	})
+
+	t.Run("should keep mfa of the session on new tokens", func(t *testing.T) {
+		user := model.User{ID: 666, Username: "UserLogin", Password: "HashedPassword", PepperID: "1"}
+
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("CompareHashAndPassword", "current", "pepper", "HashedPassword").Return(false, nil)
+		authMock.On("HashAndSalt", "nextPassword", "pepper").Return("NewHashedPassword", nil)
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UpdatePassword", mock.Anything, 666, "NewHashedPassword", "1").Return(nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("RevokeAll", mock.Anything, 666).Return(nil)
+		tokensMock.On("IssueTokens", mock.Anything, user, true).Return(model.AuthTokens{AccessToken: "secretToken"}, nil)
+
+		service := userService{
+			auth:     &authMock,
+			repo:     &repoMock,
+			tokens:   &tokensMock,
+			cfg:      config.Config{PasswordPepper: "pepper", PasswordPepperID: "1"},
+			auditLog: newAuditMock(),
+		}
+
+		_, err := service.ChangePassword(context.Background(), user, "current", "nextPassword", true)
+
+		require.Equal(t, err, nil)
+		tokensMock.AssertCalled(t, "IssueTokens", mock.Anything, user, true)
+		tokensMock.AssertNotCalled(t, "IssueTokens", mock.Anything, user, false)
+	})
+
+	t.Run("should reject wrong current password", func(t *testing.T) {
+		user := model.User{ID: 666, Password: "HashedPassword", PepperID: "1"}
+
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("CompareHashAndPassword", "wrong", "pepper", "HashedPassword").Return(false, password.ErrMismatch)
+
+		service := userService{auth: &authMock, cfg: config.Config{PasswordPepper: "pepper", PasswordPepperID: "1"}, auditLog: newAuditMock()}
+
+		_, err := service.ChangePassword(context.Background(), user, "wrong", "nextPassword", false)
+
+		require.Equal(t, err, ErrWrongPassword)
+	})
+}
+
+func Test_userService_DeleteUser(t *testing.T) {
+	t.Run("should delete user and revoke sessions", func(t *testing.T) {
+		user := model.User{ID: 666, Password: "HashedPassword", PepperID: "1"}
+
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("CompareHashAndPassword", "userPassword", "pepper", "HashedPassword").Return(false, nil)
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("DeleteUser", mock.Anything, 666).Return(nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("RevokeAll", mock.Anything, 666).Return(nil)
+
+		service := userService{
+			auth:     &authMock,
+			repo:     &repoMock,
+			tokens:   &tokensMock,
+			cfg:      config.Config{PasswordPepper: "pepper", PasswordPepperID: "1"},
+			auditLog: newAuditMock(),
+		}
+
+		err := service.DeleteUser(context.Background(), user, "userPassword")
+
+		require.Equal(t, err, nil)
+		repoMock.AssertNumberOfCalls(t, "DeleteUser", 1)
+		tokensMock.AssertNumberOfCalls(t, "RevokeAll", 1)
+	})
+}
+
+func Test_userService_TwoFactorLogin(t *testing.T) {
+	t.Run("should return challenge when 2fa is enabled", func(t *testing.T) {
+		user := model.User{ID: 666, Password: "HashedPassword"}
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UserByUsername", mock.Anything, "UserLogin").Return(user, nil)
+
+		authMock := serviceMock.UserUtilsService{Mock: mock.Mock{}}
+		authMock.On("CompareHashAndPassword", "userPassword", "pepper", "HashedPassword").Return(false, nil)
+
+		twoFactorMock := serviceMock.TwoFactorService{Mock: mock.Mock{}}
+		twoFactorMock.On("Enabled", mock.Anything, 666).Return(true, nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("IssueChallenge", mock.Anything, user).Return("challenge", nil)
+
+		service := userService{
+			auth:      &authMock,
+			repo:      &repoMock,
+			tokens:    &tokensMock,
+			twoFactor: &twoFactorMock,
+			cfg:       config.Config{PasswordPepper: "pepper"},
+			auditLog:  newAuditMock(),
+		}
+
+		tokens, err := service.Authenticate(context.Background(), "UserLogin", "userPassword")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, tokens, model.AuthTokens{ChallengeToken: "challenge"})
+		tokensMock.AssertNumberOfCalls(t, "IssueTokens", 0)
+	})
+
+	t.Run("should issue mfa tokens for valid code", func(t *testing.T) {
+		user := model.User{ID: 666, Username: "UserLogin"}
+
+		repoMock := mocks.UserRepository{Mock: mock.Mock{}}
+		repoMock.On("UserByID", mock.Anything, 666).Return(user, nil)
+
+		twoFactorMock := serviceMock.TwoFactorService{Mock: mock.Mock{}}
+		twoFactorMock.On("Validate", mock.Anything, 666, "123456").Return(nil)
+
+		tokensMock := serviceMock.TokenService{Mock: mock.Mock{}}
+		tokensMock.On("IssueTokens", mock.Anything, user, true).Return(model.AuthTokens{AccessToken: "secretToken"}, nil)
+
+		service := userService{repo: &repoMock, tokens: &tokensMock, twoFactor: &twoFactorMock, auditLog: newAuditMock()}
+
+		tokens, err := service.CompleteTwoFactor(context.Background(), model.Claims{ID: 666}, "123456")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, tokens, model.AuthTokens{AccessToken: "secretToken"})
+	})
+}
+
+func disabledTwoFactor() *serviceMock.TwoFactorService {
+	m := serviceMock.TwoFactorService{Mock: mock.Mock{}}
+	m.On("Enabled", mock.Anything, mock.Anything).Return(false, nil)
+
+	return &m
 }
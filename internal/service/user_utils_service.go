@@ -3,40 +3,43 @@ package service
 import (
 	"fmt"
 	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/pkg/keyring"
+	"github.com/djokcik/gophermart/pkg/password"
 	"github.com/golang-jwt/jwt"
-	"golang.org/x/crypto/bcrypt"
 	"strings"
-	"time"
 )
 
 //go:generate mockery --name=UserUtilsService
 
 type UserUtilsService interface {
-	CreateToken(secretKey string, id int) (string, error)
-	ParseToken(accessToken string, secretKey string) (int, error)
+	CreateToken(keys *keyring.Keyring, claims model.Claims) (string, error)
+	ParseToken(accessToken string, keys *keyring.Keyring) (model.Claims, error)
 	GetJwtTokenByAuthHeader(authHeader string) (string, error)
 	HashAndSalt(pwd string, pepper string) (string, error)
-	CompareHashAndPassword(password string, hash string) error
+	// CompareHashAndPassword returns password.ErrMismatch for wrong password, rehash reports
+	// that the hash was created by outdated algorithm or parameters
+	CompareHashAndPassword(pwd string, pepper string, hash string) (rehash bool, err error)
 }
 
 func NewUserUtilsService() UserUtilsService {
-	return &userUtilsService{}
+	return &userUtilsService{hasher: password.NewDefaultHasher()}
 }
 
 type userUtilsService struct {
+	hasher *password.Hasher
 }
 
-func (a userUtilsService) CompareHashAndPassword(password string, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+func (a userUtilsService) CompareHashAndPassword(pwd string, pepper string, hash string) (bool, error) {
+	return a.hasher.Verify(pwd, pepper, hash)
 }
 
 func (a userUtilsService) HashAndSalt(pwd string, pepper string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(pwd+pepper), bcrypt.DefaultCost)
+	hash, err := a.hasher.Hash(pwd, pepper)
 	if err != nil {
-		return "", fmt.Errorf("bcryptPassword: %w", err)
+		return "", fmt.Errorf("hashPassword: %w", err)
 	}
 
-	return string(hash), nil
+	return hash, nil
 }
 
 func (a userUtilsService) GetJwtTokenByAuthHeader(authHeader string) (string, error) {
@@ -56,33 +59,22 @@ func (a userUtilsService) GetJwtTokenByAuthHeader(authHeader string) (string, er
 	return headerParts[1], nil
 }
 
-func (a userUtilsService) CreateToken(secretKey string, id int) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, model.Claims{
-		ID: id,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24 * 30).Unix(),
-			Issuer:    "gophermart",
-		},
-	})
+func (a userUtilsService) CreateToken(keys *keyring.Keyring, claims model.Claims) (string, error) {
+	claims.Issuer = "gophermart"
 
-	return token.SignedString([]byte(secretKey))
+	return keys.Sign(claims)
 }
 
-func (a userUtilsService) ParseToken(accessToken string, secretKey string) (int, error) {
-	token, err := jwt.ParseWithClaims(accessToken, &model.Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secretKey), nil
-	})
+func (a userUtilsService) ParseToken(accessToken string, keys *keyring.Keyring) (model.Claims, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &model.Claims{}, keys.Keyfunc)
 
 	if err != nil {
-		return 0, err
+		return model.Claims{}, err
 	}
 
 	if claims, ok := token.Claims.(*model.Claims); ok && token.Valid {
-		return claims.ID, nil
+		return *claims, nil
 	}
 
-	return 0, model.ErrInvalidAccessToken
+	return model.Claims{}, model.ErrInvalidAccessToken
 }
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/reporegistry"
+	"github.com/djokcik/gophermart/internal/storage"
+	appContext "github.com/djokcik/gophermart/pkg/context"
+	helpers "github.com/djokcik/gophermart/pkg/helper"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/djokcik/gophermart/provider"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Gophermart-Signature"
+	EventHeader     = "X-Gophermart-Event"
+	DeliveryHeader  = "X-Gophermart-Delivery"
+
+	webhookBatchSize   = 50
+	webhookBaseBackoff = 10 * time.Second
+	webhookMaxBackoff  = time.Hour
+)
+
+//go:generate mockery --name=WebhookService
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, url string) (model.Webhook, error)
+	WebhooksByUser(ctx context.Context, userID int) ([]model.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID int, id int) error
+	DeliveriesByWebhook(ctx context.Context, userID int, webhookID int) ([]model.WebhookDelivery, error)
+	NotifyOrderStatus(ctx context.Context, order model.Order, accrual provider.AccrualResponse) error
+	Poller(ctx context.Context) func()
+}
+
+func NewWebhookService(cfg config.Config, registry reporegistry.RepoRegistry) WebhookService {
+	return &webhookService{
+		cfg:    cfg,
+		repo:   registry.GetWebhookRepo(),
+		client: provider.NewWebhookClient(),
+	}
+}
+
+type webhookService struct {
+	cfg    config.Config
+	repo   storage.WebhookRepository
+	client provider.WebhookClient
+}
+
+func (w webhookService) CreateWebhook(ctx context.Context, url string) (model.Webhook, error) {
+	user := appContext.User(ctx)
+	if user == nil {
+		w.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+		return model.Webhook{}, ErrNotAuthenticated
+	}
+
+	secret, err := helpers.RandomHex(32)
+	if err != nil {
+		w.Log(ctx).Error().Err(err).Msg("CreateWebhook: generate secret")
+		return model.Webhook{}, err
+	}
+
+	webhook, err := w.repo.CreateWebhook(ctx, model.Webhook{UserID: user.ID, URL: url, Secret: secret})
+	if err != nil {
+		w.Log(ctx).Error().Err(err).Msg("CreateWebhook:")
+		return model.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (w webhookService) WebhooksByUser(ctx context.Context, userID int) ([]model.Webhook, error) {
+	webhooks, err := w.repo.WebhooksByUserID(ctx, userID)
+	if err != nil {
+		w.Log(ctx).Error().Err(err).Msg("WebhooksByUser:")
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (w webhookService) DeleteWebhook(ctx context.Context, userID int, id int) error {
+	err := w.repo.DeleteWebhook(ctx, userID, id)
+	if err != nil {
+		w.Log(ctx).Trace().Err(err).Msg("DeleteWebhook:")
+		return err
+	}
+
+	return nil
+}
+
+func (w webhookService) DeliveriesByWebhook(ctx context.Context, userID int, webhookID int) ([]model.WebhookDelivery, error) {
+	deliveries, err := w.repo.DeliveriesByWebhookID(ctx, userID, webhookID)
+	if err != nil {
+		w.Log(ctx).Error().Err(err).Msg("DeliveriesByWebhook:")
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// NotifyOrderStatus enqueues a delivery for every webhook of the order owner
+// when the order reaches a final status
+func (w webhookService) NotifyOrderStatus(ctx context.Context, order model.Order, accrual provider.AccrualResponse) error {
+	if order.Status == accrual.Status {
+		return nil
+	}
+
+	if accrual.Status != model.StatusProcessed && accrual.Status != model.StatusInvalid {
+		return nil
+	}
+
+	payload, err := json.Marshal(model.OrderStatusEvent{
+		Event:   model.EventOrderStatusChanged,
+		OrderID: order.ID,
+		Status:  accrual.Status,
+		Accrual: accrual.Accrual,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = w.repo.CreateDeliveries(ctx, order.UserID, model.EventOrderStatusChanged, string(payload))
+	if err != nil {
+		w.Log(ctx).Error().Err(err).Msg("NotifyOrderStatus:")
+		return err
+	}
+
+	return nil
+}
+
+func (w webhookService) Poller(ctx context.Context) func() {
+	return func() {
+		deliveries, err := w.repo.ClaimDueDeliveries(ctx, webhookBatchSize)
+		if err != nil {
+			w.Log(ctx).Error().Err(err).Msg("Poller: failed claim deliveries")
+			return
+		}
+
+		for _, delivery := range deliveries {
+			w.deliver(ctx, delivery)
+		}
+	}
+}
+
+func (w webhookService) deliver(ctx context.Context, delivery model.WebhookDelivery) {
+	headers := map[string]string{
+		SignatureHeader: "sha256=" + Sign(delivery.Secret, []byte(delivery.Payload)),
+		EventHeader:     delivery.Event,
+		DeliveryHeader:  strconv.Itoa(delivery.ID),
+	}
+
+	delivery.Attempts++
+	delivery.LastError = ""
+
+	code, err := w.client.Send(ctx, delivery.URL, headers, []byte(delivery.Payload))
+	delivery.ResponseCode = code
+
+	switch {
+	case err == nil && code >= 200 && code < 300:
+		delivery.Status = model.DeliveryDelivered
+	case delivery.Attempts >= w.cfg.WebhookMaxAttempts:
+		delivery.Status = model.DeliveryFailed
+	default:
+		delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+	}
+
+	if err != nil {
+		delivery.LastError = err.Error()
+	} else if delivery.Status != model.DeliveryDelivered {
+		delivery.LastError = fmt.Sprintf("unexpected status code: %d", code)
+	}
+
+	if delivery.NextAttemptAt.IsZero() {
+		delivery.NextAttemptAt = time.Now()
+	}
+
+	err = w.repo.UpdateDelivery(ctx, delivery)
+	if err != nil {
+		w.Log(ctx).Error().Err(err).Msg("deliver: failed update delivery")
+	}
+}
+
+// webhookBackoff returns exponential delay before the next delivery attempt
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+
+	return backoff
+}
+
+// Sign returns hex encoded HMAC-SHA256 of the payload, receivers use it to check the delivery
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w webhookService) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "webhookService").Logger()
+
+	return &logger
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/config"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage/mocks"
+	"github.com/djokcik/gophermart/provider"
+	providerMocks "github.com/djokcik/gophermart/provider/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_webhookService_NotifyOrderStatus(t *testing.T) {
+	t.Run("should enqueue deliveries when order processed", func(t *testing.T) {
+		m := mocks.WebhookRepository{Mock: mock.Mock{}}
+		m.On("CreateDeliveries", mock.Anything, 666, model.EventOrderStatusChanged,
+			`{"event":"order.status_changed","order":"1","status":"PROCESSED","accrual":10}`).
+			Return(nil)
+
+		service := webhookService{repo: &m}
+
+		err := service.NotifyOrderStatus(
+			context.Background(),
+			model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing},
+			provider.AccrualResponse{Order: "1", Status: model.StatusProcessed, Accrual: 1000},
+		)
+
+		m.AssertNumberOfCalls(t, "CreateDeliveries", 1)
+		require.Equal(t, err, nil)
+	})
+
+	t.Run("should skip intermediate statuses", func(t *testing.T) {
+		m := mocks.WebhookRepository{Mock: mock.Mock{}}
+
+		service := webhookService{repo: &m}
+
+		err := service.NotifyOrderStatus(
+			context.Background(),
+			model.Order{ID: "1", UserID: 666, Status: model.StatusNew},
+			provider.AccrualResponse{Order: "1", Status: model.StatusProcessing},
+		)
+
+		m.AssertNumberOfCalls(t, "CreateDeliveries", 0)
+		require.Equal(t, err, nil)
+	})
+}
+
+func Test_webhookService_Poller(t *testing.T) {
+	t.Run("should send signed payload and mark delivery delivered", func(t *testing.T) {
+		delivery := model.WebhookDelivery{
+			ID:      1,
+			Event:   model.EventOrderStatusChanged,
+			Payload: "{}",
+			URL:     "http://localhost/hook",
+			Secret:  "secret",
+		}
+
+		repoMock := mocks.WebhookRepository{Mock: mock.Mock{}}
+		repoMock.On("ClaimDueDeliveries", mock.Anything, webhookBatchSize).
+			Return([]model.WebhookDelivery{delivery}, nil)
+		repoMock.On("UpdateDelivery", mock.Anything, mock.MatchedBy(func(d model.WebhookDelivery) bool {
+			return d.Status == model.DeliveryDelivered && d.Attempts == 1 && d.ResponseCode == 200
+		})).Return(nil)
+
+		clientMock := providerMocks.WebhookClient{Mock: mock.Mock{}}
+		clientMock.On("Send", mock.Anything, "http://localhost/hook", map[string]string{
+			SignatureHeader: "sha256=" + Sign("secret", []byte("{}")),
+			EventHeader:     model.EventOrderStatusChanged,
+			DeliveryHeader:  "1",
+		}, []byte("{}")).Return(200, nil)
+
+		service := webhookService{repo: &repoMock, client: &clientMock, cfg: config.Config{WebhookMaxAttempts: 3}}
+
+		service.Poller(context.Background())()
+
+		clientMock.AssertNumberOfCalls(t, "Send", 1)
+		repoMock.AssertNumberOfCalls(t, "UpdateDelivery", 1)
+	})
+
+	t.Run("should fail delivery when attempts are exhausted", func(t *testing.T) {
+		delivery := model.WebhookDelivery{ID: 1, Attempts: 2, Payload: "{}", URL: "http://localhost/hook"}
+
+		repoMock := mocks.WebhookRepository{Mock: mock.Mock{}}
+		repoMock.On("ClaimDueDeliveries", mock.Anything, webhookBatchSize).
+			Return([]model.WebhookDelivery{delivery}, nil)
+		repoMock.On("UpdateDelivery", mock.Anything, mock.MatchedBy(func(d model.WebhookDelivery) bool {
+			return d.Status == model.DeliveryFailed && d.Attempts == 3 && d.ResponseCode == 500
+		})).Return(nil)
+
+		clientMock := providerMocks.WebhookClient{Mock: mock.Mock{}}
+		clientMock.On("Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(500, nil)
+
+		service := webhookService{repo: &repoMock, client: &clientMock, cfg: config.Config{WebhookMaxAttempts: 3}}
+
+		service.Poller(context.Background())()
+
+		repoMock.AssertNumberOfCalls(t, "UpdateDelivery", 1)
+	})
+}
+
+func Test_webhookBackoff(t *testing.T) {
+	t.Run("should grow exponentially up to limit", func(t *testing.T) {
+		require.Equal(t, webhookBackoff(1), 10*time.Second)
+		require.Equal(t, webhookBackoff(3), 40*time.Second)
+		require.Equal(t, webhookBackoff(20), time.Hour)
+	})
+}
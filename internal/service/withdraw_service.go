@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/internal/reporegistry"
@@ -9,23 +10,46 @@ import (
 	appContext "github.com/djokcik/gophermart/pkg/context"
 	"github.com/djokcik/gophermart/pkg/logging"
 	"github.com/rs/zerolog"
+	"math"
+	"strconv"
+	"time"
 )
 
 //go:generate mockery --name=WithdrawService
 
 type WithdrawService interface {
-	ProcessWithdraw(ctx context.Context, orderID model.OrderID, sum model.Amount) error
+	// ProcessWithdraw debits the balance, sum above the confirmation threshold is only reserved
+	// and returned pending withdrawal has to be confirmed by ConfirmWithdraw
+	ProcessWithdraw(ctx context.Context, orderID model.OrderID, sum model.Amount) (*model.PendingWithdraw, error)
+	ConfirmWithdraw(ctx context.Context, id int, confirm model.WithdrawConfirmDto) error
+	// ReverseWithdraw returns points of cancelled store order, the admin is taken from the context
+	ReverseWithdraw(ctx context.Context, withdrawID int, reverse model.ReverseWithdrawDto) (model.WithdrawReversal, error)
+	// Cleaner returns reserved points of not confirmed withdrawals
+	Cleaner(ctx context.Context) func()
 	WithdrawLogsByUserID(ctx context.Context, userID int) ([]model.Withdraw, error)
 	AmountWithdrawByUser(ctx context.Context, userID int) (model.Amount, error)
 }
 
-func NewWithdrawService(cfg config.Config, registry reporegistry.RepoRegistry) WithdrawService {
-	return &withdrawService{cfg: cfg, repo: registry.GetWithdrawRepo()}
+func NewWithdrawService(cfg config.Config, registry reporegistry.RepoRegistry, events EventService) WithdrawService {
+	return &withdrawService{
+		cfg:       cfg,
+		repo:      registry.GetWithdrawRepo(),
+		orders:    registry.GetOrderRepo(),
+		events:    events,
+		users:     NewUserService(cfg, registry),
+		twoFactor: NewTwoFactorService(cfg, registry),
+		auditLog:  NewAuditService(cfg, registry),
+	}
 }
 
 type withdrawService struct {
-	cfg  config.Config
-	repo storage.WithdrawRepository
+	cfg       config.Config
+	repo      storage.WithdrawRepository
+	orders    storage.OrderRepository
+	events    EventService
+	users     UserService
+	twoFactor TwoFactorService
+	auditLog  AuditService
 }
 
 func (o withdrawService) AmountWithdrawByUser(ctx context.Context, userID int) (model.Amount, error) {
@@ -48,22 +72,170 @@ func (o withdrawService) WithdrawLogsByUserID(ctx context.Context, userID int) (
 	return withdrawLogs, nil
 }
 
-func (o withdrawService) ProcessWithdraw(ctx context.Context, orderID model.OrderID, sum model.Amount) error {
+func (o withdrawService) ProcessWithdraw(ctx context.Context, orderID model.OrderID, sum model.Amount) (*model.PendingWithdraw, error) {
 	user := appContext.User(ctx)
 	if user == nil {
 		o.Log(ctx).Err(ErrNotAuthenticated).Msg("")
-		return ErrNotAuthenticated
+		return nil, ErrNotAuthenticated
+	}
+
+	if err := o.checkOrderPolicy(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	withdraw := model.Withdraw{OrderID: orderID, Sum: sum, UserID: user.ID}
+
+	if o.requiresConfirmation(sum) {
+		pending, err := o.repo.CreatePendingWithdraw(ctx, withdraw, time.Now().Add(o.cfg.WithdrawConfirmTTL))
+		if err != nil {
+			o.Log(ctx).Warn().Err(err).Msg("ProcessWithdraw: create pending withdraw")
+			return nil, err
+		}
+
+		o.audit(ctx, "withdrawal_pending", pending.ID, map[string]interface{}{"order": orderID, "sum": sum})
+		o.publishBalance(ctx, user.ID)
+
+		return &pending, nil
 	}
 
-	err := o.repo.ProcessWithdraw(ctx, model.Withdraw{OrderID: orderID, Sum: sum, UserID: user.ID})
+	err := o.repo.ProcessWithdraw(ctx, withdraw)
 	if err != nil {
 		o.Log(ctx).Warn().Err(err).Msg("ProcessWithdraw:")
+		return nil, err
+	}
+
+	o.auditLog.Record(ctx, model.AuditEntry{
+		Action:     "withdrawal",
+		TargetType: model.AuditTargetOrder,
+		TargetID:   string(orderID),
+		Details:    map[string]interface{}{"sum": sum},
+	})
+	o.publishBalance(ctx, user.ID)
+
+	return nil, nil
+}
+
+// checkOrderPolicy denies to pay with points for the order uploaded for accrual when numbers are exclusive
+func (o withdrawService) checkOrderPolicy(ctx context.Context, orderID model.OrderID) error {
+	if o.cfg.WithdrawOrderPolicy != config.WithdrawOrderExclusive {
+		return nil
+	}
+
+	_, err := o.orders.OrderByID(ctx, orderID)
+	if err == nil {
+		o.Log(ctx).Trace().Err(ErrOrderUploadedForAccrual).Str("orderID", string(orderID)).Msg("")
+		return ErrOrderUploadedForAccrual
+	}
+
+	if !errors.Is(err, storage.ErrNotFound) {
+		o.Log(ctx).Error().Err(err).Msg("checkOrderPolicy:")
 		return err
 	}
 
 	return nil
 }
 
+func (o withdrawService) requiresConfirmation(sum model.Amount) bool {
+	threshold := model.Amount(math.Round(o.cfg.WithdrawConfirmThreshold * 100))
+
+	return threshold > 0 && sum > threshold
+}
+
+// ConfirmWithdraw accepts the password or 2fa code of the user, the code is checked when both are given
+func (o withdrawService) ConfirmWithdraw(ctx context.Context, id int, confirm model.WithdrawConfirmDto) error {
+	user := appContext.User(ctx)
+	if user == nil {
+		o.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+		return ErrNotAuthenticated
+	}
+
+	var err error
+	if confirm.Code != "" {
+		err = o.twoFactor.Validate(ctx, user.ID, confirm.Code)
+	} else {
+		err = o.users.VerifyPassword(ctx, *user, confirm.Password)
+	}
+
+	if err != nil {
+		o.Log(ctx).Trace().Err(err).Msg("ConfirmWithdraw: invalid confirmation")
+		return err
+	}
+
+	withdraw, err := o.repo.ConfirmPendingWithdraw(ctx, user.ID, id)
+	if err != nil {
+		o.Log(ctx).Warn().Err(err).Msg("ConfirmWithdraw:")
+		return err
+	}
+
+	o.audit(ctx, "withdrawal_confirmed", id, map[string]interface{}{"order": withdraw.OrderID, "sum": withdraw.Sum})
+
+	return nil
+}
+
+func (o withdrawService) ReverseWithdraw(ctx context.Context, withdrawID int, reverse model.ReverseWithdrawDto) (model.WithdrawReversal, error) {
+	admin := appContext.User(ctx)
+	if admin == nil {
+		o.Log(ctx).Err(ErrNotAuthenticated).Msg("")
+		return model.WithdrawReversal{}, ErrNotAuthenticated
+	}
+
+	reversal, err := o.repo.ReverseWithdraw(ctx, model.WithdrawReversal{
+		WithdrawID: withdrawID,
+		Sum:        reverse.Sum,
+		Reason:     reverse.Reason,
+		AdminID:    admin.ID,
+		ExpiresAt:  o.cfg.PointsExpireAt(time.Now()),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrOwnWithdraw) {
+			return model.WithdrawReversal{}, ErrAdminSelfAction
+		}
+
+		o.Log(ctx).Warn().Err(err).Msg("ReverseWithdraw:")
+		return model.WithdrawReversal{}, err
+	}
+
+	o.audit(ctx, "withdraw_reversal", withdrawID, map[string]interface{}{
+		"userID": reversal.UserID,
+		"sum":    reversal.Sum,
+		"reason": reversal.Reason,
+	})
+
+	o.publishBalance(ctx, reversal.UserID)
+
+	return reversal, nil
+}
+
+func (o withdrawService) Cleaner(ctx context.Context) func() {
+	return func() {
+		released, err := o.repo.ReleaseExpiredWithdrawals(ctx)
+		if err != nil {
+			o.Log(ctx).Error().Err(err).Msg("Cleaner: failed release expired withdrawals")
+			return
+		}
+
+		for _, pending := range released {
+			o.audit(ctx, "withdrawal_expired", pending.ID, map[string]interface{}{"userID": pending.UserID, "sum": pending.Sum})
+			o.publishBalance(ctx, pending.UserID)
+		}
+	}
+}
+
+func (o withdrawService) audit(ctx context.Context, action string, withdrawID int, details map[string]interface{}) {
+	o.auditLog.Record(ctx, model.AuditEntry{
+		Action:     action,
+		TargetType: model.AuditTargetWithdrawal,
+		TargetID:   strconv.Itoa(withdrawID),
+		Details:    details,
+	})
+}
+
+func (o withdrawService) publishBalance(ctx context.Context, userID int) {
+	if err := o.events.PublishBalance(ctx, userID); err != nil {
+		o.Log(ctx).Error().Err(err).Msg("publish balance")
+	}
+}
+
 func (o withdrawService) Log(ctx context.Context) *zerolog.Logger {
 	_, logger := logging.GetCtxLogger(ctx)
 	logger = logger.With().Str(logging.ServiceKey, "withdrawService").Logger()
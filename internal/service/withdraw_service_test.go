@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/model"
+	serviceMocks "github.com/djokcik/gophermart/internal/service/mocks"
+	"github.com/djokcik/gophermart/internal/storage"
 	"github.com/djokcik/gophermart/internal/storage/mocks"
 	appContext "github.com/djokcik/gophermart/pkg/context"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func Test_withdrawService_AmountWithdrawByUser(t *testing.T) {
@@ -51,13 +55,204 @@ func Test_withdrawService_ProcessWithdraw(t *testing.T) {
 		m.On("ProcessWithdraw", mock.Anything, model.Withdraw{OrderID: "1", Sum: 1000, UserID: 666}).
 			Return(nil)
 
-		service := withdrawService{repo: &m}
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := withdrawService{repo: &m, events: &eventsMock, auditLog: newAuditMock()}
 
 		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
 
-		err := service.ProcessWithdraw(ctx, "1", 1000)
+		pending, err := service.ProcessWithdraw(ctx, "1", 1000)
 
 		m.AssertNumberOfCalls(t, "ProcessWithdraw", 1)
+		eventsMock.AssertNumberOfCalls(t, "PublishBalance", 1)
+		require.Equal(t, err, nil)
+		require.Nil(t, pending)
+	})
+
+	t.Run("should create pending withdraw above threshold", func(t *testing.T) {
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		m.On("CreatePendingWithdraw", mock.Anything, model.Withdraw{OrderID: "1", Sum: 50001, UserID: 666}, mock.Anything).
+			Return(model.PendingWithdraw{ID: 7, OrderID: "1", Sum: 50001, UserID: 666}, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := withdrawService{
+			repo:     &m,
+			events:   &eventsMock,
+			cfg:      config.Config{WithdrawConfirmThreshold: 500, WithdrawConfirmTTL: time.Minute},
+			auditLog: newAuditMock(),
+		}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		pending, err := service.ProcessWithdraw(ctx, "1", 50001)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, pending, &model.PendingWithdraw{ID: 7, OrderID: "1", Sum: 50001, UserID: 666})
+		m.AssertNumberOfCalls(t, "ProcessWithdraw", 0)
+
+		expiresAt := m.Calls[0].Arguments.Get(2).(time.Time)
+		require.WithinDuration(t, expiresAt, time.Now().Add(time.Minute), time.Second)
+	})
+
+	t.Run("should debit sum equal to threshold immediately", func(t *testing.T) {
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		m.On("ProcessWithdraw", mock.Anything, model.Withdraw{OrderID: "1", Sum: 50000, UserID: 666}).Return(nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := withdrawService{repo: &m, events: &eventsMock, cfg: config.Config{WithdrawConfirmThreshold: 500}, auditLog: newAuditMock()}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		pending, err := service.ProcessWithdraw(ctx, "1", 50000)
+
+		require.Equal(t, err, nil)
+		require.Nil(t, pending)
+	})
+}
+
+func Test_withdrawService_ProcessWithdraw_OrderPolicy(t *testing.T) {
+	t.Run("should reject order uploaded for accrual when numbers are exclusive", func(t *testing.T) {
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+
+		ordersMock := mocks.OrderRepository{Mock: mock.Mock{}}
+		ordersMock.On("OrderByID", mock.Anything, model.OrderID("1")).Return(model.Order{ID: "1", UserID: 666}, nil)
+
+		service := withdrawService{
+			repo:     &m,
+			orders:   &ordersMock,
+			cfg:      config.Config{WithdrawOrderPolicy: config.WithdrawOrderExclusive},
+			auditLog: newAuditMock(),
+		}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		_, err := service.ProcessWithdraw(ctx, "1", 1000)
+
+		require.Equal(t, err, ErrOrderUploadedForAccrual)
+		m.AssertNumberOfCalls(t, "ProcessWithdraw", 0)
+	})
+
+	t.Run("should not check orders when numbers are shared", func(t *testing.T) {
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		m.On("ProcessWithdraw", mock.Anything, mock.Anything).Return(nil)
+
+		ordersMock := mocks.OrderRepository{Mock: mock.Mock{}}
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := withdrawService{
+			repo:     &m,
+			orders:   &ordersMock,
+			events:   &eventsMock,
+			cfg:      config.Config{WithdrawOrderPolicy: config.WithdrawOrderShared},
+			auditLog: newAuditMock(),
+		}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		_, err := service.ProcessWithdraw(ctx, "1", 1000)
+
 		require.Equal(t, err, nil)
+		ordersMock.AssertNumberOfCalls(t, "OrderByID", 0)
+	})
+}
+
+func Test_withdrawService_ConfirmWithdraw(t *testing.T) {
+	t.Run("should confirm with password", func(t *testing.T) {
+		user := model.User{ID: 666}
+
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		m.On("ConfirmPendingWithdraw", mock.Anything, 666, 7).Return(model.Withdraw{ID: 1, OrderID: "1", Sum: 50001}, nil)
+
+		usersMock := serviceMocks.UserService{Mock: mock.Mock{}}
+		usersMock.On("VerifyPassword", mock.Anything, user, "password").Return(nil)
+
+		service := withdrawService{repo: &m, users: &usersMock, auditLog: newAuditMock()}
+
+		ctx := appContext.WithUser(context.Background(), &user)
+
+		err := service.ConfirmWithdraw(ctx, 7, model.WithdrawConfirmDto{Password: "password"})
+
+		require.Equal(t, err, nil)
+		m.AssertNumberOfCalls(t, "ConfirmPendingWithdraw", 1)
+	})
+
+	t.Run("should not confirm with invalid code", func(t *testing.T) {
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+
+		twoFactorMock := serviceMocks.TwoFactorService{Mock: mock.Mock{}}
+		twoFactorMock.On("Validate", mock.Anything, 666, "000000").Return(ErrInvalidTwoFactorCode)
+
+		service := withdrawService{repo: &m, twoFactor: &twoFactorMock, auditLog: newAuditMock()}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 666})
+
+		err := service.ConfirmWithdraw(ctx, 7, model.WithdrawConfirmDto{Code: "000000"})
+
+		require.Equal(t, err, ErrInvalidTwoFactorCode)
+		m.AssertNumberOfCalls(t, "ConfirmPendingWithdraw", 0)
+	})
+}
+
+func Test_withdrawService_ReverseWithdraw(t *testing.T) {
+	t.Run("should reverse withdraw and publish balance of the user", func(t *testing.T) {
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		m.On("ReverseWithdraw", mock.Anything, mock.MatchedBy(func(reversal model.WithdrawReversal) bool {
+			return reversal.WithdrawID == 7 && reversal.Sum == 500 && reversal.AdminID == 1 &&
+				reversal.ExpiresAt.After(time.Now().AddDate(0, 11, 0))
+		})).
+			Return(model.WithdrawReversal{ID: 3, WithdrawID: 7, UserID: 666, Sum: 500, Reason: "cancelled", AdminID: 1}, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := withdrawService{cfg: config.Config{PointsLifetimeMonths: 12}, repo: &m, events: &eventsMock, auditLog: newAuditMock()}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 1})
+
+		reversal, err := service.ReverseWithdraw(ctx, 7, model.ReverseWithdrawDto{Sum: 500, Reason: "cancelled"})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, reversal.ID, 3)
+		eventsMock.AssertNumberOfCalls(t, "PublishBalance", 1)
+	})
+
+	t.Run("should not reverse own withdrawal", func(t *testing.T) {
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		m.On("ReverseWithdraw", mock.Anything, mock.Anything).Return(model.WithdrawReversal{}, storage.ErrOwnWithdraw)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+
+		service := withdrawService{cfg: config.Config{PointsLifetimeMonths: 12}, repo: &m, events: &eventsMock, auditLog: newAuditMock()}
+
+		ctx := appContext.WithUser(context.Background(), &model.User{ID: 1})
+
+		_, err := service.ReverseWithdraw(ctx, 7, model.ReverseWithdrawDto{Reason: "cancelled"})
+
+		require.Equal(t, err, ErrAdminSelfAction)
+		eventsMock.AssertNotCalled(t, "PublishBalance", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_withdrawService_Cleaner(t *testing.T) {
+	t.Run("should publish balance of released withdrawals", func(t *testing.T) {
+		m := mocks.WithdrawRepository{Mock: mock.Mock{}}
+		m.On("ReleaseExpiredWithdrawals", mock.Anything).
+			Return([]model.PendingWithdraw{{ID: 7, UserID: 666, Sum: 50001}}, nil)
+
+		eventsMock := serviceMocks.EventService{Mock: mock.Mock{}}
+		eventsMock.On("PublishBalance", mock.Anything, 666).Return(nil)
+
+		service := withdrawService{repo: &m, events: &eventsMock, auditLog: newAuditMock()}
+
+		service.Cleaner(context.Background())()
+
+		eventsMock.AssertNumberOfCalls(t, "PublishBalance", 1)
 	})
 }
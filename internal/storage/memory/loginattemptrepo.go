@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"sync"
+	"time"
+)
+
+// NewLoginAttemptRepository keeps attempts in process memory, suitable for a single instance only
+func NewLoginAttemptRepository() storage.LoginAttemptRepository {
+	return &loginAttemptRepository{attempts: make(map[string]model.LoginAttempt)}
+}
+
+type loginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]model.LoginAttempt
+}
+
+func (r *loginAttemptRepository) LoginAttempt(_ context.Context, key string) (model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return model.LoginAttempt{Key: key}, nil
+	}
+
+	return attempt, nil
+}
+
+func (r *loginAttemptRepository) RegisterFailure(_ context.Context, key string, window time.Duration) (model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	attempt, ok := r.attempts[key]
+	if !ok || attempt.FirstFailedAt.Before(now.Add(-window)) {
+		attempt = model.LoginAttempt{Key: key, FirstFailedAt: now, BlockedUntil: attempt.BlockedUntil}
+	}
+
+	attempt.Failures++
+	r.attempts[key] = attempt
+
+	return attempt, nil
+}
+
+func (r *loginAttemptRepository) Block(_ context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempt, ok := r.attempts[key]; ok {
+		attempt.BlockedUntil = until
+		r.attempts[key] = attempt
+	}
+
+	return nil
+}
+
+func (r *loginAttemptRepository) ResetLoginAttempts(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+
+	return nil
+}
+
+func (r *loginAttemptRepository) DeleteExpiredLoginAttempts(_ context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, attempt := range r.attempts {
+		if attempt.FirstFailedAt.Before(before) && attempt.BlockedUntil.Before(now) {
+			delete(r.attempts, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"context"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_loginAttemptRepository_RegisterFailure(t *testing.T) {
+	t.Run("should start over after window", func(t *testing.T) {
+		repo := &loginAttemptRepository{attempts: make(map[string]model.LoginAttempt)}
+		ctx := context.Background()
+
+		repo.RegisterFailure(ctx, "login:user", time.Minute)
+		attempt, _ := repo.RegisterFailure(ctx, "login:user", time.Minute)
+		require.Equal(t, attempt.Failures, 2)
+
+		attempt, _ = repo.RegisterFailure(ctx, "login:user", 0)
+		require.Equal(t, attempt.Failures, 1)
+	})
+}
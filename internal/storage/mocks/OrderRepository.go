@@ -4,11 +4,11 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	model "github.com/djokcik/gophermart/internal/model"
-	mock "github.com/stretchr/testify/mock"
-
 	provider "github.com/djokcik/gophermart/provider"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // OrderRepository is an autogenerated mock type for the OrderRepository type
@@ -16,6 +16,64 @@ type OrderRepository struct {
 	mock.Mock
 }
 
+// AccrualVolume provides a mock function with given fields: ctx, userID, since
+func (_m *OrderRepository) AccrualVolume(ctx context.Context, userID int, since time.Time) (model.Amount, error) {
+	ret := _m.Called(ctx, userID, since)
+
+	var r0 model.Amount
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) model.Amount); ok {
+		r0 = rf(ctx, userID, since)
+	} else {
+		r0 = ret.Get(0).(model.Amount)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, userID, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AccrualVolumes provides a mock function with given fields: ctx, since
+func (_m *OrderRepository) AccrualVolumes(ctx context.Context, since time.Time) ([]model.AccrualVolume, error) {
+	ret := _m.Called(ctx, since)
+
+	var r0 []model.AccrualVolume
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) []model.AccrualVolume); ok {
+		r0 = rf(ctx, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.AccrualVolume)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, since)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CompleteBonuses provides a mock function with given fields: ctx, id
+func (_m *OrderRepository) CompleteBonuses(ctx context.Context, id model.OrderID) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, model.OrderID) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateOrder provides a mock function with given fields: ctx, order
 func (_m *OrderRepository) CreateOrder(ctx context.Context, order model.Order) error {
 	ret := _m.Called(ctx, order)
@@ -30,6 +88,29 @@ func (_m *OrderRepository) CreateOrder(ctx context.Context, order model.Order) e
 	return r0
 }
 
+// CreateOrders provides a mock function with given fields: ctx, userID, ids
+func (_m *OrderRepository) CreateOrders(ctx context.Context, userID int, ids []model.OrderID) ([]model.OrderUploadResult, error) {
+	ret := _m.Called(ctx, userID, ids)
+
+	var r0 []model.OrderUploadResult
+	if rf, ok := ret.Get(0).(func(context.Context, int, []model.OrderID) []model.OrderUploadResult); ok {
+		r0 = rf(ctx, userID, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.OrderUploadResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int, []model.OrderID) error); ok {
+		r1 = rf(ctx, userID, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // OrderByID provides a mock function with given fields: ctx, id
 func (_m *OrderRepository) OrderByID(ctx context.Context, id model.OrderID) (model.Order, error) {
 	ret := _m.Called(ctx, id)
@@ -97,13 +178,57 @@ func (_m *OrderRepository) OrdersByUserID(ctx context.Context, userID int) ([]mo
 	return r0, r1
 }
 
-// UpdateForAccrual provides a mock function with given fields: ctx, order, accrual
-func (_m *OrderRepository) UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse) error {
-	ret := _m.Called(ctx, order, accrual)
+// OverrideOrder provides a mock function with given fields: ctx, id, status, accrual, expiresAt
+func (_m *OrderRepository) OverrideOrder(ctx context.Context, id model.OrderID, status model.Status, accrual model.Amount, expiresAt time.Time) (model.OrderOverride, error) {
+	ret := _m.Called(ctx, id, status, accrual, expiresAt)
+
+	var r0 model.OrderOverride
+	if rf, ok := ret.Get(0).(func(context.Context, model.OrderID, model.Status, model.Amount, time.Time) model.OrderOverride); ok {
+		r0 = rf(ctx, id, status, accrual, expiresAt)
+	} else {
+		r0 = ret.Get(0).(model.OrderOverride)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.OrderID, model.Status, model.Amount, time.Time) error); ok {
+		r1 = rf(ctx, id, status, accrual, expiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PendingBonusOrders provides a mock function with given fields: ctx, limit
+func (_m *OrderRepository) PendingBonusOrders(ctx context.Context, limit int) ([]model.Order, error) {
+	ret := _m.Called(ctx, limit)
+
+	var r0 []model.Order
+	if rf, ok := ret.Get(0).(func(context.Context, int) []model.Order); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Order)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateForAccrual provides a mock function with given fields: ctx, order, accrual, expiresAt
+func (_m *OrderRepository) UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse, expiresAt time.Time) error {
+	ret := _m.Called(ctx, order, accrual, expiresAt)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, model.Order, provider.AccrualResponse) error); ok {
-		r0 = rf(ctx, order, accrual)
+	if rf, ok := ret.Get(0).(func(context.Context, model.Order, provider.AccrualResponse, time.Time) error); ok {
+		r0 = rf(ctx, order, accrual, expiresAt)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -28,6 +28,143 @@ func (_m *UserRepository) CreateUser(ctx context.Context, user model.User) error
 	return r0
 }
 
+// DeleteUser provides a mock function with given fields: ctx, userID
+func (_m *UserRepository) DeleteUser(ctx context.Context, userID int) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LockUser provides a mock function with given fields: ctx, id, reason
+func (_m *UserRepository) LockUser(ctx context.Context, id int, reason string) error {
+	ret := _m.Called(ctx, id, reason)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) error); ok {
+		r0 = rf(ctx, id, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PepperUsage provides a mock function with given fields: ctx
+func (_m *UserRepository) PepperUsage(ctx context.Context) ([]model.PepperUsage, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []model.PepperUsage
+	if rf, ok := ret.Get(0).(func(context.Context) []model.PepperUsage); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.PepperUsage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PromoteAdmins provides a mock function with given fields: ctx, usernames
+func (_m *UserRepository) PromoteAdmins(ctx context.Context, usernames []string) (int, error) {
+	ret := _m.Called(ctx, usernames)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, []string) int); ok {
+		r0 = rf(ctx, usernames)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, usernames)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchUsers provides a mock function with given fields: ctx, query, limit
+func (_m *UserRepository) SearchUsers(ctx context.Context, query string, limit int) ([]model.UserSummary, error) {
+	ret := _m.Called(ctx, query, limit)
+
+	var r0 []model.UserSummary
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []model.UserSummary); ok {
+		r0 = rf(ctx, query, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.UserSummary)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, query, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRole provides a mock function with given fields: ctx, id, role
+func (_m *UserRepository) SetRole(ctx context.Context, id int, role model.Role) error {
+	ret := _m.Called(ctx, id, role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, model.Role) error); ok {
+		r0 = rf(ctx, id, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnlockUser provides a mock function with given fields: ctx, id
+func (_m *UserRepository) UnlockUser(ctx context.Context, id int) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdatePassword provides a mock function with given fields: ctx, userID, hash, pepperID
+func (_m *UserRepository) UpdatePassword(ctx context.Context, userID int, hash string, pepperID string) error {
+	ret := _m.Called(ctx, userID, hash, pepperID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string) error); ok {
+		r0 = rf(ctx, userID, hash, pepperID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UserByID provides a mock function with given fields: ctx, id
 func (_m *UserRepository) UserByID(ctx context.Context, id int) (model.User, error) {
 	ret := _m.Called(ctx, id)
@@ -69,3 +206,24 @@ func (_m *UserRepository) UserByUsername(ctx context.Context, username string) (
 
 	return r0, r1
 }
+
+// UserSummary provides a mock function with given fields: ctx, id
+func (_m *UserRepository) UserSummary(ctx context.Context, id int) (model.UserSummary, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 model.UserSummary
+	if rf, ok := ret.Get(0).(func(context.Context, int) model.UserSummary); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(model.UserSummary)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
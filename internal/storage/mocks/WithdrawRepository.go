@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	model "github.com/djokcik/gophermart/internal/model"
 	mock "github.com/stretchr/testify/mock"
@@ -35,6 +36,71 @@ func (_m *WithdrawRepository) AmountWithdrawByUser(ctx context.Context, userID i
 	return r0, r1
 }
 
+// ConfirmPendingWithdraw provides a mock function with given fields: ctx, userID, id
+func (_m *WithdrawRepository) ConfirmPendingWithdraw(ctx context.Context, userID int, id int) (model.Withdraw, error) {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 model.Withdraw
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) model.Withdraw); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		r0 = ret.Get(0).(model.Withdraw)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, userID, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreatePendingWithdraw provides a mock function with given fields: ctx, withdraw, expiresAt
+func (_m *WithdrawRepository) CreatePendingWithdraw(ctx context.Context, withdraw model.Withdraw, expiresAt time.Time) (model.PendingWithdraw, error) {
+	ret := _m.Called(ctx, withdraw, expiresAt)
+
+	var r0 model.PendingWithdraw
+	if rf, ok := ret.Get(0).(func(context.Context, model.Withdraw, time.Time) model.PendingWithdraw); ok {
+		r0 = rf(ctx, withdraw, expiresAt)
+	} else {
+		r0 = ret.Get(0).(model.PendingWithdraw)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.Withdraw, time.Time) error); ok {
+		r1 = rf(ctx, withdraw, expiresAt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PaidOrderIDs provides a mock function with given fields: ctx, ids
+func (_m *WithdrawRepository) PaidOrderIDs(ctx context.Context, ids []model.OrderID) ([]model.OrderID, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 []model.OrderID
+	if rf, ok := ret.Get(0).(func(context.Context, []model.OrderID) []model.OrderID); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.OrderID)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []model.OrderID) error); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ProcessWithdraw provides a mock function with given fields: ctx, withdraw
 func (_m *WithdrawRepository) ProcessWithdraw(ctx context.Context, withdraw model.Withdraw) error {
 	ret := _m.Called(ctx, withdraw)
@@ -49,6 +115,73 @@ func (_m *WithdrawRepository) ProcessWithdraw(ctx context.Context, withdraw mode
 	return r0
 }
 
+// ReleaseExpiredWithdrawals provides a mock function with given fields: ctx
+func (_m *WithdrawRepository) ReleaseExpiredWithdrawals(ctx context.Context) ([]model.PendingWithdraw, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []model.PendingWithdraw
+	if rf, ok := ret.Get(0).(func(context.Context) []model.PendingWithdraw); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.PendingWithdraw)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReverseWithdraw provides a mock function with given fields: ctx, reversal
+func (_m *WithdrawRepository) ReverseWithdraw(ctx context.Context, reversal model.WithdrawReversal) (model.WithdrawReversal, error) {
+	ret := _m.Called(ctx, reversal)
+
+	var r0 model.WithdrawReversal
+	if rf, ok := ret.Get(0).(func(context.Context, model.WithdrawReversal) model.WithdrawReversal); ok {
+		r0 = rf(ctx, reversal)
+	} else {
+		r0 = ret.Get(0).(model.WithdrawReversal)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.WithdrawReversal) error); ok {
+		r1 = rf(ctx, reversal)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WithdrawLogs provides a mock function with given fields: ctx, afterID, limit
+func (_m *WithdrawRepository) WithdrawLogs(ctx context.Context, afterID int, limit int) ([]model.Withdraw, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 []model.Withdraw
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) []model.Withdraw); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.Withdraw)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WithdrawLogsByUserID provides a mock function with given fields: ctx, userID
 func (_m *WithdrawRepository) WithdrawLogsByUserID(ctx context.Context, userID int) ([]model.Withdraw, error) {
 	ret := _m.Called(ctx, userID)
@@ -0,0 +1,280 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const adjustmentColumns = "id, user_id, sum, reason_code, comment, status, COALESCE(created_by, 0), COALESCE(decided_by, 0), created_at, decided_at"
+
+func NewAdjustmentRepository(db *sql.DB) storage.AdjustmentRepository {
+	return &adjustmentRepository{db: db}
+}
+
+type adjustmentRepository struct {
+	db *sql.DB
+}
+
+func (r adjustmentRepository) CreateAdjustment(ctx context.Context, adjustment model.Adjustment, threshold model.Amount, since time.Time, expiresAt time.Time) (model.Adjustment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CreateAdjustment: prepare transaction")
+		return model.Adjustment{}, err
+	}
+
+	// the user lock serializes adjustments of the user, so parallel requests can't all fit the threshold
+	apply, err := r.fitsThreshold(ctx, tx, adjustment, threshold, since)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CreateAdjustment: unable to rollback")
+		}
+		return model.Adjustment{}, err
+	}
+
+	row := tx.QueryRowContext(ctx, `INSERT INTO balance_adjustments (user_id, sum, reason_code, comment, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+adjustmentColumns,
+		adjustment.UserID, adjustment.Sum, adjustment.ReasonCode, adjustment.Comment, model.AdjustmentPending, adjustment.CreatedBy)
+
+	adjustment, err = scanAdjustment(row)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CreateAdjustment: exec balance_adjustments")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CreateAdjustment: unable to rollback")
+		}
+		return model.Adjustment{}, err
+	}
+
+	if apply {
+		adjustment, err = r.applyAdjustment(ctx, tx, adjustment, adjustment.CreatedBy, expiresAt)
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("CreateAdjustment: unable to rollback")
+			}
+			return model.Adjustment{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("CreateAdjustment: unable to commit")
+		return model.Adjustment{}, err
+	}
+
+	return adjustment, nil
+}
+
+func (r adjustmentRepository) ApproveAdjustment(ctx context.Context, id int, approverID int, expiresAt time.Time) (model.Adjustment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ApproveAdjustment: prepare transaction")
+		return model.Adjustment{}, err
+	}
+
+	adjustment, err := r.pendingAdjustment(ctx, tx, id)
+	if err == nil {
+		adjustment, err = r.applyAdjustment(ctx, tx, adjustment, approverID, expiresAt)
+	}
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ApproveAdjustment: unable to rollback")
+		}
+		return model.Adjustment{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("ApproveAdjustment: unable to commit")
+		return model.Adjustment{}, err
+	}
+
+	return adjustment, nil
+}
+
+func (r adjustmentRepository) RejectAdjustment(ctx context.Context, id int, deciderID int) (model.Adjustment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("RejectAdjustment: prepare transaction")
+		return model.Adjustment{}, err
+	}
+
+	adjustment, err := r.pendingAdjustment(ctx, tx, id)
+	if err == nil {
+		adjustment, err = r.decideAdjustment(ctx, tx, adjustment.ID, model.AdjustmentRejected, deciderID)
+	}
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("RejectAdjustment: unable to rollback")
+		}
+		return model.Adjustment{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("RejectAdjustment: unable to commit")
+		return model.Adjustment{}, err
+	}
+
+	return adjustment, nil
+}
+
+func (r adjustmentRepository) Adjustment(ctx context.Context, id int) (model.Adjustment, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+adjustmentColumns+" FROM balance_adjustments WHERE id = $1", id)
+
+	adjustment, err := scanAdjustment(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Adjustment{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("Adjustment: invalid scan")
+		return model.Adjustment{}, err
+	}
+
+	return adjustment, nil
+}
+
+func (r adjustmentRepository) Adjustments(ctx context.Context, status model.AdjustmentStatus) ([]model.Adjustment, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+adjustmentColumns+` FROM balance_adjustments
+		WHERE $1 = '' OR status = $1 ORDER BY created_at`, status)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("Adjustments: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	adjustments := make([]model.Adjustment, 0)
+	for rows.Next() {
+		adjustment, err := scanAdjustment(rows)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("Adjustments: invalid scan")
+			return nil, err
+		}
+
+		adjustments = append(adjustments, adjustment)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("Adjustments: query rows was error")
+		return nil, err
+	}
+
+	return adjustments, nil
+}
+
+// fitsThreshold locks the user and checks the sum together with adjustments the author applied
+// to the user without approval since the time
+func (r adjustmentRepository) fitsThreshold(ctx context.Context, tx *sql.Tx, adjustment model.Adjustment, threshold model.Amount, since time.Time) (bool, error) {
+	if _, err := lockUsers(ctx, tx, adjustment.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("fitsThreshold: lock user")
+		return false, err
+	}
+
+	row := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(ABS(sum)), 0) FROM balance_adjustments
+		WHERE user_id = $1 AND created_by = $2 AND decided_by = $2 AND status = $3 AND created_at >= $4`,
+		adjustment.UserID, adjustment.CreatedBy, model.AdjustmentApplied, since)
+
+	var applied model.Amount
+	if err := row.Scan(&applied); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("fitsThreshold: select applied")
+		return false, err
+	}
+
+	sum := adjustment.Sum
+	if sum < 0 {
+		sum = -sum
+	}
+
+	return sum+applied <= threshold, nil
+}
+
+// pendingAdjustment locks the adjustment, so concurrent approvals can't apply it twice
+func (r adjustmentRepository) pendingAdjustment(ctx context.Context, tx *sql.Tx, id int) (model.Adjustment, error) {
+	row := tx.QueryRowContext(ctx, "SELECT "+adjustmentColumns+" FROM balance_adjustments WHERE id = $1 FOR UPDATE", id)
+
+	adjustment, err := scanAdjustment(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Adjustment{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("pendingAdjustment: invalid scan")
+		return model.Adjustment{}, err
+	}
+
+	if adjustment.Status != model.AdjustmentPending {
+		return model.Adjustment{}, storage.ErrAdjustmentDecided
+	}
+
+	return adjustment, nil
+}
+
+// applyAdjustment changes the balance the same way as accruals and withdrawals: credit adds a point lot,
+// debit consumes lots which expire first
+func (r adjustmentRepository) applyAdjustment(ctx context.Context, tx *sql.Tx, adjustment model.Adjustment, deciderID int, expiresAt time.Time) (model.Adjustment, error) {
+	balances, err := lockUsers(ctx, tx, adjustment.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Adjustment{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("applyAdjustment: lock user")
+		return model.Adjustment{}, err
+	}
+
+	if adjustment.Sum > 0 {
+		err = addPointLot(ctx, tx, adjustment.UserID, adjustment.Sum, model.LotSourceAdjustment, expiresAt)
+	} else {
+		if balances[adjustment.UserID] < -adjustment.Sum {
+			return model.Adjustment{}, storage.ErrInsufficientFunds
+		}
+
+		_, err = consumePointLots(ctx, tx, adjustment.UserID, -adjustment.Sum)
+	}
+	if err != nil {
+		r.Log(ctx).Warn().Err(err).Msg("applyAdjustment: point lots")
+		return model.Adjustment{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", adjustment.Sum, adjustment.UserID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("applyAdjustment: exec users")
+		return model.Adjustment{}, err
+	}
+
+	return r.decideAdjustment(ctx, tx, adjustment.ID, model.AdjustmentApplied, deciderID)
+}
+
+func (r adjustmentRepository) decideAdjustment(ctx context.Context, tx *sql.Tx, id int, status model.AdjustmentStatus, deciderID int) (model.Adjustment, error) {
+	row := tx.QueryRowContext(ctx, `UPDATE balance_adjustments SET status = $2, decided_by = $3, decided_at = current_timestamp
+		WHERE id = $1 RETURNING `+adjustmentColumns, id, status, deciderID)
+
+	adjustment, err := scanAdjustment(row)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("decideAdjustment: exec balance_adjustments")
+		return model.Adjustment{}, err
+	}
+
+	return adjustment, nil
+}
+
+func (r adjustmentRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database adjustmentRepository").Logger()
+
+	return &logger
+}
+
+func scanAdjustment(row rowScanner) (model.Adjustment, error) {
+	var adjustment model.Adjustment
+	err := row.Scan(&adjustment.ID, &adjustment.UserID, &adjustment.Sum, &adjustment.ReasonCode, &adjustment.Comment,
+		&adjustment.Status, &adjustment.CreatedBy, &adjustment.DecidedBy, &adjustment.CreatedAt, &adjustment.DecidedAt)
+
+	return adjustment, err
+}
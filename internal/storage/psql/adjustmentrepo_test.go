@@ -0,0 +1,203 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var adjustmentRowColumns = []string{"id", "user_id", "sum", "reason_code", "comment", "status", "created_by", "decided_by", "created_at", "decided_at"}
+
+func Test_adjustmentRepository_CreateAdjustment(t *testing.T) {
+	t.Run("should apply credit at once", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &adjustmentRepository{db: db}
+
+		now := time.Now()
+		since := now.Add(-24 * time.Hour)
+		expiresAt := now.AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(ABS\\(sum\\)\\), 0\\) FROM balance_adjustments").
+			WithArgs(666, 1, model.AdjustmentApplied, since).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectQuery("INSERT INTO balance_adjustments").
+			WithArgs(666, model.Amount(500), "GOODWILL", "late delivery", model.AdjustmentPending, 1).
+			WillReturnRows(sqlmock.NewRows(adjustmentRowColumns).
+				AddRow(7, 666, 500, "GOODWILL", "late delivery", "PENDING", 1, 0, now, nil))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(500), model.LotSourceAdjustment, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(500), 666).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("UPDATE balance_adjustments SET status = \\$2, decided_by = \\$3").
+			WithArgs(7, model.AdjustmentApplied, 1).
+			WillReturnRows(sqlmock.NewRows(adjustmentRowColumns).
+				AddRow(7, 666, 500, "GOODWILL", "late delivery", "APPLIED", 1, 1, now, now))
+		mock.ExpectCommit()
+
+		adjustment, err := repo.CreateAdjustment(context.Background(), model.Adjustment{
+			UserID: 666, Sum: 500, ReasonCode: "GOODWILL", Comment: "late delivery", CreatedBy: 1,
+		}, 100000, since, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, adjustment.Status, model.AdjustmentApplied)
+		require.Equal(t, adjustment.DecidedBy, 1)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_adjustmentRepository_ApproveAdjustment(t *testing.T) {
+	t.Run("should reject debit above the balance", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &adjustmentRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM balance_adjustments WHERE id = \\$1 FOR UPDATE").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows(adjustmentRowColumns).
+				AddRow(7, 666, -5000, "FRAUD", "chargeback", "PENDING", 1, 0, now, nil))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+		mock.ExpectRollback()
+
+		_, err = repo.ApproveAdjustment(context.Background(), 7, 2, now)
+
+		require.Equal(t, err, storage.ErrInsufficientFunds)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should not apply decided adjustment", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &adjustmentRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM balance_adjustments WHERE id = \\$1 FOR UPDATE").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows(adjustmentRowColumns).
+				AddRow(7, 666, 5000, "GOODWILL", "late delivery", "APPLIED", 1, 2, now, now))
+		mock.ExpectRollback()
+
+		_, err = repo.ApproveAdjustment(context.Background(), 7, 3, now)
+
+		require.Equal(t, err, storage.ErrAdjustmentDecided)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_adjustmentRepository_CreateAdjustment_Threshold(t *testing.T) {
+	tests := []struct {
+		name    string
+		sum     model.Amount
+		applied model.Amount
+		apply   bool
+	}{
+		{name: "should apply debit within threshold", sum: -100000, apply: true},
+		{name: "should wait for approval above threshold", sum: 100001, apply: false},
+		{name: "should wait for approval of debit above threshold", sum: -100001, apply: false},
+		{name: "should apply with recent adjustments within threshold", sum: 40000, applied: 60000, apply: true},
+		{name: "should wait for approval with recent adjustments above threshold", sum: 40000, applied: 60001, apply: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+
+			repo := &adjustmentRepository{db: db}
+
+			now := time.Now()
+			since := now.Add(-24 * time.Hour)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+				WithArgs(666).
+				WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000000))
+			mock.ExpectQuery("SELECT COALESCE\\(SUM\\(ABS\\(sum\\)\\), 0\\) FROM balance_adjustments").
+				WithArgs(666, 1, model.AdjustmentApplied, since).
+				WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(tt.applied))
+			mock.ExpectQuery("INSERT INTO balance_adjustments").
+				WithArgs(666, tt.sum, "GOODWILL", "test", model.AdjustmentPending, 1).
+				WillReturnRows(sqlmock.NewRows(adjustmentRowColumns).
+					AddRow(7, 666, tt.sum, "GOODWILL", "test", "PENDING", 1, 0, now, nil))
+			if tt.apply {
+				mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+					WithArgs(666).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000000))
+				if tt.sum > 0 {
+					mock.ExpectExec("INSERT INTO point_lots").WillReturnResult(sqlmock.NewResult(1, 1))
+				} else {
+					mock.ExpectQuery("SELECT id, remaining, expires_at FROM point_lots").
+						WillReturnRows(sqlmock.NewRows([]string{"id", "remaining", "expires_at"}).AddRow(1, 1000000, now))
+					mock.ExpectExec("UPDATE point_lots SET remaining").WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+					WithArgs(tt.sum, 666).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery("UPDATE balance_adjustments SET status = \\$2, decided_by = \\$3").
+					WithArgs(7, model.AdjustmentApplied, 1).
+					WillReturnRows(sqlmock.NewRows(adjustmentRowColumns).
+						AddRow(7, 666, tt.sum, "GOODWILL", "test", "APPLIED", 1, 1, now, now))
+			}
+			mock.ExpectCommit()
+
+			adjustment, err := repo.CreateAdjustment(context.Background(), model.Adjustment{
+				UserID: 666, Sum: tt.sum, ReasonCode: "GOODWILL", Comment: "test", CreatedBy: 1,
+			}, 100000, since, now)
+
+			require.Equal(t, err, nil)
+			require.Equal(t, adjustment.Status == model.AdjustmentApplied, tt.apply)
+			require.Equal(t, mock.ExpectationsWereMet(), nil)
+		})
+	}
+
+	t.Run("should not create adjustment of deleted user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &adjustmentRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+		mock.ExpectRollback()
+
+		_, err = repo.CreateAdjustment(context.Background(), model.Adjustment{
+			UserID: 666, Sum: 500, ReasonCode: "GOODWILL", Comment: "test", CreatedBy: 1,
+		}, 100000, time.Now(), time.Now())
+
+		require.Equal(t, err, storage.ErrNotFound)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
@@ -0,0 +1,93 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+)
+
+func NewAuditRepository(db *sql.DB) storage.AuditRepository {
+	return &auditRepository{db: db}
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func (r auditRepository) AddAuditEntry(ctx context.Context, entry model.AuditEntry) error {
+	details := []byte("{}")
+	if len(entry.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(entry.Details); err != nil {
+			r.Log(ctx).Error().Err(err).Msg("AddAuditEntry: marshal details")
+			return err
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO audit_log (actor_id, action, target_type, target_id, ip, request_id, details)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7)`,
+		entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, entry.IP, entry.RequestID, details)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AddAuditEntry: invalid insert")
+		return err
+	}
+
+	return nil
+}
+
+func (r auditRepository) AuditEntries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, COALESCE(actor_id, 0), action, target_type, target_id, ip, request_id,
+		details, created_at FROM audit_log
+		WHERE ($1 = 0 OR actor_id = $1) AND ($2 = '' OR action = $2)
+			AND ($3 = '' OR target_type = $3) AND ($4 = '' OR target_id = $4)
+			AND ($5::timestamp IS NULL OR created_at >= $5) AND ($6::timestamp IS NULL OR created_at < $6)
+			AND ($7 = 0 OR id < $7)
+		ORDER BY id DESC LIMIT $8`,
+		filter.ActorID, filter.Action, filter.TargetType, filter.TargetID,
+		sql.NullTime{Time: filter.From, Valid: !filter.From.IsZero()},
+		sql.NullTime{Time: filter.To, Valid: !filter.To.IsZero()},
+		filter.BeforeID, filter.Limit)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AuditEntries: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]model.AuditEntry, 0)
+	for rows.Next() {
+		var entry model.AuditEntry
+		var details []byte
+
+		err = rows.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.TargetType, &entry.TargetID,
+			&entry.IP, &entry.RequestID, &details, &entry.CreatedAt)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("AuditEntries: invalid scan")
+			return nil, err
+		}
+
+		if err = json.Unmarshal(details, &entry.Details); err != nil {
+			r.Log(ctx).Error().Err(err).Msg("AuditEntries: unmarshal details")
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AuditEntries: query rows was error")
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r auditRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database auditRepository").Logger()
+
+	return &logger
+}
@@ -0,0 +1,91 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_auditRepository_AddAuditEntry(t *testing.T) {
+	t.Run("should save details as json", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &auditRepository{db: db}
+
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(1, "user_locked", model.AuditTargetUser, "666", "10.0.0.1", "req-1", []byte(`{"reason":"fraud"}`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err = repo.AddAuditEntry(context.Background(), model.AuditEntry{
+			ActorID:    1,
+			Action:     "user_locked",
+			TargetType: model.AuditTargetUser,
+			TargetID:   "666",
+			IP:         "10.0.0.1",
+			RequestID:  "req-1",
+			Details:    map[string]interface{}{"reason": "fraud"},
+		})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should save empty details of anonymous actor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &auditRepository{db: db}
+
+		mock.ExpectExec("INSERT INTO audit_log (.+) VALUES \\(NULLIF\\(\\$1, 0\\)").
+			WithArgs(0, "login_failed", model.AuditTargetLogin, "UserLogin", "", "", []byte("{}")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err = repo.AddAuditEntry(context.Background(), model.AuditEntry{
+			Action:     "login_failed",
+			TargetType: model.AuditTargetLogin,
+			TargetID:   "UserLogin",
+		})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_auditRepository_AuditEntries(t *testing.T) {
+	t.Run("should return filtered entries", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &auditRepository{db: db}
+
+		now := time.Now()
+		from := now.Add(-time.Hour)
+
+		rows := sqlmock.NewRows([]string{"id", "actor_id", "action", "target_type", "target_id", "ip", "request_id", "details", "created_at"}).
+			AddRow(2, 1, "user_locked", "user", "666", "10.0.0.1", "req-1", []byte(`{"reason":"fraud"}`), now).
+			AddRow(1, 0, "login_failed", "login", "UserLogin", "10.0.0.2", "", []byte(`{}`), now)
+		mock.ExpectQuery("SELECT (.+) FROM audit_log (.+) ORDER BY id DESC LIMIT \\$8").
+			WithArgs(0, "", "", "", sql.NullTime{Time: from, Valid: true}, sql.NullTime{}, 0, 100).
+			WillReturnRows(rows)
+
+		entries, err := repo.AuditEntries(context.Background(), model.AuditFilter{From: from, Limit: 100})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, entries, []model.AuditEntry{
+			{ID: 2, ActorID: 1, Action: "user_locked", TargetType: "user", TargetID: "666", IP: "10.0.0.1", RequestID: "req-1",
+				Details: map[string]interface{}{"reason": "fraud"}, CreatedAt: now},
+			{ID: 1, Action: "login_failed", TargetType: "login", TargetID: "UserLogin", IP: "10.0.0.2",
+				Details: map[string]interface{}{}, CreatedAt: now},
+		})
+	})
+}
@@ -0,0 +1,246 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const campaignColumns = "id, name, starts_at, ends_at, multiplier, bonus, user_cap, created_at"
+
+func NewCampaignRepository(db *sql.DB) storage.CampaignRepository {
+	return &campaignRepository{db: db}
+}
+
+type campaignRepository struct {
+	db *sql.DB
+}
+
+func (r campaignRepository) CreateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO campaigns (name, starts_at, ends_at, multiplier, bonus, user_cap)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+campaignColumns,
+		campaign.Name, campaign.StartsAt, campaign.EndsAt, campaign.Multiplier, campaign.Bonus, campaign.UserCap)
+
+	campaign, err := scanCampaign(row)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CreateCampaign: invalid insert")
+		return model.Campaign{}, err
+	}
+
+	return campaign, nil
+}
+
+func (r campaignRepository) UpdateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error) {
+	row := r.db.QueryRowContext(ctx, `UPDATE campaigns
+		SET name = $2, starts_at = $3, ends_at = $4, multiplier = $5, bonus = $6, user_cap = $7
+		WHERE id = $1 AND deleted_at IS NULL RETURNING `+campaignColumns,
+		campaign.ID, campaign.Name, campaign.StartsAt, campaign.EndsAt, campaign.Multiplier, campaign.Bonus, campaign.UserCap)
+
+	campaign, err := scanCampaign(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Campaign{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("UpdateCampaign: invalid update")
+		return model.Campaign{}, err
+	}
+
+	return campaign, nil
+}
+
+// DeleteCampaign hides the campaign, bonuses already credited by it are kept
+func (r campaignRepository) DeleteCampaign(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE campaigns SET deleted_at = current_timestamp WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("DeleteCampaign: invalid delete")
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r campaignRepository) Campaign(ctx context.Context, id int) (model.Campaign, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+campaignColumns+" FROM campaigns WHERE id = $1 AND deleted_at IS NULL", id)
+
+	campaign, err := scanCampaign(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Campaign{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("Campaign: invalid scan")
+		return model.Campaign{}, err
+	}
+
+	return campaign, nil
+}
+
+func (r campaignRepository) Campaigns(ctx context.Context) ([]model.Campaign, error) {
+	return r.queryCampaigns(ctx, "SELECT "+campaignColumns+" FROM campaigns WHERE deleted_at IS NULL ORDER BY starts_at")
+}
+
+func (r campaignRepository) ActiveCampaigns(ctx context.Context, at time.Time) ([]model.Campaign, error) {
+	return r.queryCampaigns(ctx, "SELECT "+campaignColumns+` FROM campaigns
+		WHERE deleted_at IS NULL AND starts_at <= $1 AND ends_at > $1 ORDER BY id`, at)
+}
+
+func (r campaignRepository) queryCampaigns(ctx context.Context, query string, args ...interface{}) ([]model.Campaign, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("queryCampaigns: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := make([]model.Campaign, 0)
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("queryCampaigns: invalid scan")
+			return nil, err
+		}
+
+		campaigns = append(campaigns, campaign)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("queryCampaigns: query rows was error")
+		return nil, err
+	}
+
+	return campaigns, nil
+}
+
+// AddCampaignBonus credits the bonus of the locked order accrual limited by the rest of the user cap. Bonus which
+// is already credited for the order or doesn't fit the cap is returned with zero Sum.
+func (r campaignRepository) AddCampaignBonus(ctx context.Context, bonus model.CampaignBonus, campaign model.Campaign, expiresAt time.Time) (model.CampaignBonus, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AddCampaignBonus: prepare transaction")
+		return model.CampaignBonus{}, err
+	}
+
+	accrual, err := lockBonusOrder(ctx, tx, bonus.OrderID)
+	if err == nil {
+		bonus.Sum = campaign.BonusFor(accrual)
+	}
+	if err != nil || bonus.Sum <= 0 {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("AddCampaignBonus: unable to rollback")
+		}
+
+		if err == nil || errors.Is(err, sql.ErrNoRows) {
+			return model.CampaignBonus{}, nil
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("AddCampaignBonus: lock order")
+		return model.CampaignBonus{}, err
+	}
+
+	// the lock serializes bonuses of the user, so concurrent orders can't exceed the cap
+	if _, err = lockUsers(ctx, tx, bonus.UserID); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("AddCampaignBonus: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.CampaignBonus{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("AddCampaignBonus: lock user")
+		return model.CampaignBonus{}, err
+	}
+
+	if campaign.UserCap > 0 {
+		var credited model.Amount
+		row := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(sum), 0) FROM campaign_bonuses
+			WHERE campaign_id = $1 AND user_id = $2`, bonus.CampaignID, bonus.UserID)
+		if err = row.Scan(&credited); err != nil {
+			r.Log(ctx).Error().Err(err).Msg("AddCampaignBonus: select credited")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("AddCampaignBonus: unable to rollback")
+			}
+			return model.CampaignBonus{}, err
+		}
+
+		if rest := campaign.UserCap - credited; bonus.Sum > rest {
+			bonus.Sum = rest
+		}
+
+		if bonus.Sum <= 0 {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("AddCampaignBonus: unable to rollback")
+			}
+			return model.CampaignBonus{}, nil
+		}
+	}
+
+	row := tx.QueryRowContext(ctx, `INSERT INTO campaign_bonuses (campaign_id, order_id, user_id, sum)
+		VALUES ($1, $2, $3, $4) ON CONFLICT (campaign_id, order_id) DO NOTHING RETURNING id, created_at`,
+		bonus.CampaignID, bonus.OrderID, bonus.UserID, bonus.Sum)
+	if err = row.Scan(&bonus.ID, &bonus.CreatedAt); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("AddCampaignBonus: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.CampaignBonus{}, nil
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("AddCampaignBonus: exec campaign_bonuses")
+		return model.CampaignBonus{}, err
+	}
+
+	err = addPointLot(ctx, tx, bonus.UserID, bonus.Sum, model.LotSourceCampaign, expiresAt)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", bonus.Sum, bonus.UserID)
+	}
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AddCampaignBonus: credit balance")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("AddCampaignBonus: unable to rollback")
+		}
+		return model.CampaignBonus{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("AddCampaignBonus: unable to commit")
+		return model.CampaignBonus{}, err
+	}
+
+	return bonus, nil
+}
+
+func (r campaignRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database campaignRepository").Logger()
+
+	return &logger
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCampaign(row rowScanner) (model.Campaign, error) {
+	var campaign model.Campaign
+	err := row.Scan(&campaign.ID, &campaign.Name, &campaign.StartsAt, &campaign.EndsAt,
+		&campaign.Multiplier, &campaign.Bonus, &campaign.UserCap, &campaign.CreatedAt)
+
+	return campaign, err
+}
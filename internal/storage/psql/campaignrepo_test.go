@@ -0,0 +1,147 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_campaignRepository_ActiveCampaigns(t *testing.T) {
+	t.Run("should return campaigns running at the time", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &campaignRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectQuery("SELECT (.+) FROM campaigns WHERE deleted_at IS NULL AND starts_at <= \\$1 AND ends_at > \\$1").
+			WithArgs(now).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "starts_at", "ends_at", "multiplier", "bonus", "user_cap", "created_at"}).
+				AddRow(1, "weekend", now, now, 2.0, 0, 1000, now))
+
+		campaigns, err := repo.ActiveCampaigns(context.Background(), now)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, campaigns, []model.Campaign{
+			{ID: 1, Name: "weekend", StartsAt: now, EndsAt: now, Multiplier: 2, UserCap: 1000, CreatedAt: now},
+		})
+	})
+}
+
+func Test_campaignRepository_DeleteCampaign(t *testing.T) {
+	t.Run("should return not found for deleted campaign", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &campaignRepository{db: db}
+
+		mock.ExpectExec("UPDATE campaigns SET deleted_at = current_timestamp").
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.DeleteCampaign(context.Background(), 7)
+
+		require.Equal(t, err, storage.ErrNotFound)
+	})
+}
+
+func Test_campaignRepository_AddCampaignBonus(t *testing.T) {
+	t.Run("should credit bonus limited by user cap", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &campaignRepository{db: db}
+
+		now := time.Now()
+		expiresAt := now.AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders WHERE id = \\$1 AND status = \\$2 AND bonuses_pending FOR UPDATE").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}).AddRow(1000))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(sum\\), 0\\) FROM campaign_bonuses").
+			WithArgs(1, 666).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(800))
+		mock.ExpectQuery("INSERT INTO campaign_bonuses (.+) ON CONFLICT \\(campaign_id, order_id\\) DO NOTHING").
+			WithArgs(1, model.OrderID("123"), 666, model.Amount(200)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, now))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(200), model.LotSourceCampaign, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(200), 666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		bonus, err := repo.AddCampaignBonus(context.Background(),
+			model.CampaignBonus{CampaignID: 1, OrderID: "123", UserID: 666}, model.Campaign{ID: 1, Multiplier: 1.5, UserCap: 1000}, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, bonus, model.CampaignBonus{ID: 5, CampaignID: 1, OrderID: "123", UserID: 666, Sum: 200, CreatedAt: now})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("should skip bonus when cap is reached", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &campaignRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders WHERE id = \\$1 AND status = \\$2 AND bonuses_pending FOR UPDATE").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}).AddRow(1000))
+		mock.ExpectQuery("SELECT balance FROM users").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(sum\\), 0\\) FROM campaign_bonuses").
+			WithArgs(1, 666).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1000))
+		mock.ExpectRollback()
+
+		bonus, err := repo.AddCampaignBonus(context.Background(),
+			model.CampaignBonus{CampaignID: 1, OrderID: "123", UserID: 666}, model.Campaign{ID: 1, Bonus: 500, UserCap: 1000}, time.Now())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, bonus.Sum, model.Amount(0))
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("should skip order which doesn't wait for bonuses", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &campaignRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}))
+		mock.ExpectRollback()
+
+		bonus, err := repo.AddCampaignBonus(context.Background(),
+			model.CampaignBonus{CampaignID: 1, OrderID: "123", UserID: 666}, model.Campaign{ID: 1, Bonus: 500}, time.Now())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, bonus, model.CampaignBonus{})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
@@ -0,0 +1,87 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+const (
+	listenerMinReconnect = 10 * time.Second
+	listenerMaxReconnect = time.Minute
+	listenerPingInterval = 90 * time.Second
+)
+
+func NewEventRepository(db *sql.DB, dsn string) storage.EventRepository {
+	return &eventRepository{db: db, dsn: dsn}
+}
+
+type eventRepository struct {
+	db  *sql.DB
+	dsn string
+}
+
+func (r eventRepository) Notify(ctx context.Context, channel string, payload string) error {
+	_, err := r.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("Notify: invalid send notification")
+		return err
+	}
+
+	return nil
+}
+
+// Listen subscribes to PostgreSQL notifications of the channel on a dedicated connection.
+// The returned channel is closed when ctx is done.
+func (r eventRepository) Listen(ctx context.Context, channel string) (<-chan string, error) {
+	listener := pq.NewListener(r.dsn, listenerMinReconnect, listenerMaxReconnect, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			r.Log(ctx).Warn().Err(err).Msgf("Listen: listener event %d", ev)
+		}
+	})
+
+	if err := listener.Listen(channel); err != nil {
+		r.Log(ctx).Err(err).Msg("Listen: invalid listen channel")
+		listener.Close()
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer listener.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-listener.Notify:
+				// nil notification is sent after the connection has been re-established
+				if n == nil {
+					continue
+				}
+
+				select {
+				case out <- n.Extra:
+				case <-ctx.Done():
+					return
+				}
+			case <-time.After(listenerPingInterval):
+				go listener.Ping()
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+func (r eventRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database eventRepository").Logger()
+
+	return &logger
+}
@@ -0,0 +1,28 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_eventRepository_Notify(t *testing.T) {
+	t.Run("should send notification to channel", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &eventRepository{db: db}
+
+		mock.ExpectExec("SELECT pg_notify\\(\\$1, \\$2\\)").
+			WithArgs("events", "{}").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = repo.Notify(context.Background(), "events", "{}")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
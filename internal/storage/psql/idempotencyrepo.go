@@ -0,0 +1,99 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+)
+
+func NewIdempotencyRepository(db *sql.DB) storage.IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+type idempotencyRepository struct {
+	db *sql.DB
+}
+
+// CreateIdempotencyKey takes over expired record with the same key, so the key can be used again after TTL
+func (r idempotencyRepository) CreateIdempotencyKey(ctx context.Context, record model.IdempotencyRecord) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE SET fingerprint = excluded.fingerprint, status_code = NULL, content_type = '',
+			response = NULL, created_at = current_timestamp, expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= current_timestamp`,
+		record.UserID, record.Key, record.Fingerprint, record.ExpiresAt)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("CreateIdempotencyKey: invalid insert key")
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r idempotencyRepository) IdempotencyKey(ctx context.Context, userID int, key string) (model.IdempotencyRecord, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT fingerprint, status_code, content_type, response, expires_at
+		from idempotency_keys where user_id = $1 AND key = $2`, userID, key)
+
+	record := model.IdempotencyRecord{UserID: userID, Key: key}
+	var statusCode sql.NullInt32
+	err := row.Scan(&record.Fingerprint, &statusCode, &record.ContentType, &record.Response, &record.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.IdempotencyRecord{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Err(err).Msg("IdempotencyKey: invalid scan")
+		return model.IdempotencyRecord{}, err
+	}
+
+	record.StatusCode = int(statusCode.Int32)
+
+	return record, nil
+}
+
+func (r idempotencyRepository) SaveIdempotencyResponse(ctx context.Context, record model.IdempotencyRecord) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE idempotency_keys SET status_code = $3, content_type = $4, response = $5
+		WHERE user_id = $1 AND key = $2`, record.UserID, record.Key, record.StatusCode, record.ContentType, record.Response)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("SaveIdempotencyResponse: invalid update key")
+		return err
+	}
+
+	return nil
+}
+
+func (r idempotencyRepository) DeleteIdempotencyKey(ctx context.Context, userID int, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2", userID, key)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("DeleteIdempotencyKey: invalid delete key")
+		return err
+	}
+
+	return nil
+}
+
+func (r idempotencyRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= current_timestamp")
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("DeleteExpiredIdempotencyKeys: invalid delete keys")
+		return err
+	}
+
+	return nil
+}
+
+func (r idempotencyRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database idempotencyRepository").Logger()
+
+	return &logger
+}
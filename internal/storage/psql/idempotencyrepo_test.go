@@ -0,0 +1,58 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_idempotencyRepository_CreateIdempotencyKey(t *testing.T) {
+	t.Run("should not take over active key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &idempotencyRepository{db: db}
+		expiresAt := time.Now()
+
+		mock.ExpectExec("INSERT INTO idempotency_keys").
+			WithArgs(666, "key", "fingerprint", expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		created, err := repo.CreateIdempotencyKey(context.Background(), model.IdempotencyRecord{
+			UserID:      666,
+			Key:         "key",
+			Fingerprint: "fingerprint",
+			ExpiresAt:   expiresAt,
+		})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, created, false)
+	})
+}
+
+func Test_idempotencyRepository_IdempotencyKey(t *testing.T) {
+	t.Run("should return key in progress with empty status", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &idempotencyRepository{db: db}
+		expiresAt := time.Now()
+
+		mock.ExpectQuery("SELECT fingerprint, status_code, content_type, response, expires_at from idempotency_keys").
+			WithArgs(666, "key").
+			WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "content_type", "response", "expires_at"}).
+				AddRow("fingerprint", nil, "", nil, expiresAt))
+
+		record, err := repo.IdempotencyKey(context.Background(), 666, "key")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, record, model.IdempotencyRecord{UserID: 666, Key: "key", Fingerprint: "fingerprint", ExpiresAt: expiresAt})
+	})
+}
@@ -0,0 +1,108 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+func NewLoginAttemptRepository(db *sql.DB) storage.LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+func (r loginAttemptRepository) LoginAttempt(ctx context.Context, key string) (model.LoginAttempt, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT failures, first_failed_at, blocked_until
+		from login_attempts where key=$1`, key)
+
+	attempt, err := scanLoginAttempt(row, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.LoginAttempt{Key: key}, nil
+		}
+
+		r.Log(ctx).Err(err).Msg("LoginAttempt: invalid scan")
+		return model.LoginAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+func (r loginAttemptRepository) RegisterFailure(ctx context.Context, key string, window time.Duration) (model.LoginAttempt, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO login_attempts AS a (key, failures, first_failed_at)
+		VALUES ($1, 1, current_timestamp)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN a.first_failed_at < current_timestamp - make_interval(secs => $2)
+				THEN 1 ELSE a.failures + 1 END,
+			first_failed_at = CASE WHEN a.first_failed_at < current_timestamp - make_interval(secs => $2)
+				THEN current_timestamp ELSE a.first_failed_at END
+		RETURNING failures, first_failed_at, blocked_until`, key, window.Seconds())
+
+	attempt, err := scanLoginAttempt(row, key)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("RegisterFailure: invalid save attempt")
+		return model.LoginAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+func (r loginAttemptRepository) Block(ctx context.Context, key string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE login_attempts SET blocked_until = $1 WHERE key = $2", until, key)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("Block: invalid update attempt")
+		return err
+	}
+
+	return nil
+}
+
+func (r loginAttemptRepository) ResetLoginAttempts(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE key = $1", key)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("ResetLoginAttempts: invalid delete attempt")
+		return err
+	}
+
+	return nil
+}
+
+func (r loginAttemptRepository) DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_attempts
+		WHERE first_failed_at < $1 AND (blocked_until IS NULL OR blocked_until < current_timestamp)`, before)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("DeleteExpiredLoginAttempts: invalid delete attempts")
+		return err
+	}
+
+	return nil
+}
+
+func scanLoginAttempt(row *sql.Row, key string) (model.LoginAttempt, error) {
+	attempt := model.LoginAttempt{Key: key}
+
+	var blockedUntil sql.NullTime
+	err := row.Scan(&attempt.Failures, &attempt.FirstFailedAt, &blockedUntil)
+	if err != nil {
+		return model.LoginAttempt{}, err
+	}
+
+	attempt.BlockedUntil = blockedUntil.Time
+
+	return attempt, nil
+}
+
+func (r loginAttemptRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database loginAttemptRepository").Logger()
+
+	return &logger
+}
@@ -0,0 +1,51 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_loginAttemptRepository_LoginAttempt(t *testing.T) {
+	t.Run("should return empty attempt for unknown key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &loginAttemptRepository{db: db}
+
+		mock.ExpectQuery("SELECT failures, first_failed_at, blocked_until").
+			WithArgs("login:user").
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "first_failed_at", "blocked_until"}))
+
+		attempt, err := repo.LoginAttempt(context.Background(), "login:user")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, attempt, model.LoginAttempt{Key: "login:user"})
+	})
+}
+
+func Test_loginAttemptRepository_RegisterFailure(t *testing.T) {
+	t.Run("should increment failures", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &loginAttemptRepository{db: db}
+		now := time.Now()
+
+		mock.ExpectQuery("INSERT INTO login_attempts AS a").
+			WithArgs("ip:10.0.0.1", float64(60)).
+			WillReturnRows(sqlmock.NewRows([]string{"failures", "first_failed_at", "blocked_until"}).AddRow(3, now, nil))
+
+		attempt, err := repo.RegisterFailure(context.Background(), "ip:10.0.0.1", time.Minute)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, attempt, model.LoginAttempt{Key: "ip:10.0.0.1", Failures: 3, FirstFailedAt: now})
+	})
+}
@@ -0,0 +1,49 @@
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'delivery_status') THEN
+        CREATE TYPE "delivery_status" AS ENUM (
+            'PENDING',
+            'DELIVERED',
+            'FAILED'
+        );
+    END IF;
+END$$;
+
+create table webhooks
+(
+    id serial not null
+        constraint webhooks_pk
+            primary key,
+    user_id int not null
+        constraint webhooks_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    url text not null,
+    secret text not null,
+    created_at timestamp default current_timestamp
+);
+
+create index webhooks_user_id_index
+    on webhooks (user_id);
+
+create table webhook_deliveries
+(
+    id serial not null
+        constraint webhook_deliveries_pk
+            primary key,
+    webhook_id int not null
+        constraint webhook_deliveries_webhooks_id_fk
+            references webhooks
+            on update cascade on delete cascade,
+    event text not null,
+    payload text not null,
+    status delivery_status default 'PENDING' not null,
+    attempts int default 0 not null,
+    response_code int default 0 not null,
+    last_error text default '' not null,
+    next_attempt_at timestamp default current_timestamp not null,
+    created_at timestamp default current_timestamp
+);
+
+create index webhook_deliveries_status_next_attempt_at_index
+    on webhook_deliveries (status, next_attempt_at);
@@ -0,0 +1,30 @@
+create table refresh_tokens
+(
+    id serial not null
+        constraint refresh_tokens_pk
+            primary key,
+    user_id int not null
+        constraint refresh_tokens_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    session_id text not null,
+    token_hash text not null,
+    created_at timestamp default current_timestamp,
+    expires_at timestamp not null,
+    used_at timestamp,
+    revoked_at timestamp
+);
+
+create unique index refresh_tokens_token_hash_uindex
+    on refresh_tokens (token_hash);
+
+create index refresh_tokens_session_id_index
+    on refresh_tokens (session_id);
+
+create table revoked_tokens
+(
+    jti text not null
+        constraint revoked_tokens_pk
+            primary key,
+    expires_at timestamp not null
+);
@@ -0,0 +1,2 @@
+alter table users
+    drop column if exists pepper_id;
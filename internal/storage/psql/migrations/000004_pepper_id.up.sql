@@ -0,0 +1,5 @@
+alter table users
+    add column pepper_id varchar(32) not null default '1';
+
+alter table users
+    alter column pepper_id drop default;
@@ -0,0 +1,9 @@
+create table login_attempts
+(
+    key text not null
+        constraint login_attempts_pk
+            primary key,
+    failures int default 0 not null,
+    first_failed_at timestamp default current_timestamp not null,
+    blocked_until timestamp
+);
@@ -0,0 +1,2 @@
+alter table users
+    add column deleted_at timestamp;
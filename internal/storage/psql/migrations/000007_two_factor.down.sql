@@ -0,0 +1,5 @@
+alter table refresh_tokens
+    drop column if exists mfa;
+
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,32 @@
+create table user_totp
+(
+    user_id int not null
+        constraint user_totp_pk
+            primary key
+        constraint user_totp_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    secret text not null,
+    enabled boolean default false not null,
+    last_step bigint default 0 not null,
+    created_at timestamp default current_timestamp
+);
+
+create table recovery_codes
+(
+    id serial not null
+        constraint recovery_codes_pk
+            primary key,
+    user_id int not null
+        constraint recovery_codes_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    code_hash text not null,
+    used_at timestamp
+);
+
+create index recovery_codes_user_id_index
+    on recovery_codes (user_id);
+
+alter table refresh_tokens
+    add column mfa boolean default false not null;
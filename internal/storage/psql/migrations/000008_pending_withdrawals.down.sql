@@ -0,0 +1,5 @@
+UPDATE users SET balance = balance + p.sum
+FROM (SELECT user_id, SUM(sum) AS sum FROM pending_withdrawals GROUP BY user_id) p
+WHERE users.id = p.user_id;
+
+DROP TABLE IF EXISTS pending_withdrawals;
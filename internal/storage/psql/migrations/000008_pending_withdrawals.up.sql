@@ -0,0 +1,17 @@
+create table pending_withdrawals
+(
+    id serial not null
+        constraint pending_withdrawals_pk
+            primary key,
+    user_id int not null
+        constraint pending_withdrawals_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    order_id text not null,
+    sum int not null,
+    created_at timestamp default current_timestamp,
+    expires_at timestamp not null
+);
+
+create index pending_withdrawals_expires_at_index
+    on pending_withdrawals (expires_at);
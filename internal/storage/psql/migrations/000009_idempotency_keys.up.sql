@@ -0,0 +1,20 @@
+create table idempotency_keys
+(
+    id serial not null
+        constraint idempotency_keys_pk
+            primary key,
+    user_id int not null
+        constraint idempotency_keys_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    key text not null,
+    fingerprint text not null,
+    status_code int,
+    content_type text default '' not null,
+    response bytea,
+    created_at timestamp default current_timestamp,
+    expires_at timestamp not null
+);
+
+create unique index idempotency_keys_user_id_key_uindex
+    on idempotency_keys (user_id, key);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS pending_withdrawals_order_id_uindex;
+DROP INDEX IF EXISTS withdraw_log_order_id_uindex;
@@ -0,0 +1,12 @@
+DO $$
+BEGIN
+    IF EXISTS (SELECT order_id FROM withdraw_log GROUP BY order_id HAVING COUNT(*) > 1) THEN
+        RAISE EXCEPTION 'withdraw_log has duplicate order_id, resolve them manually before the migration';
+    END IF;
+END$$;
+
+create unique index withdraw_log_order_id_uindex
+    on withdraw_log (order_id);
+
+create unique index pending_withdrawals_order_id_uindex
+    on pending_withdrawals (order_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS withdraw_reversals;
+
+alter table withdraw_log
+    drop column if exists reversed_sum;
@@ -0,0 +1,20 @@
+alter table withdraw_log
+    add column reversed_sum int default 0 not null;
+
+create table withdraw_reversals
+(
+    id serial not null
+        constraint withdraw_reversals_pk
+            primary key,
+    withdraw_id int not null
+        constraint withdraw_reversals_withdraw_log_id_fk
+            references withdraw_log
+            on update cascade on delete cascade,
+    sum int not null,
+    reason text not null,
+    admin_id int not null,
+    created_at timestamp default current_timestamp
+);
+
+create index withdraw_reversals_withdraw_id_index
+    on withdraw_reversals (withdraw_id);
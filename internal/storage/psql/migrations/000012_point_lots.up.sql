@@ -0,0 +1,73 @@
+create table point_lots
+(
+    id serial not null
+        constraint point_lots_pk
+            primary key,
+    user_id int not null
+        constraint point_lots_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    amount int not null,
+    remaining int not null,
+    source text not null,
+    created_at timestamp default current_timestamp,
+    expires_at timestamp not null
+);
+
+create index point_lots_user_id_expires_at_index
+    on point_lots (user_id, expires_at)
+    where remaining > 0;
+
+create index point_lots_expires_at_index
+    on point_lots (expires_at)
+    where remaining > 0;
+
+-- withdraw_id or pending_withdraw_id tells which withdrawal consumed the lot
+create table point_lot_consumptions
+(
+    id serial not null
+        constraint point_lot_consumptions_pk
+            primary key,
+    lot_id int not null
+        constraint point_lot_consumptions_point_lots_id_fk
+            references point_lots
+            on update cascade on delete cascade,
+    amount int not null,
+    withdraw_id int,
+    pending_withdraw_id int
+);
+
+create index point_lot_consumptions_withdraw_id_index
+    on point_lot_consumptions (withdraw_id);
+
+create index point_lot_consumptions_pending_withdraw_id_index
+    on point_lot_consumptions (pending_withdraw_id);
+
+create table point_expirations
+(
+    id serial not null
+        constraint point_expirations_pk
+            primary key,
+    lot_id int not null
+        constraint point_expirations_point_lots_id_fk
+            references point_lots
+            on update cascade on delete cascade,
+    user_id int not null,
+    amount int not null,
+    expired_at timestamp default current_timestamp
+);
+
+create index point_expirations_user_id_index
+    on point_expirations (user_id);
+
+-- current balances and reserved points become one lot per user
+INSERT INTO point_lots (user_id, amount, remaining, source, expires_at)
+SELECT u.id, u.balance + COALESCE(p.sum, 0), u.balance, 'migration', current_timestamp + interval '12 months'
+FROM users u
+    LEFT JOIN (SELECT user_id, SUM(sum) AS sum FROM pending_withdrawals GROUP BY user_id) p ON p.user_id = u.id
+WHERE u.balance + COALESCE(p.sum, 0) > 0;
+
+INSERT INTO point_lot_consumptions (lot_id, amount, pending_withdraw_id)
+SELECT l.id, pw.sum, pw.id
+FROM pending_withdrawals pw
+    JOIN point_lots l ON l.user_id = pw.user_id AND l.source = 'migration';
@@ -0,0 +1,22 @@
+create table transfers
+(
+    id serial not null
+        constraint transfers_pk
+            primary key,
+    sender_id int not null
+        constraint transfers_sender_id_fk
+            references users
+            on update cascade on delete cascade,
+    recipient_id int not null
+        constraint transfers_recipient_id_fk
+            references users
+            on update cascade on delete cascade,
+    sum int not null,
+    created_at timestamp default current_timestamp
+);
+
+create index transfers_sender_id_created_at_index
+    on transfers (sender_id, created_at);
+
+create index transfers_recipient_id_index
+    on transfers (recipient_id);
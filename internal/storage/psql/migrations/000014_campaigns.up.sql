@@ -0,0 +1,43 @@
+create table campaigns
+(
+    id serial not null
+        constraint campaigns_pk
+            primary key,
+    name text not null,
+    starts_at timestamp not null,
+    ends_at timestamp not null,
+    multiplier double precision default 0 not null,
+    bonus int default 0 not null,
+    user_cap int default 0 not null,
+    created_at timestamp default current_timestamp,
+    deleted_at timestamp
+);
+
+create index campaigns_starts_at_ends_at_index
+    on campaigns (starts_at, ends_at)
+    where deleted_at is null;
+
+-- bonus is credited on top of the base accrual stored in orders
+create table campaign_bonuses
+(
+    id serial not null
+        constraint campaign_bonuses_pk
+            primary key,
+    campaign_id int not null
+        constraint campaign_bonuses_campaigns_id_fk
+            references campaigns
+            on update cascade on delete cascade,
+    order_id text not null,
+    user_id int not null
+        constraint campaign_bonuses_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    sum int not null,
+    created_at timestamp default current_timestamp
+);
+
+create unique index campaign_bonuses_campaign_id_order_id_uindex
+    on campaign_bonuses (campaign_id, order_id);
+
+create index campaign_bonuses_campaign_id_user_id_index
+    on campaign_bonuses (campaign_id, user_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS referral_rewards;
+
+alter table users
+    drop column if exists referred_by;
+
+alter table users
+    drop column if exists referral_code;
@@ -0,0 +1,49 @@
+alter table users
+    add column referral_code text;
+
+alter table users
+    add column referred_by int
+        constraint users_referred_by_fk
+            references users
+            on update cascade on delete set null;
+
+alter table users
+    add constraint users_referred_by_check
+        check (referred_by <> id);
+
+update users set referral_code = upper(substr(md5(random()::text || id::text), 1, 8));
+
+alter table users
+    alter column referral_code set not null;
+
+create unique index users_referral_code_uindex
+    on users (referral_code);
+
+create index users_referred_by_index
+    on users (referred_by);
+
+-- the referral is rewarded once, by the first processed order of the referee
+create table referral_rewards
+(
+    id serial not null
+        constraint referral_rewards_pk
+            primary key,
+    referee_id int not null
+        constraint referral_rewards_referee_id_fk
+            references users
+            on update cascade on delete cascade,
+    referrer_id int not null
+        constraint referral_rewards_referrer_id_fk
+            references users
+            on update cascade on delete cascade,
+    order_id text not null,
+    referrer_bonus int not null,
+    referee_bonus int not null,
+    created_at timestamp default current_timestamp
+);
+
+create unique index referral_rewards_referee_id_uindex
+    on referral_rewards (referee_id);
+
+create index referral_rewards_referrer_id_index
+    on referral_rewards (referrer_id);
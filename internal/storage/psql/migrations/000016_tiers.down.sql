@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS tier_bonuses;
+
+DROP INDEX IF EXISTS orders_user_id_uploaded_at_index;
+
+alter table users
+    drop column if exists tier_updated_at;
+
+alter table users
+    drop column if exists tier;
@@ -0,0 +1,27 @@
+alter table users
+    add column tier text default 'BASIC' not null;
+
+alter table users
+    add column tier_updated_at timestamp;
+
+create index orders_user_id_uploaded_at_index
+    on orders (user_id, uploaded_at);
+
+-- bonus of the tier multiplier is credited on top of the base accrual stored in orders
+create table tier_bonuses
+(
+    id serial not null
+        constraint tier_bonuses_pk
+            primary key,
+    order_id text not null,
+    user_id int not null
+        constraint tier_bonuses_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    tier text not null,
+    sum int not null,
+    created_at timestamp default current_timestamp
+);
+
+create unique index tier_bonuses_order_id_uindex
+    on tier_bonuses (order_id);
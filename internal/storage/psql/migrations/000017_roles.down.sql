@@ -0,0 +1,8 @@
+alter table users
+    drop column if exists locked_reason;
+
+alter table users
+    drop column if exists locked_at;
+
+alter table users
+    drop column if exists role;
@@ -0,0 +1,10 @@
+alter table users
+    add column role text default 'user' not null
+        constraint users_role_check
+            check (role in ('user', 'support', 'admin'));
+
+alter table users
+    add column locked_at timestamp;
+
+alter table users
+    add column locked_reason text;
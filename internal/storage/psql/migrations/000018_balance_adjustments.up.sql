@@ -0,0 +1,31 @@
+-- sum is positive for credits and negative for debits
+create table balance_adjustments
+(
+    id serial not null
+        constraint balance_adjustments_pk
+            primary key,
+    user_id int not null
+        constraint balance_adjustments_users_id_fk
+            references users
+            on update cascade on delete cascade,
+    sum int not null
+        constraint balance_adjustments_sum_check
+            check (sum <> 0),
+    reason_code text not null,
+    comment text not null,
+    status text not null,
+    created_by int not null
+        constraint balance_adjustments_created_by_fk
+            references users,
+    decided_by int
+        constraint balance_adjustments_decided_by_fk
+            references users,
+    created_at timestamp default current_timestamp,
+    decided_at timestamp
+);
+
+create index balance_adjustments_status_index
+    on balance_adjustments (status);
+
+create index balance_adjustments_user_id_index
+    on balance_adjustments (user_id);
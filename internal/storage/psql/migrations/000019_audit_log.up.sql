@@ -0,0 +1,38 @@
+-- actor_id is null for anonymous actions like failed logins, users are not referenced
+-- so the records outlive the accounts
+create table audit_log
+(
+    id serial not null
+        constraint audit_log_pk
+            primary key,
+    actor_id int,
+    action text not null,
+    target_type text not null default '',
+    target_id text not null default '',
+    ip text not null default '',
+    request_id text not null default '',
+    details jsonb not null default '{}',
+    created_at timestamp default current_timestamp
+);
+
+create index audit_log_actor_id_index
+    on audit_log (actor_id);
+
+create index audit_log_action_index
+    on audit_log (action);
+
+create index audit_log_target_index
+    on audit_log (target_type, target_id);
+
+create function audit_log_append_only() returns trigger as
+$$
+begin
+    raise exception 'audit_log is append-only';
+end;
+$$ language plpgsql;
+
+create trigger audit_log_append_only
+    before update or delete
+    on audit_log
+    for each row
+execute procedure audit_log_append_only();
@@ -0,0 +1,3 @@
+DELETE FROM balance_adjustments WHERE created_by IS NULL;
+
+ALTER TABLE balance_adjustments ALTER COLUMN created_by SET NOT NULL;
@@ -0,0 +1,3 @@
+-- corrections written by the scheduled reconciliation have no author
+alter table balance_adjustments
+    alter column created_by drop not null;
@@ -0,0 +1,2 @@
+-- the short logins of deleted users could be registered since, the long ones are kept
+SELECT 1;
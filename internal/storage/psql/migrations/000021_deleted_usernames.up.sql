@@ -0,0 +1,4 @@
+-- logins of deleted users are made longer than registration allows, so they can't be taken by new users
+update users
+set username = 'deleted-user-' || lpad(id::text, 10, '0')
+where deleted_at is not null;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS orders_bonuses_pending_index;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS bonuses_pending;
@@ -0,0 +1,8 @@
+-- set with the accrual of a processed order and cleared when campaign, tier and referral bonuses are applied,
+-- so bonuses which failed after the accrual was committed are retried by the poller
+alter table orders
+    add column bonuses_pending boolean default false not null;
+
+create index orders_bonuses_pending_index
+    on orders (uploaded_at)
+    where bonuses_pending;
@@ -8,7 +8,10 @@ import (
 	"github.com/djokcik/gophermart/internal/storage"
 	"github.com/djokcik/gophermart/pkg/logging"
 	"github.com/djokcik/gophermart/provider"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
+	"sort"
+	"time"
 )
 
 func NewOrderRepository(db *sql.DB) storage.OrderRepository {
@@ -47,24 +50,44 @@ func (r orderRepository) OrdersByStatus(ctx context.Context, status model.Status
 	return orders, nil
 }
 
-func (r orderRepository) UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse) error {
+func (r orderRepository) UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse, expiresAt time.Time) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		r.Log(ctx).Error().Err(err).Msg("UpdateForAccrual: prepare transaction")
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, `UPDATE orders SET status = $1, accrual = $2 
-			WHERE id = $3`, accrual.Status, accrual.Accrual, order.ID)
+	// bonuses are applied in their own transactions, the pending flag is committed with the accrual so they are retried.
+	// The status condition is checked again after the lock of a concurrent override is released,
+	// so an order overridden meanwhile is neither updated nor credited twice.
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET status = $1, accrual = $2, bonuses_pending = $3
+			WHERE id = $4 AND status IN ($5, $6)`, accrual.Status, accrual.Accrual, accrual.Status == model.StatusProcessed,
+		order.ID, model.StatusNew, model.StatusProcessing)
+	if err == nil {
+		var updated int64
+		if updated, err = result.RowsAffected(); err == nil && updated == 0 {
+			err = storage.ErrOrderFinalized
+		}
+	}
 	if err != nil {
-		r.Log(ctx).Error().Err(err).Msg("UpdateForAccrual: exec orders")
-		if err = tx.Rollback(); err != nil {
-			r.Log(ctx).Error().Err(err).Msgf("UpdateForAccrual: unable to rollback")
-			return err
+		r.Log(ctx).Warn().Err(err).Msg("UpdateForAccrual: exec orders")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("UpdateForAccrual: unable to rollback")
 		}
 		return err
 	}
 
+	if accrual.Accrual > 0 {
+		err = addPointLot(ctx, tx, order.UserID, accrual.Accrual, model.LotSourceAccrual, expiresAt)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("UpdateForAccrual: exec point_lots")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("UpdateForAccrual: unable to rollback")
+			}
+			return err
+		}
+	}
+
 	_, err = tx.ExecContext(ctx, `UPDATE users SET balance = balance + $1 WHERE id = $2`, accrual.Accrual, order.UserID)
 	if err != nil {
 		r.Log(ctx).Error().Err(err).Msg("UpdateForAccrual: exec users")
@@ -83,6 +106,56 @@ func (r orderRepository) UpdateForAccrual(ctx context.Context, order model.Order
 	return nil
 }
 
+func (r orderRepository) PendingBonusOrders(ctx context.Context, limit int) ([]model.Order, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, status, uploaded_at, accrual
+		FROM orders WHERE bonuses_pending ORDER BY uploaded_at LIMIT $1`, limit)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("PendingBonusOrders: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]model.Order, 0)
+	for rows.Next() {
+		var order model.Order
+		err = rows.Scan(&order.ID, &order.UserID, &order.Status, &order.UploadedAt, &order.Accrual)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("PendingBonusOrders: invalid scan")
+			return nil, err
+		}
+
+		orders = append(orders, order)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("PendingBonusOrders: query rows was error")
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// lockBonusOrder locks the processed order which waits for its bonuses and returns its accrual, so the bonuses
+// can't be credited for the accrual an override replaces meanwhile. sql.ErrNoRows is returned for other orders.
+func lockBonusOrder(ctx context.Context, tx *sql.Tx, id model.OrderID) (model.Amount, error) {
+	var accrual model.Amount
+	row := tx.QueryRowContext(ctx, `SELECT accrual FROM orders WHERE id = $1 AND status = $2 AND bonuses_pending FOR UPDATE`,
+		id, model.StatusProcessed)
+	err := row.Scan(&accrual)
+
+	return accrual, err
+}
+
+func (r orderRepository) CompleteBonuses(ctx context.Context, id model.OrderID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE orders SET bonuses_pending = false WHERE id = $1", id)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CompleteBonuses:")
+		return err
+	}
+
+	return nil
+}
+
 func (r orderRepository) OrdersByUserID(ctx context.Context, userID int) ([]model.Order, error) {
 	rows, err := r.db.QueryContext(ctx, `SELECT id, status, uploaded_at, accrual 
 		from orders WHERE user_id = $1 ORDER BY uploaded_at`, userID)
@@ -129,6 +202,50 @@ func (r orderRepository) CreateOrder(ctx context.Context, order model.Order) err
 	return err
 }
 
+// CreateOrders stores new orders of the user in one round trip and reports the upload result for each number
+func (r orderRepository) CreateOrders(ctx context.Context, userID int, ids []model.OrderID) ([]model.OrderUploadResult, error) {
+	numbers := make([]string, 0, len(ids))
+	for _, id := range ids {
+		numbers = append(numbers, string(id))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `WITH input AS (SELECT unnest($1::text[]) AS id),
+		inserted AS (
+			INSERT INTO orders (id, user_id, status) SELECT id, $2, 'NEW' FROM input
+			ON CONFLICT (id) DO NOTHING RETURNING id
+		)
+		SELECT i.id, CASE
+			WHEN ins.id IS NOT NULL THEN 'accepted'
+			WHEN o.user_id = $2 THEN 'already_uploaded'
+			ELSE 'uploaded_by_another_user' END
+		FROM input i LEFT JOIN inserted ins ON ins.id = i.id LEFT JOIN orders o ON o.id = i.id`,
+		pq.Array(numbers), userID)
+
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("CreateOrders: invalid save orders")
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.OrderUploadResult, 0, len(ids))
+	for rows.Next() {
+		var result model.OrderUploadResult
+		err = rows.Scan(&result.ID, &result.Result)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CreateOrders: query rows was error")
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func (r orderRepository) OrderByID(ctx context.Context, orderID model.OrderID) (model.Order, error) {
 	row := r.db.QueryRowContext(
 		ctx,
@@ -149,6 +266,222 @@ func (r orderRepository) OrderByID(ctx context.Context, orderID model.OrderID) (
 	return order, nil
 }
 
+func (r orderRepository) AccrualVolume(ctx context.Context, userID int, since time.Time) (model.Amount, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(accrual), 0) FROM orders
+		WHERE user_id = $1 AND status = $2 AND uploaded_at >= $3`, userID, model.StatusProcessed, since)
+
+	var volume model.Amount
+	if err := row.Scan(&volume); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AccrualVolume: invalid scan")
+		return 0, err
+	}
+
+	return volume, nil
+}
+
+func (r orderRepository) AccrualVolumes(ctx context.Context, since time.Time) ([]model.AccrualVolume, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT u.id, COALESCE(SUM(o.accrual), 0) FROM users u
+		LEFT JOIN orders o ON o.user_id = u.id AND o.status = $1 AND o.uploaded_at >= $2
+		WHERE u.deleted_at IS NULL GROUP BY u.id ORDER BY u.id`, model.StatusProcessed, since)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AccrualVolumes: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	volumes := make([]model.AccrualVolume, 0)
+	for rows.Next() {
+		var volume model.AccrualVolume
+		if err = rows.Scan(&volume.UserID, &volume.Volume); err != nil {
+			r.Log(ctx).Error().Err(err).Msg("AccrualVolumes: invalid scan")
+			return nil, err
+		}
+
+		volumes = append(volumes, volume)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AccrualVolumes: query rows was error")
+		return nil, err
+	}
+
+	return volumes, nil
+}
+
+// OverrideOrder locks the order, so other admins see either the old or the new accrual
+// and the poller doesn't update the order it made final
+func (r orderRepository) OverrideOrder(ctx context.Context, id model.OrderID, status model.Status, accrual model.Amount, expiresAt time.Time) (model.OrderOverride, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("OverrideOrder: prepare transaction")
+		return model.OrderOverride{}, err
+	}
+
+	override, err := r.overrideOrder(ctx, tx, id, status, accrual, expiresAt)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("OverrideOrder: unable to rollback")
+		}
+		return model.OrderOverride{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("OverrideOrder: unable to commit")
+		return model.OrderOverride{}, err
+	}
+
+	return override, nil
+}
+
+func (r orderRepository) overrideOrder(ctx context.Context, tx *sql.Tx, id model.OrderID, status model.Status, accrual model.Amount, expiresAt time.Time) (model.OrderOverride, error) {
+	row := tx.QueryRowContext(ctx, "SELECT user_id, status, uploaded_at, accrual, bonuses_pending FROM orders WHERE id = $1 FOR UPDATE", id)
+
+	order := model.Order{ID: id}
+	var pending bool
+	if err := row.Scan(&order.UserID, &order.Status, &order.UploadedAt, &order.Accrual, &pending); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.OrderOverride{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("overrideOrder: select order")
+		return model.OrderOverride{}, err
+	}
+
+	override := model.OrderOverride{
+		PreviousStatus:  order.Status,
+		PreviousAccrual: order.Accrual,
+		Delta:           accrual - order.Accrual,
+	}
+
+	// bonuses depend on the accrual, they are taken back and applied again by the poller for the new one.
+	// The referral is rewarded by any processed order, so it is taken back only when the order isn't processed anymore.
+	wasProcessed, processed := order.Status == model.StatusProcessed, status == model.StatusProcessed
+	if wasProcessed && (!processed || override.Delta != 0) {
+		reversed, err := r.reverseBonuses(ctx, tx, order, !processed)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("overrideOrder: reverse bonuses")
+			return model.OrderOverride{}, err
+		}
+
+		override.ReversedBonuses = reversed
+	}
+	pending = processed && (!wasProcessed || override.Delta != 0 || pending)
+
+	changes := map[int]model.Amount{order.UserID: override.Delta}
+	for userID, sum := range override.ReversedBonuses {
+		changes[userID] -= sum
+	}
+
+	if err := r.changeBalances(ctx, tx, changes, expiresAt); err != nil {
+		return model.OrderOverride{}, err
+	}
+
+	_, err := tx.ExecContext(ctx, "UPDATE orders SET status = $1, accrual = $2, bonuses_pending = $3 WHERE id = $4",
+		status, accrual, pending, id)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("overrideOrder: exec orders")
+		return model.OrderOverride{}, err
+	}
+
+	order.Status, order.Accrual = status, accrual
+	override.Order = order
+
+	return override, nil
+}
+
+// reverseBonuses deletes campaign, tier and optionally referral bonuses of the order
+// and returns the sums to take back by user. Bonus of a deleted referrer isn't taken back.
+func (r orderRepository) reverseBonuses(ctx context.Context, tx *sql.Tx, order model.Order, referral bool) (map[int]model.Amount, error) {
+	reversed := make(map[int]model.Amount)
+
+	for _, query := range []string{
+		"WITH deleted AS (DELETE FROM campaign_bonuses WHERE order_id = $1 RETURNING sum) SELECT COALESCE(SUM(sum), 0) FROM deleted",
+		"WITH deleted AS (DELETE FROM tier_bonuses WHERE order_id = $1 RETURNING sum) SELECT COALESCE(SUM(sum), 0) FROM deleted",
+	} {
+		var sum model.Amount
+		if err := tx.QueryRowContext(ctx, query, order.ID).Scan(&sum); err != nil {
+			return nil, err
+		}
+
+		if sum > 0 {
+			reversed[order.UserID] += sum
+		}
+	}
+
+	if !referral {
+		return reversed, nil
+	}
+
+	var referrerID int
+	var referrerBonus, refereeBonus model.Amount
+	row := tx.QueryRowContext(ctx, `WITH deleted AS (DELETE FROM referral_rewards WHERE order_id = $1 AND referee_id = $2
+			RETURNING referrer_id, referrer_bonus, referee_bonus)
+		SELECT d.referrer_id, CASE WHEN u.deleted_at IS NULL THEN d.referrer_bonus ELSE 0 END, d.referee_bonus
+		FROM deleted d JOIN users u ON u.id = d.referrer_id`, order.ID, order.UserID)
+	if err := row.Scan(&referrerID, &referrerBonus, &refereeBonus); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return reversed, nil
+		}
+
+		return nil, err
+	}
+
+	if refereeBonus > 0 {
+		reversed[order.UserID] += refereeBonus
+	}
+	if referrerBonus > 0 {
+		reversed[referrerID] += referrerBonus
+	}
+
+	return reversed, nil
+}
+
+// changeBalances credits point lots or consumes lots of the users, ErrInsufficientFunds is returned
+// when the points are already spent
+func (r orderRepository) changeBalances(ctx context.Context, tx *sql.Tx, changes map[int]model.Amount, expiresAt time.Time) error {
+	ids := make([]int, 0, len(changes))
+	for userID, change := range changes {
+		if change != 0 {
+			ids = append(ids, userID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Ints(ids)
+
+	balances, err := lockUsers(ctx, tx, ids...)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("changeBalances: lock users")
+		return err
+	}
+
+	for _, userID := range ids {
+		change := changes[userID]
+		if change > 0 {
+			err = addPointLot(ctx, tx, userID, change, model.LotSourceAccrual, expiresAt)
+		} else {
+			if balances[userID] < -change {
+				return storage.ErrInsufficientFunds
+			}
+
+			_, err = consumePointLots(ctx, tx, userID, -change)
+		}
+		if err != nil {
+			r.Log(ctx).Warn().Err(err).Msg("changeBalances: point lots")
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", change, userID)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("changeBalances: exec users")
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (r orderRepository) Log(ctx context.Context) *zerolog.Logger {
 	_, logger := logging.GetCtxLogger(ctx)
 	logger = logger.With().Str(logging.ServiceKey, "database orderRepository").Logger()
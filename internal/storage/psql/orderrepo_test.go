@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/provider"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -37,6 +39,82 @@ func Test_orderRepository_OrdersByStatus(t *testing.T) {
 	})
 }
 
+func Test_orderRepository_UpdateForAccrual(t *testing.T) {
+	t.Run("should credit accrual and mark bonuses of processed order pending", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		expiresAt := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE orders SET status = \\$1, accrual = \\$2, bonuses_pending = \\$3 WHERE id = \\$4 AND status IN \\(\\$5, \\$6\\)").
+			WithArgs(model.StatusProcessed, model.Amount(1000), true, model.OrderID("1"), model.StatusNew, model.StatusProcessing).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(1000), model.LotSourceAccrual, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(1000), 666).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err = repo.UpdateForAccrual(context.Background(), model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing},
+			provider.AccrualResponse{Order: "1", Status: model.StatusProcessed, Accrual: 1000}, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should not credit order finalized by override", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE orders SET status = \\$1, accrual = \\$2, bonuses_pending = \\$3 WHERE id = \\$4 AND status IN \\(\\$5, \\$6\\)").
+			WithArgs(model.StatusProcessed, model.Amount(1000), true, model.OrderID("1"), model.StatusNew, model.StatusProcessing).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = repo.UpdateForAccrual(context.Background(), model.Order{ID: "1", UserID: 666, Status: model.StatusProcessing},
+			provider.AccrualResponse{Order: "1", Status: model.StatusProcessed, Accrual: 1000}, time.Now())
+
+		require.Equal(t, err, storage.ErrOrderFinalized)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_orderRepository_PendingBonusOrders(t *testing.T) {
+	t.Run("should return processed orders with pending bonuses", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectQuery("SELECT id, user_id, status, uploaded_at, accrual FROM orders WHERE bonuses_pending ORDER BY uploaded_at LIMIT \\$1").
+			WithArgs(100).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "status", "uploaded_at", "accrual"}).
+				AddRow("1", 666, model.StatusProcessed, now, 1000))
+
+		orders, err := repo.PendingBonusOrders(context.Background(), 100)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, orders, []model.Order{
+			{ID: "1", UserID: 666, Status: model.StatusProcessed, UploadedAt: model.UploadedTime(now), Accrual: 1000},
+		})
+	})
+}
+
 func Test_orderRepository_OrdersByUserID(t *testing.T) {
 	t.Run("should return orders by userID", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
@@ -120,3 +198,252 @@ func Test_orderRepository_OrderByID(t *testing.T) {
 		)
 	})
 }
+
+func Test_orderRepository_CreateOrders(t *testing.T) {
+	t.Run("should create orders in one query", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		rows := sqlmock.NewRows([]string{"id", "result"}).
+			AddRow("1", model.UploadAccepted).
+			AddRow("2", model.UploadAlreadyUploaded)
+		mock.ExpectQuery("WITH input AS \\(SELECT unnest\\(\\$1::text\\[\\]\\) AS id\\)").
+			WithArgs("{\"1\",\"2\"}", 666).
+			WillReturnRows(rows)
+
+		results, err := repo.CreateOrders(context.Background(), 666, []model.OrderID{"1", "2"})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, results, []model.OrderUploadResult{
+			{ID: "1", Result: model.UploadAccepted},
+			{ID: "2", Result: model.UploadAlreadyUploaded},
+		})
+	})
+}
+
+func Test_orderRepository_AccrualVolumes(t *testing.T) {
+	t.Run("should return volumes of all users", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		since := time.Now()
+
+		mock.ExpectQuery("SELECT u.id, COALESCE\\(SUM\\(o.accrual\\), 0\\) FROM users u LEFT JOIN orders o").
+			WithArgs(model.StatusProcessed, since).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "sum"}).AddRow(1, 150000).AddRow(2, 0))
+
+		volumes, err := repo.AccrualVolumes(context.Background(), since)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, volumes, []model.AccrualVolume{{UserID: 1, Volume: 150000}, {UserID: 2, Volume: 0}})
+	})
+}
+
+var overrideOrderColumns = []string{"user_id", "status", "uploaded_at", "accrual", "bonuses_pending"}
+
+func Test_orderRepository_OverrideOrder(t *testing.T) {
+	t.Run("should credit the rest of the accrual and recompute bonuses", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		now := time.Now()
+		expiresAt := now.AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, uploaded_at, accrual, bonuses_pending FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows(overrideOrderColumns).AddRow(666, model.StatusProcessed, now, 1000, false))
+		mock.ExpectQuery("DELETE FROM campaign_bonuses WHERE order_id = \\$1").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(200))
+		mock.ExpectQuery("DELETE FROM tier_bonuses WHERE order_id = \\$1").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1200))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(300), model.LotSourceAccrual, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(300), 666).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE orders SET status = \\$1, accrual = \\$2, bonuses_pending = \\$3 WHERE id = \\$4").
+			WithArgs(model.StatusProcessed, model.Amount(1500), true, model.OrderID("1")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		override, err := repo.OverrideOrder(context.Background(), "1", model.StatusProcessed, 1500, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, override, model.OrderOverride{
+			Order:           model.Order{ID: "1", UserID: 666, Status: model.StatusProcessed, UploadedAt: model.UploadedTime(now), Accrual: 1500},
+			PreviousStatus:  model.StatusProcessed,
+			PreviousAccrual: 1000,
+			Delta:           500,
+			ReversedBonuses: map[int]model.Amount{666: 200},
+		})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should take back bonuses of the owner and the referrer when order becomes invalid", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, uploaded_at, accrual, bonuses_pending FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows(overrideOrderColumns).AddRow(666, model.StatusProcessed, now, 1000, false))
+		mock.ExpectQuery("DELETE FROM campaign_bonuses WHERE order_id = \\$1").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(100))
+		mock.ExpectQuery("DELETE FROM tier_bonuses WHERE order_id = \\$1").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(50))
+		mock.ExpectQuery("DELETE FROM referral_rewards WHERE order_id = \\$1 AND referee_id = \\$2").
+			WithArgs(model.OrderID("1"), 666).
+			WillReturnRows(sqlmock.NewRows([]string{"referrer_id", "referrer_bonus", "referee_bonus"}).AddRow(42, 10000, 10000))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(10000))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(11150))
+		for _, user := range []struct {
+			id  int
+			sum model.Amount
+		}{{id: 42, sum: 10000}, {id: 666, sum: 11150}} {
+			mock.ExpectQuery("SELECT id, remaining, expires_at FROM point_lots").
+				WithArgs(user.id).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "remaining", "expires_at"}).AddRow(user.id, user.sum, now))
+			mock.ExpectExec("UPDATE point_lots SET remaining = remaining - \\$1 WHERE id = \\$2").
+				WithArgs(user.sum, user.id).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+				WithArgs(-user.sum, user.id).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectExec("UPDATE orders SET status = \\$1, accrual = \\$2, bonuses_pending = \\$3 WHERE id = \\$4").
+			WithArgs(model.StatusInvalid, model.Amount(0), false, model.OrderID("1")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		override, err := repo.OverrideOrder(context.Background(), "1", model.StatusInvalid, 0, now)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, override.Delta, model.Amount(-1000))
+		require.Equal(t, override.ReversedBonuses, map[int]model.Amount{42: 10000, 666: 10150})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should not reset the order when the accrual is spent", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, uploaded_at, accrual, bonuses_pending FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows(overrideOrderColumns).AddRow(666, model.StatusProcessed, now, 1000, false))
+		mock.ExpectQuery("DELETE FROM campaign_bonuses WHERE order_id = \\$1").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectQuery("DELETE FROM tier_bonuses WHERE order_id = \\$1").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectQuery("DELETE FROM referral_rewards WHERE order_id = \\$1 AND referee_id = \\$2").
+			WithArgs(model.OrderID("1"), 666).
+			WillReturnRows(sqlmock.NewRows([]string{"referrer_id", "referrer_bonus", "referee_bonus"}))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(300))
+		mock.ExpectRollback()
+
+		_, err = repo.OverrideOrder(context.Background(), "1", model.StatusNew, 0, now)
+
+		require.Equal(t, err, storage.ErrInsufficientFunds)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should mark bonuses pending when order becomes processed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, uploaded_at, accrual, bonuses_pending FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows(overrideOrderColumns).AddRow(666, model.StatusNew, now, 0, false))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(1000), model.LotSourceAccrual, now).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(1000), 666).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE orders SET status = \\$1, accrual = \\$2, bonuses_pending = \\$3 WHERE id = \\$4").
+			WithArgs(model.StatusProcessed, model.Amount(1000), true, model.OrderID("1")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		override, err := repo.OverrideOrder(context.Background(), "1", model.StatusProcessed, 1000, now)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, override.Delta, model.Amount(1000))
+		require.Equal(t, len(override.ReversedBonuses), 0)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should change only status without balance delta", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &orderRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, status, uploaded_at, accrual, bonuses_pending FROM orders WHERE id = \\$1 FOR UPDATE").
+			WithArgs(model.OrderID("1")).
+			WillReturnRows(sqlmock.NewRows(overrideOrderColumns).AddRow(666, model.StatusProcessing, now, 0, false))
+		mock.ExpectExec("UPDATE orders SET status = \\$1, accrual = \\$2, bonuses_pending = \\$3 WHERE id = \\$4").
+			WithArgs(model.StatusInvalid, model.Amount(0), false, model.OrderID("1")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		override, err := repo.OverrideOrder(context.Background(), "1", model.StatusInvalid, 0, now)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, override.Delta, model.Amount(0))
+		require.Equal(t, override.Order.Status, model.StatusInvalid)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
@@ -0,0 +1,252 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+func NewPointLotRepository(db *sql.DB) storage.PointLotRepository {
+	return &pointLotRepository{db: db}
+}
+
+type pointLotRepository struct {
+	db *sql.DB
+}
+
+// ExpirePointLots zeroes remaining points of expired lots, writes expiration entries and debits balances
+func (r pointLotRepository) ExpirePointLots(ctx context.Context, limit int) ([]model.PointExpiration, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ExpirePointLots: prepare transaction")
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `WITH due AS (
+			SELECT id, user_id, remaining FROM point_lots
+			WHERE expires_at <= current_timestamp AND remaining > 0
+			ORDER BY expires_at LIMIT $1 FOR UPDATE SKIP LOCKED
+		), expired AS (
+			UPDATE point_lots l SET remaining = 0 FROM due WHERE l.id = due.id
+		)
+		INSERT INTO point_expirations (lot_id, user_id, amount) SELECT id, user_id, remaining FROM due
+		RETURNING id, lot_id, user_id, amount, expired_at`, limit)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ExpirePointLots: exec point_lots")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ExpirePointLots: unable to rollback")
+		}
+		return nil, err
+	}
+
+	expirations := make([]model.PointExpiration, 0)
+	for rows.Next() {
+		var expiration model.PointExpiration
+		err = rows.Scan(&expiration.ID, &expiration.LotID, &expiration.UserID, &expiration.Amount, &expiration.ExpiredAt)
+		if err != nil {
+			rows.Close()
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("ExpirePointLots: unable to rollback")
+			}
+			return nil, err
+		}
+
+		expirations = append(expirations, expiration)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ExpirePointLots: query rows was error")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ExpirePointLots: unable to rollback")
+		}
+		return nil, err
+	}
+
+	for _, expiration := range expirations {
+		_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance - $1 WHERE id = $2", expiration.Amount, expiration.UserID)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("ExpirePointLots: exec users")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("ExpirePointLots: unable to rollback")
+			}
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("ExpirePointLots: unable to commit")
+		return nil, err
+	}
+
+	return expirations, nil
+}
+
+func (r pointLotRepository) ExpiringPoints(ctx context.Context, userID int, before time.Time) (model.Amount, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(remaining), 0) FROM point_lots
+		WHERE user_id = $1 AND remaining > 0 AND expires_at > current_timestamp AND expires_at <= $2`, userID, before)
+
+	var amount model.Amount
+	if err := row.Scan(&amount); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ExpiringPoints: invalid scan")
+		return 0, err
+	}
+
+	return amount, nil
+}
+
+func (r pointLotRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database pointLotRepository").Logger()
+
+	return &logger
+}
+
+// lotConsumption is a part of the lot taken by a withdrawal
+type lotConsumption struct {
+	LotID     int
+	Amount    model.Amount
+	ExpiresAt time.Time
+}
+
+// addPointLot credits the lot, balance of the user is updated by the caller
+func addPointLot(ctx context.Context, tx *sql.Tx, userID int, amount model.Amount, source string, expiresAt time.Time) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO point_lots (user_id, amount, remaining, source, expires_at)
+		VALUES ($1, $2, $2, $3, $4)`, userID, amount, source, expiresAt)
+
+	return err
+}
+
+// consumePointLots takes sum from not expired lots of the user, the oldest lots first.
+// ErrInsufficientFunds is returned when the lots don't cover the sum.
+func consumePointLots(ctx context.Context, tx *sql.Tx, userID int, sum model.Amount) ([]lotConsumption, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, remaining, expires_at FROM point_lots
+		WHERE user_id = $1 AND remaining > 0 AND expires_at > current_timestamp
+		ORDER BY expires_at, id FOR UPDATE`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	consumptions := make([]lotConsumption, 0)
+	rest := sum
+	for rows.Next() && rest > 0 {
+		var consumption lotConsumption
+		var remaining model.Amount
+		if err = rows.Scan(&consumption.LotID, &remaining, &consumption.ExpiresAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+
+		consumption.Amount = remaining
+		if consumption.Amount > rest {
+			consumption.Amount = rest
+		}
+
+		rest -= consumption.Amount
+		consumptions = append(consumptions, consumption)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if rest > 0 {
+		return nil, storage.ErrInsufficientFunds
+	}
+
+	for _, consumption := range consumptions {
+		_, err = tx.ExecContext(ctx, "UPDATE point_lots SET remaining = remaining - $1 WHERE id = $2",
+			consumption.Amount, consumption.LotID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return consumptions, nil
+}
+
+// saveLotConsumptions remembers consumed lots of the withdrawal or the pending withdrawal
+func saveLotConsumptions(ctx context.Context, tx *sql.Tx, consumptions []lotConsumption, withdrawID int, pendingID int) error {
+	for _, consumption := range consumptions {
+		_, err := tx.ExecContext(ctx, `INSERT INTO point_lot_consumptions (lot_id, amount, withdraw_id, pending_withdraw_id)
+			VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, 0))`, consumption.LotID, consumption.Amount, withdrawID, pendingID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restorePendingLots returns points reserved by the pending withdrawal to the lots they were taken from.
+// Already expired lots are expired again by the next ExpirePointLots.
+func restorePendingLots(ctx context.Context, tx *sql.Tx, pendingID int) error {
+	_, err := tx.ExecContext(ctx, `UPDATE point_lots l SET remaining = l.remaining + c.amount
+		FROM point_lot_consumptions c WHERE c.lot_id = l.id AND c.pending_withdraw_id = $1`, pendingID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM point_lot_consumptions WHERE pending_withdraw_id = $1", pendingID)
+
+	return err
+}
+
+// restoreWithdrawLots returns sum of the withdrawal to the lots with the latest expiry first,
+// the rest not covered by consumptions is returned
+func restoreWithdrawLots(ctx context.Context, tx *sql.Tx, withdrawID int, sum model.Amount) (model.Amount, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT c.id, c.lot_id, c.amount FROM point_lot_consumptions c
+		JOIN point_lots l ON l.id = c.lot_id
+		WHERE c.withdraw_id = $1 AND c.amount > 0 ORDER BY l.expires_at DESC, l.id DESC FOR UPDATE OF c`, withdrawID)
+	if err != nil {
+		return 0, err
+	}
+
+	type restore struct {
+		consumptionID int
+		lot           lotConsumption
+	}
+
+	restores := make([]restore, 0)
+	rest := sum
+	for rows.Next() && rest > 0 {
+		var item restore
+		if err = rows.Scan(&item.consumptionID, &item.lot.LotID, &item.lot.Amount); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		if item.lot.Amount > rest {
+			item.lot.Amount = rest
+		}
+
+		rest -= item.lot.Amount
+		restores = append(restores, item)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, item := range restores {
+		_, err = tx.ExecContext(ctx, "UPDATE point_lot_consumptions SET amount = amount - $1 WHERE id = $2",
+			item.lot.Amount, item.consumptionID)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE point_lots SET remaining = remaining + $1 WHERE id = $2",
+			item.lot.Amount, item.lot.LotID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return rest, nil
+}
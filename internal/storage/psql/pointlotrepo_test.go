@@ -0,0 +1,61 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_pointLotRepository_ExpirePointLots(t *testing.T) {
+	t.Run("should write expirations and debit balances", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &pointLotRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO point_expirations").
+			WithArgs(100).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "lot_id", "user_id", "amount", "expired_at"}).
+				AddRow(1, 10, 666, 250, now))
+		mock.ExpectExec("UPDATE users SET balance = balance - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(250), 666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		expirations, err := repo.ExpirePointLots(context.Background(), 100)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, expirations, []model.PointExpiration{{ID: 1, LotID: 10, UserID: 666, Amount: 250, ExpiredAt: now}})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_pointLotRepository_ExpiringPoints(t *testing.T) {
+	t.Run("should return sum of points expiring before the time", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &pointLotRepository{db: db}
+
+		before := time.Now().Add(time.Hour)
+
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(remaining\\), 0\\) FROM point_lots").
+			WithArgs(666, before).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1500))
+
+		amount, err := repo.ExpiringPoints(context.Background(), 666, before)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, amount, model.Amount(1500))
+	})
+}
@@ -0,0 +1,190 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+// reconciliationQuery sums every table which changes the balance, one statement sees a consistent snapshot.
+// $1 is the status of applied adjustments, $2 is the reason of reconciliation corrections left out of the ledger.
+const reconciliationQuery = `SELECT u.id, u.username, u.balance,
+	(SELECT COALESCE(SUM(remaining), 0) FROM point_lots WHERE user_id = u.id),
+	(SELECT COALESCE(SUM(accrual), 0) FROM orders WHERE user_id = u.id),
+	(SELECT COALESCE(SUM(sum), 0) FROM campaign_bonuses WHERE user_id = u.id)
+		+ (SELECT COALESCE(SUM(sum), 0) FROM tier_bonuses WHERE user_id = u.id)
+		+ (SELECT COALESCE(SUM(referrer_bonus), 0) FROM referral_rewards WHERE referrer_id = u.id)
+		+ (SELECT COALESCE(SUM(referee_bonus), 0) FROM referral_rewards WHERE referee_id = u.id),
+	(SELECT COALESCE(SUM(sum), 0) FROM transfers WHERE recipient_id = u.id),
+	(SELECT COALESCE(SUM(sum), 0) FROM transfers WHERE sender_id = u.id),
+	(SELECT COALESCE(SUM(sum), 0) FROM withdraw_log WHERE user_id = u.id),
+	(SELECT COALESCE(SUM(sum), 0) FROM pending_withdrawals WHERE user_id = u.id),
+	(SELECT COALESCE(SUM(reversed_sum), 0) FROM withdraw_log WHERE user_id = u.id),
+	(SELECT COALESCE(SUM(sum), 0) FROM balance_adjustments WHERE user_id = u.id AND status = $1 AND reason_code <> $2),
+	(SELECT COALESCE(SUM(amount), 0) FROM point_expirations WHERE user_id = u.id)
+	FROM users u`
+
+func NewReconciliationRepository(db *sql.DB) storage.ReconciliationRepository {
+	return &reconciliationRepository{db: db}
+}
+
+type reconciliationRepository struct {
+	db *sql.DB
+}
+
+func (r reconciliationRepository) Reconciliation(ctx context.Context, userID int) (model.Reconciliation, error) {
+	row := r.db.QueryRowContext(ctx, reconciliationQuery+" WHERE u.id = $3",
+		model.AdjustmentApplied, model.AdjustmentReasonReconciliation, userID)
+
+	reconciliation, err := scanReconciliation(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Reconciliation{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("Reconciliation: invalid scan")
+		return model.Reconciliation{}, err
+	}
+
+	return reconciliation, nil
+}
+
+func (r reconciliationRepository) Reconciliations(ctx context.Context, afterID int, limit int) ([]model.Reconciliation, error) {
+	rows, err := r.db.QueryContext(ctx, reconciliationQuery+" WHERE u.deleted_at IS NULL AND u.id > $3 ORDER BY u.id LIMIT $4",
+		model.AdjustmentApplied, model.AdjustmentReasonReconciliation, afterID, limit)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("Reconciliations: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	reconciliations := make([]model.Reconciliation, 0)
+	for rows.Next() {
+		reconciliation, err := scanReconciliation(rows)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("Reconciliations: invalid scan")
+			return nil, err
+		}
+
+		reconciliations = append(reconciliations, reconciliation)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("Reconciliations: query rows was error")
+		return nil, err
+	}
+
+	return reconciliations, nil
+}
+
+// CorrectBalance reconciles the user again under the lock, so a concurrent operation or another run
+// doesn't make the correction stale
+func (r reconciliationRepository) CorrectBalance(ctx context.Context, userID int, actorID int, expiresAt time.Time) (model.ReconciliationCorrection, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CorrectBalance: prepare transaction")
+		return model.ReconciliationCorrection{}, err
+	}
+
+	if _, err = lockUsers(ctx, tx, userID); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CorrectBalance: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ReconciliationCorrection{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("CorrectBalance: lock user")
+		return model.ReconciliationCorrection{}, err
+	}
+
+	row := tx.QueryRowContext(ctx, reconciliationQuery+" WHERE u.id = $3",
+		model.AdjustmentApplied, model.AdjustmentReasonReconciliation, userID)
+	reconciliation, err := scanReconciliation(row)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CorrectBalance: invalid scan")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CorrectBalance: unable to rollback")
+		}
+		return model.ReconciliationCorrection{}, err
+	}
+
+	correction := model.ReconciliationCorrection{
+		BalanceDelta: reconciliation.Ledger - reconciliation.Balance,
+		LotsDelta:    reconciliation.Ledger - reconciliation.Lots,
+	}
+
+	if reconciliation.Consistent() || reconciliation.Ledger < 0 {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CorrectBalance: unable to rollback")
+		}
+
+		// negative ledger means the history itself is broken, it is left to admins
+		if reconciliation.Ledger < 0 {
+			return model.ReconciliationCorrection{}, storage.ErrInsufficientFunds
+		}
+		return model.ReconciliationCorrection{}, nil
+	}
+
+	if correction.BalanceDelta != 0 {
+		row = tx.QueryRowContext(ctx, `INSERT INTO balance_adjustments
+			(user_id, sum, reason_code, comment, status, created_by, decided_by, decided_at)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($6, 0), current_timestamp) RETURNING id`,
+			userID, correction.BalanceDelta, model.AdjustmentReasonReconciliation, "balance corrected to the ledger",
+			model.AdjustmentApplied, actorID)
+		err = row.Scan(&correction.AdjustmentID)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", correction.BalanceDelta, userID)
+		}
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("CorrectBalance: exec balance")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("CorrectBalance: unable to rollback")
+			}
+			return model.ReconciliationCorrection{}, err
+		}
+	}
+
+	if correction.LotsDelta > 0 {
+		err = addPointLot(ctx, tx, userID, correction.LotsDelta, model.LotSourceAdjustment, expiresAt)
+	} else if correction.LotsDelta < 0 {
+		// only not expired lots are consumed, ErrInsufficientFunds is returned when they don't cover the difference
+		_, err = consumePointLots(ctx, tx, userID, -correction.LotsDelta)
+	}
+	if err != nil {
+		r.Log(ctx).Warn().Err(err).Msg("CorrectBalance: point lots")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CorrectBalance: unable to rollback")
+		}
+		return model.ReconciliationCorrection{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("CorrectBalance: unable to commit")
+		return model.ReconciliationCorrection{}, err
+	}
+
+	return correction, nil
+}
+
+func (r reconciliationRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database reconciliationRepository").Logger()
+
+	return &logger
+}
+
+func scanReconciliation(row rowScanner) (model.Reconciliation, error) {
+	var r model.Reconciliation
+	err := row.Scan(&r.UserID, &r.Login, &r.Balance, &r.Lots, &r.Accruals, &r.Bonuses, &r.TransfersIn, &r.TransfersOut,
+		&r.Withdrawn, &r.Reserved, &r.Reversed, &r.Adjustments, &r.Expired)
+	r.Ledger = r.LedgerBalance()
+
+	return r, err
+}
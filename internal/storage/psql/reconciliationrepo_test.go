@@ -0,0 +1,214 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var reconciliationColumns = []string{"id", "username", "balance", "lots", "accruals", "bonuses", "transfers_in",
+	"transfers_out", "withdrawn", "reserved", "reversed", "adjustments", "expired"}
+
+func Test_reconciliationRepository_Reconciliation(t *testing.T) {
+	t.Run("should calculate ledger from history of the user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &reconciliationRepository{db: db}
+
+		mock.ExpectQuery("SELECT u.id, u.username, u.balance, (.+) FROM users u WHERE u.id = \\$3").
+			WithArgs(model.AdjustmentApplied, model.AdjustmentReasonReconciliation, 666).
+			WillReturnRows(sqlmock.NewRows(reconciliationColumns).
+				AddRow(666, "user", 1500, 1500, 2000, 300, 100, 200, 500, 100, 50, -50, 100))
+
+		reconciliation, err := repo.Reconciliation(context.Background(), 666)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, reconciliation, model.Reconciliation{
+			UserID:       666,
+			Login:        "user",
+			Balance:      1500,
+			Lots:         1500,
+			Ledger:       1500,
+			Accruals:     2000,
+			Bonuses:      300,
+			TransfersIn:  100,
+			TransfersOut: 200,
+			Withdrawn:    500,
+			Reserved:     100,
+			Reversed:     50,
+			Adjustments:  -50,
+			Expired:      100,
+		})
+		require.Equal(t, reconciliation.Consistent(), true)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should report mismatch of balance and lots", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &reconciliationRepository{db: db}
+
+		mock.ExpectQuery("SELECT u.id, u.username, u.balance").
+			WillReturnRows(sqlmock.NewRows(reconciliationColumns).
+				AddRow(666, "user", 1000, 900, 1000, 0, 0, 0, 0, 0, 0, 0, 0))
+
+		reconciliation, err := repo.Reconciliation(context.Background(), 666)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, reconciliation.Ledger, model.Amount(1000))
+		require.Equal(t, reconciliation.Consistent(), false)
+	})
+	t.Run("should return ErrNotFound for unknown user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &reconciliationRepository{db: db}
+
+		mock.ExpectQuery("SELECT u.id, u.username, u.balance").
+			WillReturnRows(sqlmock.NewRows(reconciliationColumns))
+
+		_, err = repo.Reconciliation(context.Background(), 666)
+
+		require.Equal(t, err, storage.ErrNotFound)
+	})
+}
+
+func Test_reconciliationRepository_Reconciliations(t *testing.T) {
+	t.Run("should return page of not deleted users", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &reconciliationRepository{db: db}
+
+		mock.ExpectQuery("SELECT u.id, (.+) WHERE u.deleted_at IS NULL AND u.id > \\$3 ORDER BY u.id LIMIT \\$4").
+			WithArgs(model.AdjustmentApplied, model.AdjustmentReasonReconciliation, 10, 2).
+			WillReturnRows(sqlmock.NewRows(reconciliationColumns).
+				AddRow(11, "first", 100, 100, 100, 0, 0, 0, 0, 0, 0, 0, 0).
+				AddRow(12, "second", 300, 200, 200, 0, 0, 0, 0, 0, 0, 0, 0))
+
+		reconciliations, err := repo.Reconciliations(context.Background(), 10, 2)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, len(reconciliations), 2)
+		require.Equal(t, reconciliations[0].Consistent(), true)
+		require.Equal(t, reconciliations[1].Ledger, model.Amount(200))
+		require.Equal(t, reconciliations[1].Consistent(), false)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_reconciliationRepository_CorrectBalance(t *testing.T) {
+	t.Run("should correct drifted balance with adjustment", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &reconciliationRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1500))
+		mock.ExpectQuery("SELECT u.id, u.username, u.balance").
+			WithArgs(model.AdjustmentApplied, model.AdjustmentReasonReconciliation, 666).
+			WillReturnRows(sqlmock.NewRows(reconciliationColumns).
+				AddRow(666, "user", 1500, 1000, 1000, 0, 0, 0, 0, 0, 0, 0, 0))
+		mock.ExpectQuery("INSERT INTO balance_adjustments").
+			WithArgs(666, model.Amount(-500), model.AdjustmentReasonReconciliation, sqlmock.AnyArg(), model.AdjustmentApplied, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(-500), 666).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		correction, err := repo.CorrectBalance(context.Background(), 666, 0, time.Now())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, correction, model.ReconciliationCorrection{AdjustmentID: 7, BalanceDelta: -500})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should credit missing point lots without adjustment", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &reconciliationRepository{db: db}
+
+		expiresAt := time.Now().AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance FROM users").
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+		mock.ExpectQuery("SELECT u.id, u.username, u.balance").
+			WillReturnRows(sqlmock.NewRows(reconciliationColumns).
+				AddRow(666, "user", 1000, 800, 1000, 0, 0, 0, 0, 0, 0, 0, 0))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(200), model.LotSourceAdjustment, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		correction, err := repo.CorrectBalance(context.Background(), 666, 1, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, correction, model.ReconciliationCorrection{LotsDelta: 200})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should skip consistent user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &reconciliationRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance FROM users").
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+		mock.ExpectQuery("SELECT u.id, u.username, u.balance").
+			WillReturnRows(sqlmock.NewRows(reconciliationColumns).
+				AddRow(666, "user", 1000, 1000, 1000, 0, 0, 0, 0, 0, 0, 0, 0))
+		mock.ExpectRollback()
+
+		correction, err := repo.CorrectBalance(context.Background(), 666, 1, time.Now())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, correction, model.ReconciliationCorrection{})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should refuse negative ledger", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &reconciliationRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance FROM users").
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(100))
+		mock.ExpectQuery("SELECT u.id, u.username, u.balance").
+			WillReturnRows(sqlmock.NewRows(reconciliationColumns).
+				AddRow(666, "user", 100, 100, 0, 0, 0, 0, 500, 0, 0, 0, 0))
+		mock.ExpectRollback()
+
+		_, err = repo.CorrectBalance(context.Background(), 666, 1, time.Now())
+
+		require.Equal(t, err, storage.ErrInsufficientFunds)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
@@ -0,0 +1,224 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+func NewReferralRepository(db *sql.DB) storage.ReferralRepository {
+	return &referralRepository{db: db}
+}
+
+type referralRepository struct {
+	db *sql.DB
+}
+
+func (r referralRepository) ReferrerByCode(ctx context.Context, code string) (model.User, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT id, username FROM users WHERE referral_code = $1 AND deleted_at IS NULL", code)
+
+	var user model.User
+	if err := row.Scan(&user.ID, &user.Username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("ReferrerByCode: invalid scan")
+		return model.User{}, err
+	}
+
+	return user, nil
+}
+
+func (r referralRepository) ReferralCode(ctx context.Context, userID int) (string, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT referral_code FROM users WHERE id = $1", userID)
+
+	var code string
+	if err := row.Scan(&code); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("ReferralCode: invalid scan")
+		return "", err
+	}
+
+	return code, nil
+}
+
+func (r referralRepository) ReferralsByReferrer(ctx context.Context, referrerID int) ([]model.Referral, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT u.username, u.created_at, COALESCE(rr.referrer_bonus, 0), rr.created_at
+		FROM users u LEFT JOIN referral_rewards rr ON rr.referee_id = u.id
+		WHERE u.referred_by = $1 ORDER BY u.created_at`, referrerID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReferralsByReferrer: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	referrals := make([]model.Referral, 0)
+	for rows.Next() {
+		var referral model.Referral
+		var rewardedAt sql.NullTime
+
+		err = rows.Scan(&referral.Login, &referral.CreatedAt, &referral.Bonus, &rewardedAt)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("ReferralsByReferrer: invalid scan")
+			return nil, err
+		}
+
+		if rewardedAt.Valid {
+			referral.RewardedAt = &rewardedAt.Time
+		}
+
+		referrals = append(referrals, referral)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReferralsByReferrer: query rows was error")
+		return nil, err
+	}
+
+	return referrals, nil
+}
+
+func (r referralRepository) RewardReferral(ctx context.Context, reward model.ReferralReward, maxRewards int, expiresAt time.Time) (model.ReferralReward, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("RewardReferral: prepare transaction")
+		return model.ReferralReward{}, err
+	}
+
+	if _, err = lockBonusOrder(ctx, tx, reward.OrderID); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("RewardReferral: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ReferralReward{}, nil
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("RewardReferral: lock order")
+		return model.ReferralReward{}, err
+	}
+
+	var referrerActive bool
+	row := tx.QueryRowContext(ctx, `SELECT u.referred_by, r.deleted_at IS NULL FROM users u
+		JOIN users r ON r.id = u.referred_by WHERE u.id = $1`, reward.RefereeID)
+	if err = row.Scan(&reward.ReferrerID, &referrerActive); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("RewardReferral: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ReferralReward{}, nil
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("RewardReferral: select referrer")
+		return model.ReferralReward{}, err
+	}
+
+	if reward.ReferrerID == reward.RefereeID {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("RewardReferral: unable to rollback")
+		}
+		return model.ReferralReward{}, nil
+	}
+
+	// deleted referrer gets nothing, the referee is still rewarded
+	ids := []int{reward.RefereeID}
+	if referrerActive {
+		ids = append(ids, reward.ReferrerID)
+	} else {
+		reward.ReferrerBonus = 0
+	}
+
+	// the referrer lock serializes rewards of the referrer, so concurrent referees can't exceed the cap
+	if _, err = lockUsers(ctx, tx, ids...); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("RewardReferral: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ReferralReward{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("RewardReferral: lock users")
+		return model.ReferralReward{}, err
+	}
+
+	if referrerActive && maxRewards > 0 {
+		var rewarded int
+		row = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM referral_rewards WHERE referrer_id = $1 AND referrer_bonus > 0`,
+			reward.ReferrerID)
+		if err = row.Scan(&rewarded); err != nil {
+			r.Log(ctx).Error().Err(err).Msg("RewardReferral: select rewarded")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("RewardReferral: unable to rollback")
+			}
+			return model.ReferralReward{}, err
+		}
+
+		if rewarded >= maxRewards {
+			reward.ReferrerBonus = 0
+		}
+	}
+
+	row = tx.QueryRowContext(ctx, `INSERT INTO referral_rewards (referee_id, referrer_id, order_id, referrer_bonus, referee_bonus)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (referee_id) DO NOTHING RETURNING id, created_at`,
+		reward.RefereeID, reward.ReferrerID, reward.OrderID, reward.ReferrerBonus, reward.RefereeBonus)
+	if err = row.Scan(&reward.ID, &reward.CreatedAt); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("RewardReferral: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ReferralReward{}, nil
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("RewardReferral: exec referral_rewards")
+		return model.ReferralReward{}, err
+	}
+
+	credits := []struct {
+		userID int
+		bonus  model.Amount
+	}{{reward.ReferrerID, reward.ReferrerBonus}, {reward.RefereeID, reward.RefereeBonus}}
+
+	for _, credit := range credits {
+		if credit.bonus <= 0 {
+			continue
+		}
+
+		err = addPointLot(ctx, tx, credit.userID, credit.bonus, model.LotSourceReferral, expiresAt)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", credit.bonus, credit.userID)
+		}
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("RewardReferral: credit balance")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("RewardReferral: unable to rollback")
+			}
+			return model.ReferralReward{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("RewardReferral: unable to commit")
+		return model.ReferralReward{}, err
+	}
+
+	return reward, nil
+}
+
+func (r referralRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database referralRepository").Logger()
+
+	return &logger
+}
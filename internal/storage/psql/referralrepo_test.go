@@ -0,0 +1,166 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_referralRepository_ReferrerByCode(t *testing.T) {
+	t.Run("should return not found for unknown code", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &referralRepository{db: db}
+
+		mock.ExpectQuery("SELECT id, username FROM users WHERE referral_code = \\$1").
+			WithArgs("AB12CD34").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username"}))
+
+		_, err = repo.ReferrerByCode(context.Background(), "AB12CD34")
+
+		require.Equal(t, err, storage.ErrNotFound)
+	})
+}
+
+func Test_referralRepository_RewardReferral(t *testing.T) {
+	t.Run("should credit referee only when referrer cap is reached", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &referralRepository{db: db}
+
+		now := time.Now()
+		expiresAt := now.AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders WHERE id = \\$1 AND status = \\$2 AND bonuses_pending FOR UPDATE").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}).AddRow(1000))
+		mock.ExpectQuery("SELECT u.referred_by, r.deleted_at IS NULL FROM users u").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"referred_by", "active"}).AddRow(42, true))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM referral_rewards WHERE referrer_id = \\$1").
+			WithArgs(42).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(20))
+		mock.ExpectQuery("INSERT INTO referral_rewards (.+) ON CONFLICT \\(referee_id\\) DO NOTHING").
+			WithArgs(666, 42, model.OrderID("123"), model.Amount(0), model.Amount(10000)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, now))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(10000), model.LotSourceReferral, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(10000), 666).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		reward, err := repo.RewardReferral(context.Background(), model.ReferralReward{
+			RefereeID: 666, OrderID: "123", ReferrerBonus: 10000, RefereeBonus: 10000,
+		}, 20, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, reward, model.ReferralReward{
+			ID: 5, ReferrerID: 42, RefereeID: 666, OrderID: "123", ReferrerBonus: 0, RefereeBonus: 10000, CreatedAt: now,
+		})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should credit referee only when referrer is deleted", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &referralRepository{db: db}
+
+		now := time.Now()
+		expiresAt := now.AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders WHERE id = \\$1 AND status = \\$2 AND bonuses_pending FOR UPDATE").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}).AddRow(1000))
+		mock.ExpectQuery("SELECT u.referred_by, r.deleted_at IS NULL FROM users u").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"referred_by", "active"}).AddRow(42, false))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectQuery("INSERT INTO referral_rewards (.+) ON CONFLICT \\(referee_id\\) DO NOTHING").
+			WithArgs(666, 42, model.OrderID("123"), model.Amount(0), model.Amount(10000)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, now))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(10000), model.LotSourceReferral, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(10000), 666).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		reward, err := repo.RewardReferral(context.Background(), model.ReferralReward{
+			RefereeID: 666, OrderID: "123", ReferrerBonus: 10000, RefereeBonus: 10000,
+		}, 20, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, reward, model.ReferralReward{
+			ID: 5, ReferrerID: 42, RefereeID: 666, OrderID: "123", ReferrerBonus: 0, RefereeBonus: 10000, CreatedAt: now,
+		})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should skip user without referrer", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &referralRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders WHERE id = \\$1 AND status = \\$2 AND bonuses_pending FOR UPDATE").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}).AddRow(1000))
+		mock.ExpectQuery("SELECT u.referred_by, r.deleted_at IS NULL FROM users u").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"referred_by", "active"}))
+		mock.ExpectRollback()
+
+		reward, err := repo.RewardReferral(context.Background(), model.ReferralReward{RefereeID: 666, OrderID: "123", RefereeBonus: 10000}, 20, time.Now())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, reward, model.ReferralReward{})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should skip order which doesn't wait for bonuses", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &referralRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}))
+		mock.ExpectRollback()
+
+		reward, err := repo.RewardReferral(context.Background(), model.ReferralReward{RefereeID: 666, OrderID: "123", RefereeBonus: 10000}, 20, time.Now())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, reward, model.ReferralReward{})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
@@ -0,0 +1,119 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+func NewTierRepository(db *sql.DB) storage.TierRepository {
+	return &tierRepository{db: db}
+}
+
+type tierRepository struct {
+	db *sql.DB
+}
+
+func (r tierRepository) UserTier(ctx context.Context, userID int) (model.Tier, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT tier FROM users WHERE id = $1", userID)
+
+	var tier model.Tier
+	if err := row.Scan(&tier); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("UserTier: invalid scan")
+		return "", err
+	}
+
+	return tier, nil
+}
+
+func (r tierRepository) UpdateTier(ctx context.Context, userID int, tier model.Tier) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET tier = $2, tier_updated_at = current_timestamp
+		WHERE id = $1 AND tier <> $2`, userID, tier)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("UpdateTier: invalid update")
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r tierRepository) AddTierBonus(ctx context.Context, bonus model.TierBonus, expiresAt time.Time) (model.TierBonus, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AddTierBonus: prepare transaction")
+		return model.TierBonus{}, err
+	}
+
+	accrual, err := lockBonusOrder(ctx, tx, bonus.OrderID)
+	if err == nil {
+		bonus.Sum = model.LevelOf(bonus.Tier).BonusFor(accrual)
+	}
+	if err != nil || bonus.Sum <= 0 {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("AddTierBonus: unable to rollback")
+		}
+
+		if err == nil || errors.Is(err, sql.ErrNoRows) {
+			return model.TierBonus{}, nil
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("AddTierBonus: lock order")
+		return model.TierBonus{}, err
+	}
+
+	row := tx.QueryRowContext(ctx, `INSERT INTO tier_bonuses (order_id, user_id, tier, sum)
+		VALUES ($1, $2, $3, $4) ON CONFLICT (order_id) DO NOTHING RETURNING id, created_at`,
+		bonus.OrderID, bonus.UserID, bonus.Tier, bonus.Sum)
+	if err = row.Scan(&bonus.ID, &bonus.CreatedAt); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("AddTierBonus: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TierBonus{}, nil
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("AddTierBonus: exec tier_bonuses")
+		return model.TierBonus{}, err
+	}
+
+	err = addPointLot(ctx, tx, bonus.UserID, bonus.Sum, model.LotSourceTier, expiresAt)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", bonus.Sum, bonus.UserID)
+	}
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("AddTierBonus: credit balance")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("AddTierBonus: unable to rollback")
+		}
+		return model.TierBonus{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("AddTierBonus: unable to commit")
+		return model.TierBonus{}, err
+	}
+
+	return bonus, nil
+}
+
+func (r tierRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database tierRepository").Logger()
+
+	return &logger
+}
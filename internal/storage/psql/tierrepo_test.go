@@ -0,0 +1,110 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_tierRepository_UpdateTier(t *testing.T) {
+	t.Run("should return false when tier is unchanged", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tierRepository{db: db}
+
+		mock.ExpectExec("UPDATE users SET tier = \\$2, tier_updated_at = current_timestamp WHERE id = \\$1 AND tier <> \\$2").
+			WithArgs(666, model.TierGold).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		updated, err := repo.UpdateTier(context.Background(), 666, model.TierGold)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, updated, false)
+	})
+}
+
+func Test_tierRepository_AddTierBonus(t *testing.T) {
+	t.Run("should credit bonus as point lot", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tierRepository{db: db}
+
+		now := time.Now()
+		expiresAt := now.AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders WHERE id = \\$1 AND status = \\$2 AND bonuses_pending FOR UPDATE").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}).AddRow(1000))
+		mock.ExpectQuery("INSERT INTO tier_bonuses (.+) ON CONFLICT \\(order_id\\) DO NOTHING").
+			WithArgs(model.OrderID("123"), 666, model.TierGold, model.Amount(250)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, now))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(250), model.LotSourceTier, expiresAt).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(250), 666).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		bonus, err := repo.AddTierBonus(context.Background(),
+			model.TierBonus{OrderID: "123", UserID: 666, Tier: model.TierGold}, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, bonus, model.TierBonus{ID: 5, OrderID: "123", UserID: 666, Tier: model.TierGold, Sum: 250, CreatedAt: now})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should skip already credited order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tierRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders WHERE id = \\$1 AND status = \\$2 AND bonuses_pending FOR UPDATE").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}).AddRow(1000))
+		mock.ExpectQuery("INSERT INTO tier_bonuses").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+		mock.ExpectRollback()
+
+		bonus, err := repo.AddTierBonus(context.Background(),
+			model.TierBonus{OrderID: "123", UserID: 666, Tier: model.TierGold}, time.Now())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, bonus, model.TierBonus{})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+	t.Run("should skip order which doesn't wait for bonuses", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tierRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT accrual FROM orders").
+			WithArgs(model.OrderID("123"), model.StatusProcessed).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual"}))
+		mock.ExpectRollback()
+
+		bonus, err := repo.AddTierBonus(context.Background(),
+			model.TierBonus{OrderID: "123", UserID: 666, Tier: model.TierGold}, time.Now())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, bonus, model.TierBonus{})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
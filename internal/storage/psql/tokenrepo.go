@@ -0,0 +1,174 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"time"
+)
+
+func NewTokenRepository(db *sql.DB) storage.TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+type tokenRepository struct {
+	db *sql.DB
+}
+
+func (r tokenRepository) CreateRefreshToken(ctx context.Context, token model.RefreshToken) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at, mfa) VALUES ($1, $2, $3, $4, $5)",
+		token.UserID,
+		token.SessionID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.MFA,
+	)
+
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("CreateRefreshToken: invalid save token")
+		return err
+	}
+
+	return nil
+}
+
+func (r tokenRepository) RefreshTokenByHash(ctx context.Context, hash string) (model.RefreshToken, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, user_id, session_id, expires_at, used_at IS NOT NULL, revoked_at IS NOT NULL, mfa
+		from refresh_tokens where token_hash=$1`, hash)
+
+	token := model.RefreshToken{TokenHash: hash}
+	err := row.Scan(&token.ID, &token.UserID, &token.SessionID, &token.ExpiresAt, &token.Used, &token.Revoked, &token.MFA)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.RefreshToken{}, storage.ErrNotFound
+		}
+
+		return model.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken marks the token as used and stores its successor in one transaction.
+// ErrTokenAlreadyUsed is returned when the token has been used by a concurrent request.
+func (r tokenRepository) RotateRefreshToken(ctx context.Context, usedID int, next model.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("RotateRefreshToken: prepare transaction")
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = current_timestamp
+		WHERE id = $1 AND used_at IS NULL`, usedID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("RotateRefreshToken: exec used token")
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("RotateRefreshToken: unable to rollback")
+			return err
+		}
+		return err
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("RotateRefreshToken: unable to rollback")
+			return err
+		}
+
+		return storage.ErrTokenAlreadyUsed
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at, mfa) VALUES ($1, $2, $3, $4, $5)",
+		next.UserID, next.SessionID, next.TokenHash, next.ExpiresAt, next.MFA)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("RotateRefreshToken: exec next token")
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("RotateRefreshToken: unable to rollback")
+			return err
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("RotateRefreshToken: unable to commit")
+		return err
+	}
+
+	return nil
+}
+
+func (r tokenRepository) RevokeSession(ctx context.Context, sessionID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = current_timestamp
+		WHERE session_id = $1 AND revoked_at IS NULL`, sessionID)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("RevokeSession: invalid revoke session")
+		return err
+	}
+
+	return nil
+}
+
+func (r tokenRepository) RevokeUserSessions(ctx context.Context, userID int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = current_timestamp
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("RevokeUserSessions: invalid revoke sessions")
+		return err
+	}
+
+	return nil
+}
+
+func (r tokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`, jti, expiresAt)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("RevokeAccessToken: invalid revoke token")
+		return err
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the access token or the whole session it belongs to has been revoked
+func (r tokenRepository) IsRevoked(ctx context.Context, jti string, sessionID string) (bool, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)
+		OR EXISTS(SELECT 1 FROM refresh_tokens WHERE session_id = $2 AND revoked_at IS NOT NULL)`, jti, sessionID)
+
+	var revoked bool
+	if err := row.Scan(&revoked); err != nil {
+		r.Log(ctx).Err(err).Msg("IsRevoked: invalid scan")
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+func (r tokenRepository) DeleteExpired(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at < current_timestamp")
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("DeleteExpired: revoked tokens")
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, "DELETE FROM refresh_tokens WHERE expires_at < current_timestamp")
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("DeleteExpired: refresh tokens")
+		return err
+	}
+
+	return nil
+}
+
+func (r tokenRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database tokenRepository").Logger()
+
+	return &logger
+}
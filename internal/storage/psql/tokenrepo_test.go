@@ -0,0 +1,133 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_tokenRepository_RefreshTokenByHash(t *testing.T) {
+	t.Run("should return refresh token by hash", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tokenRepository{db: db}
+		now := time.Now()
+
+		row := sqlmock.NewRows([]string{"id", "user_id", "session_id", "expires_at", "used", "revoked", "mfa"}).
+			AddRow(1, 666, "session", now, true, false, true)
+		mock.ExpectQuery("SELECT id, user_id, session_id, expires_at, used_at IS NOT NULL, revoked_at IS NOT NULL, mfa from refresh_tokens where token_hash=\\$1").
+			WithArgs("hash").
+			WillReturnRows(row)
+
+		token, err := repo.RefreshTokenByHash(context.Background(), "hash")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, token, model.RefreshToken{
+			ID:        1,
+			UserID:    666,
+			SessionID: "session",
+			TokenHash: "hash",
+			ExpiresAt: now,
+			Used:      true,
+			MFA:       true,
+		})
+	})
+}
+
+func Test_tokenRepository_RotateRefreshToken(t *testing.T) {
+	t.Run("1. should mark token used and store next token", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tokenRepository{db: db}
+		expiresAt := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE refresh_tokens SET used_at = current_timestamp WHERE id = \\$1 AND used_at IS NULL").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO refresh_tokens \\(user_id, session_id, token_hash, expires_at, mfa\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5\\)").
+			WithArgs(666, "session", "next", expiresAt, false).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		err = repo.RotateRefreshToken(context.Background(), 1, model.RefreshToken{
+			UserID:    666,
+			SessionID: "session",
+			TokenHash: "next",
+			ExpiresAt: expiresAt,
+		})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("2. should return error when token already used", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tokenRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE refresh_tokens SET used_at = current_timestamp WHERE id = \\$1 AND used_at IS NULL").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = repo.RotateRefreshToken(context.Background(), 1, model.RefreshToken{})
+
+		require.Equal(t, err, storage.ErrTokenAlreadyUsed)
+	})
+}
+
+func Test_tokenRepository_IsRevoked(t *testing.T) {
+	t.Run("should check token and session revocation", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tokenRepository{db: db}
+
+		row := sqlmock.NewRows([]string{"revoked"}).AddRow(true)
+		mock.ExpectQuery("SELECT EXISTS\\(SELECT 1 FROM revoked_tokens WHERE jti = \\$1\\)").
+			WithArgs("jti", "session").
+			WillReturnRows(row)
+
+		revoked, err := repo.IsRevoked(context.Background(), "jti", "session")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, revoked, true)
+	})
+}
+
+func Test_tokenRepository_RevokeUserSessions(t *testing.T) {
+	t.Run("should revoke all sessions of the user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &tokenRepository{db: db}
+
+		mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = current_timestamp").
+			WithArgs(666).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		err = repo.RevokeUserSessions(context.Background(), 666)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
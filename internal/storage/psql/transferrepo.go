@@ -0,0 +1,189 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+	"sort"
+)
+
+func NewTransferRepository(db *sql.DB) storage.TransferRepository {
+	return &transferRepository{db: db}
+}
+
+type transferRepository struct {
+	db *sql.DB
+}
+
+// Transfer moves points between users. Rows of both users are locked in order of id, so concurrent
+// transfers in opposite directions wait for each other instead of deadlocking.
+func (r transferRepository) Transfer(ctx context.Context, transfer model.Transfer, dailyLimit model.Amount) (model.Transfer, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("Transfer: prepare transaction")
+		return model.Transfer{}, err
+	}
+
+	balances, err := lockUsers(ctx, tx, transfer.SenderID, transfer.RecipientID)
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("Transfer: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Transfer{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("Transfer: lock users")
+		return model.Transfer{}, err
+	}
+
+	if balances[transfer.SenderID] < transfer.Sum {
+		r.Log(ctx).Warn().Err(storage.ErrInsufficientFunds).Msg("Transfer:")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("Transfer: unable to rollback")
+		}
+		return model.Transfer{}, storage.ErrInsufficientFunds
+	}
+
+	if dailyLimit > 0 {
+		var sent model.Amount
+		row := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(sum), 0) FROM transfers
+			WHERE sender_id = $1 AND created_at >= date_trunc('day', current_timestamp)`, transfer.SenderID)
+		if err = row.Scan(&sent); err != nil {
+			r.Log(ctx).Error().Err(err).Msg("Transfer: select sent today")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("Transfer: unable to rollback")
+			}
+			return model.Transfer{}, err
+		}
+
+		if sent+transfer.Sum > dailyLimit {
+			r.Log(ctx).Warn().Err(storage.ErrTransferLimitExceeded).Msg("Transfer:")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("Transfer: unable to rollback")
+			}
+			return model.Transfer{}, storage.ErrTransferLimitExceeded
+		}
+	}
+
+	consumptions, err := consumePointLots(ctx, tx, transfer.SenderID, transfer.Sum)
+	if err != nil {
+		r.Log(ctx).Warn().Err(err).Msg("Transfer: consume point lots")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("Transfer: unable to rollback")
+		}
+		return model.Transfer{}, err
+	}
+
+	// the recipient gets points with the same expiry, a transfer doesn't prolong them
+	for _, consumption := range consumptions {
+		err = addPointLot(ctx, tx, transfer.RecipientID, consumption.Amount, model.LotSourceTransfer, consumption.ExpiresAt)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("Transfer: exec point_lots")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("Transfer: unable to rollback")
+			}
+			return model.Transfer{}, err
+		}
+	}
+
+	row := tx.QueryRowContext(ctx, `INSERT INTO transfers (sender_id, recipient_id, sum) VALUES ($1, $2, $3)
+		RETURNING id, created_at`, transfer.SenderID, transfer.RecipientID, transfer.Sum)
+	if err = row.Scan(&transfer.ID, &transfer.CreatedAt); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("Transfer: exec transfers")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("Transfer: unable to rollback")
+		}
+		return model.Transfer{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance - $1 WHERE id = $2", transfer.Sum, transfer.SenderID)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", transfer.Sum, transfer.RecipientID)
+	}
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("Transfer: exec users")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("Transfer: unable to rollback")
+		}
+		return model.Transfer{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("Transfer: unable to commit")
+		return model.Transfer{}, err
+	}
+
+	return transfer, nil
+}
+
+func (r transferRepository) TransfersByUserID(ctx context.Context, userID int) ([]model.TransferLog, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT t.id, t.sender_id, s.username, r.username, t.sum, t.created_at FROM transfers t
+		JOIN users s ON s.id = t.sender_id
+		JOIN users r ON r.id = t.recipient_id
+		WHERE t.sender_id = $1 OR t.recipient_id = $1 ORDER BY t.created_at`, userID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("TransfersByUserID: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := make([]model.TransferLog, 0)
+	for rows.Next() {
+		var transfer model.TransferLog
+		var senderID int
+		var sender, recipient string
+
+		err = rows.Scan(&transfer.ID, &senderID, &sender, &recipient, &transfer.Sum, &transfer.CreatedAt)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("TransfersByUserID: invalid scan")
+			return nil, err
+		}
+
+		transfer.Direction, transfer.Counterparty = model.TransferIncoming, sender
+		if senderID == userID {
+			transfer.Direction, transfer.Counterparty = model.TransferOutgoing, recipient
+		}
+
+		transfers = append(transfers, transfer)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("TransfersByUserID: query rows was error")
+		return nil, err
+	}
+
+	return transfers, nil
+}
+
+func (r transferRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database transferRepository").Logger()
+
+	return &logger
+}
+
+// lockUsers locks rows of the users in ascending order of id and returns their balances,
+// sql.ErrNoRows is returned when a user doesn't exist
+func lockUsers(ctx context.Context, tx *sql.Tx, ids ...int) (map[int]model.Amount, error) {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+
+	balances := make(map[int]model.Amount, len(sorted))
+	for _, id := range sorted {
+		var balance model.Amount
+		row := tx.QueryRowContext(ctx, "SELECT balance FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id)
+		if err := row.Scan(&balance); err != nil {
+			return nil, err
+		}
+
+		balances[id] = balance
+	}
+
+	return balances, nil
+}
@@ -0,0 +1,114 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_transferRepository_Transfer(t *testing.T) {
+	t.Run("should lock users in order of id and move point lots", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &transferRepository{db: db}
+
+		now := time.Now()
+		expiresAt := now.AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(555).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectQuery("SELECT balance FROM users WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(2000))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(sum\\), 0\\) FROM transfers").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1000))
+		mock.ExpectQuery("SELECT id, remaining, expires_at FROM point_lots").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "remaining", "expires_at"}).AddRow(1, 2000, expiresAt))
+		mock.ExpectExec("UPDATE point_lots SET remaining = remaining - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(1500), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(555, model.Amount(1500), model.LotSourceTransfer, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO transfers").
+			WithArgs(666, 555, model.Amount(1500)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+		mock.ExpectExec("UPDATE users SET balance = balance - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(1500), 666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(1500), 555).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		transfer, err := repo.Transfer(context.Background(), model.Transfer{SenderID: 666, RecipientID: 555, Sum: 1500}, 5000)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, transfer, model.Transfer{ID: 3, SenderID: 666, RecipientID: 555, Sum: 1500, CreatedAt: model.UploadedTime(now)})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("should reject transfer above daily limit", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &transferRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT balance FROM users").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(10000))
+		mock.ExpectQuery("SELECT balance FROM users").
+			WithArgs(777).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(0))
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(sum\\), 0\\) FROM transfers").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(4000))
+		mock.ExpectRollback()
+
+		_, err = repo.Transfer(context.Background(), model.Transfer{SenderID: 666, RecipientID: 777, Sum: 1500}, 5000)
+
+		require.Equal(t, err, storage.ErrTransferLimitExceeded)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_transferRepository_TransfersByUserID(t *testing.T) {
+	t.Run("should return sent and received transfers", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &transferRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectQuery("SELECT t.id, t.sender_id, s.username, r.username, t.sum, t.created_at FROM transfers t").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "sender_id", "sender", "recipient", "sum", "created_at"}).
+				AddRow(1, 666, "me", "family", 1000, now).
+				AddRow(2, 777, "friend", "me", 250, now))
+
+		transfers, err := repo.TransfersByUserID(context.Background(), 666)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, transfers, []model.TransferLog{
+			{ID: 1, Direction: model.TransferOutgoing, Counterparty: "family", Sum: 1000, CreatedAt: model.UploadedTime(now)},
+			{ID: 2, Direction: model.TransferIncoming, Counterparty: "friend", Sum: 250, CreatedAt: model.UploadedTime(now)},
+		})
+	})
+}
@@ -0,0 +1,170 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+)
+
+func NewTwoFactorRepository(db *sql.DB) storage.TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+type twoFactorRepository struct {
+	db *sql.DB
+}
+
+// TwoFactor returns settings of the user, not enrolled user gets zero value
+func (r twoFactorRepository) TwoFactor(ctx context.Context, userID int) (model.TwoFactor, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT secret, enabled, last_step from user_totp where user_id=$1", userID)
+
+	twoFactor := model.TwoFactor{UserID: userID}
+	err := row.Scan(&twoFactor.Secret, &twoFactor.Enabled, &twoFactor.LastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TwoFactor{UserID: userID}, nil
+		}
+
+		r.Log(ctx).Err(err).Msg("TwoFactor: invalid scan")
+		return model.TwoFactor{}, err
+	}
+
+	return twoFactor, nil
+}
+
+func (r twoFactorRepository) SaveTOTPSecret(ctx context.Context, userID int, secret string) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, last_step = 0, created_at = current_timestamp
+		WHERE user_totp.enabled = false`, userID, secret)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("SaveTOTPSecret: invalid save secret")
+		return err
+	}
+
+	return nil
+}
+
+func (r twoFactorRepository) EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("EnableTOTP: prepare transaction")
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE user_totp SET enabled = true, last_step = $2 WHERE user_id = $1", userID, step)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("EnableTOTP: exec user_totp")
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("EnableTOTP: unable to rollback")
+			return err
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("EnableTOTP: exec delete recovery_codes")
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("EnableTOTP: unable to rollback")
+			return err
+		}
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err = tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("EnableTOTP: exec insert recovery_codes")
+			if err = tx.Rollback(); err != nil {
+				r.Log(ctx).Error().Err(err).Msgf("EnableTOTP: unable to rollback")
+				return err
+			}
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("EnableTOTP: unable to commit")
+		return err
+	}
+
+	return nil
+}
+
+func (r twoFactorRepository) DisableTOTP(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("DisableTOTP: prepare transaction")
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = $1", userID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("DisableTOTP: exec user_totp")
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("DisableTOTP: unable to rollback")
+			return err
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("DisableTOTP: exec recovery_codes")
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("DisableTOTP: unable to rollback")
+			return err
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("DisableTOTP: unable to commit")
+		return err
+	}
+
+	return nil
+}
+
+func (r twoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, "UPDATE user_totp SET last_step = $2 WHERE user_id = $1 AND last_step < $2",
+		userID, step)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("UseTOTPStep: invalid update step")
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE recovery_codes SET used_at = current_timestamp
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("UseRecoveryCode: invalid update code")
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r twoFactorRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database twoFactorRepository").Logger()
+
+	return &logger
+}
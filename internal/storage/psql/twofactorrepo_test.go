@@ -0,0 +1,80 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_twoFactorRepository_TwoFactor(t *testing.T) {
+	t.Run("should return empty settings for not enrolled user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &twoFactorRepository{db: db}
+
+		mock.ExpectQuery("SELECT secret, enabled, last_step from user_totp").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"secret", "enabled", "last_step"}))
+
+		twoFactor, err := repo.TwoFactor(context.Background(), 666)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, twoFactor, model.TwoFactor{UserID: 666})
+	})
+}
+
+func Test_twoFactorRepository_EnableTOTP(t *testing.T) {
+	t.Run("should enable totp and replace recovery codes", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &twoFactorRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE user_totp SET enabled = true").
+			WithArgs(666, int64(100)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM recovery_codes").
+			WithArgs(666).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO recovery_codes").
+			WithArgs(666, "hash1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO recovery_codes").
+			WithArgs(666, "hash2").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		err = repo.EnableTOTP(context.Background(), 666, 100, []string{"hash1", "hash2"})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_twoFactorRepository_UseTOTPStep(t *testing.T) {
+	t.Run("should reject already used step", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &twoFactorRepository{db: db}
+
+		mock.ExpectExec("UPDATE user_totp SET last_step").
+			WithArgs(666, int64(100)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		used, err := repo.UseTOTPStep(context.Background(), 666, 100)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, used, false)
+	})
+}
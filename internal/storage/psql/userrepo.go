@@ -9,9 +9,12 @@ import (
 	"github.com/djokcik/gophermart/pkg/logging"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 )
 
+const userSummaryColumns = "id, username, role, tier, balance, created_at, locked_at, COALESCE(locked_reason, '')"
+
 func NewUserRepository(db *sql.DB) storage.UserRepository {
 	return &userRepository{
 		db: db,
@@ -23,9 +26,14 @@ type userRepository struct {
 }
 
 func (r userRepository) CreateUser(ctx context.Context, user model.User) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO users (username, password) VALUES ($1, $2)", user.Username, user.Password)
+	_, err := r.db.ExecContext(ctx, `INSERT INTO users (username, password, pepper_id, referral_code, referred_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0))`, user.Username, user.Password, user.PepperID, user.ReferralCode, user.ReferredBy)
 	if err != nil {
 		if err, ok := err.(pgx.PgError); ok && err.Code == pgerrcode.UniqueViolation /* or just == "23505" */ {
+			if err.ConstraintName == "users_referral_code_uindex" {
+				return storage.ErrReferralCodeTaken
+			}
+
 			return storage.ErrLoginAlreadyExists
 		}
 
@@ -36,10 +44,11 @@ func (r userRepository) CreateUser(ctx context.Context, user model.User) error {
 }
 
 func (r userRepository) UserByUsername(ctx context.Context, username string) (model.User, error) {
-	row := r.db.QueryRowContext(ctx, "SELECT id, password, created_at, balance from users where username=$1", username)
+	row := r.db.QueryRowContext(ctx, `SELECT id, password, pepper_id, created_at, balance, role, locked_at
+		from users where username=$1 AND deleted_at IS NULL`, username)
 
 	user := model.User{Username: username}
-	err := row.Scan(&user.ID, &user.Password, &user.CreatedAt, &user.Balance)
+	err := row.Scan(&user.ID, &user.Password, &user.PepperID, &user.CreatedAt, &user.Balance, &user.Role, &user.LockedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.User{}, storage.ErrNotFound
@@ -52,10 +61,11 @@ func (r userRepository) UserByUsername(ctx context.Context, username string) (mo
 }
 
 func (r userRepository) UserByID(ctx context.Context, id int) (model.User, error) {
-	row := r.db.QueryRowContext(ctx, "SELECT username, password, created_at, balance from users where id=$1", id)
+	row := r.db.QueryRowContext(ctx, `SELECT username, password, pepper_id, created_at, balance, role, locked_at
+		from users where id=$1 AND deleted_at IS NULL`, id)
 
 	user := model.User{ID: id}
-	err := row.Scan(&user.Username, &user.Password, &user.CreatedAt, &user.Balance)
+	err := row.Scan(&user.Username, &user.Password, &user.PepperID, &user.CreatedAt, &user.Balance, &user.Role, &user.LockedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.User{}, storage.ErrNotFound
@@ -67,9 +77,193 @@ func (r userRepository) UserByID(ctx context.Context, id int) (model.User, error
 	return user, nil
 }
 
+func (r userRepository) UpdatePassword(ctx context.Context, userID int, hash string, pepperID string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET password = $1, pepper_id = $2 WHERE id = $3", hash, pepperID, userID)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("UpdatePassword: invalid update password")
+		return err
+	}
+
+	return nil
+}
+
+func (r userRepository) PepperUsage(ctx context.Context) ([]model.PepperUsage, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT pepper_id, count(*) FROM users GROUP BY pepper_id ORDER BY pepper_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make([]model.PepperUsage, 0)
+	for rows.Next() {
+		var item model.PepperUsage
+		if err = rows.Scan(&item.PepperID, &item.Users); err != nil {
+			return nil, err
+		}
+
+		usage = append(usage, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("PepperUsage: query rows was error")
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+func (r userRepository) DeleteUser(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("DeleteUser: prepare transaction")
+		return err
+	}
+
+	// the anonymized login is longer than registration allows, so no user can take it and block the deletion
+	res, err := tx.ExecContext(ctx, `UPDATE users SET username = 'deleted-user-' || lpad(id::text, 10, '0'), password = '',
+		deleted_at = current_timestamp WHERE id = $1 AND deleted_at IS NULL`, userID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("DeleteUser: exec users")
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("DeleteUser: unable to rollback")
+			return err
+		}
+		return err
+	}
+
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("DeleteUser: unable to rollback")
+			return err
+		}
+
+		return storage.ErrNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM webhooks WHERE user_id = $1", userID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("DeleteUser: exec webhooks")
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("DeleteUser: unable to rollback")
+			return err
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("DeleteUser: unable to commit")
+		return err
+	}
+
+	return nil
+}
+
+// SearchUsers returns users whose login contains query, ignoring case
+func (r userRepository) SearchUsers(ctx context.Context, query string, limit int) ([]model.UserSummary, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+userSummaryColumns+` FROM users
+		WHERE deleted_at IS NULL AND position(lower($1) in lower(username)) > 0 ORDER BY username LIMIT $2`, query, limit)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("SearchUsers: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]model.UserSummary, 0)
+	for rows.Next() {
+		user, err := scanUserSummary(rows)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("SearchUsers: invalid scan")
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("SearchUsers: query rows was error")
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r userRepository) UserSummary(ctx context.Context, id int) (model.UserSummary, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+userSummaryColumns+" FROM users WHERE id = $1 AND deleted_at IS NULL", id)
+
+	user, err := scanUserSummary(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.UserSummary{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("UserSummary: invalid scan")
+		return model.UserSummary{}, err
+	}
+
+	return user, nil
+}
+
+func (r userRepository) LockUser(ctx context.Context, id int, reason string) error {
+	return r.updateUser(ctx, "LockUser", `UPDATE users SET locked_at = COALESCE(locked_at, current_timestamp), locked_reason = $2
+		WHERE id = $1 AND deleted_at IS NULL`, id, reason)
+}
+
+func (r userRepository) UnlockUser(ctx context.Context, id int) error {
+	return r.updateUser(ctx, "UnlockUser", `UPDATE users SET locked_at = NULL, locked_reason = NULL
+		WHERE id = $1 AND deleted_at IS NULL`, id)
+}
+
+func (r userRepository) SetRole(ctx context.Context, id int, role model.Role) error {
+	return r.updateUser(ctx, "SetRole", "UPDATE users SET role = $2 WHERE id = $1 AND deleted_at IS NULL", id, role)
+}
+
+func (r userRepository) PromoteAdmins(ctx context.Context, usernames []string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET role = $2
+		WHERE username = ANY($1) AND role <> $2 AND deleted_at IS NULL`, pq.Array(usernames), model.RoleAdmin)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("PromoteAdmins: invalid update")
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), nil
+}
+
+// updateUser executes the update of one not deleted user, ErrNotFound is returned when nothing is updated
+func (r userRepository) updateUser(ctx context.Context, method string, query string, args ...interface{}) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("%s: invalid update", method)
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
 func (r userRepository) Log(ctx context.Context) *zerolog.Logger {
 	_, logger := logging.GetCtxLogger(ctx)
 	logger = logger.With().Str(logging.ServiceKey, "database userRepository").Logger()
 
 	return &logger
 }
+
+func scanUserSummary(row rowScanner) (model.UserSummary, error) {
+	var user model.UserSummary
+	err := row.Scan(&user.ID, &user.Login, &user.Role, &user.Tier, &user.Balance, &user.CreatedAt,
+		&user.LockedAt, &user.LockReason)
+
+	return user, err
+}
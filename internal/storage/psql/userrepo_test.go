@@ -22,13 +22,13 @@ func Test_userRepository_CreateUser(t *testing.T) {
 		repo := &userRepository{db: db}
 
 		mock.
-			ExpectExec("INSERT INTO users \\(username, password\\) VALUES \\(\\$1, \\$2\\)").
-			WithArgs("test", "userPassword").
+			ExpectExec("INSERT INTO users \\(username, password, pepper_id, referral_code, referred_by\\)").
+			WithArgs("test", "userPassword", "1", "ABCD1234", 7).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err = repo.CreateUser(
 			context.Background(),
-			model.User{Username: "test", Password: "userPassword"},
+			model.User{Username: "test", Password: "userPassword", PepperID: "1", ReferralCode: "ABCD1234", ReferredBy: 7},
 		)
 
 		require.Equal(t, err, nil)
@@ -43,17 +43,38 @@ func Test_userRepository_CreateUser(t *testing.T) {
 		repo := &userRepository{db: db}
 
 		mock.
-			ExpectExec("INSERT INTO users \\(username, password\\) VALUES \\(\\$1, \\$2\\)").
-			WithArgs("test", "userPassword").
+			ExpectExec("INSERT INTO users \\(username, password, pepper_id, referral_code, referred_by\\)").
+			WithArgs("test", "userPassword", "1", "ABCD1234", 0).
 			WillReturnError(pgx.PgError{Code: pgerrcode.UniqueViolation})
 
 		err = repo.CreateUser(
 			context.Background(),
-			model.User{Username: "test", Password: "userPassword"},
+			model.User{Username: "test", Password: "userPassword", PepperID: "1", ReferralCode: "ABCD1234"},
 		)
 
 		require.Equal(t, err, storage.ErrLoginAlreadyExists)
 	})
+
+	t.Run("3. Should return error as duplicate referral code", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &userRepository{db: db}
+
+		mock.
+			ExpectExec("INSERT INTO users").
+			WithArgs("test", "userPassword", "1", "ABCD1234", 0).
+			WillReturnError(pgx.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "users_referral_code_uindex"})
+
+		err = repo.CreateUser(
+			context.Background(),
+			model.User{Username: "test", Password: "userPassword", PepperID: "1", ReferralCode: "ABCD1234"},
+		)
+
+		require.Equal(t, err, storage.ErrReferralCodeTaken)
+	})
 }
 
 func Test_userRepository_UserByUsername(t *testing.T) {
@@ -66,9 +87,9 @@ func Test_userRepository_UserByUsername(t *testing.T) {
 		repo := &userRepository{db: db}
 		now := time.Now()
 
-		row := sqlmock.NewRows([]string{"id", "password", "created_at", "balance"}).
-			AddRow(666, "testPassword", now, 1000)
-		mock.ExpectQuery("SELECT id, password, created_at, balance from users where username=\\$1").
+		row := sqlmock.NewRows([]string{"id", "password", "pepper_id", "created_at", "balance", "role", "locked_at"}).
+			AddRow(666, "testPassword", "1", now, 1000, "user", nil)
+		mock.ExpectQuery("SELECT id, password, pepper_id, created_at, balance, role, locked_at from users where username=\\$1 AND deleted_at IS NULL").
 			WithArgs("testUsername").
 			WillReturnRows(row)
 
@@ -82,8 +103,10 @@ func Test_userRepository_UserByUsername(t *testing.T) {
 			ID:        666,
 			Username:  "testUsername",
 			Password:  "testPassword",
+			PepperID:  "1",
 			CreatedAt: now,
 			Balance:   1000,
+			Role:      model.RoleUser,
 		},
 		)
 	})
@@ -99,9 +122,9 @@ func Test_userRepository_UserByID(t *testing.T) {
 		repo := &userRepository{db: db}
 		now := time.Now()
 
-		row := sqlmock.NewRows([]string{"username", "password", "created_at", "balance"}).
-			AddRow("testUsername", "testPassword", now, 1000)
-		mock.ExpectQuery("SELECT username, password, created_at, balance from users where id=\\$1").
+		row := sqlmock.NewRows([]string{"username", "password", "pepper_id", "created_at", "balance", "role", "locked_at"}).
+			AddRow("testUsername", "testPassword", "1", now, 1000, "admin", now)
+		mock.ExpectQuery("SELECT username, password, pepper_id, created_at, balance, role, locked_at from users where id=\\$1 AND deleted_at IS NULL").
 			WithArgs(666).
 			WillReturnRows(row)
 
@@ -115,9 +138,138 @@ func Test_userRepository_UserByID(t *testing.T) {
 			ID:        666,
 			Username:  "testUsername",
 			Password:  "testPassword",
+			PepperID:  "1",
 			CreatedAt: now,
 			Balance:   1000,
+			Role:      model.RoleAdmin,
+			LockedAt:  &now,
 		},
 		)
 	})
 }
+
+func Test_userRepository_SearchUsers(t *testing.T) {
+	t.Run("should find users by part of login", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &userRepository{db: db}
+		now := time.Now()
+
+		mock.ExpectQuery("SELECT (.+) FROM users WHERE deleted_at IS NULL AND position\\(lower\\(\\$1\\) in lower\\(username\\)\\) > 0").
+			WithArgs("Ali", 50).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "role", "tier", "balance", "created_at", "locked_at", "locked_reason"}).
+				AddRow(1, "alice", "user", "GOLD", 1000, now, now, "fraud"))
+
+		users, err := repo.SearchUsers(context.Background(), "Ali", 50)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, users, []model.UserSummary{{
+			ID: 1, Login: "alice", Role: model.RoleUser, Tier: model.TierGold, Balance: 1000, CreatedAt: now, LockedAt: &now, LockReason: "fraud",
+		}})
+	})
+}
+
+func Test_userRepository_LockUser(t *testing.T) {
+	t.Run("should return not found for unknown user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &userRepository{db: db}
+
+		mock.ExpectExec("UPDATE users SET locked_at = COALESCE\\(locked_at, current_timestamp\\), locked_reason = \\$2").
+			WithArgs(666, "fraud").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.LockUser(context.Background(), 666, "fraud")
+
+		require.Equal(t, err, storage.ErrNotFound)
+	})
+}
+
+func Test_userRepository_UpdatePassword(t *testing.T) {
+	t.Run("should update password hash", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &userRepository{db: db}
+
+		mock.ExpectExec("UPDATE users SET password = \\$1, pepper_id = \\$2 WHERE id = \\$3").
+			WithArgs("newHash", "2", 666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err = repo.UpdatePassword(context.Background(), 666, "newHash", "2")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_userRepository_PepperUsage(t *testing.T) {
+	t.Run("should count users by pepper", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &userRepository{db: db}
+
+		rows := sqlmock.NewRows([]string{"pepper_id", "count"}).AddRow("1", 10).AddRow("2", 3)
+		mock.ExpectQuery("SELECT pepper_id, count\\(\\*\\) FROM users GROUP BY pepper_id").WillReturnRows(rows)
+
+		usage, err := repo.PepperUsage(context.Background())
+
+		require.Equal(t, err, nil)
+		require.Equal(t, usage, []model.PepperUsage{{PepperID: "1", Users: 10}, {PepperID: "2", Users: 3}})
+	})
+}
+
+func Test_userRepository_DeleteUser(t *testing.T) {
+	t.Run("should anonymize user and remove webhooks", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &userRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users SET username = 'deleted-user-' \\|\\| lpad\\(id::text, 10, '0'\\)").
+			WithArgs(666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM webhooks WHERE user_id = \\$1").
+			WithArgs(666).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err = repo.DeleteUser(context.Background(), 666)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("should return ErrNotFound for deleted user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &userRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users SET username = 'deleted-user-' \\|\\| lpad\\(id::text, 10, '0'\\)").
+			WithArgs(666).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = repo.DeleteUser(context.Background(), 666)
+
+		require.Equal(t, err, storage.ErrNotFound)
+	})
+}
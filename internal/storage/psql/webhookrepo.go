@@ -0,0 +1,185 @@
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/rs/zerolog"
+)
+
+// webhookClaimTimeout is the time a claimed delivery stays invisible for other pollers
+const webhookClaimTimeout = "1 minute"
+
+func NewWebhookRepository(db *sql.DB) storage.WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+type webhookRepository struct {
+	db *sql.DB
+}
+
+func (r webhookRepository) CreateWebhook(ctx context.Context, webhook model.Webhook) (model.Webhook, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		"INSERT INTO webhooks (user_id, url, secret) VALUES ($1, $2, $3) RETURNING id, created_at",
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+	)
+
+	err := row.Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("CreateWebhook: invalid save webhook")
+		return model.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (r webhookRepository) WebhooksByUserID(ctx context.Context, userID int) ([]model.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, url, created_at
+		from webhooks WHERE user_id = $1 ORDER BY id`, userID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]model.Webhook, 0)
+	for rows.Next() {
+		webhook := model.Webhook{UserID: userID}
+		err = rows.Scan(&webhook.ID, &webhook.URL, &webhook.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("WebhooksByUserID: query rows was error")
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (r webhookRepository) DeleteWebhook(ctx context.Context, userID int, id int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("DeleteWebhook: invalid delete webhook")
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r webhookRepository) CreateDeliveries(ctx context.Context, userID int, event string, payload string) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $2, $3 FROM webhooks WHERE user_id = $1`, userID, event, payload)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("CreateDeliveries: invalid save deliveries")
+		return err
+	}
+
+	return nil
+}
+
+func (r webhookRepository) DeliveriesByWebhookID(ctx context.Context, userID int, webhookID int) ([]model.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT d.id, d.event, d.payload, d.status, d.attempts, d.response_code,
+		d.last_error, d.next_attempt_at, d.created_at
+		from webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.webhook_id = $1 AND w.user_id = $2 ORDER BY d.id DESC LIMIT 100`, webhookID, userID)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := model.WebhookDelivery{WebhookID: webhookID}
+		err = rows.Scan(&delivery.ID, &delivery.Event, &delivery.Payload, &delivery.Status, &delivery.Attempts,
+			&delivery.ResponseCode, &delivery.LastError, &delivery.NextAttemptAt, &delivery.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("DeliveriesByWebhookID: query rows was error")
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries picks pending deliveries whose attempt time has come and postpones them,
+// so that concurrent pollers do not send the same delivery twice
+func (r webhookRepository) ClaimDueDeliveries(ctx context.Context, limit int) ([]model.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE webhook_deliveries d
+		SET next_attempt_at = current_timestamp + interval '`+webhookClaimTimeout+`'
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'PENDING' AND next_attempt_at <= current_timestamp
+			ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.attempts, d.created_at, w.url, w.secret`, limit)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]model.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := model.WebhookDelivery{Status: model.DeliveryPending}
+		err = rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload, &delivery.Attempts,
+			&delivery.CreatedAt, &delivery.URL, &delivery.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ClaimDueDeliveries: query rows was error")
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r webhookRepository) UpdateDelivery(ctx context.Context, delivery model.WebhookDelivery) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, attempts = $2, response_code = $3,
+		last_error = $4, next_attempt_at = $5 WHERE id = $6`,
+		delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.LastError, delivery.NextAttemptAt, delivery.ID)
+	if err != nil {
+		r.Log(ctx).Err(err).Msg("UpdateDelivery: invalid update delivery")
+		return err
+	}
+
+	return nil
+}
+
+func (r webhookRepository) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "database webhookRepository").Logger()
+
+	return &logger
+}
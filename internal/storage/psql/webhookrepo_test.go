@@ -0,0 +1,81 @@
+package psql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_webhookRepository_CreateWebhook(t *testing.T) {
+	t.Run("should create webhook", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &webhookRepository{db: db}
+		now := time.Now()
+
+		row := sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now)
+		mock.ExpectQuery("INSERT INTO webhooks \\(user_id, url, secret\\) VALUES \\(\\$1, \\$2, \\$3\\) RETURNING id, created_at").
+			WithArgs(666, "http://localhost/hook", "secret").
+			WillReturnRows(row)
+
+		webhook, err := repo.CreateWebhook(
+			context.Background(),
+			model.Webhook{UserID: 666, URL: "http://localhost/hook", Secret: "secret"},
+		)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, webhook, model.Webhook{
+			ID:        1,
+			UserID:    666,
+			URL:       "http://localhost/hook",
+			Secret:    "secret",
+			CreatedAt: now,
+		})
+	})
+}
+
+func Test_webhookRepository_DeleteWebhook(t *testing.T) {
+	t.Run("should return not found when webhook belongs another user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &webhookRepository{db: db}
+
+		mock.ExpectExec("DELETE FROM webhooks WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(1, 666).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err = repo.DeleteWebhook(context.Background(), 666, 1)
+
+		require.Equal(t, err, storage.ErrNotFound)
+	})
+}
+
+func Test_webhookRepository_CreateDeliveries(t *testing.T) {
+	t.Run("should create delivery for each user webhook", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &webhookRepository{db: db}
+
+		mock.ExpectExec("INSERT INTO webhook_deliveries \\(webhook_id, event, payload\\) SELECT id, \\$2, \\$3 FROM webhooks WHERE user_id = \\$1").
+			WithArgs(666, model.EventOrderStatusChanged, "{}").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		err = repo.CreateDeliveries(context.Background(), 666, model.EventOrderStatusChanged, "{}")
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
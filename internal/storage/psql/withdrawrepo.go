@@ -7,7 +7,11 @@ import (
 	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/internal/storage"
 	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
+	"time"
 )
 
 func NewWithdrawRepository(db *sql.DB) storage.WithdrawRepository {
@@ -19,15 +23,11 @@ type withdrawRepository struct {
 }
 
 func (r withdrawRepository) AmountWithdrawByUser(ctx context.Context, userID int) (model.Amount, error) {
-	row := r.db.QueryRow("SELECT SUM(sum) as amount from withdraw_log GROUP BY user_id = $1", userID)
+	row := r.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(sum - reversed_sum), 0) as amount from withdraw_log WHERE user_id = $1", userID)
 
 	var amount model.Amount
 	err := row.Scan(&amount)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return 0, nil
-		}
-
 		r.Log(ctx).Error().Err(err).Msg("AmountWithdrawByUser: invalid scan")
 		return 0, err
 	}
@@ -42,6 +42,13 @@ func (r withdrawRepository) ProcessWithdraw(ctx context.Context, withdraw model.
 		return err
 	}
 
+	if err = r.checkOrderNotPaid(ctx, tx, withdraw.OrderID); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ProcessWithdraw: unable to rollback")
+		}
+		return err
+	}
+
 	row := tx.QueryRow("SELECT balance FROM users where id = $1", withdraw.UserID)
 	var balance model.Amount
 	if err = row.Scan(&balance); err != nil {
@@ -57,12 +64,35 @@ func (r withdrawRepository) ProcessWithdraw(ctx context.Context, withdraw model.
 		return storage.ErrInsufficientFunds
 	}
 
-	if _, err = tx.ExecContext(ctx, `INSERT INTO withdraw_log (user_id, sum, order_id) VALUES ($1, $2, $3)`,
-		withdraw.UserID, withdraw.Sum, withdraw.OrderID); err != nil {
+	consumptions, err := consumePointLots(ctx, tx, withdraw.UserID, withdraw.Sum)
+	if err != nil {
+		r.Log(ctx).Warn().Err(err).Msg("ProcessWithdraw: consume point lots")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ProcessWithdraw: unable to rollback")
+		}
+		return err
+	}
+
+	var withdrawID int
+	row = tx.QueryRowContext(ctx, `INSERT INTO withdraw_log (user_id, sum, order_id) VALUES ($1, $2, $3) RETURNING id`,
+		withdraw.UserID, withdraw.Sum, withdraw.OrderID)
+	if err = row.Scan(&withdrawID); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ProcessWithdraw: unable to rollback")
+		}
+
+		if isUniqueViolation(err) {
+			return storage.ErrOrderAlreadyPaid
+		}
+
 		r.Log(ctx).Error().Err(err).Msg("ProcessWithdraw: exec withdraw_log")
-		if err = tx.Rollback(); err != nil {
-			r.Log(ctx).Error().Err(err).Msgf("ProcessWithdraw: unable to rollback")
-			return err
+		return err
+	}
+
+	if err = saveLotConsumptions(ctx, tx, consumptions, withdrawID, 0); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ProcessWithdraw: exec point_lot_consumptions")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ProcessWithdraw: unable to rollback")
 		}
 		return err
 	}
@@ -85,8 +115,376 @@ func (r withdrawRepository) ProcessWithdraw(ctx context.Context, withdraw model.
 	return nil
 }
 
+func (r withdrawRepository) CreatePendingWithdraw(ctx context.Context, withdraw model.Withdraw, expiresAt time.Time) (model.PendingWithdraw, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CreatePendingWithdraw: prepare transaction")
+		return model.PendingWithdraw{}, err
+	}
+
+	if err = r.checkOrderNotPaid(ctx, tx, withdraw.OrderID); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CreatePendingWithdraw: unable to rollback")
+		}
+		return model.PendingWithdraw{}, err
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT balance FROM users where id = $1 FOR UPDATE", withdraw.UserID)
+	var balance model.Amount
+	if err = row.Scan(&balance); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CreatePendingWithdraw: invalid scan balance")
+		if err := tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("CreatePendingWithdraw: unable to rollback")
+		}
+		return model.PendingWithdraw{}, err
+	}
+
+	if balance < withdraw.Sum {
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("CreatePendingWithdraw: unable to rollback")
+			return model.PendingWithdraw{}, err
+		}
+
+		return model.PendingWithdraw{}, storage.ErrInsufficientFunds
+	}
+
+	consumptions, err := consumePointLots(ctx, tx, withdraw.UserID, withdraw.Sum)
+	if err != nil {
+		r.Log(ctx).Warn().Err(err).Msg("CreatePendingWithdraw: consume point lots")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CreatePendingWithdraw: unable to rollback")
+		}
+		return model.PendingWithdraw{}, err
+	}
+
+	pending := model.PendingWithdraw{
+		OrderID:   withdraw.OrderID,
+		Sum:       withdraw.Sum,
+		UserID:    withdraw.UserID,
+		ExpiresAt: model.UploadedTime(expiresAt),
+	}
+
+	row = tx.QueryRowContext(ctx, `INSERT INTO pending_withdrawals (user_id, order_id, sum, expires_at)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`, withdraw.UserID, withdraw.OrderID, withdraw.Sum, expiresAt)
+	if err = row.Scan(&pending.ID, &pending.CreatedAt); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CreatePendingWithdraw: unable to rollback")
+		}
+
+		if isUniqueViolation(err) {
+			return model.PendingWithdraw{}, storage.ErrOrderAlreadyPaid
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("CreatePendingWithdraw: exec pending_withdrawals")
+		return model.PendingWithdraw{}, err
+	}
+
+	if err = saveLotConsumptions(ctx, tx, consumptions, 0, pending.ID); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CreatePendingWithdraw: exec point_lot_consumptions")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("CreatePendingWithdraw: unable to rollback")
+		}
+		return model.PendingWithdraw{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET balance = balance - $1 WHERE id = $2`, withdraw.Sum, withdraw.UserID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("CreatePendingWithdraw: exec users")
+		if err := tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("CreatePendingWithdraw: unable to rollback")
+		}
+		return model.PendingWithdraw{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("CreatePendingWithdraw: unable to commit")
+		return model.PendingWithdraw{}, err
+	}
+
+	return pending, nil
+}
+
+func (r withdrawRepository) ConfirmPendingWithdraw(ctx context.Context, userID int, id int) (model.Withdraw, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ConfirmPendingWithdraw: prepare transaction")
+		return model.Withdraw{}, err
+	}
+
+	withdraw := model.Withdraw{UserID: userID}
+
+	row := tx.QueryRowContext(ctx, `DELETE FROM pending_withdrawals
+		WHERE id = $1 AND user_id = $2 AND expires_at > current_timestamp RETURNING order_id, sum`, id, userID)
+	if err = row.Scan(&withdraw.OrderID, &withdraw.Sum); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ConfirmPendingWithdraw: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Withdraw{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("ConfirmPendingWithdraw: exec pending_withdrawals")
+		return model.Withdraw{}, err
+	}
+
+	row = tx.QueryRowContext(ctx, `INSERT INTO withdraw_log (user_id, sum, order_id) VALUES ($1, $2, $3)
+		RETURNING id, processed_at`, userID, withdraw.Sum, withdraw.OrderID)
+	if err = row.Scan(&withdraw.ID, &withdraw.ProcessedAt); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ConfirmPendingWithdraw: unable to rollback")
+		}
+
+		if isUniqueViolation(err) {
+			return model.Withdraw{}, storage.ErrOrderAlreadyPaid
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("ConfirmPendingWithdraw: exec withdraw_log")
+		return model.Withdraw{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE point_lot_consumptions SET withdraw_id = $1, pending_withdraw_id = NULL
+		WHERE pending_withdraw_id = $2`, withdraw.ID, id)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ConfirmPendingWithdraw: exec point_lot_consumptions")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ConfirmPendingWithdraw: unable to rollback")
+		}
+		return model.Withdraw{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("ConfirmPendingWithdraw: unable to commit")
+		return model.Withdraw{}, err
+	}
+
+	return withdraw, nil
+}
+
+func (r withdrawRepository) ReleaseExpiredWithdrawals(ctx context.Context) ([]model.PendingWithdraw, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReleaseExpiredWithdrawals: prepare transaction")
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `DELETE FROM pending_withdrawals WHERE expires_at <= current_timestamp
+		RETURNING id, user_id, order_id, sum, created_at, expires_at`)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReleaseExpiredWithdrawals: exec pending_withdrawals")
+		if err := tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("ReleaseExpiredWithdrawals: unable to rollback")
+		}
+		return nil, err
+	}
+
+	released := make([]model.PendingWithdraw, 0)
+	for rows.Next() {
+		var pending model.PendingWithdraw
+		err = rows.Scan(&pending.ID, &pending.UserID, &pending.OrderID, &pending.Sum, &pending.CreatedAt, &pending.ExpiresAt)
+		if err != nil {
+			rows.Close()
+			if err := tx.Rollback(); err != nil {
+				r.Log(ctx).Error().Err(err).Msgf("ReleaseExpiredWithdrawals: unable to rollback")
+			}
+			return nil, err
+		}
+
+		released = append(released, pending)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReleaseExpiredWithdrawals: query rows was error")
+		if err := tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("ReleaseExpiredWithdrawals: unable to rollback")
+		}
+		return nil, err
+	}
+
+	for _, pending := range released {
+		if err = restorePendingLots(ctx, tx, pending.ID); err != nil {
+			r.Log(ctx).Error().Err(err).Msg("ReleaseExpiredWithdrawals: restore point lots")
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				r.Log(ctx).Error().Err(rollbackErr).Msgf("ReleaseExpiredWithdrawals: unable to rollback")
+			}
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE users SET balance = balance + $1 WHERE id = $2`, pending.Sum, pending.UserID)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("ReleaseExpiredWithdrawals: exec users")
+			if err := tx.Rollback(); err != nil {
+				r.Log(ctx).Error().Err(err).Msgf("ReleaseExpiredWithdrawals: unable to rollback")
+			}
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("ReleaseExpiredWithdrawals: unable to commit")
+		return nil, err
+	}
+
+	return released, nil
+}
+
+func (r withdrawRepository) ReverseWithdraw(ctx context.Context, reversal model.WithdrawReversal) (model.WithdrawReversal, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReverseWithdraw: prepare transaction")
+		return model.WithdrawReversal{}, err
+	}
+
+	var sum, reversedSum model.Amount
+	row := tx.QueryRowContext(ctx, "SELECT user_id, sum, reversed_sum FROM withdraw_log WHERE id = $1 FOR UPDATE",
+		reversal.WithdrawID)
+	if err = row.Scan(&reversal.UserID, &sum, &reversedSum); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ReverseWithdraw: unable to rollback")
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.WithdrawReversal{}, storage.ErrNotFound
+		}
+
+		r.Log(ctx).Error().Err(err).Msg("ReverseWithdraw: invalid scan withdraw_log")
+		return model.WithdrawReversal{}, err
+	}
+
+	if reversal.UserID == reversal.AdminID {
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("ReverseWithdraw: unable to rollback")
+			return model.WithdrawReversal{}, err
+		}
+
+		return model.WithdrawReversal{}, storage.ErrOwnWithdraw
+	}
+
+	rest := sum - reversedSum
+	if reversal.Sum == 0 {
+		reversal.Sum = rest
+	}
+
+	if reversal.Sum <= 0 || reversal.Sum > rest {
+		if err = tx.Rollback(); err != nil {
+			r.Log(ctx).Error().Err(err).Msgf("ReverseWithdraw: unable to rollback")
+			return model.WithdrawReversal{}, err
+		}
+
+		return model.WithdrawReversal{}, storage.ErrReversalExceeds
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE withdraw_log SET reversed_sum = reversed_sum + $1 WHERE id = $2",
+		reversal.Sum, reversal.WithdrawID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReverseWithdraw: exec withdraw_log")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ReverseWithdraw: unable to rollback")
+		}
+		return model.WithdrawReversal{}, err
+	}
+
+	row = tx.QueryRowContext(ctx, `INSERT INTO withdraw_reversals (withdraw_id, sum, reason, admin_id)
+		VALUES ($1, $2, $3, $4) RETURNING id, created_at`, reversal.WithdrawID, reversal.Sum, reversal.Reason, reversal.AdminID)
+	if err = row.Scan(&reversal.ID, &reversal.CreatedAt); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReverseWithdraw: exec withdraw_reversals")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ReverseWithdraw: unable to rollback")
+		}
+		return model.WithdrawReversal{}, err
+	}
+
+	notConsumed, err := restoreWithdrawLots(ctx, tx, reversal.WithdrawID, reversal.Sum)
+	if err == nil && notConsumed > 0 {
+		// withdrawals made before point lots have no consumptions, their points come back as a new lot
+		err = addPointLot(ctx, tx, reversal.UserID, notConsumed, model.LotSourceReversal, reversal.ExpiresAt)
+	}
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReverseWithdraw: restore point lots")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ReverseWithdraw: unable to rollback")
+		}
+		return model.WithdrawReversal{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET balance = balance + $1 WHERE id = $2", reversal.Sum, reversal.UserID)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("ReverseWithdraw: exec users")
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			r.Log(ctx).Error().Err(rollbackErr).Msgf("ReverseWithdraw: unable to rollback")
+		}
+		return model.WithdrawReversal{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		r.Log(ctx).Error().Err(err).Msgf("ReverseWithdraw: unable to commit")
+		return model.WithdrawReversal{}, err
+	}
+
+	return reversal, nil
+}
+
+// PaidOrderIDs returns numbers of ids which are already paid with points or wait for confirmation
+func (r withdrawRepository) PaidOrderIDs(ctx context.Context, ids []model.OrderID) ([]model.OrderID, error) {
+	numbers := make([]string, 0, len(ids))
+	for _, id := range ids {
+		numbers = append(numbers, string(id))
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT order_id FROM withdraw_log WHERE order_id = ANY($1)
+		UNION SELECT order_id FROM pending_withdrawals WHERE order_id = ANY($1)`, pq.Array(numbers))
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("PaidOrderIDs: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	paid := make([]model.OrderID, 0)
+	for rows.Next() {
+		var orderID model.OrderID
+		if err = rows.Scan(&orderID); err != nil {
+			return nil, err
+		}
+
+		paid = append(paid, orderID)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("PaidOrderIDs: query rows was error")
+		return nil, err
+	}
+
+	return paid, nil
+}
+
+// checkOrderNotPaid returns ErrOrderAlreadyPaid when the order number was used by a withdrawal,
+// unique indexes guard concurrent withdrawals which pass the check
+func (r withdrawRepository) checkOrderNotPaid(ctx context.Context, tx *sql.Tx, orderID model.OrderID) error {
+	row := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM withdraw_log WHERE order_id = $1)
+		OR EXISTS (SELECT 1 FROM pending_withdrawals WHERE order_id = $1)`, orderID)
+
+	var paid bool
+	if err := row.Scan(&paid); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("checkOrderNotPaid: invalid scan")
+		return err
+	}
+
+	if paid {
+		return storage.ErrOrderAlreadyPaid
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	pgErr, ok := err.(pgx.PgError)
+
+	return ok && pgErr.Code == pgerrcode.UniqueViolation
+}
+
 func (r withdrawRepository) WithdrawLogsByUserID(ctx context.Context, userID int) ([]model.Withdraw, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, sum, processed_at, order_id 
+	rows, err := r.db.QueryContext(ctx, `SELECT id, sum, processed_at, order_id, reversed_sum
 		from withdraw_log WHERE user_id = $1 ORDER BY processed_at`, userID)
 
 	if err != nil {
@@ -97,11 +495,13 @@ func (r withdrawRepository) WithdrawLogsByUserID(ctx context.Context, userID int
 	withdrawLogs := make([]model.Withdraw, 0)
 	for rows.Next() {
 		withdrawLog := model.Withdraw{UserID: userID}
-		err = rows.Scan(&withdrawLog.ID, &withdrawLog.Sum, &withdrawLog.ProcessedAt, &withdrawLog.OrderID)
+		err = rows.Scan(&withdrawLog.ID, &withdrawLog.Sum, &withdrawLog.ProcessedAt, &withdrawLog.OrderID, &withdrawLog.ReversedSum)
 		if err != nil {
 			return nil, err
 		}
 
+		withdrawLog.Status = model.WithdrawStatusOf(withdrawLog.Sum, withdrawLog.ReversedSum)
+
 		withdrawLogs = append(withdrawLogs, withdrawLog)
 	}
 
@@ -113,6 +513,38 @@ func (r withdrawRepository) WithdrawLogsByUserID(ctx context.Context, userID int
 	return withdrawLogs, nil
 }
 
+func (r withdrawRepository) WithdrawLogs(ctx context.Context, afterID int, limit int) ([]model.Withdraw, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, sum, processed_at, order_id, reversed_sum
+		FROM withdraw_log WHERE id > $1 ORDER BY id LIMIT $2`, afterID, limit)
+	if err != nil {
+		r.Log(ctx).Error().Err(err).Msg("WithdrawLogs: invalid query")
+		return nil, err
+	}
+	defer rows.Close()
+
+	withdrawLogs := make([]model.Withdraw, 0)
+	for rows.Next() {
+		var withdrawLog model.Withdraw
+		err = rows.Scan(&withdrawLog.ID, &withdrawLog.UserID, &withdrawLog.Sum, &withdrawLog.ProcessedAt,
+			&withdrawLog.OrderID, &withdrawLog.ReversedSum)
+		if err != nil {
+			r.Log(ctx).Error().Err(err).Msg("WithdrawLogs: invalid scan")
+			return nil, err
+		}
+
+		withdrawLog.Status = model.WithdrawStatusOf(withdrawLog.Sum, withdrawLog.ReversedSum)
+
+		withdrawLogs = append(withdrawLogs, withdrawLog)
+	}
+
+	if err = rows.Err(); err != nil {
+		r.Log(ctx).Error().Err(err).Msg("WithdrawLogs: query rows was error")
+		return nil, err
+	}
+
+	return withdrawLogs, nil
+}
+
 func (r withdrawRepository) Log(ctx context.Context) *zerolog.Logger {
 	_, logger := logging.GetCtxLogger(ctx)
 	logger = logger.With().Str(logging.ServiceKey, "database withdrawRepository").Logger()
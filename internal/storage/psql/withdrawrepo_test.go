@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/storage"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -20,7 +21,7 @@ func Test_withdrawRepository_AmountWithdrawByUser(t *testing.T) {
 
 		row := sqlmock.NewRows([]string{"amount"}).
 			AddRow(1000)
-		mock.ExpectQuery("SELECT SUM\\(sum\\) as amount from withdraw_log GROUP BY user_id = \\$1").
+		mock.ExpectQuery("SELECT COALESCE\\(SUM\\(sum - reversed_sum\\), 0\\) as amount from withdraw_log WHERE user_id = \\$1").
 			WithArgs(666).
 			WillReturnRows(row)
 
@@ -45,10 +46,10 @@ func Test_withdrawRepository_WithdrawLogsByUserID(t *testing.T) {
 
 		now := time.Now()
 
-		rows := sqlmock.NewRows([]string{"id", "sum", "processed_at", "order_id"}).
-			AddRow(1, 1000, now, "123").
-			AddRow(2, 4312, now, "555")
-		mock.ExpectQuery("SELECT id, sum, processed_at, order_id from withdraw_log WHERE user_id = \\$1 ORDER BY processed_at").
+		rows := sqlmock.NewRows([]string{"id", "sum", "processed_at", "order_id", "reversed_sum"}).
+			AddRow(1, 1000, now, "123", 0).
+			AddRow(2, 4312, now, "555", 312)
+		mock.ExpectQuery("SELECT id, sum, processed_at, order_id, reversed_sum from withdraw_log WHERE user_id = \\$1 ORDER BY processed_at").
 			WithArgs(666).
 			WillReturnRows(rows)
 
@@ -57,7 +58,368 @@ func Test_withdrawRepository_WithdrawLogsByUserID(t *testing.T) {
 		require.Equal(t, err, nil)
 		require.Equal(t, result, []model.Withdraw{
 			{ID: 1, OrderID: "123", Sum: 1000, ProcessedAt: model.UploadedTime(now), UserID: 666},
-			{ID: 2, OrderID: "555", Sum: 4312, ProcessedAt: model.UploadedTime(now), UserID: 666},
+			{
+				ID:          2,
+				OrderID:     "555",
+				Sum:         4312,
+				ProcessedAt: model.UploadedTime(now),
+				UserID:      666,
+				ReversedSum: 312,
+				Status:      model.WithdrawPartiallyReversed,
+			},
 		})
 	})
 }
+
+func Test_withdrawRepository_WithdrawLogs(t *testing.T) {
+	t.Run("should return page of withdraw logs of all users", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		now := time.Now()
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "sum", "processed_at", "order_id", "reversed_sum"}).
+			AddRow(11, 666, 1000, now, "123", 1000).
+			AddRow(12, 777, 500, now, "555", 0)
+		mock.ExpectQuery("SELECT id, user_id, sum, processed_at, order_id, reversed_sum FROM withdraw_log WHERE id > \\$1 ORDER BY id LIMIT \\$2").
+			WithArgs(10, 2).
+			WillReturnRows(rows)
+
+		result, err := repo.WithdrawLogs(context.Background(), 10, 2)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, result, []model.Withdraw{
+			{
+				ID:          11,
+				OrderID:     "123",
+				Sum:         1000,
+				ProcessedAt: model.UploadedTime(now),
+				UserID:      666,
+				ReversedSum: 1000,
+				Status:      model.WithdrawReversed,
+			},
+			{ID: 12, OrderID: "555", Sum: 500, ProcessedAt: model.UploadedTime(now), UserID: 777},
+		})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_withdrawRepository_ProcessWithdraw(t *testing.T) {
+	t.Run("should reject already paid order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM withdraw_log WHERE order_id = \\$1\\)").
+			WithArgs(model.OrderID("123")).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		err = repo.ProcessWithdraw(context.Background(), model.Withdraw{OrderID: "123", Sum: 100, UserID: 666})
+
+		require.Equal(t, err, storage.ErrOrderAlreadyPaid)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("should consume the oldest point lots first", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(model.OrderID("123")).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery("SELECT balance FROM users").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000))
+		mock.ExpectQuery("SELECT id, remaining, expires_at FROM point_lots (.+) ORDER BY expires_at, id FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "remaining", "expires_at"}).
+				AddRow(1, 300, time.Now()).
+				AddRow(2, 700, time.Now().Add(time.Hour)))
+		mock.ExpectExec("UPDATE point_lots SET remaining = remaining - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(300), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE point_lots SET remaining = remaining - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(200), 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO withdraw_log").
+			WithArgs(666, model.Amount(500), model.OrderID("123")).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectExec("INSERT INTO point_lot_consumptions").
+			WithArgs(1, model.Amount(300), 5, 0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO point_lot_consumptions").
+			WithArgs(2, model.Amount(200), 5, 0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(500), 666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = repo.ProcessWithdraw(context.Background(), model.Withdraw{OrderID: "123", Sum: 500, UserID: 666})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_withdrawRepository_PaidOrderIDs(t *testing.T) {
+	t.Run("should return paid order numbers", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		mock.ExpectQuery("SELECT order_id FROM withdraw_log WHERE order_id = ANY\\(\\$1\\)").
+			WillReturnRows(sqlmock.NewRows([]string{"order_id"}).AddRow("123"))
+
+		paid, err := repo.PaidOrderIDs(context.Background(), []model.OrderID{"123", "555"})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, paid, []model.OrderID{"123"})
+	})
+}
+
+func Test_withdrawRepository_CreatePendingWithdraw(t *testing.T) {
+	t.Run("should reserve sum on balance", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		now := time.Now()
+		expiresAt := now.Add(time.Minute)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(model.OrderID("123")).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery("SELECT balance FROM users where id = \\$1 FOR UPDATE").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(100000))
+		mock.ExpectQuery("SELECT id, remaining, expires_at FROM point_lots").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "remaining", "expires_at"}).AddRow(1, 100000, expiresAt))
+		mock.ExpectExec("UPDATE point_lots SET remaining = remaining - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(50001), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO pending_withdrawals").
+			WithArgs(666, model.OrderID("123"), model.Amount(50001), expiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now))
+		mock.ExpectExec("INSERT INTO point_lot_consumptions").
+			WithArgs(1, model.Amount(50001), 0, 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(50001), 666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		pending, err := repo.CreatePendingWithdraw(context.Background(), model.Withdraw{OrderID: "123", Sum: 50001, UserID: 666}, expiresAt)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, pending, model.PendingWithdraw{
+			ID:        7,
+			OrderID:   "123",
+			Sum:       50001,
+			UserID:    666,
+			CreatedAt: model.UploadedTime(now),
+			ExpiresAt: model.UploadedTime(expiresAt),
+		})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("should return insufficient funds", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(model.OrderID("123")).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery("SELECT balance FROM users").
+			WithArgs(666).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(100))
+		mock.ExpectRollback()
+
+		_, err = repo.CreatePendingWithdraw(context.Background(), model.Withdraw{OrderID: "123", Sum: 50001, UserID: 666}, time.Now())
+
+		require.Equal(t, err, storage.ErrInsufficientFunds)
+	})
+}
+
+func Test_withdrawRepository_ConfirmPendingWithdraw(t *testing.T) {
+	t.Run("should return not found for expired withdraw", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM pending_withdrawals").
+			WithArgs(7, 666).
+			WillReturnRows(sqlmock.NewRows([]string{"order_id", "sum"}))
+		mock.ExpectRollback()
+
+		_, err = repo.ConfirmPendingWithdraw(context.Background(), 666, 7)
+
+		require.Equal(t, err, storage.ErrNotFound)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_withdrawRepository_ReleaseExpiredWithdrawals(t *testing.T) {
+	t.Run("should return reserved points to balance", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		now := time.Now()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM pending_withdrawals WHERE expires_at <= current_timestamp").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "order_id", "sum", "created_at", "expires_at"}).
+				AddRow(7, 666, "123", 50001, now, now))
+		mock.ExpectExec("UPDATE point_lots l SET remaining = l.remaining \\+ c.amount").
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM point_lot_consumptions WHERE pending_withdraw_id = \\$1").
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(50001), 666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		released, err := repo.ReleaseExpiredWithdrawals(context.Background())
+
+		require.Equal(t, err, nil)
+		require.Len(t, released, 1)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
+
+func Test_withdrawRepository_ReverseWithdraw(t *testing.T) {
+	t.Run("should reverse the rest of withdrawal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+		now := time.Now()
+		expiresAt := now.AddDate(1, 0, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, sum, reversed_sum FROM withdraw_log WHERE id = \\$1 FOR UPDATE").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "sum", "reversed_sum"}).AddRow(666, 1000, 300))
+		mock.ExpectExec("UPDATE withdraw_log SET reversed_sum = reversed_sum \\+ \\$1").
+			WithArgs(model.Amount(700), 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO withdraw_reversals").
+			WithArgs(7, model.Amount(700), "order cancelled", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+		mock.ExpectQuery("SELECT c.id, c.lot_id, c.amount FROM point_lot_consumptions c").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "lot_id", "amount"}).AddRow(10, 1, 500))
+		mock.ExpectExec("UPDATE point_lot_consumptions SET amount = amount - \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(500), 10).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE point_lots SET remaining = remaining \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(500), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO point_lots").
+			WithArgs(666, model.Amount(200), model.LotSourceReversal, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE users SET balance = balance \\+ \\$1 WHERE id = \\$2").
+			WithArgs(model.Amount(700), 666).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		reversal, err := repo.ReverseWithdraw(context.Background(), model.WithdrawReversal{
+			WithdrawID: 7,
+			Reason:     "order cancelled",
+			AdminID:    1,
+			ExpiresAt:  expiresAt,
+		})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, reversal, model.WithdrawReversal{
+			ID:         3,
+			WithdrawID: 7,
+			UserID:     666,
+			Sum:        700,
+			Reason:     "order cancelled",
+			AdminID:    1,
+			CreatedAt:  model.UploadedTime(now),
+			ExpiresAt:  expiresAt,
+		})
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("should reject reversal above the rest of withdrawal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, sum, reversed_sum FROM withdraw_log").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "sum", "reversed_sum"}).AddRow(666, 1000, 300))
+		mock.ExpectRollback()
+
+		_, err = repo.ReverseWithdraw(context.Background(), model.WithdrawReversal{WithdrawID: 7, Sum: 701, Reason: "r", AdminID: 1})
+
+		require.Equal(t, err, storage.ErrReversalExceeds)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+
+	t.Run("should reject reversal of own withdrawal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+		}
+
+		repo := &withdrawRepository{db: db}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT user_id, sum, reversed_sum FROM withdraw_log").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "sum", "reversed_sum"}).AddRow(1, 1000, 0))
+		mock.ExpectRollback()
+
+		_, err = repo.ReverseWithdraw(context.Background(), model.WithdrawReversal{WithdrawID: 7, Reason: "r", AdminID: 1})
+
+		require.Equal(t, err, storage.ErrOwnWithdraw)
+		require.Equal(t, mock.ExpectationsWereMet(), nil)
+	})
+}
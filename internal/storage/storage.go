@@ -5,34 +5,231 @@ import (
 	"errors"
 	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/provider"
+	"time"
 )
 
 //go:generate mockery --name=UserRepository
 //go:generate mockery --name=OrderRepository
 //go:generate mockery --name=WithdrawRepository
+//go:generate mockery --name=WebhookRepository
+//go:generate mockery --name=EventRepository
+//go:generate mockery --name=TokenRepository
+//go:generate mockery --name=LoginAttemptRepository
+//go:generate mockery --name=TwoFactorRepository
+//go:generate mockery --name=IdempotencyRepository
+//go:generate mockery --name=PointLotRepository
+//go:generate mockery --name=TransferRepository
+//go:generate mockery --name=CampaignRepository
+//go:generate mockery --name=ReferralRepository
+//go:generate mockery --name=TierRepository
+//go:generate mockery --name=AdjustmentRepository
+//go:generate mockery --name=AuditRepository
+//go:generate mockery --name=ReconciliationRepository
 
 type UserRepository interface {
 	CreateUser(ctx context.Context, user model.User) error
 	UserByUsername(ctx context.Context, username string) (model.User, error)
 	UserByID(ctx context.Context, id int) (model.User, error)
+	UpdatePassword(ctx context.Context, userID int, hash string, pepperID string) error
+	PepperUsage(ctx context.Context) ([]model.PepperUsage, error)
+	// DeleteUser anonymizes the user, orders and withdrawals are kept for accounting
+	DeleteUser(ctx context.Context, userID int) error
+	SearchUsers(ctx context.Context, query string, limit int) ([]model.UserSummary, error)
+	UserSummary(ctx context.Context, id int) (model.UserSummary, error)
+	// LockUser keeps the time of the first lock when the reason is updated
+	LockUser(ctx context.Context, id int, reason string) error
+	UnlockUser(ctx context.Context, id int) error
+	SetRole(ctx context.Context, id int, role model.Role) error
+	// PromoteAdmins grants admin role to the users and returns the number of promoted ones
+	PromoteAdmins(ctx context.Context, usernames []string) (int, error)
 }
 
 type OrderRepository interface {
 	OrderByID(ctx context.Context, id model.OrderID) (model.Order, error)
 	CreateOrder(ctx context.Context, order model.Order) error
+	CreateOrders(ctx context.Context, userID int, ids []model.OrderID) ([]model.OrderUploadResult, error)
 	OrdersByStatus(ctx context.Context, status model.Status) ([]model.Order, error)
 	OrdersByUserID(ctx context.Context, userID int) ([]model.Order, error)
-	UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse) error
+	// UpdateForAccrual credits accrual to the balance as a point lot expiring at expiresAt,
+	// bonuses of the order are marked pending when it becomes PROCESSED.
+	// ErrOrderFinalized is returned when the order got a final status meanwhile, e.g. by an admin override.
+	UpdateForAccrual(ctx context.Context, order model.Order, accrual provider.AccrualResponse, expiresAt time.Time) error
+	// PendingBonusOrders returns processed orders whose bonuses are not applied yet, oldest first
+	PendingBonusOrders(ctx context.Context, limit int) ([]model.Order, error)
+	CompleteBonuses(ctx context.Context, id model.OrderID) error
+	// AccrualVolume returns sum of accruals of processed orders uploaded since the time
+	AccrualVolume(ctx context.Context, userID int, since time.Time) (model.Amount, error)
+	// AccrualVolumes returns volume of every not deleted user, users without orders have zero volume
+	AccrualVolumes(ctx context.Context, since time.Time) ([]model.AccrualVolume, error)
+	// OverrideOrder sets status and accrual of the order, the difference with the credited accrual
+	// is applied to the balance. Bonuses of the previous accrual are taken back and the processed order
+	// is marked pending for bonuses of the new one. ErrInsufficientFunds is returned when the points are already spent.
+	OverrideOrder(ctx context.Context, id model.OrderID, status model.Status, accrual model.Amount, expiresAt time.Time) (model.OrderOverride, error)
 }
 
 type WithdrawRepository interface {
 	ProcessWithdraw(ctx context.Context, withdraw model.Withdraw) error
+	// CreatePendingWithdraw reserves the sum on the balance until the withdrawal is confirmed or expired
+	CreatePendingWithdraw(ctx context.Context, withdraw model.Withdraw, expiresAt time.Time) (model.PendingWithdraw, error)
+	// ConfirmPendingWithdraw moves not expired pending withdrawal to withdraw log, ErrNotFound otherwise
+	ConfirmPendingWithdraw(ctx context.Context, userID int, id int) (model.Withdraw, error)
+	// ReleaseExpiredWithdrawals returns reserved points of expired withdrawals to the balance
+	ReleaseExpiredWithdrawals(ctx context.Context) ([]model.PendingWithdraw, error)
+	PaidOrderIDs(ctx context.Context, ids []model.OrderID) ([]model.OrderID, error)
+	// ReverseWithdraw returns the sum to the balance, zero sum reverses the rest of the withdrawal.
+	// ErrOwnWithdraw is returned when the withdrawal belongs to the admin of the reversal.
+	ReverseWithdraw(ctx context.Context, reversal model.WithdrawReversal) (model.WithdrawReversal, error)
 	WithdrawLogsByUserID(ctx context.Context, userID int) ([]model.Withdraw, error)
+	// WithdrawLogs returns withdrawals of all users in order of id starting after afterID
+	WithdrawLogs(ctx context.Context, afterID int, limit int) ([]model.Withdraw, error)
 	AmountWithdrawByUser(ctx context.Context, userID int) (model.Amount, error)
 }
 
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook model.Webhook) (model.Webhook, error)
+	WebhooksByUserID(ctx context.Context, userID int) ([]model.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID int, id int) error
+	CreateDeliveries(ctx context.Context, userID int, event string, payload string) error
+	DeliveriesByWebhookID(ctx context.Context, userID int, webhookID int) ([]model.WebhookDelivery, error)
+	ClaimDueDeliveries(ctx context.Context, limit int) ([]model.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery model.WebhookDelivery) error
+}
+
+type EventRepository interface {
+	Notify(ctx context.Context, channel string, payload string) error
+	Listen(ctx context.Context, channel string) (<-chan string, error)
+}
+
+// LoginAttemptRepository counts failed logins, a missing key is returned as zero LoginAttempt
+type LoginAttemptRepository interface {
+	LoginAttempt(ctx context.Context, key string) (model.LoginAttempt, error)
+	// RegisterFailure increments failures of the key, the counter starts over when the first failure is older than window
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (model.LoginAttempt, error)
+	Block(ctx context.Context, key string, until time.Time) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+	DeleteExpiredLoginAttempts(ctx context.Context, before time.Time) error
+}
+
+type TwoFactorRepository interface {
+	TwoFactor(ctx context.Context, userID int) (model.TwoFactor, error)
+	// SaveTOTPSecret starts enrollment, the secret replaces not yet confirmed one
+	SaveTOTPSecret(ctx context.Context, userID int, secret string) error
+	// EnableTOTP confirms enrollment and replaces recovery codes
+	EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error
+	DisableTOTP(ctx context.Context, userID int) error
+	// UseTOTPStep remembers the step, false is returned when the step or a later one was already used
+	UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+}
+
+type IdempotencyRepository interface {
+	// CreateIdempotencyKey stores the record unless not expired record with the same key exists, false is returned then
+	CreateIdempotencyKey(ctx context.Context, record model.IdempotencyRecord) (bool, error)
+	IdempotencyKey(ctx context.Context, userID int, key string) (model.IdempotencyRecord, error)
+	SaveIdempotencyResponse(ctx context.Context, record model.IdempotencyRecord) error
+	DeleteIdempotencyKey(ctx context.Context, userID int, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) error
+}
+
+// PointLotRepository expires points, lots are credited and consumed by order and withdraw repositories
+type PointLotRepository interface {
+	ExpirePointLots(ctx context.Context, limit int) ([]model.PointExpiration, error)
+	// ExpiringPoints is the sum of not yet expired points which expire before the time
+	ExpiringPoints(ctx context.Context, userID int, before time.Time) (model.Amount, error)
+}
+
+type TransferRepository interface {
+	// Transfer moves the sum from sender to recipient, ErrTransferLimitExceeded is returned when the sender
+	// would exceed dailyLimit of the current day, zero dailyLimit disables the check
+	Transfer(ctx context.Context, transfer model.Transfer, dailyLimit model.Amount) (model.Transfer, error)
+	// TransfersByUserID returns sent and received transfers of the user
+	TransfersByUserID(ctx context.Context, userID int) ([]model.TransferLog, error)
+}
+
+type CampaignRepository interface {
+	CreateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error)
+	UpdateCampaign(ctx context.Context, campaign model.Campaign) (model.Campaign, error)
+	DeleteCampaign(ctx context.Context, id int) error
+	Campaign(ctx context.Context, id int) (model.Campaign, error)
+	Campaigns(ctx context.Context) ([]model.Campaign, error)
+	// ActiveCampaigns returns campaigns running at the time
+	ActiveCampaigns(ctx context.Context, at time.Time) ([]model.Campaign, error)
+	// AddCampaignBonus credits the campaign bonus of the order accrual limited by user cap of the campaign as a point lot.
+	// Zero bonus is returned when the processed order doesn't wait for its bonuses.
+	AddCampaignBonus(ctx context.Context, bonus model.CampaignBonus, campaign model.Campaign, expiresAt time.Time) (model.CampaignBonus, error)
+}
+
+type ReferralRepository interface {
+	// ReferrerByCode returns not deleted owner of the referral code
+	ReferrerByCode(ctx context.Context, code string) (model.User, error)
+	ReferralCode(ctx context.Context, userID int) (string, error)
+	ReferralsByReferrer(ctx context.Context, referrerID int) ([]model.Referral, error)
+	// RewardReferral credits bonuses of the referral once, zero reward is returned for orders which don't wait
+	// for their bonuses, not referred users and already rewarded referrals. Referrer gets nothing when deleted
+	// or after maxRewards rewarded referrals, zero disables the cap.
+	RewardReferral(ctx context.Context, reward model.ReferralReward, maxRewards int, expiresAt time.Time) (model.ReferralReward, error)
+}
+
+type TierRepository interface {
+	UserTier(ctx context.Context, userID int) (model.Tier, error)
+	// UpdateTier returns false when the user already has the tier
+	UpdateTier(ctx context.Context, userID int, tier model.Tier) (bool, error)
+	// AddTierBonus credits bonus of the tier on top of the order accrual once per order. Already credited bonus
+	// is returned with zero Sum, as well as the bonus of the order which doesn't wait for its bonuses.
+	AddTierBonus(ctx context.Context, bonus model.TierBonus, expiresAt time.Time) (model.TierBonus, error)
+}
+
+type AdjustmentRepository interface {
+	// CreateAdjustment saves pending adjustment. It is applied at once in the same transaction when its sum together with
+	// adjustments the author applied to the user without approval since the time doesn't exceed threshold.
+	CreateAdjustment(ctx context.Context, adjustment model.Adjustment, threshold model.Amount, since time.Time, expiresAt time.Time) (model.Adjustment, error)
+	// ApproveAdjustment applies pending adjustment, ErrAdjustmentDecided is returned for decided ones
+	ApproveAdjustment(ctx context.Context, id int, approverID int, expiresAt time.Time) (model.Adjustment, error)
+	RejectAdjustment(ctx context.Context, id int, deciderID int) (model.Adjustment, error)
+	Adjustment(ctx context.Context, id int) (model.Adjustment, error)
+	// Adjustments returns adjustments with the status, all of them for empty status
+	Adjustments(ctx context.Context, status model.AdjustmentStatus) ([]model.Adjustment, error)
+}
+
+// AuditRepository is append-only, entries are never changed or deleted
+type AuditRepository interface {
+	AddAuditEntry(ctx context.Context, entry model.AuditEntry) error
+	AuditEntries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error)
+}
+
+// ReconciliationRepository compares balances with point lots and the history of operations
+type ReconciliationRepository interface {
+	Reconciliation(ctx context.Context, userID int) (model.Reconciliation, error)
+	// Reconciliations returns not deleted users in order of id starting after afterID
+	Reconciliations(ctx context.Context, afterID int, limit int) ([]model.Reconciliation, error)
+	// CorrectBalance brings the balance and point lots of the user to the ledger, the balance difference is
+	// written as applied adjustment with RECONCILIATION reason. Zero correction is returned for consistent user,
+	// ErrInsufficientFunds when the ledger is negative or expired lots can't cover the difference.
+	CorrectBalance(ctx context.Context, userID int, actorID int, expiresAt time.Time) (model.ReconciliationCorrection, error)
+}
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token model.RefreshToken) error
+	RefreshTokenByHash(ctx context.Context, hash string) (model.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, usedID int, next model.RefreshToken) error
+	RevokeSession(ctx context.Context, sessionID string) error
+	RevokeUserSessions(ctx context.Context, userID int) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string, sessionID string) (bool, error)
+	DeleteExpired(ctx context.Context) error
+}
+
 var (
 	ErrNotFound           = errors.New("storage: not found")
 	ErrLoginAlreadyExists = errors.New("storage: login already exists")
 	ErrInsufficientFunds  = errors.New("storage: insufficient funds")
+	ErrOrderAlreadyPaid   = errors.New("storage: order already paid with points")
+	ErrReversalExceeds    = errors.New("storage: reversal exceeds withdrawn sum")
+	ErrTokenAlreadyUsed   = errors.New("storage: refresh token already used")
+	ErrReferralCodeTaken  = errors.New("storage: referral code already taken")
+
+	ErrTransferLimitExceeded = errors.New("storage: daily transfer limit exceeded")
+	ErrAdjustmentDecided     = errors.New("storage: adjustment already decided")
+	ErrOrderFinalized        = errors.New("storage: order already has a final status")
+	ErrOwnWithdraw           = errors.New("storage: withdrawal belongs to the admin")
 )
@@ -9,7 +9,10 @@ import (
 type ContextKey string
 
 const (
-	userKey ContextKey = "userKey"
+	userKey      ContextKey = "userKey"
+	claimsKey    ContextKey = "claimsKey"
+	clientIPKey  ContextKey = "clientIPKey"
+	requestIDKey ContextKey = "requestIDKey"
 )
 
 func (c ContextKey) String() string {
@@ -30,6 +33,36 @@ func User(ctx context.Context) *model.User {
 	return nil
 }
 
+func WithClaims(ctx context.Context, claims *model.Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+func Claims(ctx context.Context) *model.Claims {
+	if ctxValue := ctx.Value(claimsKey); ctxValue != nil {
+		if claims, ok := ctxValue.(*model.Claims); ok {
+			return claims
+		}
+	}
+
+	return nil
+}
+
+// WithRequestInfo stores the client IP and the request id, the audit log records them with every action
+func WithRequestInfo(ctx context.Context, ip string, requestID string) context.Context {
+	ctx = context.WithValue(ctx, clientIPKey, ip)
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
 const (
 	contextKeyPrefix = "gophermartLogging-"
 )
@@ -11,3 +11,17 @@ func SetTicker(fn func(), seconds time.Duration) {
 		fn()
 	}
 }
+
+// SetDailyTicker calls fn every day at the time of day given as offset from midnight UTC
+func SetDailyTicker(fn func(), at time.Duration) {
+	now := time.Now().UTC()
+	next := now.Truncate(24 * time.Hour).Add(at)
+	if !next.After(now) {
+		next = next.Add(24 * time.Hour)
+	}
+
+	time.Sleep(next.Sub(now))
+	fn()
+
+	SetTicker(fn, 24*time.Hour)
+}
@@ -0,0 +1,16 @@
+package helpers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RandomHex returns hex encoded string of n cryptographically secure random bytes
+func RandomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,263 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"os"
+	"sort"
+)
+
+// LegacyKeyID is assumed for tokens issued without kid header
+const LegacyKeyID = "default"
+
+var (
+	ErrUnknownKey        = errors.New("unknown signing key")
+	ErrAlgorithmMismatch = errors.New("token algorithm doesn't match the key")
+	ErrNoSigningKey      = errors.New("signing key is not configured")
+)
+
+// Key is a single entry of the keyring. A key without private part is only used to verify
+// tokens, which allows old keys to stay valid for a while after the rotation.
+type Key struct {
+	ID         string
+	Algorithm  string
+	Secret     []byte
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+func (k Key) canSign() bool {
+	if k.Algorithm == jwt.SigningMethodHS256.Alg() {
+		return len(k.Secret) > 0
+	}
+
+	return k.PrivateKey != nil
+}
+
+func (k Key) signKey() interface{} {
+	if k.Algorithm == jwt.SigningMethodHS256.Alg() {
+		return k.Secret
+	}
+
+	return k.PrivateKey
+}
+
+func (k Key) verifyKey() interface{} {
+	if k.Algorithm == jwt.SigningMethodHS256.Alg() {
+		return k.Secret
+	}
+
+	return k.PublicKey
+}
+
+// Keyring signs tokens with the current key and verifies them with any known key by kid
+type Keyring struct {
+	keys      map[string]Key
+	signingID string
+}
+
+// New creates keyring, signingID selects the key for new tokens
+func New(signingID string, keys ...Key) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]Key, len(keys)), signingID: signingID}
+
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("keyring: key id is required")
+		}
+
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("keyring: duplicate key %q", key.ID)
+		}
+
+		switch key.Algorithm {
+		case jwt.SigningMethodHS256.Alg():
+			if len(key.Secret) == 0 {
+				return nil, fmt.Errorf("keyring: key %q has no secret", key.ID)
+			}
+		case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg():
+			if key.PublicKey == nil {
+				return nil, fmt.Errorf("keyring: key %q has no public key", key.ID)
+			}
+		default:
+			return nil, fmt.Errorf("keyring: key %q has unsupported algorithm %q", key.ID, key.Algorithm)
+		}
+
+		k.keys[key.ID] = key
+	}
+
+	signing, ok := k.keys[signingID]
+	if !ok || !signing.canSign() {
+		return nil, fmt.Errorf("keyring: %w: %q", ErrNoSigningKey, signingID)
+	}
+
+	return k, nil
+}
+
+// NewHMAC creates keyring with the single HS256 key
+func NewHMAC(secret string) (*Keyring, error) {
+	return New(LegacyKeyID, Key{ID: LegacyKeyID, Algorithm: jwt.SigningMethodHS256.Alg(), Secret: []byte(secret)})
+}
+
+// Sign signs claims with the current signing key and puts its id into kid header
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	key := k.keys[k.signingID]
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.signKey())
+}
+
+// Keyfunc resolves verification key by kid header for jwt.Parse
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, ErrAlgorithmMismatch
+	}
+
+	return key.verifyKey(), nil
+}
+
+// Keys returns all keys ordered by id
+func (k *Keyring) Keys() []Key {
+	keys := make([]Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	return keys
+}
+
+// SigningKeyID returns id of the key used for new tokens
+func (k *Keyring) SigningKeyID() string {
+	return k.signingID
+}
+
+// String doesn't expose key material, so the keyring is safe to log
+func (k *Keyring) String() string {
+	ids := make([]string, 0, len(k.keys))
+	for _, key := range k.Keys() {
+		ids = append(ids, key.ID+":"+key.Algorithm)
+	}
+
+	return fmt.Sprintf("keyring{signing: %s, keys: %v}", k.signingID, ids)
+}
+
+type keySpec struct {
+	ID             string `json:"kid"`
+	Algorithm      string `json:"alg"`
+	Secret         string `json:"secret,omitempty"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	PublicKeyFile  string `json:"public_key_file,omitempty"`
+}
+
+// LoadFile reads keys from JSON file with list of {kid, alg, secret, private_key_file, public_key_file}.
+// PEM files are expected in PKCS#1/PKCS#8 for private and PKIX for public keys.
+func LoadFile(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []keySpec
+	if err = json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("keyring: parse %s: %w", path, err)
+	}
+
+	keys := make([]Key, 0, len(specs))
+	for _, spec := range specs {
+		key, err := spec.load()
+		if err != nil {
+			return nil, fmt.Errorf("keyring: key %q: %w", spec.ID, err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (s keySpec) load() (Key, error) {
+	key := Key{ID: s.ID, Algorithm: s.Algorithm}
+
+	switch s.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		key.Secret = []byte(s.Secret)
+		return key, nil
+	case jwt.SigningMethodRS256.Alg():
+		if s.PrivateKeyFile != "" {
+			private, err := readPEM(s.PrivateKeyFile, func(b []byte) (interface{}, error) {
+				return jwt.ParseRSAPrivateKeyFromPEM(b)
+			})
+			if err != nil {
+				return Key{}, err
+			}
+
+			key.PrivateKey = private
+			key.PublicKey = &private.(*rsa.PrivateKey).PublicKey
+		}
+
+		if s.PublicKeyFile != "" {
+			public, err := readPEM(s.PublicKeyFile, func(b []byte) (interface{}, error) {
+				return jwt.ParseRSAPublicKeyFromPEM(b)
+			})
+			if err != nil {
+				return Key{}, err
+			}
+
+			key.PublicKey = public
+		}
+	case jwt.SigningMethodEdDSA.Alg():
+		if s.PrivateKeyFile != "" {
+			private, err := readPEM(s.PrivateKeyFile, func(b []byte) (interface{}, error) {
+				return jwt.ParseEdPrivateKeyFromPEM(b)
+			})
+			if err != nil {
+				return Key{}, err
+			}
+
+			key.PrivateKey = private
+			key.PublicKey = private.(ed25519.PrivateKey).Public()
+		}
+
+		if s.PublicKeyFile != "" {
+			public, err := readPEM(s.PublicKeyFile, func(b []byte) (interface{}, error) {
+				return jwt.ParseEdPublicKeyFromPEM(b)
+			})
+			if err != nil {
+				return Key{}, err
+			}
+
+			key.PublicKey = public
+		}
+	default:
+		return Key{}, fmt.Errorf("unsupported algorithm %q", s.Algorithm)
+	}
+
+	return key, nil
+}
+
+func readPEM(path string, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(data)
+}
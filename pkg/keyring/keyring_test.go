@@ -0,0 +1,297 @@
+package keyring
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	require.Equal(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600), nil)
+
+	return path
+}
+
+func writeFile(t *testing.T, dir string, name string, data string) string {
+	path := filepath.Join(dir, name)
+	require.Equal(t, os.WriteFile(path, []byte(data), 0600), nil)
+
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Equal(t, err, nil)
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.Equal(t, err, nil)
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.Equal(t, err, nil)
+	edPrivateDER, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	require.Equal(t, err, nil)
+	edPublicDER, err := x509.MarshalPKIXPublicKey(edPublic)
+	require.Equal(t, err, nil)
+
+	rsaPrivateFile := writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	rsaPublicFile := writePEM(t, dir, "rsa.pub", "PUBLIC KEY", rsaPublicDER)
+	edPrivateFile := writePEM(t, dir, "ed.pem", "PRIVATE KEY", edPrivateDER)
+	edPublicFile := writePEM(t, dir, "ed.pub", "PUBLIC KEY", edPublicDER)
+	brokenFile := writeFile(t, dir, "broken.pem", "not a pem")
+
+	tests := []struct {
+		name    string
+		keys    string
+		want    []Key
+		wantErr bool
+	}{
+		{
+			name: "hmac secret",
+			keys: `[{"kid":"h1","alg":"HS256","secret":"s3cr3t"}]`,
+			want: []Key{{ID: "h1", Algorithm: "HS256", Secret: []byte("s3cr3t")}},
+		},
+		{
+			name: "rsa private key derives public key",
+			keys: `[{"kid":"r1","alg":"RS256","private_key_file":"` + rsaPrivateFile + `"}]`,
+			want: []Key{{ID: "r1", Algorithm: "RS256", PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey}},
+		},
+		{
+			name: "rsa public key only verifies",
+			keys: `[{"kid":"r0","alg":"RS256","public_key_file":"` + rsaPublicFile + `"}]`,
+			want: []Key{{ID: "r0", Algorithm: "RS256", PublicKey: &rsaKey.PublicKey}},
+		},
+		{
+			name: "ed25519 keys",
+			keys: `[{"kid":"e1","alg":"EdDSA","private_key_file":"` + edPrivateFile + `"},` +
+				`{"kid":"e0","alg":"EdDSA","public_key_file":"` + edPublicFile + `"}]`,
+			want: []Key{
+				{ID: "e1", Algorithm: "EdDSA", PrivateKey: edPrivate, PublicKey: edPublic},
+				{ID: "e0", Algorithm: "EdDSA", PublicKey: edPublic},
+			},
+		},
+		{
+			name:    "invalid json",
+			keys:    `{"kid":"h1"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported algorithm",
+			keys:    `[{"kid":"n1","alg":"none"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "missing pem file",
+			keys:    `[{"kid":"r1","alg":"RS256","private_key_file":"` + filepath.Join(dir, "missing.pem") + `"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "broken pem file",
+			keys:    `[{"kid":"r1","alg":"RS256","private_key_file":"` + brokenFile + `"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "rsa pem given for ed25519",
+			keys:    `[{"kid":"e1","alg":"EdDSA","public_key_file":"` + rsaPublicFile + `"}]`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, dir, "keys"+string(rune('a'+i))+".json", tt.keys)
+
+			keys, err := LoadFile(path)
+
+			require.Equal(t, err != nil, tt.wantErr, err)
+			if !tt.wantErr {
+				require.Equal(t, keys, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadFile(filepath.Join(dir, "missing.json"))
+
+		require.Equal(t, errors.Is(err, os.ErrNotExist), true)
+	})
+}
+
+func TestNew(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Equal(t, err, nil)
+
+	tests := []struct {
+		name      string
+		signingID string
+		keys      []Key
+		wantErr   error
+	}{
+		{
+			name:      "signing key is selected by id",
+			signingID: "r1",
+			keys: []Key{
+				{ID: "h1", Algorithm: "HS256", Secret: []byte("secret")},
+				{ID: "r1", Algorithm: "RS256", PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey},
+			},
+		},
+		{
+			name:      "unknown signing key",
+			signingID: "r2",
+			keys:      []Key{{ID: "r1", Algorithm: "RS256", PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey}},
+			wantErr:   ErrNoSigningKey,
+		},
+		{
+			name:      "signing key without private part",
+			signingID: "r1",
+			keys:      []Key{{ID: "r1", Algorithm: "RS256", PublicKey: &rsaKey.PublicKey}},
+			wantErr:   ErrNoSigningKey,
+		},
+		{
+			name:      "duplicate kid",
+			signingID: "h1",
+			keys: []Key{
+				{ID: "h1", Algorithm: "HS256", Secret: []byte("first")},
+				{ID: "h1", Algorithm: "HS256", Secret: []byte("second")},
+			},
+			wantErr: errors.New(`keyring: duplicate key "h1"`),
+		},
+		{
+			name:      "hmac without secret",
+			signingID: "h1",
+			keys:      []Key{{ID: "h1", Algorithm: "HS256"}},
+			wantErr:   errors.New(`keyring: key "h1" has no secret`),
+		},
+		{
+			name:      "key without id",
+			signingID: "",
+			keys:      []Key{{Algorithm: "HS256", Secret: []byte("secret")}},
+			wantErr:   errors.New("keyring: key id is required"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := New(tt.signingID, tt.keys...)
+
+			if tt.wantErr == nil {
+				require.Equal(t, err, nil)
+				require.Equal(t, k.SigningKeyID(), tt.signingID)
+				return
+			}
+
+			require.NotEqual(t, err, nil)
+			if errors.Is(tt.wantErr, ErrNoSigningKey) {
+				require.Equal(t, errors.Is(err, ErrNoSigningKey), true)
+			} else {
+				require.Equal(t, err.Error(), tt.wantErr.Error())
+			}
+		})
+	}
+}
+
+func TestKeyring_Keyfunc(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Equal(t, err, nil)
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.Equal(t, err, nil)
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.Equal(t, err, nil)
+
+	k, err := New("r2",
+		Key{ID: LegacyKeyID, Algorithm: "HS256", Secret: []byte("legacy")},
+		Key{ID: "r1", Algorithm: "RS256", PublicKey: &rsaKey.PublicKey},
+		Key{ID: "r2", Algorithm: "RS256", PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey},
+		Key{ID: "e1", Algorithm: "EdDSA", PrivateKey: edPrivate, PublicKey: edPrivate.Public()},
+	)
+	require.Equal(t, err, nil)
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.StandardClaims{Subject: "666"})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+
+		signed, err := token.SignedString(key)
+		require.Equal(t, err, nil)
+
+		return signed
+	}
+
+	current, err := k.Sign(jwt.StandardClaims{Subject: "666"})
+	require.Equal(t, err, nil)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "token of the signing key", token: current},
+		{name: "token of the old key selected by kid", token: sign(jwt.SigningMethodRS256, "r1", rsaKey)},
+		{name: "token of ed25519 key", token: sign(jwt.SigningMethodEdDSA, "e1", edPrivate)},
+		{name: "token without kid uses legacy key", token: sign(jwt.SigningMethodHS256, "", []byte("legacy"))},
+		{name: "unknown kid", token: sign(jwt.SigningMethodHS256, "h9", []byte("legacy")), wantErr: ErrUnknownKey},
+		{
+			// the public key is known to everyone, it must not be accepted as HMAC secret
+			name:    "hmac token signed with public key of rsa kid",
+			token:   sign(jwt.SigningMethodHS256, "r1", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublicDER})),
+			wantErr: ErrAlgorithmMismatch,
+		},
+		{name: "rsa token with kid of ed25519 key", token: sign(jwt.SigningMethodRS256, "e1", rsaKey), wantErr: ErrAlgorithmMismatch},
+		{name: "legacy hmac key with other secret", token: sign(jwt.SigningMethodHS256, LegacyKeyID, []byte("other")), wantErr: jwt.ErrSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := jwt.Parse(tt.token, k.Keyfunc)
+
+			if tt.wantErr == nil {
+				require.Equal(t, err, nil)
+				require.Equal(t, token.Valid, true)
+				return
+			}
+
+			var validationErr *jwt.ValidationError
+			require.Equal(t, errors.As(err, &validationErr), true, err)
+			require.Equal(t, errors.Is(validationErr.Inner, tt.wantErr), true, err)
+		})
+	}
+
+	t.Run("signs with kid of the signing key", func(t *testing.T) {
+		token, _, err := new(jwt.Parser).ParseUnverified(current, jwt.MapClaims{})
+
+		require.Equal(t, err, nil)
+		require.Equal(t, token.Header["kid"], "r2")
+		require.Equal(t, token.Method.Alg(), "RS256")
+	})
+}
+
+func TestKeyring_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Equal(t, err, nil)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.Equal(t, err, nil)
+
+	k, err := New("h1",
+		Key{ID: "h1", Algorithm: "HS256", Secret: []byte("secret")},
+		Key{ID: "r1", Algorithm: "RS256", PrivateKey: rsaKey, PublicKey: &rsaKey.PublicKey},
+		Key{ID: "e1", Algorithm: "EdDSA", PrivateKey: edPrivate, PublicKey: edPublic},
+	)
+	require.Equal(t, err, nil)
+
+	set := k.JWKS()
+
+	require.Equal(t, len(set.Keys), 2)
+	require.Equal(t, set.Keys[0].KeyID, "e1")
+	require.Equal(t, set.Keys[0].KeyType, "OKP")
+	require.Equal(t, set.Keys[0].Curve, "Ed25519")
+	require.Equal(t, set.Keys[1].KeyID, "r1")
+	require.Equal(t, set.Keys[1].KeyType, "RSA")
+	require.Equal(t, set.Keys[1].E, "AQAB")
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/go-chi/chi/v5/middleware"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// Idempotency replays stored response for requests repeated with the same Idempotency-Key header.
+// Key reused with another body is rejected, requests without the header are passed through.
+// Responses with 5xx status are not stored, so such requests can be retried.
+func Idempotency(idempotency service.IdempotencyService) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(rw, r)
+				return
+			}
+
+			ctx := r.Context()
+
+			_, logger := logging.GetCtxLogger(ctx)
+			logger = logger.With().Str(logging.ServiceKey, "Idempotency").Logger()
+
+			user := context.User(ctx)
+			if user == nil {
+				http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				http.Error(rw, "invalid Idempotency-Key", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				logger.Trace().Err(err).Msg("Idempotency: invalid read body")
+				http.Error(rw, "invalid read body", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record, replay, err := idempotency.Begin(ctx, user.ID, key, requestFingerprint(r, body))
+			if err != nil {
+				switch {
+				case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+					http.Error(rw, "Idempotency-Key is already used with another request", http.StatusUnprocessableEntity)
+				case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+					http.Error(rw, "request with this Idempotency-Key is in progress", http.StatusConflict)
+				default:
+					logger.Error().Err(err).Msg("Idempotency: begin")
+					http.Error(rw, "internal error", http.StatusInternalServerError)
+				}
+
+				return
+			}
+
+			if replay {
+				logger.Trace().Int("userID", user.ID).Str("key", key).Msg("Idempotency: replay response")
+
+				if record.ContentType != "" {
+					rw.Header().Set("Content-Type", record.ContentType)
+				}
+				rw.Header().Set(IdempotentReplayedHeader, "true")
+				rw.WriteHeader(record.StatusCode)
+				rw.Write(record.Response)
+				return
+			}
+
+			var response bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(rw, r.ProtoMajor)
+			ww.Tee(&response)
+
+			processed := false
+			defer func() {
+				if processed {
+					return
+				}
+
+				// handler panicked or failed, the key is released for the retry
+				if err := idempotency.Cancel(ctx, user.ID, key); err != nil {
+					logger.Error().Err(err).Msg("Idempotency: cancel")
+				}
+			}()
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			if status >= http.StatusInternalServerError {
+				return
+			}
+
+			// the request has taken effect, so the key is kept even when the response can't be stored
+			processed = true
+
+			err = idempotency.Complete(ctx, model.IdempotencyRecord{
+				UserID:      user.ID,
+				Key:         key,
+				StatusCode:  status,
+				ContentType: ww.Header().Get("Content-Type"),
+				Response:    response.Bytes(),
+			})
+			if err != nil {
+				logger.Error().Err(err).Msg("Idempotency: complete")
+			}
+		})
+	}
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write([]byte(strconv.Itoa(len(body)) + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
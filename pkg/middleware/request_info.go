@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/djokcik/gophermart/pkg/context"
+	"github.com/go-chi/chi/v5/middleware"
+	"net"
+	"net/http"
+)
+
+// RequestInfo stores the client IP and the request id in the context,
+// it has to be used after middleware.RealIP and middleware.RequestID
+func RequestInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		ctx := context.WithRequestInfo(r.Context(), ip, middleware.GetReqID(r.Context()))
+
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/djokcik/gophermart/internal/model"
+	"github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"net/http"
+)
+
+// RequireRole lets through only users with one of the roles, it has to be used after UserContext
+func RequireRole(roles ...model.Role) func(next http.Handler) http.Handler {
+	allowed := make(map[model.Role]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			_, logger := logging.GetCtxLogger(ctx)
+			logger = logger.With().Str(logging.ServiceKey, "RequireRole").Logger()
+
+			user := context.User(ctx)
+			if user == nil {
+				http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			if !allowed[user.Role] {
+				logger.Warn().Int("userID", user.ID).Str("role", string(user.Role)).Str("url", r.URL.Path).
+					Msg("RequireRole: access denied")
+				http.Error(rw, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"github.com/djokcik/gophermart/internal/service"
+	"github.com/djokcik/gophermart/pkg/context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"net/http"
+)
+
+// RequireTwoFactor lets through only sessions opened with the second factor when the user has enabled it.
+// With enrollmentRequired users without the second factor are rejected as well.
+func RequireTwoFactor(twoFactor service.TwoFactorService, enrollmentRequired bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			_, logger := logging.GetCtxLogger(ctx)
+			logger = logger.With().Str(logging.ServiceKey, "RequireTwoFactor").Logger()
+
+			user := context.User(ctx)
+			claims := context.Claims(ctx)
+			if user == nil || claims == nil {
+				http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			enabled, err := twoFactor.Enabled(ctx, user.ID)
+			if err != nil {
+				logger.Error().Err(err).Msg("RequireTwoFactor: check 2fa")
+				http.Error(rw, "internal error", http.StatusInternalServerError)
+				return
+			}
+
+			if !enabled && enrollmentRequired {
+				logger.Trace().Int("userID", user.ID).Msg("RequireTwoFactor: 2fa is not enabled")
+				http.Error(rw, "2fa enrollment required", http.StatusForbidden)
+				return
+			}
+
+			if enabled && !claims.MFA {
+				logger.Trace().Int("userID", user.ID).Msg("RequireTwoFactor: session without 2fa")
+				http.Error(rw, "2fa required", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
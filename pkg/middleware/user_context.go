@@ -1,7 +1,6 @@
 package middleware
 
 import (
-	"github.com/djokcik/gophermart/internal/config"
 	"github.com/djokcik/gophermart/internal/handler"
 	"github.com/djokcik/gophermart/internal/model"
 	"github.com/djokcik/gophermart/internal/service"
@@ -11,7 +10,7 @@ import (
 	"net/http"
 )
 
-func UserContext(userRepo storage.UserRepository, auth service.UserUtilsService, cfg config.Config) func(next http.Handler) http.Handler {
+func UserContext(userRepo storage.UserRepository, auth service.UserUtilsService, tokens service.TokenService) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -33,7 +32,7 @@ func UserContext(userRepo storage.UserRepository, auth service.UserUtilsService,
 				token = cookie.Value
 			}
 
-			id, err := auth.ParseToken(token, cfg.Key)
+			claims, err := tokens.ParseAccessToken(ctx, token)
 			if err != nil {
 				status := http.StatusBadRequest
 				if err == model.ErrInvalidAccessToken {
@@ -45,15 +44,22 @@ func UserContext(userRepo storage.UserRepository, auth service.UserUtilsService,
 				return
 			}
 
-			user, err := userRepo.UserByID(ctx, id)
+			user, err := userRepo.UserByID(ctx, claims.ID)
 			if err != nil {
-				logger.Trace().Err(err).Msgf("RequireUser: user with id %d not found", id)
+				logger.Trace().Err(err).Msgf("RequireUser: user with id %d not found", claims.ID)
 				http.Error(rw, "Unauthorized", http.StatusUnauthorized)
 
 				return
 			}
 
+			if user.LockedAt != nil {
+				logger.Trace().Int("userID", user.ID).Msg("RequireUser: account is locked")
+				http.Error(rw, "account is locked", http.StatusForbidden)
+				return
+			}
+
 			ctx = context.WithUser(r.Context(), &user)
+			ctx = context.WithClaims(ctx, &claims)
 			logger.Trace().Str("user", user.Username).Msg("RequireUser: successfully authorized")
 			next.ServeHTTP(rw, r.WithContext(ctx))
 		})
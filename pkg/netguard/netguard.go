@@ -0,0 +1,55 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned when the connection goes to the server itself or to the internal network
+var ErrForbiddenAddress = errors.New("netguard: address is not public")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), it isn't reachable from the internet either
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublic returns false for loopback, private, link-local, multicast and unspecified addresses
+func IsPublic(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsMulticast() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// IsPublicHost checks the host of an url before it is resolved: ip literals and localhost names.
+// Other names are checked by Control when they are resolved.
+func IsPublicHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return IsPublic(ip)
+	}
+
+	return true
+}
+
+// Control is set to net.Dialer.Control, it runs on the resolved address right before the connection,
+// so a name which resolves to an internal address or changes the address after a check (DNS rebinding) is refused
+func Control(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if !IsPublic(net.ParseIP(host)) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{ip: "8.8.8.8", public: true},
+		{ip: "2001:4860:4860::8888", public: true},
+		{ip: "127.0.0.1", public: false},
+		{ip: "::1", public: false},
+		{ip: "10.1.2.3", public: false},
+		{ip: "172.16.0.1", public: false},
+		{ip: "192.168.1.1", public: false},
+		{ip: "169.254.169.254", public: false},
+		{ip: "fe80::1", public: false},
+		{ip: "fc00::1", public: false},
+		{ip: "0.0.0.0", public: false},
+		{ip: "::", public: false},
+		{ip: "100.64.0.1", public: false},
+		{ip: "224.0.0.1", public: false},
+		{ip: "::ffff:127.0.0.1", public: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			require.Equal(t, IsPublic(net.ParseIP(tt.ip)), tt.public)
+		})
+	}
+}
+
+func TestIsPublicHost(t *testing.T) {
+	tests := []struct {
+		host   string
+		public bool
+	}{
+		{host: "partner.app", public: true},
+		{host: "93.184.216.34", public: true},
+		{host: "localhost", public: false},
+		{host: "LOCALHOST.", public: false},
+		{host: "api.localhost", public: false},
+		{host: "169.254.169.254", public: false},
+		{host: "::1", public: false},
+		{host: "", public: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			require.Equal(t, IsPublicHost(tt.host), tt.public)
+		})
+	}
+}
+
+func TestControl(t *testing.T) {
+	t.Run("should refuse connection to loopback", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		dialer := net.Dialer{Control: Control}
+
+		_, err := dialer.DialContext(context.Background(), "tcp", server.Listener.Addr().String())
+
+		require.Equal(t, errors.Is(err, ErrForbiddenAddress), true)
+	})
+}
@@ -0,0 +1,37 @@
+package password
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// LegacyBcrypt verifies hashes of bcrypt(password+pepper) created before Argon2id.
+// bcrypt ignores input after 72 bytes, so new hashes must not be created with it.
+type LegacyBcrypt struct{}
+
+func (b LegacyBcrypt) Hash(password string, pepper string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password+pepper), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (b LegacyBcrypt) Verify(password string, pepper string, encoded string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password+pepper))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+
+	return err
+}
+
+func (b LegacyBcrypt) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b LegacyBcrypt) Outdated(string) bool {
+	return true
+}
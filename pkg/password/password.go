@@ -0,0 +1,73 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+var (
+	ErrMismatch      = errors.New("password: hash and password mismatch")
+	ErrUnknownScheme = errors.New("password: unknown hash format")
+	ErrInvalidHash   = errors.New("password: invalid hash")
+)
+
+// Scheme is a password hashing algorithm. Every scheme decides itself how the pepper is mixed in.
+type Scheme interface {
+	Hash(password string, pepper string) (string, error)
+	Verify(password string, pepper string, encoded string) error
+	// Identify reports whether the hash was produced by the scheme
+	Identify(encoded string) bool
+	// Outdated reports whether the hash was produced with other parameters than the current ones
+	Outdated(encoded string) bool
+}
+
+// Hasher hashes passwords with the current scheme and verifies hashes of any known scheme
+type Hasher struct {
+	current Scheme
+	legacy  []Scheme
+}
+
+func NewHasher(current Scheme, legacy ...Scheme) *Hasher {
+	return &Hasher{current: current, legacy: legacy}
+}
+
+// NewDefaultHasher hashes with Argon2id and accepts bcrypt hashes of the previous versions
+func NewDefaultHasher() *Hasher {
+	return NewHasher(DefaultArgon2id, LegacyBcrypt{})
+}
+
+func (h *Hasher) Hash(password string, pepper string) (string, error) {
+	return h.current.Hash(password, pepper)
+}
+
+// Verify checks password against the hash, rehash is true when the hash should be replaced by the current scheme
+func (h *Hasher) Verify(password string, pepper string, encoded string) (rehash bool, err error) {
+	if h.current.Identify(encoded) {
+		if err = h.current.Verify(password, pepper, encoded); err != nil {
+			return false, err
+		}
+
+		return h.current.Outdated(encoded), nil
+	}
+
+	for _, scheme := range h.legacy {
+		if scheme.Identify(encoded) {
+			if err = scheme.Verify(password, pepper, encoded); err != nil {
+				return false, err
+			}
+
+			return true, nil
+		}
+	}
+
+	return false, ErrUnknownScheme
+}
+
+// prehash mixes the pepper with HMAC-SHA256, so the password of any length fits into the hash input
+func prehash(password string, pepper string) []byte {
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(password))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,120 @@
+package password
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// testArgon2id keeps tests fast, parameters don't change the format
+var testArgon2id = Argon2id{Memory: 64, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestHasher_Verify(t *testing.T) {
+	t.Run("should verify hash of the current scheme", func(t *testing.T) {
+		h := NewHasher(testArgon2id, LegacyBcrypt{})
+
+		hash, err := h.Hash("password", "pepper")
+		require.Equal(t, err, nil)
+		require.Equal(t, testArgon2id.Identify(hash), true)
+
+		rehash, err := h.Verify("password", "pepper", hash)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, rehash, false)
+	})
+
+	t.Run("should salt every hash", func(t *testing.T) {
+		first, err := testArgon2id.Hash("password", "pepper")
+		require.Equal(t, err, nil)
+		second, err := testArgon2id.Hash("password", "pepper")
+		require.Equal(t, err, nil)
+
+		require.NotEqual(t, first, second)
+	})
+
+	t.Run("should reject wrong password", func(t *testing.T) {
+		h := NewHasher(testArgon2id)
+
+		hash, err := h.Hash("password", "pepper")
+		require.Equal(t, err, nil)
+
+		_, err = h.Verify("Password", "pepper", hash)
+
+		require.Equal(t, err, ErrMismatch)
+	})
+
+	t.Run("should reject other pepper", func(t *testing.T) {
+		h := NewHasher(testArgon2id)
+
+		hash, err := h.Hash("password", "pepper")
+		require.Equal(t, err, nil)
+
+		_, err = h.Verify("password", "other", hash)
+
+		require.Equal(t, err, ErrMismatch)
+	})
+
+	t.Run("should ask to rehash with the current parameters", func(t *testing.T) {
+		old := testArgon2id
+		old.Time = 2
+
+		hash, err := old.Hash("password", "pepper")
+		require.Equal(t, err, nil)
+
+		rehash, err := NewHasher(testArgon2id).Verify("password", "pepper", hash)
+
+		require.Equal(t, err, nil)
+		require.Equal(t, rehash, true)
+	})
+
+	t.Run("should verify legacy bcrypt hash and ask to rehash", func(t *testing.T) {
+		legacy, err := LegacyBcrypt{}.Hash("password", "pepper")
+		require.Equal(t, err, nil)
+
+		h := NewHasher(testArgon2id, LegacyBcrypt{})
+
+		rehash, err := h.Verify("password", "pepper", legacy)
+		require.Equal(t, err, nil)
+		require.Equal(t, rehash, true)
+
+		_, err = h.Verify("password", "other", legacy)
+		require.Equal(t, err, ErrMismatch)
+	})
+
+	t.Run("should reject unknown scheme", func(t *testing.T) {
+		_, err := NewHasher(testArgon2id, LegacyBcrypt{}).Verify("password", "pepper", "plain")
+
+		require.Equal(t, err, ErrUnknownScheme)
+	})
+}
+
+func TestArgon2id_Verify(t *testing.T) {
+	valid, err := testArgon2id.Hash("password", "pepper")
+	require.Equal(t, err, nil)
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "zero time", encoded: "$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA"},
+		{name: "zero threads", encoded: "$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA"},
+		{name: "zero memory", encoded: "$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA"},
+		{name: "negative time", encoded: "$argon2id$v=19$m=64,t=-1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA"},
+		{name: "threads overflow", encoded: "$argon2id$v=19$m=64,t=1,p=256$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA"},
+		{name: "other version", encoded: "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA"},
+		{name: "missing parameters", encoded: "$argon2id$v=19$m=64$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA"},
+		{name: "invalid salt", encoded: "$argon2id$v=19$m=64,t=1,p=1$!!!$aGFzaA"},
+		{name: "empty hash", encoded: "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$"},
+		{name: "missing parts", encoded: "$argon2id$v=19$m=64,t=1,p=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, testArgon2id.Verify("password", "pepper", tt.encoded), ErrInvalidHash)
+			require.Equal(t, testArgon2id.Outdated(tt.encoded), true)
+		})
+	}
+
+	t.Run("valid hash", func(t *testing.T) {
+		require.Equal(t, testArgon2id.Verify("password", "pepper", valid), nil)
+		require.Equal(t, testArgon2id.Outdated(valid), false)
+	})
+}
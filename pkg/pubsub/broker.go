@@ -0,0 +1,59 @@
+package pubsub
+
+import (
+	"sync"
+)
+
+// Broker is an in-process publish/subscribe hub. Messages are dropped for subscribers
+// which do not keep up, so a slow consumer never blocks publishers.
+type Broker struct {
+	mu          sync.RWMutex
+	buffer      int
+	subscribers map[string]map[chan interface{}]struct{}
+}
+
+func NewBroker(buffer int) *Broker {
+	return &Broker{
+		buffer:      buffer,
+		subscribers: make(map[string]map[chan interface{}]struct{}),
+	}
+}
+
+// Subscribe returns channel with messages of the topic and function which cancels subscription
+func (b *Broker) Subscribe(topic string) (<-chan interface{}, func()) {
+	ch := make(chan interface{}, b.buffer)
+
+	b.mu.Lock()
+	if _, ok := b.subscribers[topic]; !ok {
+		b.subscribers[topic] = make(map[chan interface{}]struct{})
+	}
+	b.subscribers[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.subscribers[topic], ch)
+			if len(b.subscribers[topic]) == 0 {
+				delete(b.subscribers, topic)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Publish sends message to all subscribers of the topic
+func (b *Broker) Publish(topic string, msg interface{}) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[topic] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package totp
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 seed "12345678901234567890" of RFC 6238 in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 Appendix B, SHA1; the RFC lists 8 digits, 6 digit codes are their last digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().String(), func(t *testing.T) {
+			code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+
+			require.Equal(t, err, nil)
+			require.Equal(t, code, tt.code)
+		})
+	}
+
+	t.Run("should accept lower case secret", func(t *testing.T) {
+		code, err := Code("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", Step(time.Unix(59, 0)))
+
+		require.Equal(t, err, nil)
+		require.Equal(t, code, "287082")
+	})
+
+	t.Run("should reject invalid secret", func(t *testing.T) {
+		_, err := Code("not base32!", 1)
+
+		require.NotEqual(t, err, nil)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	codeOf := func(step int64) string {
+		code, err := Code(rfcSecret, step)
+		require.Equal(t, err, nil)
+
+		return code
+	}
+
+	tests := []struct {
+		name  string
+		code  string
+		step  int64
+		valid bool
+	}{
+		{name: "current step", code: codeOf(current), step: current, valid: true},
+		{name: "previous step", code: codeOf(current - 1), step: current - 1, valid: true},
+		{name: "next step", code: codeOf(current + 1), step: current + 1, valid: true},
+		{name: "two steps ago", code: codeOf(current - 2)},
+		{name: "two steps ahead", code: codeOf(current + 2)},
+		{name: "short code", code: codeOf(current)[:5]},
+		{name: "long code", code: codeOf(current) + "0"},
+		{name: "empty code", code: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now)
+
+			require.Equal(t, ok, tt.valid)
+			require.Equal(t, step, tt.step)
+		})
+	}
+
+	t.Run("should accept code at the edges of its period", func(t *testing.T) {
+		start := time.Unix(current*Period, 0)
+		code := codeOf(current)
+
+		for _, at := range []time.Time{start, start.Add(Period*time.Second - time.Second)} {
+			step, ok := Validate(rfcSecret, code, at)
+
+			require.Equal(t, ok, true)
+			require.Equal(t, step, current)
+		}
+	})
+
+	t.Run("should return the step of the code, so a replay in the next period is detected", func(t *testing.T) {
+		code := codeOf(current)
+
+		first, ok := Validate(rfcSecret, code, now)
+		require.Equal(t, ok, true)
+
+		replayed, ok := Validate(rfcSecret, code, now.Add(Period*time.Second))
+		require.Equal(t, ok, true)
+
+		require.Equal(t, replayed, first)
+	})
+
+	t.Run("should reject code of the other secret", func(t *testing.T) {
+		other, err := GenerateSecret()
+		require.Equal(t, err, nil)
+
+		_, ok := Validate(other, codeOf(current), now)
+
+		require.Equal(t, ok, false)
+	})
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	require.Equal(t, err, nil)
+	second, err := GenerateSecret()
+	require.Equal(t, err, nil)
+
+	require.Equal(t, len(first), 32)
+	require.NotEqual(t, first, second)
+
+	_, err = Code(first, 1)
+	require.Equal(t, err, nil)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Gophermart", "user@mail", rfcSecret))
+
+	require.Equal(t, err, nil)
+	require.Equal(t, uri.Scheme, "otpauth")
+	require.Equal(t, uri.Host, "totp")
+	require.Equal(t, uri.Path, "/Gophermart:user@mail")
+	require.Equal(t, uri.Query().Get("secret"), rfcSecret)
+	require.Equal(t, uri.Query().Get("issuer"), "Gophermart")
+	require.Equal(t, uri.Query().Get("digits"), "6")
+	require.Equal(t, uri.Query().Get("period"), "30")
+}
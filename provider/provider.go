@@ -7,12 +7,17 @@ import (
 )
 
 //go:generate mockery --name=AccrualClient
+//go:generate mockery --name=WebhookClient
 
 type (
 	AccrualClient interface {
 		GetOrder(ctx context.Context, orderID model.OrderID) (AccrualResponse, error)
 	}
 
+	WebhookClient interface {
+		Send(ctx context.Context, url string, headers map[string]string, body []byte) (int, error)
+	}
+
 	AccrualResponse struct {
 		Order   model.OrderID `json:"order"`
 		Status  model.Status  `json:"status"`
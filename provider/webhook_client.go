@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"github.com/djokcik/gophermart/pkg/logging"
+	"github.com/djokcik/gophermart/pkg/netguard"
+	"github.com/rs/zerolog"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+type webhookClient struct {
+	client httpClient
+}
+
+func (o webhookClient) Send(ctx context.Context, url string, headers map[string]string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		o.Log(ctx).Error().Err(err).Msg("request was interrupted")
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		o.Log(ctx).Warn().Err(err).Msg("request ended with error")
+		return 0, err
+	}
+
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	return res.StatusCode, nil
+}
+
+func (o webhookClient) Log(ctx context.Context) *zerolog.Logger {
+	_, logger := logging.GetCtxLogger(ctx)
+	logger = logger.With().Str(logging.ServiceKey, "webhookClient").Logger()
+
+	return &logger
+}
+
+// NewWebhookClient sends only to public addresses: urls are given by users, the check runs on every dial,
+// proxies from the environment and redirects are not followed, so they can't lead the request to the internal network
+func NewWebhookClient() WebhookClient {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: netguard.Control}
+
+	return &webhookClient{
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 5 * time.Second},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}